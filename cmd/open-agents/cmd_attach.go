@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/open-agents/bridge/internal/control"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// detachKey is Ctrl-], the telnet escape
+const detachKey = 0x1d
+
+var attachCmd = &cobra.Command{
+	Use:   "attach <session-id>",
+	Short: "Attach this terminal to a running session",
+	Long: `Attach this terminal to a running session. The terminal sees the same
+stream as the web dashboard.
+
+PTY sessions are mirrored as they are: keystrokes go to the agent and the
+session follows the size of this terminal. Press Ctrl-] to detach.
+
+ACP sessions are shown as a chat: each line you type is sent as a prompt.
+Lines starting with / are commands: /cancel, /interrupt <prompt>,
+/approve <id> [option], /deny <id> [option] and /detach (or Ctrl-D).
+
+A terminal controls a session nobody else controls; otherwise it watches
+read-only until control is released, or takes control with --takeover.
+
+The session ID may be abbreviated to any unique prefix.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runAttach,
+}
+
+var (
+	attachDevice   string
+	attachTakeover bool
+)
+
+func init() {
+	attachCmd.Flags().StringVarP(&attachDevice, "device", "d", "", "Device name (default: current device)")
+	attachCmd.Flags().BoolVar(&attachTakeover, "takeover", false, "Take control from the client controlling the session")
+}
+
+func runAttach(cmd *cobra.Command, args []string) error {
+	c, err := dialBridge(attachDevice)
+	if err != nil {
+		return err
+	}
+	info, err := resolveLiveSession(c, args[0])
+	if err != nil {
+		return err
+	}
+	clientID := fmt.Sprintf("attach-%d", os.Getpid())
+	stream, err := c.Attach(info.ID, control.AttachRequest{ClientID: clientID, Takeover: attachTakeover})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if info.Protocol == "pty" {
+		return attachTerminal(stream, info, clientID)
+	}
+	return attachChat(c, stream, info, clientID)
+}
+
+// controlNotice describes who controls the session from this terminal's view
+func controlNotice(payload map[string]interface{}, clientID string) string {
+	ctrl, _ := payload["controller"].(map[string]interface{})
+	if ctrl == nil {
+		return "nobody controls the session; type to take control"
+	}
+	if ctrl["clientId"] == clientID {
+		return "this terminal controls the session"
+	}
+	return fmt.Sprintf("read-only: the session is controlled by %v client %v (attach with --takeover to take control)", ctrl["kind"], ctrl["clientId"])
+}
+
+// attachTerminal mirrors a PTY session in raw mode until Ctrl-] or the end of the session
+func attachTerminal(stream *control.Attached, info control.SessionInfo, clientID string) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("attaching to a PTY session needs a terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	// Notices go on their own line; raw mode needs explicit \r\n
+	notice := func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stdout, "\r\n[open-agents] "+format+"\r\n", args...)
+	}
+	resize := func() {
+		if cols, rows, err := term.GetSize(fd); err == nil {
+			stream.Resize(cols, rows)
+		}
+	}
+
+	stopResize := watchResize(resize)
+	defer stopResize()
+
+	var once sync.Once
+	done := make(chan string, 2)
+	finish := func(reason string) { once.Do(func() { done <- reason }) }
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				finish("")
+				return
+			}
+			data := buf[:n]
+			if i := bytes.IndexByte(data, detachKey); i >= 0 {
+				if i > 0 {
+					stream.Input(data[:i])
+				}
+				finish("detached")
+				return
+			}
+			stream.Input(data)
+		}
+	}()
+
+	go func() {
+		readOnlyShown := false
+		for {
+			ev, err := stream.Next()
+			if err != nil {
+				finish("connection to the bridge closed")
+				return
+			}
+			switch ev.Type {
+			case control.EventAttached:
+				if sb, _ := ev.Payload["scrollback"].(string); sb != "" {
+					os.Stdout.WriteString(sb)
+				}
+				notice("attached to %s (%s); Ctrl-] detaches; %s", info.ID, info.CLIType, controlNotice(ev.Payload, clientID))
+				// A resize makes full-screen CLIs redraw for this terminal
+				resize()
+			case "chat:response", "session:output":
+				if text, ok := ev.Payload["content"].(string); ok {
+					os.Stdout.WriteString(text)
+				}
+			case control.EventError:
+				if ev.Payload["code"] == "not_controller" {
+					if !readOnlyShown {
+						notice("%v", ev.Payload["error"])
+						readOnlyShown = true
+					}
+					continue
+				}
+				notice("%v", ev.Payload["error"])
+			case "control:changed":
+				readOnlyShown = false
+				notice("%s", controlNotice(ev.Payload, clientID))
+			case "agent:status":
+				// A PTY session only goes idle when its CLI exits
+				if ev.Payload["status"] == "idle" {
+					finish("the CLI exited")
+					return
+				}
+			case control.EventDetached:
+				finish(fmt.Sprintf("%v", ev.Payload["reason"]))
+				return
+			}
+		}
+	}()
+
+	if reason := <-done; reason != "" {
+		notice("%s", reason)
+	}
+	return nil
+}
+
+// attachChat shows an ACP session line by line and sends typed lines as prompts
+func attachChat(c *control.Client, stream *control.Attached, info control.SessionInfo, clientID string) error {
+	var outMu sync.Mutex
+	say := func(format string, args ...interface{}) {
+		outMu.Lock()
+		defer outMu.Unlock()
+		fmt.Printf(format, args...)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		midLine := false // the agent's reply is streaming
+		endLine := func() {
+			if midLine {
+				say("\n")
+				midLine = false
+			}
+		}
+		for {
+			ev, err := stream.Next()
+			if err != nil {
+				endLine()
+				say("[open-agents] connection to the bridge closed\n")
+				return
+			}
+			p := ev.Payload
+			switch ev.Type {
+			case control.EventAttached:
+				say("[open-agents] attached to %s (%s, %s); %s\n", info.ID, info.CLIType, info.WorkDir, controlNotice(p, clientID))
+				say("[open-agents] type a prompt; /cancel, /interrupt, /approve, /deny, /detach\n")
+			case "chat:prompt":
+				endLine()
+				say("\n> %v\n", p["content"])
+			case "chat:response":
+				if text, ok := p["content"].(string); ok && text != "" {
+					say("%s", text)
+					midLine = !strings.HasSuffix(text, "\n")
+				}
+			case "tool:call":
+				endLine()
+				tc, _ := p["toolCall"].(map[string]interface{})
+				say("  ⚙ %v [%v]\n", tc["name"], tc["status"])
+			case "permission:request":
+				endLine()
+				say("  🔐 permission %v: %v", p["id"], p["toolName"])
+				if d, _ := p["description"].(string); d != "" {
+					say(" - %s", oneLine(d, 100))
+				}
+				if r, _ := p["risk"].(string); r != "" {
+					say(" (%s risk)", r)
+				}
+				say("\n     /approve %v or /deny %v\n", p["id"], p["id"])
+			case "session:queue":
+				if p["reason"] == "queued" {
+					items, _ := p["items"].([]interface{})
+					say("  (queued, %d waiting)\n", len(items))
+				}
+			case "session:cancelled":
+				endLine()
+				say("  (turn cancelled)\n")
+			case "session:error":
+				if id, _ := p["clientId"].(string); id != "" && id != clientID {
+					continue // addressed to another client
+				}
+				endLine()
+				say("  ✖ %v\n", p["error"])
+			case "control:changed":
+				endLine()
+				say("[open-agents] %s\n", controlNotice(p, clientID))
+			case "agent:status":
+				if p["status"] == "idle" {
+					endLine()
+				}
+			case control.EventError:
+				endLine()
+				say("  ✖ %v\n", p["error"])
+			case control.EventDetached:
+				endLine()
+				say("[open-agents] %v\n", p["reason"])
+				return
+			}
+		}
+	}()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case line, ok := <-lines:
+			if !ok || strings.TrimSpace(line) == "/detach" || strings.ContainsRune(line, detachKey) {
+				return nil
+			}
+			if err := chatCommand(c, info.ID, clientID, line); err != nil {
+				say("  ✖ %v\n", err)
+			}
+		}
+	}
+}
+
+// chatCommand sends a typed line: a prompt or one of the / commands
+func chatCommand(c *control.Client, sessionID, clientID, line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if !strings.HasPrefix(line, "/") {
+		return c.Send(sessionID, control.SendRequest{Content: line, ClientID: clientID})
+	}
+
+	fields := strings.Fields(line)
+	arg := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	switch fields[0] {
+	case "/cancel":
+		return c.Cancel(sessionID, control.StopRequest{ClientID: clientID})
+	case "/interrupt":
+		prompt := strings.TrimSpace(strings.TrimPrefix(line, "/interrupt"))
+		if prompt == "" {
+			return fmt.Errorf("usage: /interrupt <prompt>")
+		}
+		return c.Send(sessionID, control.SendRequest{Content: prompt, Interrupt: true, ClientID: clientID})
+	case "/approve", "/deny":
+		if arg(1) == "" {
+			return fmt.Errorf("usage: %s <id> [option]", fields[0])
+		}
+		return c.Resolve(arg(1), control.Decision{SessionID: sessionID, Approved: fields[0] == "/approve", OptionID: arg(2), ClientID: clientID})
+	}
+	return fmt.Errorf("unknown command %s", fields[0])
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/open-agents/bridge/internal/audit"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Verify and export the local audit log",
+	Long: `The bridge keeps an append-only, hash-chained audit log of permission
+requests and decisions, files written and commands run for agents, session
+starts and stops, synced config and rules, and scanner alerts.
+
+The chain is keyed with a per-device key kept under ~/.open-agents/keys,
+outside the audit directory. Keep a copy of the key elsewhere and pass it
+with --key to verify a log independently of the device.`,
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the audit log for tampering",
+	Long: `Check that no entry of the audit log was modified, removed, reordered or
+cut off the end by anyone without the audit key. Exits with status 1 when
+the log does not verify.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runAuditVerify,
+}
+
+var auditExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a compliance report (json or csv)",
+	Long: `Export audit entries with the verification result and a summary of
+what they cover. JSON reports hold the entries as recorded; CSV has one row
+per entry with a readable summary.`,
+	Example: `  open-agents audit export --since 2026-10-01 -o october.json
+  open-agents audit export --format csv --type permission. --since 24h`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runAuditExport,
+}
+
+var (
+	auditDevice  string
+	auditFile    string
+	auditKeyFile string
+	auditJSON    bool
+	auditFormat  string
+	auditOutput  string
+	auditSince   string
+	auditUntil   string
+	auditSession string
+	auditTypes   []string
+)
+
+func init() {
+	auditCmd.AddCommand(auditVerifyCmd, auditExportCmd)
+	for _, c := range []*cobra.Command{auditVerifyCmd, auditExportCmd} {
+		c.Flags().StringVarP(&auditDevice, "device", "d", "", "Device name (default: current device)")
+		c.Flags().StringVar(&auditFile, "file", "", "Audit log to read (default: the device's)")
+		c.Flags().StringVar(&auditKeyFile, "key", "", "Audit key to verify with (default: the device's)")
+	}
+	auditVerifyCmd.Flags().BoolVar(&auditJSON, "json", false, "Print JSON")
+	auditExportCmd.Flags().StringVarP(&auditFormat, "format", "f", "json", "Output format: json or csv")
+	auditExportCmd.Flags().StringVarP(&auditOutput, "output", "o", "", "Write to file instead of stdout")
+	auditExportCmd.Flags().StringVar(&auditSince, "since", "", "Only entries from this time (RFC 3339, YYYY-MM-DD, or a duration ago like 24h)")
+	auditExportCmd.Flags().StringVar(&auditUntil, "until", "", "Only entries before this time")
+	auditExportCmd.Flags().StringVar(&auditSession, "session", "", "Only entries of this session")
+	auditExportCmd.Flags().StringSliceVar(&auditTypes, "type", nil, `Only entries of these types; "permission." selects every permission type`)
+}
+
+// auditDeviceName returns the device whose log is read
+func auditDeviceName() string {
+	if cfg, err := loadDeviceConfig(auditDevice); err == nil {
+		return cfg.DeviceName
+	}
+	return ""
+}
+
+// auditPath returns the audit log to read
+func auditPath() string {
+	if auditFile != "" {
+		return auditFile
+	}
+	return audit.PathFor(auditDeviceName())
+}
+
+// auditKey reads the key the log is chained with
+func auditKey() ([]byte, error) {
+	path := auditKeyFile
+	if path == "" {
+		path = audit.KeyFile(auditDeviceName())
+	}
+	key, err := audit.ReadKey(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no audit key at %s", path)
+	}
+	return key, err
+}
+
+// verifyAudit verifies the log at path with the audit key
+func verifyAudit(path string) (audit.Report, error) {
+	key, err := auditKey()
+	if err != nil {
+		return audit.Report{}, err
+	}
+	return audit.Verify(path, key)
+}
+
+func runAuditVerify(cmd *cobra.Command, args []string) error {
+	path := auditPath()
+	r, err := verifyAudit(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no audit log at %s", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	if auditJSON {
+		if err := printJSON(r); err != nil {
+			return err
+		}
+	} else if r.OK() {
+		fmt.Printf("Audit log OK: %d entries, last #%d %s\n", r.Entries, r.LastSeq, shortHash(r.LastHash))
+	} else {
+		fmt.Printf("Audit log FAILED verification: %d entries, %d problem(s)\n", r.Entries, len(r.Problems))
+		for _, p := range r.Problems {
+			where := "head"
+			if p.Line > 0 {
+				where = fmt.Sprintf("line %d", p.Line)
+			}
+			fmt.Printf("  %s: %s\n", where, p.Reason)
+		}
+	}
+	if !r.OK() {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runAuditExport(cmd *cobra.Command, args []string) error {
+	if auditFormat != "json" && auditFormat != "csv" {
+		return fmt.Errorf("unknown format %q (want json or csv)", auditFormat)
+	}
+	f := audit.Filter{SessionID: auditSession, Types: auditTypes}
+	var err error
+	if f.Since, err = parseAuditTime(auditSince); err != nil {
+		return err
+	}
+	if f.Until, err = parseAuditTime(auditUntil); err != nil {
+		return err
+	}
+
+	path := auditPath()
+	r, err := verifyAudit(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no audit log at %s", path)
+	}
+	if err != nil {
+		return err
+	}
+	entries, err := audit.Read(path)
+	if err != nil {
+		return err
+	}
+	x := audit.NewExport(path, r, entries, f)
+
+	var w io.Writer = os.Stdout
+	if auditOutput != "" {
+		out, err := os.Create(auditOutput)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		w = out
+	}
+	if auditFormat == "csv" {
+		err = x.WriteCSV(w)
+	} else {
+		err = x.WriteJSON(w)
+	}
+	if err != nil {
+		return err
+	}
+	if !r.OK() {
+		fmt.Fprintf(os.Stderr, "Warning: the audit log failed verification (%d problem(s)); run 'open-agents audit verify'\n", len(r.Problems))
+	}
+	if auditOutput != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d audit entries to %s\n", x.Summary.Entries, auditOutput)
+	}
+	return nil
+}
+
+// parseAuditTime reads an RFC 3339 time, a date, or a duration before now
+func parseAuditTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		t := time.Now().Add(-d)
+		return &t, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid time %q: use RFC 3339, YYYY-MM-DD or a duration like 24h", s)
+}
+
+func shortHash(h string) string {
+	if len(h) > 16 {
+		return h[:16]
+	}
+	return h
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/open-agents/bridge/internal/control"
+	"github.com/spf13/cobra"
+)
+
+var clisCmd = &cobra.Command{
+	Use:   "clis",
+	Short: "Review CLI definitions synced from the server",
+	Long: `CLI definitions synced from the server name commands this device would
+run. They are not used until approved here, and an approval lapses as soon
+as the server changes the definition.`,
+}
+
+var clisSyncedCmd = &cobra.Command{
+	Use:          "synced",
+	Short:        "List synced CLI definitions and whether they are approved",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runClisSynced,
+}
+
+var clisApproveCmd = &cobra.Command{
+	Use:   "approve <cli-type>",
+	Short: "Approve a synced CLI definition",
+	Long: `Show a synced CLI definition and approve it, so sessions of that CLI
+type run it. CLI types defined in the local config keep their local
+definition.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runClisApprove,
+}
+
+var (
+	clisDevice string
+	clisJSON   bool
+	clisYes    bool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{clisSyncedCmd, clisApproveCmd} {
+		c.Flags().StringVarP(&clisDevice, "device", "d", "", "Device name (default: current device)")
+		c.Flags().BoolVar(&clisJSON, "json", false, "Print JSON")
+		clisCmd.AddCommand(c)
+	}
+	clisApproveCmd.Flags().BoolVarP(&clisYes, "yes", "y", false, "Approve without asking")
+}
+
+func runClisSynced(cmd *cobra.Command, args []string) error {
+	c, err := dialBridge(clisDevice)
+	if err != nil {
+		return err
+	}
+	list, err := c.SyncedCLIs()
+	if err != nil {
+		return err
+	}
+	if clisJSON {
+		if list == nil {
+			list = []control.SyncedCLI{}
+		}
+		return printJSON(list)
+	}
+	if len(list) == 0 {
+		fmt.Println("No synced CLI definitions")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLI\tSTATE\tCOMMAND\tFINGERPRINT")
+	for _, s := range list {
+		state := "pending"
+		switch {
+		case s.Overridden:
+			state = "local config wins"
+		case s.Approved:
+			state = "approved"
+		}
+		command := strings.TrimSpace(s.Definition.Command + " " + strings.Join(s.Definition.Args, " "))
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, state, oneLine(orDash(command), 50), s.Fingerprint[:12])
+	}
+	return w.Flush()
+}
+
+func runClisApprove(cmd *cobra.Command, args []string) error {
+	c, err := dialBridge(clisDevice)
+	if err != nil {
+		return err
+	}
+	list, err := c.SyncedCLIs()
+	if err != nil {
+		return err
+	}
+	var synced *control.SyncedCLI
+	for i := range list {
+		if list[i].Name == args[0] {
+			synced = &list[i]
+		}
+	}
+	if synced == nil {
+		return fmt.Errorf("no synced definition for CLI %s", args[0])
+	}
+
+	if !clisYes {
+		data, _ := json.MarshalIndent(synced.Definition, "", "  ")
+		fmt.Printf("Synced definition of %s:\n%s\n", synced.Name, data)
+		fmt.Print("Allow this device to run it? [y/N] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return fmt.Errorf("not approved")
+		}
+	}
+	if err := c.ApproveCLI(synced.Name, control.CLIApproval{Fingerprint: synced.Fingerprint}); err != nil {
+		return err
+	}
+	if clisJSON {
+		return printJSON(map[string]interface{}{"ok": true, "name": synced.Name, "fingerprint": synced.Fingerprint})
+	}
+	fmt.Printf("Approved %s (%s)\n", synced.Name, synced.Fingerprint[:12])
+	return nil
+}
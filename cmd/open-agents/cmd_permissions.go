@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/open-agents/bridge/internal/control"
+	"github.com/open-agents/bridge/internal/permission"
+	"github.com/spf13/cobra"
+)
+
+var permissionsCmd = &cobra.Command{
+	Use:   "permissions",
+	Short: "Answer the permission requests of running sessions",
+}
+
+var permissionsListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List pending permission requests",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runPermissionsList,
+}
+
+var permissionsApproveCmd = &cobra.Command{
+	Use:   "approve <request-id>",
+	Short: "Approve a permission request",
+	Long: `Approve a pending permission request. ACP agents are answered with
+allow_once unless --option names another of the request's options, e.g.
+allow_always.
+
+The request ID may be abbreviated to any unique prefix.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPermissionDecision(args[0], true)
+	},
+}
+
+var permissionsDenyCmd = &cobra.Command{
+	Use:   "deny <request-id>",
+	Short: "Deny a permission request",
+	Long: `Deny a pending permission request. ACP agents are answered with
+reject_once unless --option names another of the request's options, e.g.
+reject_always.
+
+The request ID may be abbreviated to any unique prefix.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPermissionDecision(args[0], false)
+	},
+}
+
+var permissionsRememberedCmd = &cobra.Command{
+	Use:   "remembered",
+	Short: "List remembered permission decisions",
+	Long: `List the decisions answered with "always" (an ACP allow_always or
+reject_always option, or --remember). Later requests for the same tool and
+input, in the same project and from the same CLI, are answered with them
+without asking.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runPermissionsRemembered,
+}
+
+var permissionsForgetCmd = &cobra.Command{
+	Use:   "forget <decision-id>",
+	Short: "Revoke a remembered permission decision",
+	Long: `Revoke a remembered permission decision, so matching requests are
+asked again. The decision ID may be abbreviated to any unique prefix.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runPermissionsForget,
+}
+
+var (
+	permissionsDevice   string
+	permissionsJSON     bool
+	permissionsOption   string
+	permissionsRemember bool
+	permissionsSession  string
+)
+
+func init() {
+	for _, c := range []*cobra.Command{permissionsListCmd, permissionsApproveCmd, permissionsDenyCmd, permissionsRememberedCmd, permissionsForgetCmd} {
+		c.Flags().StringVarP(&permissionsDevice, "device", "d", "", "Device name (default: current device)")
+		c.Flags().BoolVar(&permissionsJSON, "json", false, "Print JSON")
+		permissionsCmd.AddCommand(c)
+	}
+	permissionsApproveCmd.Flags().StringVar(&permissionsOption, "option", "", "ACP option to answer with (default: allow_once)")
+	permissionsDenyCmd.Flags().StringVar(&permissionsOption, "option", "", "ACP option to answer with (default: reject_once)")
+	for _, c := range []*cobra.Command{permissionsApproveCmd, permissionsDenyCmd} {
+		c.Flags().BoolVar(&permissionsRemember, "remember", false, "Answer later requests of the same kind alike")
+		c.Flags().StringVarP(&permissionsSession, "session", "s", "", "Session of the request, when several have one with that ID")
+	}
+}
+
+func runPermissionsList(cmd *cobra.Command, args []string) error {
+	c, err := dialBridge(permissionsDevice)
+	if err != nil {
+		return err
+	}
+	list, err := c.Permissions()
+	if err != nil {
+		return err
+	}
+	if permissionsJSON {
+		if list == nil {
+			list = []control.PermissionInfo{}
+		}
+		return printJSON(list)
+	}
+	if len(list) == 0 {
+		fmt.Println("No pending permission requests")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSESSION\tTOOL\tRISK\tTIMEOUT\tDESCRIPTION")
+	for _, p := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.ID, orDash(shortID(p.SessionID)), orDash(p.ToolName),
+			orDash(p.Risk), timeLeft(p.Deadline), orDash(oneLine(p.Description, 60)))
+	}
+	return w.Flush()
+}
+
+// timeLeft shows how long a request waits before its default answer
+func timeLeft(deadline time.Time) string {
+	if deadline.IsZero() {
+		return "-"
+	}
+	left := time.Until(deadline).Round(time.Second)
+	if left < 0 {
+		left = 0
+	}
+	return left.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// findPermission looks a pending request up by ID or unique ID prefix, in
+// sessionID if given; agents of different sessions may use the same IDs
+func findPermission(list []control.PermissionInfo, sessionID, id string) (control.PermissionInfo, error) {
+	var exact, matches []control.PermissionInfo
+	for _, p := range list {
+		if sessionID != "" && p.SessionID != sessionID {
+			continue
+		}
+		if p.ID == id {
+			exact = append(exact, p)
+		}
+		if strings.HasPrefix(p.ID, id) {
+			matches = append(matches, p)
+		}
+	}
+	if len(exact) > 0 {
+		matches = exact
+	}
+	switch len(matches) {
+	case 0:
+		return control.PermissionInfo{}, fmt.Errorf("permission request %s is not pending", id)
+	case 1:
+		return matches[0], nil
+	}
+	ids := make([]string, len(matches))
+	for i, p := range matches {
+		ids[i] = p.ID + " (session " + orDash(p.SessionID) + ")"
+	}
+	sort.Strings(ids)
+	return control.PermissionInfo{}, fmt.Errorf("permission request id %s is ambiguous, pick one with --session: %s", id, strings.Join(ids, ", "))
+}
+
+func runPermissionDecision(id string, approved bool) error {
+	c, err := dialBridge(permissionsDevice)
+	if err != nil {
+		return err
+	}
+	list, err := c.Permissions()
+	if err != nil {
+		return err
+	}
+	p, err := findPermission(list, permissionsSession, id)
+	if err != nil {
+		return err
+	}
+	if err := c.Resolve(p.ID, control.Decision{SessionID: p.SessionID, Approved: approved, OptionID: permissionsOption, Remember: permissionsRemember}); err != nil {
+		return err
+	}
+
+	result := "denied"
+	if approved {
+		result = "approved"
+	}
+	if permissionsJSON {
+		return printJSON(map[string]interface{}{"ok": true, "id": p.ID, "sessionId": p.SessionID, "result": result})
+	}
+	fmt.Printf("Permission request %s: %s\n", p.ID, result)
+	return nil
+}
+
+func runPermissionsRemembered(cmd *cobra.Command, args []string) error {
+	c, err := dialBridge(permissionsDevice)
+	if err != nil {
+		return err
+	}
+	list, err := c.Remembered()
+	if err != nil {
+		return err
+	}
+	if permissionsJSON {
+		if list == nil {
+			list = []permission.Remembered{}
+		}
+		return printJSON(list)
+	}
+	if len(list) == 0 {
+		fmt.Println("No remembered permission decisions")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDECISION\tCLI\tPROJECT\tTOOL\tINPUT\tUSES")
+	for _, r := range list {
+		decision := "deny"
+		if r.Approved {
+			decision = "allow"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n", shortID(r.ID), decision, orDash(r.CLIType), orDash(r.WorkDir),
+			r.Tool, oneLine(r.Input, 50), r.Uses)
+	}
+	return w.Flush()
+}
+
+func runPermissionsForget(cmd *cobra.Command, args []string) error {
+	c, err := dialBridge(permissionsDevice)
+	if err != nil {
+		return err
+	}
+	list, err := c.Remembered()
+	if err != nil {
+		return err
+	}
+	var matches []permission.Remembered
+	for _, r := range list {
+		if r.ID == args[0] {
+			matches = []permission.Remembered{r}
+			break
+		}
+		if strings.HasPrefix(r.ID, args[0]) {
+			matches = append(matches, r)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return fmt.Errorf("no remembered decision %s", args[0])
+	case 1:
+	default:
+		ids := make([]string, len(matches))
+		for i, r := range matches {
+			ids[i] = r.ID
+		}
+		return fmt.Errorf("decision id %s is ambiguous: %s", args[0], strings.Join(ids, ", "))
+	}
+
+	r := matches[0]
+	if err := c.Forget(r.ID); err != nil {
+		return err
+	}
+	if permissionsJSON {
+		return printJSON(map[string]interface{}{"ok": true, "id": r.ID})
+	}
+	fmt.Printf("Forgot decision %s: %s %s\n", shortID(r.ID), r.Tool, oneLine(r.Input, 60))
+	return nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/open-agents/bridge/internal/audit"
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/rules"
+	"github.com/open-agents/bridge/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Check auto-approval rules before syncing them",
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Replay past permission requests through proposed rules",
+	Long: `Replay past permission requests through the device's current rules and
+through the proposed ones, and report the requests whose outcome would
+change. Requests come from the audit log (hook requests only; rules are not
+applied to ACP agents) or from a fixture file: a JSON list of
+{"id", "request": {"tool", "command", "path", "workDir", ...}, "approved"}.
+
+The proposed rules are also checked for rules that cannot work, rules an
+earlier rule always wins over (shadowed or unreachable), approvals broader
+than they look, and rules no replayed request reaches.
+
+Exits with status 1 when a rule is invalid, shadowed or unreachable.`,
+	Example: `  open-agents rules test --rules proposed.json
+  open-agents rules test --rules proposed.json --fixture requests.json --json`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runRulesTest,
+}
+
+var (
+	rulesFile    string
+	rulesAgainst string
+	rulesFixture string
+	rulesDevice  string
+	rulesAudit   string
+	rulesSince   string
+	rulesJSON    bool
+)
+
+func init() {
+	rulesCmd.AddCommand(rulesTestCmd)
+	f := rulesTestCmd.Flags()
+	f.StringVar(&rulesFile, "rules", "", "Proposed rules: a JSON list of rules, or a config with \"rules\"")
+	f.StringVar(&rulesAgainst, "against", "", "Rules to compare with (default: the device's current rules)")
+	f.StringVar(&rulesFixture, "fixture", "", "Replay the requests of this file instead of the audit log")
+	f.StringVarP(&rulesDevice, "device", "d", "", "Device name (default: current device)")
+	f.StringVar(&rulesAudit, "audit-file", "", "Audit log to replay (default: the device's)")
+	f.StringVar(&rulesSince, "since", "", "Only requests from this time (RFC 3339, YYYY-MM-DD, or a duration ago like 168h)")
+	f.BoolVar(&rulesJSON, "json", false, "Print JSON")
+	rulesTestCmd.MarkFlagRequired("rules")
+}
+
+func runRulesTest(cmd *cobra.Command, args []string) error {
+	proposed, err := readRulesFile(rulesFile)
+	if err != nil {
+		return err
+	}
+	cfg, _ := loadDeviceConfig(rulesDevice)
+	var current []config.AutoApprovalRule
+	if rulesAgainst != "" {
+		if current, err = readRulesFile(rulesAgainst); err != nil {
+			return err
+		}
+	} else if cfg != nil {
+		current = cfg.Rules
+	}
+
+	since, err := parseAuditTime(rulesSince)
+	if err != nil {
+		return err
+	}
+	cases, skipped, source, err := rulesTestCases(cfg)
+	if err != nil {
+		return err
+	}
+	if since != nil {
+		kept := cases[:0]
+		for _, c := range cases {
+			if !c.Request.Time.Before(*since) {
+				kept = append(kept, c)
+			}
+		}
+		cases = kept
+	}
+
+	im := rules.Simulate(current, proposed, cases)
+	im.Skipped = skipped
+	if rulesJSON {
+		err = printJSON(im)
+	} else {
+		err = printImpact(im, source)
+	}
+	if err != nil {
+		return err
+	}
+	for _, is := range im.Issues {
+		if is.Kind == rules.IssueInvalid || is.Kind == rules.IssueShadowed || is.Kind == rules.IssueUnreachable {
+			os.Exit(1)
+		}
+	}
+	return nil
+}
+
+// readRulesFile reads a JSON list of rules, or the rules of a config file
+func readRulesFile(path string) ([]config.AutoApprovalRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []config.AutoApprovalRule
+	if err := json.Unmarshal(data, &list); err == nil {
+		return list, nil
+	}
+	var wrapped struct {
+		Rules []config.AutoApprovalRule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("%s: not a list of rules: %v", path, err)
+	}
+	return wrapped.Rules, nil
+}
+
+// rulesTestCases returns the requests to replay and where they came from
+func rulesTestCases(cfg *config.Config) ([]rules.Case, int, string, error) {
+	if rulesFixture != "" {
+		data, err := os.ReadFile(rulesFixture)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		var cases []rules.Case
+		if err := json.Unmarshal(data, &cases); err != nil {
+			return nil, 0, "", fmt.Errorf("%s: not a list of requests: %v", rulesFixture, err)
+		}
+		return cases, 0, rulesFixture, nil
+	}
+
+	path := rulesAudit
+	if path == "" {
+		name := ""
+		if cfg != nil {
+			name = cfg.DeviceName
+		}
+		path = audit.PathFor(name)
+	}
+	entries, err := audit.Read(path)
+	if os.IsNotExist(err) {
+		return nil, 0, "", fmt.Errorf("no audit log at %s; use --fixture to replay requests from a file", path)
+	}
+	if err != nil {
+		return nil, 0, "", err
+	}
+	s := scanner.New()
+	s.LoadCustomRules(config.ConfigDir())
+	cases, skipped := rules.CasesFromAudit(entries, s)
+	return cases, skipped, path, nil
+}
+
+func printImpact(im rules.Impact, source string) error {
+	fmt.Printf("Replayed %d requests from %s", im.Cases, source)
+	if im.Skipped > 0 {
+		fmt.Printf(" (%d ACP requests skipped)", im.Skipped)
+	}
+	fmt.Print("\n\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tCURRENT\tPROPOSED")
+	for _, a := range []string{rules.ActionApprove, rules.ActionAsk, rules.ActionDeny} {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", a, im.Before[a], im.After[a])
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(im.Changed) == 0 {
+		fmt.Println("\nNo request would be decided differently")
+	} else {
+		fmt.Printf("\n%d requests would be decided differently:\n", len(im.Changed))
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tREQUEST\tCURRENT\tPROPOSED\tRULE\tANSWERED")
+		for _, c := range im.Changed {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", orDash(c.ID), oneLine(describeRequest(c.Request), 50),
+				c.Before.Action, c.After.Action, orDash(c.After.RuleID), answered(c.Case))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(im.Hits) > 0 {
+		ids := make([]string, 0, len(im.Hits))
+		for id := range im.Hits {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			if im.Hits[ids[i]] != im.Hits[ids[j]] {
+				return im.Hits[ids[i]] > im.Hits[ids[j]]
+			}
+			return ids[i] < ids[j]
+		})
+		hits := make([]string, len(ids))
+		for i, id := range ids {
+			hits[i] = fmt.Sprintf("%s %d", id, im.Hits[id])
+		}
+		fmt.Printf("\nRequests decided per rule: %s\n", strings.Join(hits, ", "))
+	}
+
+	if len(im.Issues) == 0 {
+		fmt.Println("\nNo issues found in the proposed rules")
+		return nil
+	}
+	fmt.Printf("\n%d issue(s) in the proposed rules:\n", len(im.Issues))
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, is := range im.Issues {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", orDash(is.RuleID), is.Kind, is.Message)
+	}
+	return w.Flush()
+}
+
+func describeRequest(r rules.Request) string {
+	switch {
+	case r.Command != "":
+		return r.Tool + " " + r.Command
+	case r.Path != "":
+		return r.Tool + " " + r.Path
+	}
+	return r.Tool
+}
+
+// answered says how a replayed request was answered when it was made
+func answered(c rules.Case) string {
+	if c.Approved == nil {
+		return "-"
+	}
+	verdict := "denied"
+	if *c.Approved {
+		verdict = "approved"
+	}
+	if c.DecidedBy == "" {
+		return verdict
+	}
+	return verdict + " by " + c.DecidedBy
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/permission"
+	"github.com/open-agents/bridge/internal/policy"
+	"github.com/open-agents/bridge/internal/protocol"
+	"github.com/open-agents/bridge/internal/rules"
+	"github.com/open-agents/bridge/internal/scanner"
+	"github.com/open-agents/bridge/internal/session"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Exit codes of 'run' besides the agent's own
+const (
+	exitTimeout     = 124 // as timeout(1)
+	exitInterrupted = 130 // as a shell after SIGINT
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run one prompt through an agent CLI, without the cloud",
+	Long: `Start a session locally, send one prompt and stream the agent's output to
+stdout. No pairing or server connection is needed; the CLI definitions,
+policy, resource limits and auto-approval rules of the device config are used
+when there is one.
+
+Permission requests are answered by the auto-approval rules first, then by
+--on-permission: "ask" prompts on the terminal (and denies when there is
+none), "approve" and "deny" answer every request.
+
+The exit status is 0 when the agent ends its turn normally, 1 when it fails,
+124 on --timeout and 130 when interrupted. PTY sessions run until the CLI
+exits and return its exit status.
+
+With --json, stdout is the normalized message stream, one JSON object per
+line, including the permission answers that were sent.
+
+Examples:
+  open-agents run --cli claude --prompt "Fix the failing test"
+  git diff --cached | open-agents run --cli codex --prompt - --json
+  open-agents run --cli gemini --workdir ./api --permission-mode accept-all \
+    --on-permission deny --timeout 10m --prompt "Review this package"`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runRun,
+}
+
+var (
+	runCLI            string
+	runWorkDir        string
+	runPrompt         string
+	runPermissionMode string
+	runJSON           bool
+	runOnPermission   string
+	runTimeout        time.Duration
+	runDevice         string
+	runVerbose        bool
+)
+
+func init() {
+	runCmd.Flags().StringVar(&runCLI, "cli", "", "CLI type, e.g. claude, codex, gemini")
+	runCmd.Flags().StringVarP(&runWorkDir, "workdir", "w", ".", "Working directory")
+	runCmd.Flags().StringVarP(&runPrompt, "prompt", "p", "", `Prompt to send ("-" reads stdin; default: stdin when it is not a terminal)`)
+	runCmd.Flags().StringVar(&runPermissionMode, "permission-mode", "default", "Permission mode of the CLI")
+	runCmd.Flags().BoolVar(&runJSON, "json", false, "Print the message stream as JSON lines")
+	runCmd.Flags().StringVar(&runOnPermission, "on-permission", "ask", "Answer to permission requests no rule matches: ask, approve or deny")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", 0, "Cancel the run after this long (0 = no limit)")
+	runCmd.Flags().StringVarP(&runDevice, "device", "d", "", "Device whose config to use (default: current device, if any)")
+	runCmd.Flags().BoolVarP(&runVerbose, "verbose", "v", false, "Log bridge internals to stderr")
+	runCmd.MarkFlagRequired("cli")
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	switch runOnPermission {
+	case "ask", "approve", "deny":
+	default:
+		return fmt.Errorf("--on-permission must be ask, approve or deny")
+	}
+	prompt, err := readRunPrompt()
+	if err != nil {
+		return err
+	}
+	workDir, err := filepath.Abs(runWorkDir)
+	if err != nil {
+		return err
+	}
+
+	// Session internals log through the standard logger
+	if runVerbose {
+		log.SetOutput(os.Stderr)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	// Pairing is optional; an unpaired machine runs with the defaults
+	cfg, err := loadDeviceConfig(runDevice)
+	if err != nil {
+		if runDevice != "" {
+			return fmt.Errorf("device %s not found: %w", runDevice, err)
+		}
+		cfg = &config.Config{}
+	}
+
+	mgr := session.NewManager()
+	mgr.SetResourceLimits(cfg.ResourceLimits)
+	mgr.SetSandboxConfig(cfg.Sandbox)
+	mgr.SetCLIDefinitions(cfg.CLIs)
+	mgr.SetPolicy(policy.New(cfg.CLIEnabled, cfg.Policy))
+
+	// Messages are handled in order, off the adapter's read loop, so a
+	// permission prompt waiting for the user doesn't stall the agent's output
+	msgs := make(chan protocol.Message, 1024)
+	mgr.SetOutputCallback(func(sessionID string, msg protocol.Message) {
+		msgs <- msg
+	})
+
+	cols, rows := 120, 30
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		cols, rows = w, h
+	}
+	sess, err := mgr.CreateWithOptions(session.CreateOptions{
+		CLIType:        runCLI,
+		WorkDir:        workDir,
+		SessionID:      uuid.New().String(),
+		Cols:           cols,
+		Rows:           rows,
+		PermissionMode: runPermissionMode,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start %s: %w", runCLI, err)
+	}
+
+	r := &oneShot{
+		sess:    sess,
+		rules:   rules.NewEngine(cfg.Rules),
+		scanner: scanner.New(),
+		onPerm:  runOnPermission,
+		tty:     openPromptTTY(),
+	}
+	for _, err := range r.rules.Errors() {
+		fmt.Fprintf(os.Stderr, "Warning: invalid auto-approval rule, requests it matches will ask: %v\n", err)
+	}
+	if runJSON {
+		r.enc = json.NewEncoder(os.Stdout)
+	}
+	code := r.run(msgs, prompt)
+	mgr.StopAll()
+	if code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+// readRunPrompt returns --prompt, reading stdin for "-" or when no prompt
+// was given and stdin is not a terminal
+func readRunPrompt() (string, error) {
+	prompt := runPrompt
+	if prompt == "-" || (prompt == "" && !term.IsTerminal(int(os.Stdin.Fd()))) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		prompt = string(data)
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return "", fmt.Errorf("no prompt given, use --prompt or pipe it on stdin")
+	}
+	return prompt, nil
+}
+
+// openPromptTTY returns the terminal to ask permission questions on: stdin
+// when it is a terminal, else the controlling terminal, else nil
+func openPromptTTY() *bufio.Reader {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return bufio.NewReader(os.Stdin)
+	}
+	if f, err := os.Open("/dev/tty"); err == nil {
+		return bufio.NewReader(f)
+	}
+	return nil
+}
+
+// oneShot drives one prompt through a session
+type oneShot struct {
+	sess    *session.Session
+	rules   *rules.Engine
+	scanner *scanner.Scanner
+	onPerm  string
+	tty     *bufio.Reader
+	enc     *json.Encoder // nil for human output
+}
+
+// run sends the prompt and handles the session's messages until the turn
+// (or, for PTY sessions, the CLI) ends. It returns the exit status.
+func (r *oneShot) run(msgs <-chan protocol.Message, prompt string) int {
+	if err := r.sess.Send(prompt); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to send the prompt: %v\n", err)
+		return 1
+	}
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	var timeout <-chan time.Time
+	if runTimeout > 0 {
+		timeout = time.After(runTimeout)
+	}
+	cancelled, timedOut := false, false
+
+	for {
+		select {
+		case msg := <-msgs:
+			if done, code := r.handle(msg); done {
+				if timedOut {
+					return exitTimeout
+				}
+				return code
+			}
+		case <-timeout:
+			timedOut = true
+			fmt.Fprintf(os.Stderr, "✖ timed out after %s\n", runTimeout)
+			if !r.cancel() {
+				return exitTimeout
+			}
+		case <-sigs:
+			// The first interrupt cancels the turn, the second gives up
+			if cancelled || !r.cancel() {
+				return exitInterrupted
+			}
+			cancelled = true
+		}
+	}
+}
+
+// cancel asks an ACP agent to stop its turn; PTY sessions can't be cancelled
+func (r *oneShot) cancel() bool {
+	if r.sess.GetProtocolName() != "acp" {
+		return false
+	}
+	r.sess.Protocol.SendMessage(protocol.Message{Type: protocol.MessageTypeCancel, Content: "user_cancelled"})
+	return true
+}
+
+// handle prints one message and reports whether the run is over
+func (r *oneShot) handle(msg protocol.Message) (done bool, code int) {
+	if r.enc != nil {
+		r.enc.Encode(msg)
+	}
+
+	switch msg.Type {
+	case protocol.MessageTypeContent:
+		if text, ok := msg.Content.(string); ok && r.enc == nil {
+			os.Stdout.WriteString(text)
+		}
+
+	case protocol.MessageTypeToolCall:
+		if tc, ok := msg.Content.(protocol.ToolCall); ok && r.enc == nil && tc.Name != "" {
+			fmt.Fprintf(os.Stderr, "⚙ %s [%s]\n", tc.Name, tc.Status)
+		}
+
+	case protocol.MessageTypePermission:
+		if req, ok := msg.Content.(protocol.PermissionRequest); ok {
+			r.answer(req)
+		}
+
+	case protocol.MessageTypeError:
+		if r.enc == nil {
+			fmt.Fprintf(os.Stderr, "✖ %v\n", msg.Content)
+		}
+		// Agent stderr is informational; a dead agent or a limit hit ends the run
+		switch source, _ := msg.Meta["source"].(string); source {
+		case "exit", "limit":
+			return true, 1
+		}
+
+	case protocol.MessageTypeStatus:
+		if msg.Content != protocol.StatusIdle {
+			break
+		}
+		if exitCode, ok := msg.Meta["exit_code"].(int); ok {
+			return true, exitCode
+		}
+		if reason, ok := msg.Meta["stopReason"].(string); ok {
+			if r.enc == nil {
+				fmt.Println()
+			}
+			switch reason {
+			case "end_turn":
+				return true, 0
+			case "cancelled":
+				return true, exitInterrupted
+			}
+			if r.enc == nil {
+				fmt.Fprintf(os.Stderr, "✖ agent stopped: %s\n", reason)
+			}
+			return true, 1
+		}
+	}
+	return false, 0
+}
+
+// answer decides a permission request and sends the answer to the agent
+func (r *oneShot) answer(req protocol.PermissionRequest) {
+	approved, always, decidedBy, reason := r.decide(req)
+	optionID := protocol.PickOption(req.Options, approved, always)
+
+	r.sess.Protocol.SendMessage(protocol.Message{
+		Type:    protocol.MessageTypePermission,
+		Content: protocol.PermissionResponse{ID: req.ID, OptionID: optionID},
+	})
+
+	if r.enc != nil {
+		r.enc.Encode(protocol.Message{
+			Type:    protocol.MessageTypePermission,
+			Content: protocol.PermissionResponse{ID: req.ID, OptionID: optionID},
+			Meta:    map[string]interface{}{"decidedBy": decidedBy, "reason": reason},
+		})
+	} else if decidedBy != "user" {
+		fmt.Fprintf(os.Stderr, "🔐 %s: %s (%s)\n", req.ToolName, optionID, decidedBy)
+		if runVerbose {
+			fmt.Fprintf(os.Stderr, "   %s\n", reason)
+		}
+	}
+}
+
+// decide applies the auto-approval rules, then --on-permission. reason
+// explains what the rules made of the request.
+func (r *oneShot) decide(req protocol.PermissionRequest) (approved, always bool, decidedBy, reason string) {
+	tool, path, command := permission.Classify(req.Kind, req.ToolInput)
+	d := r.rules.Decide(rules.Request{
+		Tool:           tool,
+		Path:           path,
+		Command:        command,
+		SessionID:      r.sess.ID,
+		CLIType:        r.sess.CLIType,
+		WorkDir:        r.sess.WorkDir,
+		PermissionMode: r.sess.PermissionMode,
+		Risk:           req.Risk,
+		Findings:       rules.ScanFindings(r.scanner, req.ToolInput),
+	})
+	switch d.Action {
+	case "auto-approve":
+		return true, false, "rule " + d.RuleID, d.Reason
+	case "deny":
+		return false, false, "rule " + d.RuleID, d.Reason
+	}
+
+	switch r.onPerm {
+	case "approve":
+		return true, false, "--on-permission", d.Reason
+	case "deny":
+		return false, false, "--on-permission", d.Reason
+	}
+	if r.tty == nil {
+		return false, false, "no terminal to ask on", d.Reason
+	}
+	approved, always, decidedBy = r.ask(req)
+	return approved, always, decidedBy, d.Reason
+}
+
+// ask prompts on the terminal until it gets an answer
+func (r *oneShot) ask(req protocol.PermissionRequest) (approved, always bool, decidedBy string) {
+	fmt.Fprintf(os.Stderr, "\n🔐 Permission requested: %s", req.ToolName)
+	if req.Risk != "" {
+		fmt.Fprintf(os.Stderr, " (%s risk)", req.Risk)
+	}
+	fmt.Fprintln(os.Stderr)
+	if cmd, _ := req.ToolInput["command"].(string); cmd != "" {
+		fmt.Fprintf(os.Stderr, "   command: %s\n", cmd)
+	}
+	for {
+		fmt.Fprint(os.Stderr, "Allow? [y]es, [a]lways, [n]o, [N]ever: ")
+		line, err := r.tty.ReadString('\n')
+		switch strings.TrimSpace(line) {
+		case "y", "Y", "yes":
+			return true, false, "user"
+		case "a", "A", "always":
+			return true, true, "user"
+		case "n", "no":
+			return false, false, "user"
+		case "N", "never":
+			return false, true, "user"
+		}
+		if err != nil {
+			return false, false, "no answer"
+		}
+	}
+}
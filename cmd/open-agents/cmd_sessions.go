@@ -0,0 +1,381 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/control"
+	"github.com/open-agents/bridge/internal/storage"
+	"github.com/open-agents/bridge/internal/transcript"
+	"github.com/spf13/cobra"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Work with sessions and their history",
+}
+
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export <session-id>",
+	Short: "Export a session transcript (md, html or jsonl)",
+	Long: `Export the transcript of a session: prompts, replies, tool calls with
+file diffs, permission decisions and token usage.
+
+The session ID may be abbreviated to any unique prefix.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionsExport,
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List the sessions of the running bridge",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runSessionsList,
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <session-id>",
+	Short: "Show a session and its latest messages",
+	Long: `Show a running session (controller, queue, busy state) and its latest
+messages from the local history. Sessions that have ended are shown from
+history alone.
+
+The session ID may be abbreviated to any unique prefix.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runSessionsShow,
+}
+
+var sessionsSendCmd = &cobra.Command{
+	Use:   "send <session-id> [prompt...]",
+	Short: "Send a prompt to a running session",
+	Long: `Send a prompt to a running session. Without a prompt argument, or with
+"-", the prompt is read from stdin. A busy agent queues the prompt until its
+current turn ends; --interrupt cancels the turn and sends it right away.`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         runSessionsSend,
+}
+
+var sessionsStopCmd = &cobra.Command{
+	Use:          "stop <session-id>",
+	Short:        "Stop a running session",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSessionAction(args[0], "stopped", func(c *control.Client, id string) error {
+			return c.Stop(id, control.StopRequest{})
+		})
+	},
+}
+
+var sessionsCancelCmd = &cobra.Command{
+	Use:          "cancel <session-id>",
+	Short:        "Cancel the current turn of a session and drop its queued prompts",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSessionAction(args[0], "cancelled", func(c *control.Client, id string) error {
+			return c.Cancel(id, control.StopRequest{})
+		})
+	},
+}
+
+var (
+	sessionsDevice    string
+	sessionsJSON      bool
+	sessionsLines     int
+	sessionsInterrupt bool
+)
+
+var (
+	exportFormat   string
+	exportThoughts bool
+	exportOutput   string
+)
+
+func init() {
+	sessionsExportCmd.Flags().StringVarP(&exportFormat, "format", "f", "md", "Output format: md, html or jsonl")
+	sessionsExportCmd.Flags().BoolVar(&exportThoughts, "thoughts", false, "Include agent thinking")
+	sessionsExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write to file instead of stdout")
+	sessionsCmd.AddCommand(sessionsExportCmd)
+
+	for _, c := range []*cobra.Command{sessionsListCmd, sessionsShowCmd, sessionsSendCmd, sessionsStopCmd, sessionsCancelCmd} {
+		c.Flags().StringVarP(&sessionsDevice, "device", "d", "", "Device name (default: current device)")
+		c.Flags().BoolVar(&sessionsJSON, "json", false, "Print JSON")
+		sessionsCmd.AddCommand(c)
+	}
+	sessionsShowCmd.Flags().IntVarP(&sessionsLines, "lines", "n", 10, "Number of history messages to show")
+	sessionsSendCmd.Flags().BoolVar(&sessionsInterrupt, "interrupt", false, "Cancel the current turn and send now")
+}
+
+func openSessionStore() (*storage.Store, error) {
+	return storage.NewStore(filepath.Join(config.ConfigDir(), "sessions"))
+}
+
+func runSessionsExport(cmd *cobra.Command, args []string) error {
+	format, err := transcript.ParseFormat(exportFormat)
+	if err != nil {
+		return err
+	}
+	store, err := openSessionStore()
+	if err != nil {
+		return fmt.Errorf("failed to open session history: %w", err)
+	}
+	h, err := transcript.Find(store.ListSessions(), args[0])
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if exportOutput != "" {
+		f, err := os.Create(exportOutput)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := transcript.Render(w, h, format, transcript.Options{Thoughts: exportThoughts}); err != nil {
+		return err
+	}
+	if exportOutput != "" {
+		fmt.Fprintf(os.Stderr, "Exported session %s to %s\n", h.SessionID, exportOutput)
+	}
+	return nil
+}
+
+// dialBridge connects to the control socket of a device's running bridge
+func dialBridge(device string) (*control.Client, error) {
+	cfg, err := loadDeviceConfig(device)
+	if err != nil {
+		return nil, fmt.Errorf("bridge is not configured, run 'open-agents pair' first")
+	}
+	c, err := control.Dial(control.PathsFor(cfg.DeviceName))
+	if errors.Is(err, control.ErrNotRunning) {
+		return nil, fmt.Errorf("bridge is not running, start it with 'open-agents start'")
+	}
+	return c, err
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// findLiveSession looks a running session up by ID or unique ID prefix
+func findLiveSession(list []control.SessionInfo, id string) (control.SessionInfo, error) {
+	var matches []control.SessionInfo
+	for _, s := range list {
+		if s.ID == id {
+			return s, nil
+		}
+		if strings.HasPrefix(s.ID, id) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return control.SessionInfo{}, fmt.Errorf("session %s is not running", id)
+	case 1:
+		return matches[0], nil
+	}
+	ids := make([]string, len(matches))
+	for i, s := range matches {
+		ids[i] = s.ID
+	}
+	sort.Strings(ids)
+	return control.SessionInfo{}, fmt.Errorf("session id %s is ambiguous: %s", id, strings.Join(ids, ", "))
+}
+
+// resolveLiveSession expands a session ID prefix against the running sessions
+func resolveLiveSession(c *control.Client, id string) (control.SessionInfo, error) {
+	list, err := c.Sessions()
+	if err != nil {
+		return control.SessionInfo{}, err
+	}
+	return findLiveSession(list, id)
+}
+
+func sessionState(s control.SessionInfo) string {
+	if s.Busy {
+		return "busy"
+	}
+	return s.Status
+}
+
+func controllerName(s *control.SessionInfo) string {
+	if s.Controller == nil {
+		return "-"
+	}
+	return s.Controller.Kind + ":" + s.Controller.ClientID
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func runSessionsList(cmd *cobra.Command, args []string) error {
+	c, err := dialBridge(sessionsDevice)
+	if err != nil {
+		return err
+	}
+	list, err := c.Sessions()
+	if err != nil {
+		return err
+	}
+	if sessionsJSON {
+		if list == nil {
+			list = []control.SessionInfo{}
+		}
+		return printJSON(list)
+	}
+	if len(list) == 0 {
+		fmt.Println("No running sessions")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCLI\tSTATE\tQUEUED\tCONTROLLER\tAGE\tWORKDIR")
+	for _, s := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", shortID(s.ID), s.CLIType, sessionState(s), len(s.Queue),
+			controllerName(&s), time.Since(s.CreatedAt).Round(time.Second), s.WorkDir)
+	}
+	return w.Flush()
+}
+
+// sessionDetail is the JSON form of 'sessions show'
+type sessionDetail struct {
+	Session *control.SessionInfo `json:"session,omitempty"`
+	History []storage.Message    `json:"history"`
+}
+
+func runSessionsShow(cmd *cobra.Command, args []string) error {
+	var detail sessionDetail
+	id := args[0]
+
+	// The daemon knows the live state; history also covers ended sessions
+	if c, err := dialBridge(sessionsDevice); err == nil {
+		if s, err := resolveLiveSession(c, id); err == nil {
+			detail.Session = &s
+			id = s.ID
+		}
+	}
+	var history *storage.SessionHistory
+	if store, err := openSessionStore(); err == nil {
+		history, _ = transcript.Find(store.ListSessions(), id)
+	}
+	if detail.Session == nil && history == nil {
+		return fmt.Errorf("session %s not found", args[0])
+	}
+	detail.History = []storage.Message{}
+	if history != nil {
+		msgs := history.Messages
+		if sessionsLines >= 0 && len(msgs) > sessionsLines {
+			msgs = msgs[len(msgs)-sessionsLines:]
+		}
+		detail.History = msgs
+	}
+
+	if sessionsJSON {
+		return printJSON(detail)
+	}
+	if s := detail.Session; s != nil {
+		fmt.Printf("Session:      %s\n", s.ID)
+		fmt.Printf("CLI:          %s (%s)\n", s.CLIType, s.Protocol)
+		fmt.Printf("Work dir:     %s\n", s.WorkDir)
+		if s.PermissionMode != "" {
+			fmt.Printf("Permissions:  %s\n", s.PermissionMode)
+		}
+		fmt.Printf("State:        %s\n", sessionState(*s))
+		fmt.Printf("Controller:   %s\n", controllerName(s))
+		fmt.Printf("Started:      %s\n", s.CreatedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("Last active:  %s\n", s.LastActiveAt.Format("2006-01-02 15:04:05"))
+		if s.Sandboxed {
+			fmt.Println("Sandboxed:    yes")
+		}
+		if s.ScheduleRunID != "" {
+			fmt.Printf("Schedule run: %s\n", s.ScheduleRunID)
+		}
+		for i, q := range s.Queue {
+			fmt.Printf("Queued %d:     %s\n", i+1, oneLine(q.Content, 70))
+		}
+	} else {
+		fmt.Printf("Session:      %s (not running)\n", history.SessionID)
+		fmt.Printf("CLI:          %s\n", history.CLIType)
+		fmt.Printf("Work dir:     %s\n", history.WorkDir)
+		fmt.Printf("Started:      %s\n", history.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	if len(detail.History) > 0 {
+		fmt.Println()
+		for _, m := range detail.History {
+			label := m.Role
+			if m.Type != "" {
+				label += "/" + m.Type
+			}
+			fmt.Printf("%s  %-16s %s\n", m.Timestamp.Format("15:04:05"), label, oneLine(m.Content, 100))
+		}
+	}
+	return nil
+}
+
+// oneLine flattens text to a single line of at most n runes
+func oneLine(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return text
+}
+
+func runSessionsSend(cmd *cobra.Command, args []string) error {
+	prompt := strings.Join(args[1:], " ")
+	if prompt == "" || prompt == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		prompt = strings.TrimSpace(string(data))
+	}
+	if prompt == "" {
+		return fmt.Errorf("prompt is empty")
+	}
+	return runSessionAction(args[0], "sent", func(c *control.Client, id string) error {
+		return c.Send(id, control.SendRequest{Content: prompt, Interrupt: sessionsInterrupt})
+	})
+}
+
+// runSessionAction resolves a running session and applies an action to it
+func runSessionAction(id, done string, action func(c *control.Client, id string) error) error {
+	c, err := dialBridge(sessionsDevice)
+	if err != nil {
+		return err
+	}
+	s, err := resolveLiveSession(c, id)
+	if err != nil {
+		return err
+	}
+	if err := action(c, s.ID); err != nil {
+		return err
+	}
+	if sessionsJSON {
+		return printJSON(map[string]interface{}{"ok": true, "sessionId": s.ID, "result": done})
+	}
+	fmt.Printf("Session %s: %s\n", s.ID, done)
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/open-agents/bridge/internal/bridge"
 	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/localui"
 	"github.com/open-agents/bridge/internal/logger"
 	"github.com/open-agents/bridge/internal/tray"
 	"github.com/spf13/cobra"
@@ -19,6 +20,8 @@ var (
 	logLevel   string
 	headless   bool
 	deviceName string
+	localMode  bool
+	listenAddr string
 )
 
 var startCmd = &cobra.Command{
@@ -36,7 +39,11 @@ Examples:
   open-agents start --device work-pc
 
   # Start with debug logging
-  open-agents start --log-level debug`,
+  open-agents start --log-level debug
+
+  # Run without the cloud: serve a web UI on localhost instead
+  open-agents start --local
+  open-agents start --local --listen 127.0.0.1:8080`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Determine which device to use
 		targetDevice := deviceName
@@ -64,7 +71,9 @@ Examples:
 		// Try multi-device config first
 		if targetDevice != "" {
 			cfg, err = config.LoadDevice(targetDevice)
-			if err != nil {
+			if err != nil && localMode {
+				cfg = &config.Config{DeviceName: targetDevice}
+			} else if err != nil {
 				logger.Error("Error loading device config '%s': %v", targetDevice, err)
 				fmt.Fprintf(os.Stderr, "Device '%s' not found. Run 'open-agents devices' to see available devices.\n", targetDevice)
 				os.Exit(1)
@@ -72,7 +81,10 @@ Examples:
 		} else {
 			// Fall back to legacy single-device config
 			cfg, err = config.Load()
-			if err != nil {
+			if err != nil && localMode {
+				// Local mode needs no pairing
+				cfg = &config.Config{}
+			} else if err != nil {
 				logger.Error("Error loading config: %v", err)
 				fmt.Println("Please run 'open-agents pair' first to configure the bridge.")
 				os.Exit(1)
@@ -83,9 +95,13 @@ Examples:
 		if cfg.DeviceName != "" {
 			logger.Info("Device: %s", cfg.DeviceName)
 		}
-		logger.Info("Environment: %s", cfg.GetEnvironment())
-		logger.Info("Device ID: %s", cfg.DeviceID)
-		logger.Info("Server: %s", cfg.ServerURL)
+		if localMode {
+			logger.Info("Mode: local (no server connection)")
+		} else {
+			logger.Info("Environment: %s", cfg.GetEnvironment())
+			logger.Info("Device ID: %s", cfg.DeviceID)
+			logger.Info("Server: %s", cfg.ServerURL)
+		}
 		logger.Info("Log level: %s", logLevel)
 		logger.Info("Log file: ~/.open-agents/logs/bridge-%s.log", time.Now().Format("2006-01-02"))
 		logger.Info("E2EE: Keys loaded")
@@ -96,6 +112,15 @@ Examples:
 			os.Exit(1)
 		}
 
+		if localMode {
+			if err := b.ServeLocal(listenAddr); err != nil {
+				logger.Error("Error starting local web UI: %v", err)
+				fmt.Fprintf(os.Stderr, "Could not serve the web UI on %s: %v\n", listenAddr, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Open Agents is running locally. Open this URL in a browser:\n\n  %s\n\n", b.LocalURL())
+		}
+
 		// Setup system tray notification
 		trayTitle := "Open Agents"
 		if cfg.DeviceName != "" {
@@ -131,4 +156,6 @@ func init() {
 	startCmd.Flags().StringVarP(&logLevel, "log-level", "l", "info", "Log level (error, warn, info, debug)")
 	startCmd.Flags().BoolVarP(&headless, "headless", "H", false, "Run in headless mode (no system tray)")
 	startCmd.Flags().StringVarP(&deviceName, "device", "d", "", "Device name to start (default: current device)")
+	startCmd.Flags().BoolVar(&localMode, "local", false, "Serve a web UI on localhost instead of connecting to the cloud")
+	startCmd.Flags().StringVar(&listenAddr, "listen", localui.DefaultAddr, "Address of the local web UI (with --local)")
 }
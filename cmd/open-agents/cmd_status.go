@@ -1,34 +1,123 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/control"
+	"github.com/open-agents/bridge/internal/metrics"
 	"github.com/spf13/cobra"
 )
 
+var statusDevice string
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show bridge status",
-	Long:  `Display the current status of the Open Agents bridge.`,
+	Long: `Display the current status of the Open Agents bridge. When the bridge
+is running, its connection state, sessions, pending permissions and health
+are read from the local control socket.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cfg, err := config.Load()
+		cfg, err := loadDeviceConfig(statusDevice)
 		if err != nil {
-			fmt.Println("Status: Not configured")
-			fmt.Println("Run 'open-agents pair' to configure the bridge.")
-			os.Exit(0)
+			// An unpaired machine may still run the bridge in local mode
+			cfg = &config.Config{DeviceName: statusDevice}
+			if _, derr := control.Dial(control.PathsFor(statusDevice)); derr != nil {
+				fmt.Println("Status: Not configured")
+				fmt.Println("Run 'open-agents pair' to configure the bridge, or 'open-agents start --local' to run without the cloud.")
+				os.Exit(0)
+			}
 		}
 
 		fmt.Println("Open Agents Bridge Status")
 		fmt.Println("=========================")
-		fmt.Printf("Device ID:    %s\n", cfg.DeviceID)
-		fmt.Printf("User ID:      %s\n", cfg.UserID)
-		fmt.Printf("Server:       %s\n", cfg.ServerURL)
+		if cfg.DeviceName != "" {
+			fmt.Printf("Device:       %s\n", cfg.DeviceName)
+		}
+		if cfg.DeviceID != "" {
+			fmt.Printf("Device ID:    %s\n", cfg.DeviceID)
+			fmt.Printf("User ID:      %s\n", cfg.UserID)
+			fmt.Printf("Server:       %s\n", cfg.ServerURL)
+		}
 		fmt.Println()
 
-		// TODO: Check if bridge is running
-		fmt.Println("Status: Configured (not running)")
-		fmt.Println("Run 'open-agents start' to start the bridge.")
+		client, err := control.Dial(control.PathsFor(cfg.DeviceName))
+		if errors.Is(err, control.ErrNotRunning) {
+			fmt.Println("Status: Configured (not running)")
+			fmt.Println("Run 'open-agents start' to start the bridge.")
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not reach the bridge: %v\n", err)
+			os.Exit(1)
+		}
+		st, err := client.Status()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not read bridge status: %v\n", err)
+			os.Exit(1)
+		}
+		printStatus(st)
 	},
 }
+
+func init() {
+	statusCmd.Flags().StringVarP(&statusDevice, "device", "d", "", "Device name (default: current device)")
+}
+
+// loadDeviceConfig loads the config the bridge of a device was started with:
+// the named device, $OPEN_AGENTS_DEVICE, or the single-device config
+func loadDeviceConfig(device string) (*config.Config, error) {
+	if device == "" {
+		device = os.Getenv("OPEN_AGENTS_DEVICE")
+	}
+	if device != "" {
+		return config.LoadDevice(device)
+	}
+	return config.Load()
+}
+
+func printStatus(st control.Status) {
+	fmt.Printf("Status:       Running (pid %d, up %s)\n", st.PID, time.Since(st.StartedAt).Round(time.Second))
+	fmt.Printf("Connection:   %s since %s\n", st.Connection.State, st.Connection.Since.Format("2006-01-02 15:04:05"))
+	if st.LocalUI != "" {
+		fmt.Printf("Web UI:       %s\n", st.LocalUI)
+	}
+	if attempts, ok := st.Reconnect["total_attempts"]; ok {
+		fmt.Printf("Reconnects:   %v attempts, %v succeeded\n", attempts, st.Reconnect["successful_attempts"])
+	}
+	fmt.Printf("Sessions:     %d active\n", st.Sessions["active"])
+	fmt.Printf("Queued:       %d prompt(s)\n", st.Queued)
+	fmt.Printf("Permissions:  %d pending\n", st.Permissions)
+	if st.Health != nil {
+		var problems []string
+		for name, check := range st.Health.Checks {
+			if check.Status != metrics.HealthStatusHealthy {
+				problems = append(problems, fmt.Sprintf("%s: %s", name, check.Message))
+			}
+		}
+		fmt.Printf("Health:       %s\n", st.Health.Status)
+		for _, p := range problems {
+			fmt.Printf("  - %s\n", p)
+		}
+	}
+
+	history := st.Connection.History
+	if len(history) > 5 {
+		history = history[len(history)-5:]
+	}
+	if len(history) > 0 {
+		fmt.Println()
+		fmt.Println("Recent connection changes:")
+		for _, t := range history {
+			reason := ""
+			if t.Reason != "" {
+				reason = " (" + strings.ReplaceAll(t.Reason, "_", " ") + ")"
+			}
+			fmt.Printf("  %s  %s -> %s%s\n", t.At.Format("15:04:05"), t.From, t.To, reason)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/open-agents/bridge/internal/sandbox"
 	"github.com/spf13/cobra"
 )
 
@@ -15,9 +16,16 @@ var rootCmd = &cobra.Command{
 
 It enables remote monitoring, permission management, and real-time
 collaboration across multiple devices.`,
+	// main prints the error once
+	SilenceErrors: true,
 }
 
 func main() {
+	// Sandboxed sessions re-exec the bridge as the namespace helper
+	if len(os.Args) > 1 && os.Args[1] == sandbox.InitArg {
+		sandbox.Init()
+	}
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -32,4 +40,11 @@ func init() {
 	rootCmd.AddCommand(logsCmd)
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(sessionsCmd)
+	rootCmd.AddCommand(permissionsCmd)
+	rootCmd.AddCommand(clisCmd)
+	rootCmd.AddCommand(attachCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(rulesCmd)
 }
@@ -0,0 +1,7 @@
+package main
+
+// watchResize is a no-op on Windows, which has no SIGWINCH; the session keeps
+// the size the terminal had when it attached
+func watchResize(resize func()) (stop func()) {
+	return func() {}
+}
@@ -3,10 +3,14 @@ module github.com/open-agents/bridge
 go 1.21
 
 require (
+	github.com/bmatcuk/doublestar/v4 v4.6.1
 	github.com/creack/pty v1.1.24
 	github.com/google/uuid v1.6.0
 	github.com/gorilla/websocket v1.5.1
 	github.com/spf13/cobra v1.8.0
+	github.com/stretchr/testify v1.11.1
+	golang.org/x/term v0.15.0
+	mvdan.cc/sh/v3 v3.7.0
 )
 
 require (
@@ -14,7 +18,7 @@ require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	github.com/stretchr/testify v1.11.1 // indirect
 	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
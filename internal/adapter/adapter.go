@@ -1,6 +1,10 @@
 package adapter
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/open-agents/bridge/internal/clidef"
+)
 
 // OutputEvent represents output from the CLI
 type OutputEvent struct {
@@ -51,13 +55,29 @@ var registry = map[string]func() Adapter{
 	"gemini": func() Adapter { return NewGeminiAdapter() },
 }
 
-// Get returns an adapter by name
+// definitions holds the configured CLI definitions on top of the built-ins
+var definitions = clidef.NewRegistry(nil)
+
+// SetDefinitions applies configured CLI definitions. Get prefers a configured
+// definition over the dedicated adapter of the same name.
+func SetDefinitions(defs map[string]clidef.Definition) {
+	definitions.Set(defs)
+}
+
+// Get returns an adapter by name. CLIs with a configured definition, and
+// CLIs without a dedicated adapter, get a generic one built from their
+// definition.
 func Get(name string) (Adapter, error) {
-	factory, ok := registry[name]
-	if !ok {
-		return nil, fmt.Errorf("unknown adapter: %s", name)
+	if definitions.Configured(name) {
+		return FromDefinition(name, definitions.Get(name)), nil
 	}
-	return factory(), nil
+	if factory, ok := registry[name]; ok {
+		return factory(), nil
+	}
+	if definitions.Known(name) {
+		return FromDefinition(name, definitions.Get(name)), nil
+	}
+	return nil, fmt.Errorf("unknown adapter: %s", name)
 }
 
 // List returns all available adapter names
@@ -66,5 +86,10 @@ func List() []string {
 	for name := range registry {
 		names = append(names, name)
 	}
+	for _, name := range definitions.Names() {
+		if _, ok := registry[name]; !ok {
+			names = append(names, name)
+		}
+	}
 	return names
 }
@@ -0,0 +1,52 @@
+package adapter
+
+import (
+	"github.com/open-agents/bridge/internal/clidef"
+)
+
+// GenericAdapter runs any CLI described by a definition
+type GenericAdapter struct {
+	BaseAdapter
+	args []string
+}
+
+// FromDefinition creates an adapter for a CLI definition
+func FromDefinition(name string, def clidef.Definition) *GenericAdapter {
+	env := make([]string, 0, len(def.Env))
+	for k, v := range def.Env {
+		env = append(env, k+"="+v)
+	}
+	displayName := def.DisplayName
+	if displayName == "" {
+		displayName = name
+	}
+	command := def.Command
+	if command == "" {
+		command = name
+	}
+	return &GenericAdapter{
+		BaseAdapter: BaseAdapter{
+			name:        name,
+			displayName: displayName,
+			command:     command,
+			extraEnv:    env,
+		},
+		args: def.Args,
+	}
+}
+
+func (a *GenericAdapter) Name() string {
+	return a.name
+}
+
+func (a *GenericAdapter) DisplayName() string {
+	return a.displayName
+}
+
+func (a *GenericAdapter) Start(workDir string, args []string) error {
+	return a.BaseAdapter.Start(workDir, append(append([]string(nil), a.args...), args...))
+}
+
+func (a *GenericAdapter) StartWithSize(workDir string, args []string, cols, rows int) error {
+	return a.BaseAdapter.StartWithSize(workDir, append(append([]string(nil), a.args...), args...), cols, rows)
+}
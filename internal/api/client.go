@@ -76,10 +76,13 @@ func (c *Client) request(method, path string, body interface{}) ([]byte, error)
 // Permission Rules
 
 type PermissionRule struct {
-	ID      string `json:"id"`
-	Pattern string `json:"pattern"`
-	Tool    string `json:"tool"`
-	Action  string `json:"action"`
+	ID          string `json:"id"`
+	Pattern     string `json:"pattern"`
+	Tool        string `json:"tool"`
+	Action      string `json:"action"`
+	Expression  string `json:"expression,omitempty"`
+	Priority    int    `json:"priority,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 func (c *Client) GetPermissionRules(project string) ([]PermissionRule, error) {
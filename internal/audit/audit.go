@@ -0,0 +1,333 @@
+// Package audit keeps an append-only, hash-chained record of what happened
+// on the device: permission requests and decisions, file writes and
+// commands run for agents, session starts and stops, synced config and
+// scanner alerts.
+//
+// Each entry carries the hash of the previous one and its own hash over
+// both, so editing, removing or reordering entries breaks the chain. The
+// hashes are HMAC-SHA256 under a per-device key kept outside the audit
+// directory (see KeyFile): without the key a rewritten chain cannot be made
+// to verify again. The last sequence number and hash are also kept in a
+// separate head file, which catches entries cut off the end of the log.
+//
+// Verification therefore shows that the log was written by someone holding
+// the key. It cannot tell the bridge apart from anyone else who can read the
+// key file; for that, keep a copy of the key or of exported reports off the
+// device.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/open-agents/bridge/internal/config"
+)
+
+// Entry types
+const (
+	PermissionRequest  = "permission.request"
+	PermissionDecision = "permission.decision"
+	FileWrite          = "fs.write"
+	TerminalCommand    = "terminal.command"
+	TerminalExit       = "terminal.exit"
+	SessionStart       = "session.start"
+	SessionStop        = "session.stop"
+	ConfigSync         = "config.sync"
+	RulesSync          = "rules.sync"
+	ScannerAlert       = "scanner.alert"
+	Tampered           = "audit.tampered" // the log did not match its head when opened
+)
+
+// Entry is one audited event
+type Entry struct {
+	Seq       uint64         `json:"seq"`
+	Time      time.Time      `json:"time"`
+	Type      string         `json:"type"`
+	DeviceID  string         `json:"deviceId,omitempty"`
+	SessionID string         `json:"sessionId,omitempty"`
+	Actor     string         `json:"actor,omitempty"` // who did or decided it: a client, "rule:<id>", "agent", ...
+	Data      map[string]any `json:"data,omitempty"`
+	Prev      string         `json:"prev"` // hash of the previous entry; empty for the first
+	Hash      string         `json:"hash,omitempty"`
+}
+
+// sum computes the keyed hash of an entry without its own hash
+func (e Entry) sum(key []byte) (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// head is the last entry written, kept next to the log
+type head struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// PathFor returns the audit log of a device; each bridge writes its own
+func PathFor(device string) string {
+	if device == "" {
+		device = "default"
+	}
+	return filepath.Join(config.ConfigDir(), "audit", device+".jsonl")
+}
+
+// HeadFile returns the file holding the head of the log at path
+func HeadFile(path string) string {
+	return path + ".head"
+}
+
+// KeyFile returns the file holding the key of a device's audit log. It is
+// kept with the device credentials rather than next to the log, so that
+// write access to the audit directory is not enough to rebuild the chain.
+func KeyFile(device string) string {
+	if device == "" {
+		device = "default"
+	}
+	return filepath.Join(config.ConfigDir(), "keys", "audit-"+device+".key")
+}
+
+// keySize is the length of a generated key in bytes
+const keySize = 32
+
+// ReadKey reads the key at path
+func ReadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil || len(key) < keySize {
+		return nil, fmt.Errorf("audit key %s is malformed", path)
+	}
+	return key, nil
+}
+
+// LoadKey reads the key at path, generating a new one if there is none yet
+func LoadKey(path string) ([]byte, error) {
+	key, err := ReadKey(path)
+	if !os.IsNotExist(err) {
+		return key, err
+	}
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		// Another process created it first
+		return ReadKey(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Log appends entries to a file. Only one process should write a log.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	key  []byte
+	f    *os.File
+	last head
+}
+
+// Open opens the log at path for appending, creating it if needed. Entries
+// are chained with key, which must be the key the log was started with.
+func Open(path string, key []byte) (*Log, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("audit log needs a key")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	l := &Log{path: path, key: key}
+	var entries int
+	var last, before Entry
+	err := scan(path, func(_ int, e Entry, err error) {
+		if err == nil {
+			entries++
+			before, last = last, e
+		}
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	l.last = head{Seq: last.Seq, Hash: last.Hash}
+	// The chain goes on from the last entry, so a log cut or appended to
+	// while the bridge was stopped is recorded before it would verify again
+	headSeq, tampered := checkHead(path, entries, last, before)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	// A write cut short by a crash leaves a partial line; start a new one
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if r, err := os.Open(path); err == nil {
+			if _, err := r.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+				f.Write([]byte("\n"))
+			}
+			r.Close()
+		}
+	}
+	l.f = f
+	if tampered != "" {
+		if _, err := l.Append(Entry{Type: Tampered, Data: map[string]any{"reason": tampered, "headSeq": headSeq, "logSeq": last.Seq}}); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Path returns the file of the log
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append chains an entry to the log; Seq, Time, Prev and Hash are set here
+func (l *Log) Append(e Entry) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return Entry{}, fmt.Errorf("audit log is closed")
+	}
+
+	data, err := normalize(e.Data)
+	if err != nil {
+		return Entry{}, err
+	}
+	e.Data = data
+	e.Seq = l.last.Seq + 1
+	e.Time = time.Now().UTC()
+	e.Prev = l.last.Hash
+	sum, err := e.sum(l.key)
+	if err != nil {
+		return Entry{}, err
+	}
+	e.Hash = sum
+	line, err := json.Marshal(e)
+	if err != nil {
+		return Entry{}, err
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		return Entry{}, err
+	}
+	if err := l.f.Sync(); err != nil {
+		return Entry{}, err
+	}
+	l.last = head{Seq: e.Seq, Hash: e.Hash}
+	writeHead(l.path, l.last)
+	return e, nil
+}
+
+// normalize round-trips data through JSON, so the hash written is the one
+// Verify computes from the decoded line (struct fields become sorted keys)
+func normalize(data map[string]any) (map[string]any, error) {
+	if data == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+func writeHead(path string, h head) {
+	data, _ := json.Marshal(h)
+	tmp := HeadFile(path) + ".tmp"
+	if os.WriteFile(tmp, data, 0600) == nil {
+		os.Rename(tmp, HeadFile(path))
+	}
+}
+
+// scan calls fn with each line of the log, numbered from 1, decoded or with
+// the error that kept it from decoding
+func scan(path string, fn func(line int, e Entry, err error)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		data, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			e, err := decode(data)
+			fn(n, e, err)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// decode parses a line, keeping numbers as written so they hash the same
+func decode(data []byte) (Entry, error) {
+	var e Entry
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&e); err != nil {
+		return Entry{}, fmt.Errorf("malformed entry: %v", err)
+	}
+	return e, nil
+}
+
+// Read returns the entries of the log at path, skipping malformed lines
+func Read(path string) ([]Entry, error) {
+	var entries []Entry
+	err := scan(path, func(_ int, e Entry, err error) {
+		if err == nil {
+			entries = append(entries, e)
+		}
+	})
+	return entries, err
+}
@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X, Y int
+}
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func writeLog(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Append(Entry{Type: SessionStart, SessionID: "s1", Actor: "web", Data: map[string]any{"cliType": "claude", "workDir": "/repo"}})
+	l.Append(Entry{Type: PermissionRequest, SessionID: "s1", Data: map[string]any{"id": 7, "tool": "execute_bash", "risk": "high", "at": point{1, 2}}})
+	l.Append(Entry{Type: PermissionDecision, SessionID: "s1", Actor: "rule:tests", Data: map[string]any{"id": 7, "approved": true}})
+	l.Close()
+
+	// Reopening continues the chain
+	l, err = Open(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := l.Append(Entry{Type: SessionStop, SessionID: "s1", Actor: "web"}); e.Seq != 4 {
+		t.Fatalf("seq after reopen = %d", e.Seq)
+	}
+	l.Close()
+	return path
+}
+
+func TestVerify(t *testing.T) {
+	path := writeLog(t)
+	r, err := Verify(path, testKey)
+	if err != nil || !r.OK() || r.Entries != 4 || r.LastSeq != 4 {
+		t.Fatalf("intact log: %+v, %v", r, err)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(string(data), "\n")
+	tamper := func(name string, edit func([]string) []string) {
+		t.Helper()
+		os.WriteFile(path, []byte(strings.Join(edit(append([]string(nil), lines...)), "")), 0600)
+		if r, _ := Verify(path, testKey); r.OK() {
+			t.Errorf("%s: not detected", name)
+		}
+	}
+	tamper("edited", func(l []string) []string {
+		l[2] = strings.Replace(l[2], `"approved":true`, `"approved":false`, 1)
+		return l
+	})
+	tamper("removed", func(l []string) []string { return append(l[:1], l[2:]...) })
+	tamper("swapped", func(l []string) []string { l[1], l[2] = l[2], l[1]; return l })
+	tamper("truncated", func(l []string) []string { return l[:3] })
+	tamper("garbled", func(l []string) []string { l[0] = "{" + l[0]; return l })
+}
+
+func TestOpenRecordsAlteredLog(t *testing.T) {
+	for name, alter := range map[string]func(path string, lines []string){
+		"cut": func(path string, lines []string) {
+			os.WriteFile(path, []byte(strings.Join(lines[:2], "")), 0600)
+		},
+		"head removed": func(path string, lines []string) {
+			os.Remove(HeadFile(path))
+		},
+	} {
+		path := writeLog(t)
+		data, _ := os.ReadFile(path)
+		alter(path, strings.SplitAfter(string(data), "\n"))
+
+		// Going on from the last entry left would hide it
+		l, err := Open(path, testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Append(Entry{Type: SessionStart, SessionID: "s2"})
+		l.Close()
+
+		r, err := Verify(path, testKey)
+		if err != nil || r.OK() {
+			t.Errorf("%s: %+v, %v", name, r, err)
+			continue
+		}
+		if entries, _ := Read(path); !containsType(entries, Tampered) {
+			t.Errorf("%s: no %s entry", name, Tampered)
+		}
+	}
+}
+
+func containsType(entries []Entry, typ string) bool {
+	for _, e := range entries {
+		if e.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func TestVerifyRejectsChainRebuiltWithoutKey(t *testing.T) {
+	path := writeLog(t)
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rewrite an entry and recompute the whole chain and head, as someone
+	// with write access to the log but not the key could
+	os.Remove(path)
+	os.Remove(HeadFile(path))
+	l, err := Open(path, []byte("guessed-key-guessed-key-guessed!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Type == PermissionDecision {
+			e.Data["approved"] = false
+		}
+		l.Append(e)
+	}
+	l.Close()
+
+	if r, _ := Verify(path, testKey); r.OK() {
+		t.Error("rebuilt chain verified without the key")
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "audit-test.key")
+	key, err := LoadKey(path)
+	if err != nil || len(key) != keySize {
+		t.Fatalf("LoadKey = %x, %v", key, err)
+	}
+	again, err := LoadKey(path)
+	if err != nil || !bytes.Equal(key, again) {
+		t.Errorf("reloaded key = %x, %v; want %x", again, err, key)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v, %v", info.Mode(), err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	path := writeLog(t)
+	r, _ := Verify(path, testKey)
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x := NewExport(path, r, entries, Filter{Types: []string{"permission."}})
+	if x.Summary.Entries != 2 || x.Summary.Approved != 1 || x.Summary.DecidedBy["rule"] != 1 {
+		t.Errorf("summary = %+v", x.Summary)
+	}
+
+	var buf bytes.Buffer
+	if err := x.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "7 approved by rule:tests") {
+		t.Errorf("csv = %s", buf.String())
+	}
+}
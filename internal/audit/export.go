@@ -0,0 +1,191 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Filter selects entries for an export; zero fields match everything
+type Filter struct {
+	Since     *time.Time `json:"since,omitempty"`
+	Until     *time.Time `json:"until,omitempty"`
+	SessionID string     `json:"sessionId,omitempty"`
+	Types     []string   `json:"types,omitempty"` // a type, or a prefix ending in "." such as "permission."
+}
+
+// Match reports whether an entry passes the filter
+func (f Filter) Match(e Entry) bool {
+	if f.Since != nil && e.Time.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && !e.Time.Before(*f.Until) {
+		return false
+	}
+	if f.SessionID != "" && e.SessionID != f.SessionID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if e.Type == t || strings.HasSuffix(t, ".") && strings.HasPrefix(e.Type, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary counts what an export covers
+type Summary struct {
+	Entries   int            `json:"entries"`
+	From      *time.Time     `json:"from,omitempty"`
+	To        *time.Time     `json:"to,omitempty"`
+	ByType    map[string]int `json:"byType"`
+	Sessions  int            `json:"sessions"`
+	Approved  int            `json:"approved"`
+	Denied    int            `json:"denied"`
+	DecidedBy map[string]int `json:"decidedBy"` // "user", "rule", "grant", "remembered", "timeout", ...
+}
+
+// Export is a compliance report: the verification result, a summary and
+// the selected entries
+type Export struct {
+	GeneratedAt  time.Time `json:"generatedAt"`
+	Log          string    `json:"log"`
+	Verification Report    `json:"verification"`
+	Filter       Filter    `json:"filter"`
+	Summary      Summary   `json:"summary"`
+	Entries      []Entry   `json:"entries"`
+}
+
+// NewExport builds a report over the entries f selects
+func NewExport(path string, report Report, entries []Entry, f Filter) Export {
+	x := Export{
+		GeneratedAt:  time.Now().UTC(),
+		Log:          path,
+		Verification: report,
+		Filter:       f,
+		Summary:      Summary{ByType: map[string]int{}, DecidedBy: map[string]int{}},
+		Entries:      []Entry{},
+	}
+	sessions := map[string]bool{}
+	for _, e := range entries {
+		if !f.Match(e) {
+			continue
+		}
+		x.Entries = append(x.Entries, e)
+		s := &x.Summary
+		at := e.Time
+		if s.From == nil {
+			s.From = &at
+		}
+		s.To = &at
+		s.ByType[e.Type]++
+		if e.SessionID != "" {
+			sessions[e.SessionID] = true
+		}
+		if e.Type == PermissionDecision {
+			if approved, _ := e.Data["approved"].(bool); approved {
+				s.Approved++
+			} else {
+				s.Denied++
+			}
+			s.DecidedBy[decider(e.Actor)]++
+		}
+	}
+	x.Summary.Entries = len(x.Entries)
+	x.Summary.Sessions = len(sessions)
+	return x
+}
+
+// decider groups actors: "rule:ls" and "rule:tests" are both "rule"; any
+// client answering is "user"
+func decider(actor string) string {
+	kind, _, found := strings.Cut(actor, ":")
+	switch {
+	case found:
+		return kind
+	case actor == "timeout" || actor == "":
+		return orNone(actor)
+	}
+	return "user"
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// WriteJSON writes the report as indented JSON
+func (x Export) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(x)
+}
+
+// WriteCSV writes one row per entry, with a readable summary of each
+func (x Export) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"seq", "time", "type", "session", "actor", "summary", "hash"})
+	for _, e := range x.Entries {
+		cw.Write([]string{
+			fmt.Sprint(e.Seq), e.Time.Format(time.RFC3339), e.Type, e.SessionID, e.Actor, Describe(e), e.Hash,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Describe summarizes an entry in one line
+func Describe(e Entry) string {
+	str := func(k string) string {
+		if v, ok := e.Data[k]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+	switch e.Type {
+	case PermissionRequest:
+		return strings.TrimSpace(fmt.Sprintf("%s %s (%s risk)", str("tool"), str("description"), orNone(str("risk"))))
+	case PermissionDecision:
+		verdict := "denied"
+		if approved, _ := e.Data["approved"].(bool); approved {
+			verdict = "approved"
+		}
+		s := fmt.Sprintf("%s %s by %s", str("id"), verdict, orNone(e.Actor))
+		if r := str("reason"); r != "" {
+			s += ": " + r
+		}
+		return s
+	case FileWrite:
+		return fmt.Sprintf("%s (%s bytes)", str("path"), str("bytes"))
+	case TerminalCommand:
+		return str("command")
+	case TerminalExit:
+		return fmt.Sprintf("%s exited %s", str("terminalId"), str("exitCode"))
+	case SessionStart:
+		return fmt.Sprintf("%s in %s", str("cliType"), str("workDir"))
+	case ScannerAlert:
+		return fmt.Sprintf("[%s] %s (%s)", str("level"), str("title"), str("ruleId"))
+	}
+	if len(e.Data) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + str(k)
+	}
+	return strings.Join(parts, " ")
+}
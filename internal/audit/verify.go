@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Problem is a place where the log does not match its chain
+type Problem struct {
+	Line   int    `json:"line,omitempty"`
+	Seq    uint64 `json:"seq,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Report is the result of verifying a log
+type Report struct {
+	Entries  int       `json:"entries"`
+	LastSeq  uint64    `json:"lastSeq"`
+	LastHash string    `json:"lastHash,omitempty"`
+	Problems []Problem `json:"problems,omitempty"`
+}
+
+// OK reports whether the log verified without problems
+func (r Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify checks every entry of the log at path: sequence numbers count up
+// from 1 without gaps, each entry names the hash of the one before, each
+// hash matches its entry under key, the log ends where its head file says
+// and no reopening of the log found it altered. A log that verifies was written by a holder of the key; it is not
+// proof against someone who could read the key file.
+func Verify(path string, key []byte) (Report, error) {
+	var r Report
+	var prev, before Entry
+	problem := func(line int, seq uint64, format string, args ...any) {
+		r.Problems = append(r.Problems, Problem{Line: line, Seq: seq, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	err := scan(path, func(line int, e Entry, err error) {
+		if err != nil {
+			problem(line, 0, "%v", err)
+			return
+		}
+		r.Entries++
+		if e.Seq != prev.Seq+1 {
+			problem(line, e.Seq, "sequence jumps from %d to %d", prev.Seq, e.Seq)
+		}
+		if e.Prev != prev.Hash {
+			problem(line, e.Seq, "previous hash does not match entry %d", prev.Seq)
+		}
+		if sum, err := e.sum(key); err != nil || sum != e.Hash {
+			problem(line, e.Seq, "entry was modified (hash mismatch)")
+		}
+		if e.Type == Tampered {
+			problem(line, e.Seq, "log did not match its head when opened: %v", e.Data["reason"])
+		}
+		before, prev = prev, e
+	})
+	if err != nil {
+		return r, err
+	}
+	r.LastSeq, r.LastHash = prev.Seq, prev.Hash
+	if seq, reason := checkHead(path, r.Entries, prev, before); reason != "" {
+		problem(0, seq, "%s", reason)
+	}
+	return r, nil
+}
+
+// checkHead compares the end of a log, its last entry and the one before,
+// with its head file. It returns why they differ, if they do, and the
+// sequence number the head names.
+func checkHead(path string, entries int, last, before Entry) (uint64, string) {
+	data, err := os.ReadFile(HeadFile(path))
+	if err != nil {
+		if entries > 0 {
+			return 0, "head file is missing"
+		}
+		return 0, ""
+	}
+	var h head
+	if err := json.Unmarshal(data, &h); err != nil {
+		return 0, "head file is malformed"
+	}
+	switch {
+	case h.Seq > last.Seq:
+		return h.Seq, fmt.Sprintf("log ends at entry %d but %d were written", last.Seq, h.Seq)
+	case h.Seq == last.Seq-1 && h.Hash == before.Hash:
+		// the bridge is between writing an entry and its head
+	case h.Seq < last.Seq:
+		return h.Seq, fmt.Sprintf("log has entries after the head (%d), appended outside the bridge", h.Seq)
+	case h.Hash != last.Hash:
+		return h.Seq, "last entry does not match the head"
+	}
+	return h.Seq, ""
+}
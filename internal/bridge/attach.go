@@ -0,0 +1,213 @@
+package bridge
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/open-agents/bridge/internal/control"
+	"github.com/open-agents/bridge/internal/session"
+)
+
+const (
+	// attachBuffer is how many events an attached terminal may lag behind
+	// before it is detached; blocking would stall output to the web too
+	attachBuffer = 1024
+	// scrollbackSize is how much recent PTY output is replayed on attach
+	scrollbackSize = 64 * 1024
+)
+
+// attachHub copies each session's web stream to the local terminals attached to it
+type attachHub struct {
+	mu         sync.Mutex
+	subs       map[string]map[*attachment]struct{}
+	scrollback map[string][]byte
+}
+
+func newAttachHub() *attachHub {
+	return &attachHub{
+		subs:       make(map[string]map[*attachment]struct{}),
+		scrollback: make(map[string][]byte),
+	}
+}
+
+// publish hands a message sent to the web to the session's attached terminals
+func (h *attachHub) publish(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+	sessionID, _ := payload["sessionId"].(string)
+	if sessionID == "" {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if text, ok := payload["content"].(string); ok && msg.Type == "chat:response" && payload["protocol"] == "pty" {
+		buf := append(h.scrollback[sessionID], text...)
+		if len(buf) > scrollbackSize {
+			buf = buf[len(buf)-scrollbackSize:]
+		}
+		h.scrollback[sessionID] = buf
+	}
+
+	ev := control.Event{Type: msg.Type, Payload: payload, Timestamp: msg.Timestamp}
+	for a := range h.subs[sessionID] {
+		select {
+		case a.events <- ev:
+		default:
+			h.detachLocked(a, "the terminal fell too far behind the session output")
+		}
+	}
+
+	if msg.Type == "session:stopped" {
+		for a := range h.subs[sessionID] {
+			h.detachLocked(a, "session stopped")
+		}
+		delete(h.scrollback, sessionID)
+	}
+}
+
+// subscribe registers an attachment and queues its first event
+func (h *attachHub) subscribe(a *attachment, ready control.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ready.Payload["scrollback"] = string(h.scrollback[a.sessionID])
+	a.events <- ready
+	if h.subs[a.sessionID] == nil {
+		h.subs[a.sessionID] = make(map[*attachment]struct{})
+	}
+	h.subs[a.sessionID][a] = struct{}{}
+}
+
+// detachLocked ends an attachment's stream with a reason; h.mu must be held
+func (h *attachHub) detachLocked(a *attachment, reason string) {
+	if _, ok := h.subs[a.sessionID][a]; !ok {
+		return
+	}
+	delete(h.subs[a.sessionID], a)
+	if len(h.subs[a.sessionID]) == 0 {
+		delete(h.subs, a.sessionID)
+	}
+	if reason != "" {
+		select {
+		case a.events <- control.Event{
+			Type:      control.EventDetached,
+			Payload:   map[string]interface{}{"sessionId": a.sessionID, "reason": reason},
+			Timestamp: time.Now().UnixMilli(),
+		}:
+		default:
+		}
+	}
+	close(a.events)
+	go a.released()
+}
+
+// closeAll detaches every terminal, e.g. when the bridge stops
+func (h *attachHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subs := range h.subs {
+		for a := range subs {
+			h.detachLocked(a, "bridge stopped")
+		}
+	}
+}
+
+// attachment is one local terminal attached to a session
+type attachment struct {
+	b         *Bridge
+	sessionID string
+	clientID  string
+	events    chan control.Event
+}
+
+func (a *attachment) Events() <-chan control.Event {
+	return a.events
+}
+
+func (a *attachment) Input(data []byte) error {
+	sess := a.b.sessions.Get(a.sessionID)
+	if sess == nil {
+		return control.NotFound("session %s not found", a.sessionID)
+	}
+	if err := (localAPI{a.b}).requireLocalControl(a.sessionID, a.clientID); err != nil {
+		return err
+	}
+	return sess.WriteRaw(data)
+}
+
+// Resize follows the attached terminal's size; viewers don't resize the
+// controller's terminal, so their resizes are ignored
+func (a *attachment) Resize(cols, rows int) error {
+	ctrl := a.b.sessions.Owners().Controller(a.sessionID)
+	if ctrl == nil || ctrl.ClientID != a.clientID {
+		return nil
+	}
+	return a.b.sessions.Resize(a.sessionID, cols, rows)
+}
+
+func (a *attachment) Close() {
+	a.b.attach.mu.Lock()
+	defer a.b.attach.mu.Unlock()
+	a.b.attach.detachLocked(a, "")
+}
+
+// released gives up the control a detached terminal held
+func (a *attachment) released() {
+	b := a.b
+	previous := b.sessions.Owners().Controller(a.sessionID)
+	if previous == nil || previous.ClientID != a.clientID {
+		return
+	}
+	if b.sessions.Owners().Release(a.sessionID, a.clientID) {
+		b.logInfo("[Control] Local terminal %s detached from session %s", a.clientID, a.sessionID)
+		b.sendControlChanged(a.sessionID, nil, previous, "detached")
+	}
+}
+
+// Attach attaches a local terminal to a session. A terminal takes control of
+// a session nobody controls (or any session with Takeover); otherwise it
+// watches read-only until control is released.
+func (a localAPI) Attach(id string, req control.AttachRequest) (control.Attachment, error) {
+	b := a.b
+	if b.sessions.Get(id) == nil {
+		return nil, control.NotFound("session %s not found", id)
+	}
+	clientID := req.ClientID
+	if clientID == "" {
+		clientID = fmt.Sprintf("attach-%d-%d", os.Getpid(), time.Now().UnixNano())
+	}
+
+	owners := b.sessions.Owners()
+	var ctrl session.Controller
+	if req.Takeover {
+		var previous *session.Controller
+		ctrl, previous = owners.Takeover(id, clientID, session.ClientLocal)
+		b.logInfo("[Control] Local terminal %s took over session %s", clientID, id)
+		b.sendControlChanged(id, &ctrl, previous, "takeover")
+	} else if c, _, claimed := owners.Claim(id, clientID, session.ClientLocal); claimed {
+		ctrl = c
+		b.logInfo("[Control] Local terminal %s now controls session %s", clientID, id)
+		b.sendControlChanged(id, &ctrl, nil, "claimed")
+	} else {
+		ctrl = c
+	}
+
+	att := &attachment{b: b, sessionID: id, clientID: clientID, events: make(chan control.Event, attachBuffer)}
+	b.attach.subscribe(att, control.Event{
+		Type: control.EventAttached,
+		Payload: map[string]interface{}{
+			"sessionId":  id,
+			"clientId":   clientID,
+			"controller": ctrl,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	b.logInfo("[Control] Local terminal %s attached to session %s", clientID, id)
+	return att, nil
+}
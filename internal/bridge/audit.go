@@ -0,0 +1,127 @@
+package bridge
+
+import (
+	"sort"
+
+	"github.com/open-agents/bridge/internal/audit"
+	"github.com/open-agents/bridge/internal/protocol"
+	"github.com/open-agents/bridge/internal/scanner"
+	"github.com/open-agents/bridge/internal/session"
+)
+
+// auditEvent appends an entry to the local audit log, if it could be opened
+func (b *Bridge) auditEvent(typ, sessionID, actor string, data map[string]interface{}) {
+	if b.audit == nil {
+		return
+	}
+	_, err := b.audit.Append(audit.Entry{
+		Type:      typ,
+		DeviceID:  b.config.DeviceID,
+		SessionID: sessionID,
+		Actor:     actor,
+		Data:      data,
+	})
+	if err != nil {
+		b.logError("[Audit] Failed to record %s: %v", typ, err)
+	}
+}
+
+// auditPermissionRequest records a permission request as it arrives,
+// whoever ends up answering it
+func (b *Bridge) auditPermissionRequest(origin, sessionID, id, tool, description, risk string, input map[string]interface{}) {
+	b.auditEvent(audit.PermissionRequest, sessionID, "", map[string]interface{}{
+		"id":          id,
+		"origin":      origin,
+		"tool":        tool,
+		"description": description,
+		"risk":        risk,
+		"input":       input,
+	})
+}
+
+// auditPermissionDecision records the answer to a permission request;
+// decidedBy is the approving client, or "rule:<id>", "grant:<id>",
+// "remembered:<id>" or "timeout"
+func (b *Bridge) auditPermissionDecision(sessionID, id string, approved bool, optionID, decidedBy, reason string) {
+	data := map[string]interface{}{"id": id, "approved": approved}
+	if optionID != "" {
+		data["optionId"] = optionID
+	}
+	if reason != "" {
+		data["reason"] = reason
+	}
+	b.auditEvent(audit.PermissionDecision, sessionID, decidedBy, data)
+}
+
+// auditActivity records a file write or command an ACP agent had the bridge
+// carry out
+func (b *Bridge) auditActivity(sessionID string, msg protocol.Message) {
+	typ := map[interface{}]string{
+		protocol.ActivityFileWrite:   audit.FileWrite,
+		protocol.ActivityCommand:     audit.TerminalCommand,
+		protocol.ActivityCommandExit: audit.TerminalExit,
+	}[msg.Content]
+	if typ == "" {
+		b.logDebug("[Audit] Unknown activity %v", msg.Content)
+		return
+	}
+	b.auditEvent(typ, sessionID, "agent", msg.Meta)
+}
+
+// auditSessionStart records a session start; by is the client or component
+// that started it
+func (b *Bridge) auditSessionStart(sess *session.Session, by string, extra map[string]interface{}) {
+	data := map[string]interface{}{
+		"cliType":        sess.CLIType,
+		"workDir":        sess.WorkDir,
+		"permissionMode": sess.PermissionMode,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	b.auditEvent(audit.SessionStart, sess.ID, by, data)
+}
+
+// auditAlerts records scanner alerts raised for a session
+func (b *Bridge) auditAlerts(sessionID, direction string, alerts []scanner.Alert) {
+	for _, a := range alerts {
+		b.auditEvent(audit.ScannerAlert, sessionID, "", map[string]interface{}{
+			"direction":  direction,
+			"category":   a.Category,
+			"level":      a.Level,
+			"ruleId":     a.RuleID,
+			"title":      a.Title,
+			"findingId":  a.FindingID,
+			"redacted":   a.Redacted,
+			"confidence": a.Confidence,
+		})
+	}
+}
+
+// auditConfigSync records which parts of the config the web replaced.
+// Environment variables are recorded by name only; their values may be
+// secrets.
+func (b *Bridge) auditConfigSync(payload map[string]interface{}) {
+	var sections []string
+	data := map[string]interface{}{}
+	for _, k := range []string{"envVars", "cliEnabled", "permissions", "policy", "clis", "schedules"} {
+		v, ok := payload[k]
+		if !ok {
+			continue
+		}
+		sections = append(sections, k)
+		if k == "envVars" {
+			names := []string{}
+			if m, ok := v.(map[string]interface{}); ok {
+				for name := range m {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			v = names
+		}
+		data[k] = v
+	}
+	data["sections"] = sections
+	b.auditEvent(audit.ConfigSync, "", "web", data)
+}
@@ -15,18 +15,27 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/open-agents/bridge/internal/alert"
 	"github.com/open-agents/bridge/internal/api"
+	"github.com/open-agents/bridge/internal/audit"
+	"github.com/open-agents/bridge/internal/checkpoint"
+	"github.com/open-agents/bridge/internal/clidef"
 	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/control"
 	"github.com/open-agents/bridge/internal/crypto"
+	"github.com/open-agents/bridge/internal/limits"
+	"github.com/open-agents/bridge/internal/localui"
 	"github.com/open-agents/bridge/internal/logger"
 	"github.com/open-agents/bridge/internal/loopdetect"
 	mcpPkg "github.com/open-agents/bridge/internal/mcp"
 	"github.com/open-agents/bridge/internal/metrics"
 	"github.com/open-agents/bridge/internal/multiagent"
 	"github.com/open-agents/bridge/internal/permission"
+	"github.com/open-agents/bridge/internal/policy"
 	"github.com/open-agents/bridge/internal/protocol"
 	"github.com/open-agents/bridge/internal/reconnect"
 	"github.com/open-agents/bridge/internal/rules"
+	"github.com/open-agents/bridge/internal/sandbox"
 	"github.com/open-agents/bridge/internal/scanner"
+	"github.com/open-agents/bridge/internal/schedule"
 	"github.com/open-agents/bridge/internal/session"
 	"github.com/open-agents/bridge/internal/storage"
 )
@@ -74,10 +83,33 @@ type Bridge struct {
 	stateManager      *StateManager
 	reconnectCallback *reconnect.CallbackManager
 	reconnectMetrics  *reconnect.Metrics
-
-	// Permission ID -> Session ID mapping for precise routing
-	permSessionMap map[string]string
-	permSessionMu  sync.RWMutex
+	checkpoints       *checkpoint.Store
+	remembered        *permission.Memory
+	cliApprovals      *clidef.Approvals
+	audit             *audit.Log
+	grants            *permission.Grants
+	scheduler         *schedule.Scheduler
+	scheduleRuns      *schedule.RunStore
+	controlServer     *control.Server
+	attach            *attachHub
+	localUI           *localui.Server // set in local mode, replaces the server connection
+	local             *localState
+	startedAt         time.Time
+
+	// Permission requests waiting for an answer, hook and ACP alike
+	permissions *permission.Registry
+
+	// Conversation history: streamed agent text of the current turn per session
+	pendingReplies map[string]*pendingText
+	historyMu      sync.Mutex
+
+	// Sessions that already moved to a fallback CLI
+	fallingBack map[string]bool
+	fallbackMu  sync.Mutex
+
+	// Streaming security scans of session output, per session and message type
+	outputScans  map[string]map[protocol.MessageType]*outputScan
+	outputScanMu sync.Mutex
 
 	// Message queue for ordered processing without blocking readLoop
 	messageQueue chan Message
@@ -100,29 +132,63 @@ func New(cfg *config.Config) (*Bridge, error) {
 		permServer:        permission.NewServer(handler),
 		store:             store,
 		rulesEngine:       rules.NewEngine(cfg.Rules),
+		remembered:        permission.NewMemory(filepath.Join(config.ConfigDir(), "remembered.json")),
+		cliApprovals:      clidef.NewApprovals(filepath.Join(config.ConfigDir(), "approved-clis.json")),
 		apiClient:         api.NewClient(cfg),
 		done:              make(chan struct{}),
 		scanner:           scanner.New(),
 		loopDetectors:     make(map[string]*loopdetect.Detector),
-		permSessionMap:    make(map[string]string),
+		pendingReplies:    make(map[string]*pendingText),
+		fallingBack:       make(map[string]bool),
 		reconnectStrategy: reconnect.NewStrategy(),
 		stateManager:      NewStateManager(),
 		reconnectCallback: reconnect.NewCallbackManager(),
 		reconnectMetrics:  reconnect.NewMetrics(),
 		messageQueue:      make(chan Message, 100), // Buffered queue for ordered processing
+		attach:            newAttachHub(),
 	}
+	b.grants = permission.NewGrants(b.grantEnded)
+	b.permissions = permission.NewRegistry(b.permissionTimedOut)
+	handler.SetTimeout(cfg.PermissionTimeout.Timeout(0))
+	handler.OnTimeout(b.hookTimedOut)
+
+	// Apply resource limits config
+	b.sessions.SetResourceLimits(cfg.ResourceLimits)
+	b.sessions.SetSandboxConfig(cfg.Sandbox)
+	b.applyCLIDefinitions()
+	b.applyPolicy()
 
 	// Apply scanner config
 	if cfg.ScannerEnabled != nil {
 		b.scanner.SetEnabled(*cfg.ScannerEnabled)
 	}
 	b.scanner.LoadCustomRules(config.ConfigDir())
+	b.applyRedaction()
 
 	// Initialize S3 uploader if configured
 	if cfg.S3Config != nil {
 		b.s3Uploader = storage.NewS3Uploader(cfg.S3Config)
 	}
 
+	// Append-only audit log of permissions, writes, commands and config changes
+	if key, err := audit.LoadKey(audit.KeyFile(cfg.DeviceName)); err != nil {
+		logger.Warn("audit log disabled: %v", err)
+	} else if log, err := audit.Open(audit.PathFor(cfg.DeviceName), key); err != nil {
+		logger.Warn("audit log disabled: %v", err)
+	} else {
+		b.audit = log
+	}
+
+	// Initialize workspace checkpoints (requires git)
+	if checkpoint.Available() {
+		b.checkpoints = checkpoint.NewStore(filepath.Join(config.ConfigDir(), "checkpoints"))
+	} else {
+		logger.Warn("git not found, workspace checkpoints disabled")
+	}
+
+	// Load scheduled sessions and the records of earlier runs
+	b.initSchedules()
+
 	// Initialize MCP manager
 	b.mcpManager = mcpPkg.NewManager(config.ConfigDir())
 
@@ -168,7 +234,7 @@ func New(cfg *config.Config) (*Bridge, error) {
 	metrics.RegisterHealthCheck("goroutines", metrics.GoroutineHealthChecker(1000))
 	metrics.RegisterHealthCheck("websocket", metrics.WebSocketHealthChecker(func() bool {
 		b.connMu.Lock()
-		connected := b.conn != nil
+		connected := b.conn != nil || b.localUI != nil
 		b.connMu.Unlock()
 		return connected
 	}))
@@ -188,11 +254,18 @@ func (b *Bridge) Start() error {
 	}
 
 	// Sync rules from API on startup
-	go b.syncRulesFromAPI()
+	if b.localUI == nil {
+		go b.syncRulesFromAPI()
+	}
+
+	for _, err := range b.rulesEngine.Errors() {
+		b.logWarn("Invalid auto-approval rule, requests it matches will ask: %v", err)
+	}
 
 	// Set up permission request forwarding with rules engine
 	b.permHandler.OnRequest(func(req permission.Request) {
 		req.DeviceID = b.config.DeviceID
+		b.auditPermissionRequest(permission.OriginHook, req.SessionID, req.ID, permission.ToolName(req.PermissionType), req.Description, req.Risk, req.Detail)
 
 		// Check auto-approval rules
 		path := ""
@@ -206,25 +279,42 @@ func (b *Bridge) Start() error {
 			}
 		}
 
-		action, ruleID := b.rulesEngine.Evaluate(req.PermissionType, path, command)
+		policyReq := b.policyRequest(req.SessionID, permission.ToolName(req.PermissionType), path, command, req.Risk, req.Detail)
+		decision := b.rulesEngine.Decide(policyReq)
 
-		switch action {
-		case "auto-approve":
-			b.logInfo("Auto-approved by rule %s: %s", ruleID, req.Description)
+		if decision.Action == "deny" {
+			b.logInfo("Auto-denied by rule %s: %s (%s)", decision.RuleID, req.Description, decision.Reason)
+			b.auditPermissionDecision(req.SessionID, req.ID, false, "", "rule:"+decision.RuleID, decision.Reason)
+			b.permHandler.Resolve(permission.Response{ID: req.ID, Approved: false})
+			return
+		}
+
+		// Remembered "always" answers come next; they can't override a deny rule
+		if r, ok := b.rememberedFor(req.SessionID, "", policyReq.Tool, req.Detail); ok {
+			b.logInfo("Answered by remembered decision %s (approved=%v): %s", r.ID, r.Approved, req.Description)
+			b.auditPermissionDecision(req.SessionID, req.ID, r.Approved, "", "remembered:"+r.ID, "")
+			b.permHandler.Resolve(permission.Response{ID: req.ID, Approved: r.Approved})
+			return
+		}
+
+		if decision.Action == "auto-approve" {
+			b.logInfo("Auto-approved by rule %s: %s (%s)", decision.RuleID, req.Description, decision.Reason)
+			b.auditPermissionDecision(req.SessionID, req.ID, true, "", "rule:"+decision.RuleID, decision.Reason)
 			b.permHandler.Resolve(permission.Response{ID: req.ID, Approved: true})
 			return
-		case "deny":
-			b.logInfo("Auto-denied by rule %s: %s", ruleID, req.Description)
-			b.permHandler.Resolve(permission.Response{ID: req.ID, Approved: false})
+		}
+
+		// Grants the user gave for a while only answer what would be asked
+		if g, ok := b.grantFor(req.SessionID, policyReq.Tool, path, command); ok {
+			b.logInfo("Approved by grant %s: %s", g.ID, req.Description)
+			b.auditPermissionDecision(req.SessionID, req.ID, true, "", "grant:"+g.ID, "")
+			b.permHandler.Resolve(permission.Response{ID: req.ID, Approved: true})
 			return
 		}
+		b.logDebug("Rules ask for %s: %s", req.Description, decision.Reason)
 
 		// Default: forward to Web for user decision
-		b.sendMessage(Message{
-			Type:      "permission:request",
-			Payload:   req,
-			Timestamp: time.Now().UnixMilli(),
-		})
+		b.sendMessage(b.permissionRequestMessage(b.trackHookPermission(req)))
 	})
 
 	// Set up session output forwarding
@@ -232,11 +322,36 @@ func (b *Bridge) Start() error {
 	// All sendMessage calls are dispatched asynchronously to avoid deadlock
 	// between logger mutex and bridge mutex across goroutines.
 	b.sessions.SetOutputCallback(func(sessionID string, msg protocol.Message) {
-		go b.forwardSessionOutput(sessionID, msg)
+		// Scanned here, in the order output arrives, so text split between
+		// messages is matched as a whole
+		out, released, alerts := b.scanOutput(sessionID, msg)
+		go func() {
+			b.sendAlerts(sessionID, "output", alerts)
+			b.forwardReleased(sessionID, released)
+			b.forwardSessionOutput(sessionID, msg, out)
+		}()
 	})
+
+	// Scheduled sessions run whether or not the server is reachable
+	b.scheduler.Start()
+
+	// Local control API for the CLI (status, sessions, permissions)
+	b.startedAt = time.Now()
+	b.startControlServer()
+
+	// Local mode serves the web UI itself; there is no server to reach
+	if b.localUI != nil {
+		return b.runLocal()
+	}
+
 	if err := b.connect(); err != nil {
+		if b.controlServer != nil {
+			b.controlServer.Stop()
+		}
 		return err
 	}
+	b.stateManager.SetState(StateConnected, "connection_established")
+	go b.reportScheduleRuns()
 
 	// Note: device:online message is sent by the server (room.ts) when bridge connects
 	// No need to send it here to avoid duplicate notifications
@@ -265,8 +380,24 @@ func (b *Bridge) Start() error {
 
 func (b *Bridge) Stop() {
 	close(b.done)
+	b.scheduler.Stop()
+	b.grants.Stop()
+	b.permissions.Stop()
+	b.attach.closeAll()
+	if b.controlServer != nil {
+		b.controlServer.Stop()
+	}
+	if b.localUI != nil {
+		b.localUI.Stop()
+	}
+	for _, sess := range b.sessions.List() {
+		b.auditEvent(audit.SessionStop, sess.ID, "shutdown", nil)
+	}
 	b.sessions.StopAll()
 	b.permServer.Stop()
+	if b.audit != nil {
+		b.audit.Close()
+	}
 	b.connMu.Lock()
 	if b.conn != nil {
 		b.conn.Close()
@@ -370,6 +501,12 @@ func (b *Bridge) readLoop() {
 
 			// Note: device:online message is sent by the server (room.ts) when bridge reconnects
 			b.logInfo("[Bridge] ✅ Connected successfully")
+
+			// Report scheduled runs that finished while offline
+			go b.reportScheduleRuns()
+
+			// Ask again for permissions the web may have missed
+			go b.resendPermissions()
 		}
 
 		b.logInfo("[Bridge] 🔍 Waiting for message on WebSocket...")
@@ -430,8 +567,10 @@ func (b *Bridge) messageWorker() {
 }
 
 
-// forwardSessionOutput forwards protocol messages from CLI to WebSocket
-func (b *Bridge) forwardSessionOutput(sessionID string, msg protocol.Message) {
+// forwardSessionOutput forwards protocol messages from CLI to WebSocket. out
+// is msg as it may leave the device, with scanner findings masked; the local
+// history keeps msg.
+func (b *Bridge) forwardSessionOutput(sessionID string, msg, out protocol.Message) {
 	// Record metrics
 	metrics.RecordMessage(sessionID)
 
@@ -444,49 +583,39 @@ func (b *Bridge) forwardSessionOutput(sessionID string, msg protocol.Message) {
 		protocolName = sess.GetProtocolName()
 	}
 
-	// Security scan output content
-	if contentStr, ok := msg.Content.(string); ok {
-		if alerts := b.scanner.Scan(contentStr); len(alerts) > 0 {
-			for _, a := range alerts {
-				b.sendMessage(Message{
-					Type: "security:alert",
-					Payload: map[string]interface{}{
-						"sessionId":   sessionID,
-						"deviceId":    b.config.DeviceID,
-						"category":    a.Category,
-						"level":       a.Level,
-						"ruleId":      a.RuleID,
-						"title":       a.Title,
-						"description": a.Description,
-						"match":       a.Match,
-					},
-					Timestamp: time.Now().UnixMilli(),
-				})
-			}
-			b.logInfo("[Scanner] %d alert(s) in session %s", len(alerts), sessionID)
-		}
-	}
-
 	switch msg.Type {
 	case protocol.MessageTypeContent:
+		// PTY output is raw terminal data, only structured replies go to history
+		if text, ok := msg.Content.(string); ok && protocolName != "pty" {
+			b.recordReply(sessionID, text)
+		}
+		if out.Content == "" {
+			break // all of it held back by the output scan
+		}
 		b.sendMessage(Message{
 			Type: "chat:response",
 			Payload: map[string]interface{}{
 				"sessionId": sessionID,
 				"deviceId":  b.config.DeviceID,
-				"content":   msg.Content,
+				"content":   out.Content,
 				"protocol":  protocolName,
 			},
 			Timestamp: time.Now().UnixMilli(),
 		})
 
 	case protocol.MessageTypeThought:
+		if text, ok := msg.Content.(string); ok {
+			b.recordThought(sessionID, text)
+		}
+		if out.Content == "" {
+			break
+		}
 		b.sendMessage(Message{
 			Type: "chat:thought",
 			Payload: map[string]interface{}{
 				"sessionId": sessionID,
 				"deviceId":  b.config.DeviceID,
-				"content":   msg.Content,
+				"content":   out.Content,
 				"protocol":  protocolName,
 			},
 			Timestamp: time.Now().UnixMilli(),
@@ -496,6 +625,9 @@ func (b *Bridge) forwardSessionOutput(sessionID string, msg protocol.Message) {
 		metrics.RecordToolCall(sessionID, fmt.Sprintf("%v", msg.Content))
 
 		toolName := fmt.Sprintf("%v", msg.Content)
+		if tc, ok := msg.Content.(protocol.ToolCall); ok {
+			b.recordToolCall(sessionID, tc)
+		}
 		if _, ok := b.loopDetectors[sessionID]; !ok {
 			b.loopDetectors[sessionID] = loopdetect.New(30, 5, 10)
 		}
@@ -517,7 +649,7 @@ func (b *Bridge) forwardSessionOutput(sessionID string, msg protocol.Message) {
 			Payload: map[string]interface{}{
 				"sessionId": sessionID,
 				"deviceId":  b.config.DeviceID,
-				"toolCall":  msg.Content,
+				"toolCall":  out.Content,
 				"protocol":  protocolName,
 			},
 			Timestamp: time.Now().UnixMilli(),
@@ -525,29 +657,33 @@ func (b *Bridge) forwardSessionOutput(sessionID string, msg protocol.Message) {
 
 	case protocol.MessageTypePermission:
 		permReq := msg.Content.(protocol.PermissionRequest)
+		b.auditPermissionRequest(permission.OriginACP, sessionID, fmt.Sprintf("%v", permReq.ID), permReq.ToolName, permReq.Description, permReq.Risk, permReq.ToolInput)
 
-		permIDStr := fmt.Sprintf("%v", permReq.ID)
-		b.permSessionMu.Lock()
-		b.permSessionMap[permIDStr] = sessionID
-		b.permSessionMu.Unlock()
+		tool := acpTool(permReq.ToolName, permReq.Kind, permReq.ToolInput)
+		_, path, command := permission.Classify(permReq.Kind, permReq.ToolInput)
+		// Deny rules come first; neither remembered answers nor grants override them
+		decision := b.rulesEngine.Decide(b.policyRequest(sessionID, tool, path, command, permReq.Risk, permReq.ToolInput))
+		if decision.Action == rules.ActionDeny && b.answerACP(sessionID, permReq, false, "rule:"+decision.RuleID) {
+			return
+		}
+		if r, ok := b.rememberedFor(sessionID, permReq.Kind, tool, permReq.ToolInput); ok && b.answerACP(sessionID, permReq, r.Approved, "remembered:"+r.ID) {
+			return
+		}
+		if g, ok := b.grantFor(sessionID, tool, path, command); ok && b.answerACP(sessionID, permReq, true, "grant:"+g.ID) {
+			return
+		}
 
-		b.sendMessage(Message{
-			Type: "permission:request",
-			Payload: map[string]interface{}{
-				"sessionId":   sessionID,
-				"deviceId":    b.config.DeviceID,
-				"id":          permReq.ID,
-				"toolName":    permReq.ToolName,
-				"toolInput":   permReq.ToolInput,
-				"description": permReq.Description,
-				"risk":        permReq.Risk,
-				"options":     permReq.Options,
-				"protocol":    protocolName,
-			},
-			Timestamp: time.Now().UnixMilli(),
-		})
+		pending := b.trackACPPermission(sessionID, protocolName, permReq)
+		b.recordPermissionRequest(sessionID, permReq)
+		b.sendMessage(b.permissionRequestMessage(pending))
+
+	case protocol.MessageTypeActivity:
+		b.auditActivity(sessionID, msg)
 
 	case protocol.MessageTypeStatus:
+		if msg.Content == protocol.StatusIdle {
+			b.flushReply(sessionID)
+		}
 		b.sendMessage(Message{
 			Type: "agent:status",
 			Payload: map[string]interface{}{
@@ -558,6 +694,15 @@ func (b *Bridge) forwardSessionOutput(sessionID string, msg protocol.Message) {
 			},
 			Timestamp: time.Now().UnixMilli(),
 		})
+		// Idle with a stopReason ends a turn; send whatever was queued meanwhile
+		if _, ended := msg.Meta["stopReason"]; ended && msg.Content == protocol.StatusIdle {
+			b.grants.EndTurn(sessionID)
+			b.dropPermissions(sessionID, "turn_ended")
+			b.turnEnded(sessionID)
+		}
+		if msg.Content == protocol.StatusIdle {
+			b.scheduledTurnEnded(sessionID, msg.Meta)
+		}
 
 	case protocol.MessageTypeUsage:
 		usage, ok := msg.Content.(protocol.UsageStats)
@@ -567,6 +712,7 @@ func (b *Bridge) forwardSessionOutput(sessionID string, msg protocol.Message) {
 		}
 
 		metrics.RecordTokenUsage(sessionID, int64(usage.InputTokens), int64(usage.OutputTokens), int64(usage.CacheCreation), int64(usage.CacheRead))
+		b.recordUsage(sessionID, usage)
 
 		b.sendMessage(Message{
 			Type: "session:usage",
@@ -600,26 +746,27 @@ func (b *Bridge) forwardSessionOutput(sessionID string, msg protocol.Message) {
 	case protocol.MessageTypeError:
 		metrics.RecordError(sessionID, "protocol")
 
-		if b.config.ModelFallbacks != nil {
-			if sess := b.sessions.Get(sessionID); sess != nil {
-				fallback := b.sessions.GetFallbackCLI(sess.CLIType, toFallbackConfigs(b.config.ModelFallbacks))
-				if fallback != "" {
-					b.logInfo("Attempting fallback from %s to %s for session %s", sess.CLIType, fallback, sessionID)
-					b.sendMessage(Message{
-						Type: "session:output",
-						Payload: map[string]interface{}{
-							"sessionId":  sessionID,
-							"deviceId":   b.config.DeviceID,
-							"outputType": "stderr",
-							"content":    fmt.Sprintf("[fallback] %s failed, switching to %s", sess.CLIType, fallback),
-						},
-						Timestamp: time.Now().UnixMilli(),
-					})
-					_ = b.sessions.Stop(sessionID)
-					_, _ = b.sessions.CreateWithIDAndSize(fallback, sess.WorkDir, sessionID+"-fb", 120, 30, sess.PermissionMode)
-					return
-				}
-			}
+		// Resource limit hits are reported as-is, never as a reason to fall back
+		if source, _ := msg.Meta["source"].(string); source == "limit" {
+			b.sendMessage(Message{
+				Type: "session:error",
+				Payload: map[string]interface{}{
+					"sessionId": sessionID,
+					"deviceId":  b.config.DeviceID,
+					"error":     out.Content,
+					"reason":    "resource_limit",
+					"limit":     msg.Meta["limit"],
+					"protocol":  protocolName,
+				},
+				Timestamp: time.Now().UnixMilli(),
+			})
+			b.scheduledRunFailed(sessionID, fmt.Sprintf("%v", msg.Content))
+			return
+		}
+
+		// Classified failures (rate limit, auth, timeout, crash) move to a fallback CLI
+		if b.tryFallback(sessionID, msg) {
+			return
 		}
 
 		b.sendMessage(Message{
@@ -627,11 +774,12 @@ func (b *Bridge) forwardSessionOutput(sessionID string, msg protocol.Message) {
 			Payload: map[string]interface{}{
 				"sessionId": sessionID,
 				"deviceId":  b.config.DeviceID,
-				"error":     msg.Content,
+				"error":     out.Content,
 				"protocol":  protocolName,
 			},
 			Timestamp: time.Now().UnixMilli(),
 		})
+		b.scheduledRunFailed(sessionID, fmt.Sprintf("%v", msg.Content))
 
 	default:
 		if protocolName == "pty" {
@@ -641,7 +789,7 @@ func (b *Bridge) forwardSessionOutput(sessionID string, msg protocol.Message) {
 					"sessionId":  sessionID,
 					"deviceId":   b.config.DeviceID,
 					"outputType": "stdout",
-					"content":    msg.Content,
+					"content":    out.Content,
 					"protocol":   protocolName,
 				},
 				Timestamp: time.Now().UnixMilli(),
@@ -662,16 +810,40 @@ func (b *Bridge) handleMessage(msg Message) {
 		b.handleSessionCancel(msg)
 	case "session:resize":
 		b.handleSessionResize(msg)
+	case "session:rollback":
+		b.handleSessionRollback(msg)
+	case "session:checkpoints":
+		b.handleSessionCheckpoints(msg)
+	case "session:unqueue":
+		b.handleSessionUnqueue(msg)
+	case "session:export":
+		b.handleSessionExport(msg)
 	case "chat:send":
 		b.handleChatSend(msg)
 	case "permission:response":
 		b.handlePermissionResponse(msg)
+	case "permission:list":
+		b.handlePermissionList(msg)
+	case "permission:grant":
+		b.handleGrant(msg)
+	case "permission:grant:revoke":
+		b.handleGrantRevoke(msg)
+	case "permission:grant:list":
+		b.handleGrantList(msg)
+	case "permission:remembered:list":
+		b.handleRememberedList(msg)
+	case "permission:remembered:forget":
+		b.handleRememberedForget(msg)
 	case "control:takeover":
 		b.handleControlTakeover(msg)
+	case "control:release":
+		b.handleControlRelease(msg)
 	case "config:sync":
 		b.handleConfigSync(msg)
 	case "rules:sync":
 		b.handleRulesSync(msg)
+	case "rules:simulate":
+		b.handleRulesSimulate(msg)
 	case "storage:sync":
 		b.handleStorageSync(msg)
 	case "device:restart":
@@ -682,6 +854,12 @@ func (b *Bridge) handleMessage(msg Message) {
 		b.handleMCPSync(msg)
 	case "mcp:list":
 		b.handleMCPList(msg)
+	case "cli:list":
+		b.handleCLIList(msg)
+	case "schedule:list":
+		b.handleScheduleList(msg)
+	case "schedule:run_now":
+		b.handleScheduleRunNow(msg)
 	case "multiagent:start_job":
 		b.handleMultiAgentStartJob(msg)
 	case "multiagent:pause_job":
@@ -696,6 +874,8 @@ func (b *Bridge) handleMessage(msg Message) {
 		b.handleACPQueryStatus(msg)
 	case "scanner:toggle":
 		b.handleScannerToggle(msg)
+	case "scanner:redaction:sync":
+		b.handleScannerRedactionSync(msg)
 	case "scanner:rules:sync":
 		b.handleScannerRulesSync(msg)
 	default:
@@ -733,6 +913,10 @@ func (b *Bridge) handleSessionStart(msg Message) {
 	}
 
 	sessionID, _ := payload["sessionId"].(string)
+	clientID, ok := b.requireClient(sessionID, payload)
+	if !ok {
+		return
+	}
 	cliType, _ := payload["cliType"].(string)
 	workDir, _ := payload["workDir"].(string)
 	initialCommand, _ := payload["command"].(string)
@@ -748,6 +932,31 @@ func (b *Bridge) handleSessionStart(msg Message) {
 		rows = int(r)
 	}
 
+	// Optional per-session resource limits
+	var sessionLimits *limits.Limits
+	if raw, ok := payload["limits"].(map[string]interface{}); ok {
+		data, _ := json.Marshal(raw)
+		var l limits.Limits
+		if err := json.Unmarshal(data, &l); err == nil {
+			sessionLimits = &l
+		}
+	}
+
+	// Optional per-session sandbox: true, or an object narrowing the device defaults
+	var sessionSandbox *sandbox.Config
+	switch raw := payload["sandbox"].(type) {
+	case bool:
+		if raw {
+			sessionSandbox = &sandbox.Config{}
+		}
+	case map[string]interface{}:
+		data, _ := json.Marshal(raw)
+		var sc sandbox.Config
+		if err := json.Unmarshal(data, &sc); err == nil {
+			sessionSandbox = &sc
+		}
+	}
+
 	b.logInfo("[Bridge] sessionID=%s, cliType=%s, workDir=%s, cols=%d, rows=%d, permissionMode=%s", sessionID, cliType, workDir, cols, rows, permissionMode)
 
 	if cliType == "" {
@@ -757,36 +966,56 @@ func (b *Bridge) handleSessionStart(msg Message) {
 		workDir = "."
 	}
 
-	sess, err := b.sessions.CreateWithIDAndSize(cliType, workDir, sessionID, cols, rows, permissionMode)
+	sess, err := b.sessions.CreateWithOptions(session.CreateOptions{
+		CLIType:        cliType,
+		WorkDir:        workDir,
+		SessionID:      sessionID,
+		Cols:           cols,
+		Rows:           rows,
+		PermissionMode: permissionMode,
+		Limits:         sessionLimits,
+		Sandbox:        sessionSandbox,
+	})
 	if err != nil {
 		b.logError("Failed to create session: %v", err)
 		metrics.RecordError(sessionID, "session_create")
+		if b.reportPolicyViolation(sessionID, err) {
+			return
+		}
 		b.sendMessage(Message{
 			Type: "session:error",
 			Payload: map[string]interface{}{
-				"error": err.Error(),
+				"sessionId": sessionID,
+				"deviceId":  b.config.DeviceID,
+				"error":     err.Error(),
 			},
 			Timestamp: time.Now().UnixMilli(),
 		})
 		return
 	}
 
+	// The client that started the session controls it unless someone already does
+	ctrl, _, _ := b.sessions.Owners().Claim(sess.ID, clientID, session.ClientWeb)
+
 	// Send session started notification
 	b.sendMessage(Message{
 		Type: "session:started",
 		Payload: map[string]interface{}{
-			"sessionId": sess.ID,
-			"deviceId":  b.config.DeviceID,
-			"cliType":   cliType,
-			"workDir":   workDir,
+			"sessionId":  sess.ID,
+			"deviceId":   b.config.DeviceID,
+			"cliType":    cliType,
+			"workDir":    workDir,
+			"controller": ctrl,
 		},
 		Timestamp: time.Now().UnixMilli(),
 	})
 
 	metrics.StartSession(sess.ID)
+	b.auditSessionStart(sess, clientID, nil)
 
 	// Send initial command if provided
 	if initialCommand != "" {
+		b.beginTurn(sess, initialCommand)
 		sess.Send(initialCommand)
 	}
 }
@@ -817,26 +1046,7 @@ func (b *Bridge) handleSessionSend(msg Message) {
 		sessionID, len(content), content)
 
 	// Step 3: Security scanning
-	if alerts := b.scanner.ScanWithDirection(content, scanner.DirInput); len(alerts) > 0 {
-		for _, a := range alerts {
-			b.sendMessage(Message{
-				Type: "security:alert",
-				Payload: map[string]interface{}{
-					"sessionId":   sessionID,
-					"deviceId":    b.config.DeviceID,
-					"category":    a.Category,
-					"level":       a.Level,
-					"ruleId":      a.RuleID,
-					"title":       a.Title,
-					"description": a.Description,
-					"match":       a.Match,
-					"direction":   "input",
-				},
-				Timestamp: time.Now().UnixMilli(),
-			})
-		}
-		b.logWarn("[Scanner] ⚠️ %d input alert(s) in session %s", len(alerts), sessionID)
-	}
+	b.scanInput(sessionID, content)
 
 	// Step 4: Get session
 	b.logInfo("[Bridge] 🔍 Looking up session: %s", sessionID)
@@ -854,6 +1064,14 @@ func (b *Bridge) handleSessionSend(msg Message) {
 	b.logInfo("[Bridge] ✅ Session found: ID=%s, CLI=%s, Protocol=%s, Status=%s",
 		sess.ID, sess.CLIType, sess.GetProtocolName(), sess.Status)
 
+	// Step 4b: Only the controlling client may send input, and only while policy allows the session
+	if !b.requireControl(sessionID, payload) {
+		return
+	}
+	if !b.checkSessionPolicy(sess) {
+		return
+	}
+
 	// Step 5: Check if session protocol is ready
 	if sess.Protocol == nil {
 		b.logError("[Bridge] ❌ Session protocol is nil for session %s", sessionID)
@@ -861,9 +1079,11 @@ func (b *Bridge) handleSessionSend(msg Message) {
 	}
 	b.logInfo("[Bridge] ✅ Session protocol ready: %s", sess.GetProtocolName())
 
-	// Step 6: Send message to CLI
-	b.logInfo("[Bridge] 📤 Calling sess.Send() with content length: %d", len(content))
-	if err := sess.Send(content); err != nil {
+	// Step 6: Send message to CLI now, or queue it while the agent is busy
+	// ("interrupt" mode cancels the current turn first)
+	interrupt := getString(payload, "mode") == "interrupt"
+	b.logInfo("[Bridge] 📤 Submitting prompt with content length: %d (interrupt: %v)", len(content), interrupt)
+	if err := b.submitPrompt(sess, content, b.clientID(payload), interrupt); err != nil {
 		b.logError("[Bridge] ❌ Send error: %v", err)
 		// Send error notification back to web
 		b.sendMessage(Message{
@@ -876,7 +1096,7 @@ func (b *Bridge) handleSessionSend(msg Message) {
 			Timestamp: time.Now().UnixMilli(),
 		})
 	} else {
-		b.logInfo("[Bridge] ✅ Message sent successfully to CLI")
+		b.logInfo("[Bridge] ✅ Message submitted to CLI")
 	}
 }
 
@@ -887,9 +1107,14 @@ func (b *Bridge) handleSessionStop(msg Message) {
 	}
 
 	sessionID, _ := payload["sessionId"].(string)
+	if b.sessions.Get(sessionID) != nil && !b.requireControl(sessionID, payload) {
+		return
+	}
+	b.flushReply(sessionID)
 	if err := b.sessions.Stop(sessionID); err != nil {
 		b.logInfo("Failed to stop session: %v", err)
 	}
+	b.sessionEnded(sessionID, b.clientID(payload))
 
 	// End session metrics
 	metrics.EndSession(sessionID)
@@ -920,6 +1145,18 @@ func (b *Bridge) handleSessionCancel(msg Message) {
 		b.logInfo("Session not found: %s", sessionID)
 		return
 	}
+	if !b.requireControl(sessionID, payload) {
+		return
+	}
+	b.cancelTurn(sess)
+}
+
+// cancelTurn stops the agent's current turn and drops the prompts queued behind it
+func (b *Bridge) cancelTurn(sess *session.Session) {
+	// Cancelling also drops prompts queued behind the current turn
+	if sess.Input != nil && len(sess.Input.Clear()) > 0 {
+		b.sendQueueState(sess, "cleared", "")
+	}
 
 	// Send session/cancel via protocol
 	if sess.Protocol != nil {
@@ -933,7 +1170,7 @@ func (b *Bridge) handleSessionCancel(msg Message) {
 	b.sendMessage(Message{
 		Type: "session:cancelled",
 		Payload: map[string]interface{}{
-			"sessionId": sessionID,
+			"sessionId": sess.ID,
 			"deviceId":  b.config.DeviceID,
 		},
 		Timestamp: time.Now().UnixMilli(),
@@ -957,6 +1194,11 @@ func (b *Bridge) handleSessionResize(msg Message) {
 		rows = int(r)
 	}
 
+	// Viewers don't get to resize the controller's terminal
+	if b.sessions.Get(sessionID) != nil && !b.requireControl(sessionID, payload) {
+		return
+	}
+
 	b.logInfo("[Bridge] Resizing session %s to %dx%d", sessionID, cols, rows)
 	if err := b.sessions.Resize(sessionID, cols, rows); err != nil {
 		b.logInfo("Failed to resize session: %v", err)
@@ -977,6 +1219,7 @@ func (b *Bridge) handlePermissionResponse(msg Message) {
 	}
 	approved, _ := payload["approved"].(bool)
 	optionID, _ := payload["optionId"].(string)
+	remember, _ := payload["remember"].(bool)
 
 	b.logInfo("[Bridge] Permission response: id=%v, approved=%v, optionId=%s", id, approved, optionID)
 
@@ -989,67 +1232,79 @@ func (b *Bridge) handlePermissionResponse(msg Message) {
 		idStr = fmt.Sprintf("%d", int(v))
 	}
 
-	// First resolve internal permission handler
-	b.permHandler.Resolve(permission.Response{
-		ID:       idStr,
-		Approved: approved,
-	})
-
-	// Record permission metric
-	metrics.RecordPermission("", approved)
-
-	// Also send to ACP protocol if optionId is provided
-	if optionID != "" {
-		// Look up the exact session for this permission ID
-		b.permSessionMu.RLock()
-		targetSessionID := b.permSessionMap[idStr]
-		b.permSessionMu.RUnlock()
-
-		if targetSessionID != "" {
-			// Route to the specific session
-			sess := b.sessions.Get(targetSessionID)
-			if sess != nil && sess.Protocol != nil && sess.Protocol.GetProtocolName() == "acp" {
-				b.logInfo("[Bridge] Sending permission response to ACP session: %s", sess.ID)
-				sess.Protocol.SendMessage(protocol.Message{
-					Type: protocol.MessageTypePermission,
-					Content: protocol.PermissionResponse{
-						ID:       id,
-						OptionID: optionID,
-					},
-				})
-			}
+	// Requests are answered by session and ID; agents number theirs alike
+	sessionID := getString(payload, "sessionId")
+	clientID, ok := b.requireClient(sessionID, payload)
+	if !ok {
+		return
+	}
+	p, ok := b.permissions.Get(sessionID, idStr)
+	if !ok {
+		b.logWarn("Rejected answer from %s to permission %s of session %q: not pending", clientID, idStr, sessionID)
+		b.sendMessage(Message{
+			Type: "session:error",
+			Payload: map[string]interface{}{
+				"sessionId": sessionID,
+				"deviceId":  b.config.DeviceID,
+				"clientId":  clientID,
+				"code":      "unknown_permission",
+				"error":     fmt.Sprintf("permission request %s is not pending", idStr),
+			},
+			Timestamp: time.Now().UnixMilli(),
+		})
+		return
+	}
+	// Only the session's controller may answer its agent's prompts
+	if p.Origin == permission.OriginACP && !b.requireControl(p.SessionID, payload) {
+		return
+	}
+	if _, ok := b.permissions.Remove(p.SessionID, p.ID); !ok {
+		return // answered, timed out or dropped meanwhile
+	}
+	b.resolvePermission(p, approved, optionID, clientID, remember)
+}
 
-			// Clean up mapping
-			b.permSessionMu.Lock()
-			delete(b.permSessionMap, idStr)
-			b.permSessionMu.Unlock()
-		} else {
-			// Fallback: send to all ACP sessions (backward compatibility)
-			b.logInfo("[Bridge] No session mapping for permission %s, broadcasting to all ACP sessions", idStr)
-			for _, sess := range b.sessions.List() {
-				if sess.Protocol != nil && sess.Protocol.GetProtocolName() == "acp" {
-					b.logInfo("[Bridge] Sending permission response to ACP session: %s", sess.ID)
-					sess.Protocol.SendMessage(protocol.Message{
-						Type: protocol.MessageTypePermission,
-						Content: protocol.PermissionResponse{
-							ID:       id,
-							OptionID: optionID,
-						},
-					})
-				}
-			}
+// resolvePermission answers a permission request the caller took out of
+// the registry: a hook request through the permission handler, an ACP
+// request with optionID (by default the first allow or reject option) sent
+// to its session. The answer is remembered for later requests when asked to
+// or when it is an ACP "always" option.
+func (b *Bridge) resolvePermission(p permission.Pending, approved bool, optionID, decidedBy string, remember bool) {
+	acpSession := ""
+	if p.Origin == permission.OriginACP {
+		acpSession = p.SessionID
+		if optionID == "" {
+			optionID = protocol.PickOption(p.Options, approved, false)
 		}
 	}
-}
+	b.auditPermissionDecision(p.SessionID, p.ID, approved, optionID, decidedBy, "")
+	if remember || isAlwaysOption(optionID) {
+		b.rememberDecision(p.ID, acpSession, approved, optionID, decidedBy)
+	}
+	metrics.RecordPermission("", approved)
 
-func (b *Bridge) handleControlTakeover(msg Message) {
-	payload, ok := msg.Payload.(map[string]interface{})
-	if !ok {
+	if p.Origin == permission.OriginHook {
+		b.permHandler.Resolve(permission.Response{
+			ID:       p.ID,
+			Approved: approved,
+		})
 		return
 	}
 
-	sessionID, _ := payload["sessionId"].(string)
-	b.logInfo("Control takeover for session: %s", sessionID)
+	b.recordPermissionDecision(p.SessionID, p.ID, approved, optionID, decidedBy)
+	sess := b.sessions.Get(p.SessionID)
+	if sess == nil || sess.Protocol == nil || sess.Protocol.GetProtocolName() != "acp" {
+		return
+	}
+	b.logInfo("[Bridge] Sending permission response to ACP session: %s", sess.ID)
+	sess.Protocol.SendMessage(protocol.Message{
+		Type: protocol.MessageTypePermission,
+		Content: protocol.PermissionResponse{
+			// JSON-RPC answers must carry the request's ID as the agent sent it
+			ID:       p.RawID,
+			OptionID: optionID,
+		},
+	})
 }
 
 func (b *Bridge) handleConfigSync(msg Message) {
@@ -1095,6 +1350,48 @@ func (b *Bridge) handleConfigSync(msg Message) {
 		b.logInfo("Synced permissions: %v", b.config.Permissions)
 	}
 
+	// Sync device policy
+	if raw, ok := payload["policy"].(map[string]interface{}); ok {
+		var p policy.Config
+		data, _ := json.Marshal(raw)
+		if err := json.Unmarshal(data, &p); err != nil {
+			b.logError("Failed to parse device policy: %v", err)
+		} else {
+			b.config.Policy = &p
+			b.logInfo("Synced device policy: %+v", p)
+		}
+	}
+	b.applyPolicy()
+
+	// Sync CLI definitions. They name commands the device would run, so they
+	// are held until approved on the device rather than used as they come.
+	if clis, ok := payload["clis"].(map[string]interface{}); ok {
+		var defs map[string]clidef.Definition
+		data, _ := json.Marshal(clis)
+		if err := json.Unmarshal(data, &defs); err != nil {
+			b.logError("Failed to parse CLI definitions: %v", err)
+		} else {
+			b.config.SyncedCLIs = defs
+			b.applyCLIDefinitions()
+			b.logInfo("Synced %d CLI definitions", len(defs))
+		}
+	}
+
+	// Sync scheduled sessions
+	if raw, ok := payload["schedules"].([]interface{}); ok {
+		var schedules []schedule.Schedule
+		data, _ := json.Marshal(raw)
+		if err := json.Unmarshal(data, &schedules); err != nil {
+			b.logError("Failed to parse schedules: %v", err)
+		} else {
+			b.config.Schedules = schedules
+			b.applySchedules()
+			b.logInfo("Synced %d schedules", len(schedules))
+		}
+	}
+
+	b.auditConfigSync(payload)
+
 	// Save config
 	if err := config.Save(b.config); err != nil {
 		b.logInfo("Failed to save config: %v", err)
@@ -1118,23 +1415,15 @@ func (b *Bridge) handleRulesSync(msg Message) {
 	if !ok {
 		return
 	}
-
-	var newRules []config.AutoApprovalRule
-	for _, r := range rulesData {
-		if ruleMap, ok := r.(map[string]interface{}); ok {
-			rule := config.AutoApprovalRule{
-				ID:      getString(ruleMap, "id"),
-				Pattern: getString(ruleMap, "pattern"),
-				Tool:    getString(ruleMap, "tool"),
-				Action:  getString(ruleMap, "action"),
-			}
-			newRules = append(newRules, rule)
-		}
-	}
+	newRules := rulesFromPayload(rulesData)
 
 	b.config.Rules = newRules
 	b.rulesEngine.UpdateRules(newRules)
+	for _, err := range b.rulesEngine.Errors() {
+		b.logWarn("Invalid auto-approval rule, requests it matches will ask: %v", err)
+	}
 	config.Save(b.config)
+	b.auditEvent(audit.RulesSync, "", "web", map[string]interface{}{"rules": newRules})
 
 	b.logInfo("Synced %d auto-approval rules", len(newRules))
 
@@ -1168,6 +1457,7 @@ func (b *Bridge) handleStorageSync(msg Message) {
 	}
 
 	config.Save(b.config)
+	b.auditEvent(audit.ConfigSync, "", "web", map[string]interface{}{"sections": []string{"storage"}, "storageType": storageType})
 	b.logInfo("Storage type set to: %s", storageType)
 
 	b.sendMessage(Message{
@@ -1184,6 +1474,11 @@ func getString(m map[string]interface{}, key string) string {
 	return ""
 }
 
+// scanInput raises security alerts for user input sent to a session
+func (b *Bridge) scanInput(sessionID, content string) {
+	b.sendAlerts(sessionID, "input", b.scanner.ScanWithDirection(content, scanner.DirInput))
+}
+
 func (b *Bridge) handleChatSend(msg Message) {
 	payload, ok := msg.Payload.(map[string]interface{})
 	if !ok {
@@ -1196,25 +1491,7 @@ func (b *Bridge) handleChatSend(msg Message) {
 	b.logInfo("Chat message for session %s: %s", sessionID, content)
 
 	// Scan input direction
-	if alerts := b.scanner.ScanWithDirection(content, scanner.DirInput); len(alerts) > 0 {
-		for _, a := range alerts {
-			b.sendMessage(Message{
-				Type: "security:alert",
-				Payload: map[string]interface{}{
-					"sessionId":   sessionID,
-					"deviceId":    b.config.DeviceID,
-					"category":    a.Category,
-					"level":       a.Level,
-					"ruleId":      a.RuleID,
-					"title":       a.Title,
-					"description": a.Description,
-					"match":       a.Match,
-					"direction":   "input",
-				},
-				Timestamp: time.Now().UnixMilli(),
-			})
-		}
-	}
+	b.scanInput(sessionID, content)
 
 	sess := b.sessions.Get(sessionID)
 	if sess == nil {
@@ -1222,16 +1499,30 @@ func (b *Bridge) handleChatSend(msg Message) {
 		sess, err = b.sessions.Create("kiro", ".")
 		if err != nil {
 			b.logError("Failed to create session: %v", err)
+			b.reportPolicyViolation(sessionID, err)
 			return
 		}
 	}
+	if !b.requireControl(sess.ID, payload) {
+		return
+	}
+	if !b.checkSessionPolicy(sess) {
+		return
+	}
 
-	if err := sess.Send(content); err != nil {
+	interrupt := getString(payload, "mode") == "interrupt"
+	if err := b.submitPrompt(sess, content, b.clientID(payload), interrupt); err != nil {
 		b.logInfo("Failed to send to CLI: %v", err)
 	}
 }
 
 func (b *Bridge) sendMessage(msg Message) error {
+	// Attached local terminals see the same stream as the web, even offline
+	b.attach.publish(msg)
+	if b.localUI != nil {
+		return b.publishLocal(msg)
+	}
+
 	// Phase 1: Prepare data without any lock
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -1378,16 +1669,23 @@ func (b *Bridge) syncRulesFromAPI() {
 	var configRules []config.AutoApprovalRule
 	for _, r := range rules {
 		configRules = append(configRules, config.AutoApprovalRule{
-			ID:      r.ID,
-			Pattern: r.Pattern,
-			Tool:    r.Tool,
-			Action:  r.Action,
+			ID:          r.ID,
+			Pattern:     r.Pattern,
+			Tool:        r.Tool,
+			Action:      r.Action,
+			Expression:  r.Expression,
+			Priority:    r.Priority,
+			Description: r.Description,
 		})
 	}
 
 	b.config.Rules = configRules
 	b.rulesEngine.UpdateRules(configRules)
+	for _, err := range b.rulesEngine.Errors() {
+		b.logWarn("Invalid auto-approval rule, requests it matches will ask: %v", err)
+	}
 	config.Save(b.config)
+	b.auditEvent(audit.RulesSync, "", "api", map[string]interface{}{"rules": configRules})
 	b.logInfo("Synced %d rules from API", len(configRules))
 }
 
@@ -1450,6 +1748,7 @@ func (b *Bridge) handleScannerToggle(msg Message) {
 	boolVal := enabled
 	b.config.ScannerEnabled = &boolVal
 	config.Save(b.config)
+	b.auditEvent(audit.ConfigSync, "", "web", map[string]interface{}{"sections": []string{"scanner"}, "scannerEnabled": enabled})
 	b.logInfo("[Scanner] Toggled to %v", enabled)
 
 	b.sendMessage(Message{
@@ -1491,6 +1790,7 @@ func (b *Bridge) handleScannerRulesSync(msg Message) {
 
 	// Persist to local file
 	config.SaveScannerRules(defs)
+	b.auditEvent(audit.ConfigSync, "", "web", map[string]interface{}{"sections": []string{"scannerRules"}, "scannerRules": defs})
 	b.logInfo("[Scanner] Synced %d custom rules from web", len(defs))
 
 	b.sendMessage(Message{
@@ -1593,6 +1893,12 @@ func (b *Bridge) handleMultiAgentTaskAssign(msg Message) {
 
 	b.logInfo("Task assign: %s (agent: %s) in job %s", taskId, agent, jobId)
 
+	// Refuse tasks the device policy forbids before they wait in the queue
+	if err := b.sessions.CheckPolicy(agent, ".", "accept-edits"); err != nil {
+		b.reportTaskPolicyViolation(jobId, taskId, err)
+		return
+	}
+
 	// Check process pool capacity
 	if b.sessions.ActiveCount() >= b.sessions.MaxConcurrent() {
 		b.logInfo("Process pool full, queuing task %s", taskId)
@@ -1617,6 +1923,9 @@ func (b *Bridge) startTaskSession(jobId, taskId, agent, title, description, cont
 	sess, err := b.sessions.CreateWithIDAndSize(agent, ".", taskId, 120, 30, "accept-edits")
 	if err != nil {
 		b.logInfo("Failed to create session for task %s: %v", taskId, err)
+		if b.reportTaskPolicyViolation(jobId, taskId, err) {
+			return
+		}
 		b.sendMessage(Message{
 			Type: "multiagent:task_error",
 			Payload: map[string]interface{}{
@@ -1645,6 +1954,7 @@ func (b *Bridge) startTaskSession(jobId, taskId, agent, title, description, cont
 	})
 
 	// Send the prompt to the CLI agent
+	b.beginTurn(sess, prompt)
 	if err := sess.Send(prompt); err != nil {
 		b.logInfo("Failed to send prompt for task %s: %v", taskId, err)
 	}
@@ -0,0 +1,164 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/open-agents/bridge/internal/checkpoint"
+	"github.com/open-agents/bridge/internal/session"
+)
+
+// checkpointsEnabled reports whether workspace checkpoints should be taken
+func (b *Bridge) checkpointsEnabled() bool {
+	if b.checkpoints == nil {
+		return false
+	}
+	if b.config.CheckpointsEnabled != nil && !*b.config.CheckpointsEnabled {
+		return false
+	}
+	return true
+}
+
+// checkpointBeforeTurn snapshots the session workDir before a prompt is delivered.
+// Failures are reported but never block the prompt.
+func (b *Bridge) checkpointBeforeTurn(sess *session.Session, prompt string) {
+	if !b.checkpointsEnabled() {
+		return
+	}
+
+	start := time.Now()
+	cp, created, err := b.checkpoints.Create(sess.ID, sess.WorkDir, checkpointLabel(prompt))
+	if err != nil {
+		b.logWarn("[Checkpoint] Failed to checkpoint session %s: %v", sess.ID, err)
+		return
+	}
+	if !created {
+		return
+	}
+	b.logInfo("[Checkpoint] Session %s turn %d checkpointed as %s (%v)", sess.ID, cp.Turn, cp.ID, time.Since(start))
+
+	b.sendMessage(Message{
+		Type: "session:checkpoint",
+		Payload: map[string]interface{}{
+			"sessionId":  sess.ID,
+			"deviceId":   b.config.DeviceID,
+			"checkpoint": cp,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// handleSessionRollback restores a session workDir to a previous checkpoint
+func (b *Bridge) handleSessionRollback(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	sessionID := getString(payload, "sessionId")
+	checkpointID := getString(payload, "checkpointId")
+	if sess := b.sessions.Get(sessionID); sess != nil {
+		if !b.requireControl(sessionID, payload) {
+			return
+		}
+		// Files restored under a running turn would be overwritten by the
+		// agent or mixed into its work
+		if sess.Input.Busy() {
+			b.sendSessionError(sessionID, "session is busy; cancel the current turn before rolling back")
+			return
+		}
+	} else if !b.requireEndedControl(sessionID, payload) {
+		return
+	}
+
+	if b.checkpoints == nil {
+		b.sendSessionError(sessionID, "checkpoints are not available on this device")
+		return
+	}
+
+	b.logInfo("[Checkpoint] Rolling back session %s to %q", sessionID, checkpointID)
+	result, err := b.checkpoints.Rollback(sessionID, checkpointID)
+	if err != nil {
+		b.logError("[Checkpoint] Rollback failed for session %s: %v", sessionID, err)
+		b.sendSessionError(sessionID, "rollback failed: "+err.Error())
+		return
+	}
+
+	b.sendMessage(Message{
+		Type: "session:rolledback",
+		Payload: map[string]interface{}{
+			"sessionId":          sessionID,
+			"deviceId":           b.config.DeviceID,
+			"checkpoint":         result.Checkpoint,
+			"safetyCheckpointId": result.SafetyID,
+			"restored":           result.Restored,
+			"removed":            result.Removed,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// requireEndedControl checks that the sender of payload controlled the
+// ended session, or that nobody did, in which case the sender takes it over
+func (b *Bridge) requireEndedControl(sessionID string, payload map[string]interface{}) bool {
+	clientID, ok := b.requireClient(sessionID, payload)
+	if !ok {
+		return false
+	}
+	ctrl, allowed := b.sessions.Owners().ClaimEnded(sessionID, clientID, session.ClientWeb)
+	if !allowed {
+		b.rejectInput(sessionID, clientID, ctrl)
+	}
+	return allowed
+}
+
+// handleSessionCheckpoints lists the checkpoints recorded for a session
+func (b *Bridge) handleSessionCheckpoints(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	sessionID := getString(payload, "sessionId")
+	var list []checkpoint.Checkpoint
+	if b.checkpoints != nil {
+		list = b.checkpoints.List(sessionID)
+	}
+	if list == nil {
+		list = []checkpoint.Checkpoint{}
+	}
+
+	b.sendMessage(Message{
+		Type: "session:checkpoint_list",
+		Payload: map[string]interface{}{
+			"sessionId":   sessionID,
+			"deviceId":    b.config.DeviceID,
+			"checkpoints": list,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// sendSessionError reports a session-scoped error to the web
+func (b *Bridge) sendSessionError(sessionID, errMsg string) {
+	b.sendMessage(Message{
+		Type: "session:error",
+		Payload: map[string]interface{}{
+			"sessionId": sessionID,
+			"deviceId":  b.config.DeviceID,
+			"error":     errMsg,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// checkpointLabel builds a short commit message from the prompt
+func checkpointLabel(prompt string) string {
+	label := prompt
+	if runes := []rune(label); len(runes) > 72 {
+		label = string(runes[:72]) + "..."
+	}
+	if label == "" {
+		label = "turn"
+	}
+	return "before: " + label
+}
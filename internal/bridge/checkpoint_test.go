@@ -0,0 +1,82 @@
+package bridge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/open-agents/bridge/internal/checkpoint"
+	"github.com/open-agents/bridge/internal/clidef"
+	"github.com/open-agents/bridge/internal/session"
+)
+
+func TestCheckpointLabelTruncatesByRune(t *testing.T) {
+	prompt := strings.Repeat("日本語", 30)
+	label := checkpointLabel(prompt)
+
+	if !utf8.ValidString(label) {
+		t.Fatalf("label is not valid UTF-8: %q", label)
+	}
+	want := "before: " + strings.Repeat("日本語", 24) + "..."
+	if label != want {
+		t.Errorf("label = %q, want %q", label, want)
+	}
+
+	if got := checkpointLabel("fix the bug"); got != "before: fix the bug" {
+		t.Errorf("short label = %q", got)
+	}
+	if got := checkpointLabel(""); got != "before: turn" {
+		t.Errorf("empty label = %q", got)
+	}
+}
+
+func TestRollbackChecks(t *testing.T) {
+	if !checkpoint.Available() {
+		t.Skip("git not installed")
+	}
+	b, received := connectedBridge(t)
+	b.sessions = session.NewManager()
+	b.sessions.SetCLIDefinitions(map[string]clidef.Definition{"cat": {Command: "cat", Protocol: "pty"}})
+	b.checkpoints = checkpoint.NewStore(t.TempDir())
+	defer b.sessions.StopAll()
+
+	workDir := t.TempDir()
+	file := filepath.Join(workDir, "main.go")
+	os.WriteFile(file, []byte("package main\n"), 0644)
+	sess, err := b.sessions.CreateWithOptions(session.CreateOptions{CLIType: "cat", WorkDir: workDir, SessionID: "s1"})
+	if err != nil {
+		t.Skipf("cannot start a session: %v", err)
+	}
+	if _, _, err := b.checkpoints.Create("s1", workDir, "turn 1"); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(file, []byte("package main // edited\n"), 0644)
+
+	rollback := func(clientID string) {
+		b.handleSessionRollback(Message{Payload: map[string]interface{}{"sessionId": "s1", "clientId": clientID}})
+	}
+
+	// Not while the agent is working on a turn
+	sess.Input.Begin()
+	rollback("alice")
+	if payload := sentPayload(t, received, "session:error"); !strings.Contains(payload, "busy") {
+		t.Errorf("busy session: %s", payload)
+	}
+	if data, _ := os.ReadFile(file); !strings.Contains(string(data), "edited") {
+		t.Error("rolled back a busy session")
+	}
+
+	// Once the session has ended, only its last controller may roll it back
+	b.sessions.Stop("s1")
+	rollback("bob")
+	if payload := sentPayload(t, received, "session:error"); !strings.Contains(payload, "not_controller") {
+		t.Errorf("other client: %s", payload)
+	}
+	rollback("alice")
+	sentPayload(t, received, "session:rolledback")
+	if data, _ := os.ReadFile(file); string(data) != "package main\n" {
+		t.Errorf("after rollback: %q", data)
+	}
+}
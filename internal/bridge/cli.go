@@ -0,0 +1,142 @@
+package bridge
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-agents/bridge/internal/audit"
+	"github.com/open-agents/bridge/internal/control"
+)
+
+// cliInfo describes a CLI type for the web UI
+type cliInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Command     string `json:"command"`
+	Protocol    string `json:"protocol,omitempty"`
+	Enabled     bool   `json:"enabled"`
+	Installed   bool   `json:"installed"`
+	Version     string `json:"version,omitempty"`
+	Pending     bool   `json:"pendingApproval,omitempty"` // synced definition not yet approved on the device
+}
+
+// applyCLIDefinitions sets the CLI definitions sessions are started with:
+// the local config's, and the ones synced from the server that were
+// approved on the device. The local config wins for a CLI type in both.
+func (b *Bridge) applyCLIDefinitions() {
+	defs, pending := b.cliApprovals.Filter(b.config.SyncedCLIs)
+	for name, def := range b.config.CLIs {
+		defs[name] = def
+	}
+	b.sessions.SetCLIDefinitions(defs)
+	if len(pending) > 0 {
+		b.logWarn("[CLI] Synced definitions of %s are not used until approved on this device (open-agents clis approve)", strings.Join(pending, ", "))
+	}
+}
+
+// pendingCLIs returns the synced definitions awaiting approval
+func (b *Bridge) pendingCLIs() []string {
+	_, pending := b.cliApprovals.Filter(b.config.SyncedCLIs)
+	return pending
+}
+
+// syncedCLIs describes the synced definitions for the control API
+func (b *Bridge) syncedCLIs() []control.SyncedCLI {
+	list := []control.SyncedCLI{}
+	for name, def := range b.config.SyncedCLIs {
+		_, local := b.config.CLIs[name]
+		list = append(list, control.SyncedCLI{
+			Name:        name,
+			Definition:  def,
+			Fingerprint: def.Fingerprint(),
+			Approved:    b.cliApprovals.Approved(name, def),
+			Overridden:  local,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// approveCLI approves a synced definition, provided it is still the one
+// with the given fingerprint, and starts using it
+func (b *Bridge) approveCLI(name, fingerprint string) error {
+	def, ok := b.config.SyncedCLIs[name]
+	if !ok {
+		return control.NotFound("no synced definition for CLI %s", name)
+	}
+	if def.Fingerprint() != fingerprint {
+		return control.Forbidden("fingerprint_mismatch", "the synced definition of %s changed; review it again", name)
+	}
+	if err := b.cliApprovals.Approve(name, def); err != nil {
+		return err
+	}
+	b.logInfo("[CLI] Approved synced definition of %s: %s %s", name, def.Command, strings.Join(def.Args, " "))
+	b.auditEvent(audit.ConfigSync, "", "local", map[string]interface{}{
+		"sections":    []string{"cliApproval"},
+		"cliType":     name,
+		"fingerprint": fingerprint,
+	})
+	b.applyCLIDefinitions()
+	return nil
+}
+
+// handleCLIList reports every defined CLI with its install state and version
+func (b *Bridge) handleCLIList(msg Message) {
+	// Install checks and version commands run external programs; don't block the reader
+	go func() {
+		registry := b.sessions.CLIs()
+		names := registry.Names()
+		clis := make([]cliInfo, len(names))
+
+		var wg sync.WaitGroup
+		for i, name := range names {
+			def := registry.Get(name)
+			enabled, ok := b.config.CLIEnabled[name]
+			clis[i] = cliInfo{
+				Name:        name,
+				DisplayName: def.DisplayName,
+				Command:     def.Command,
+				Protocol:    def.Protocol,
+				Enabled:     enabled || !ok,
+			}
+			wg.Add(1)
+			go func(info *cliInfo) {
+				defer wg.Done()
+				if info.Installed = def.Installed(); !info.Installed {
+					return
+				}
+				version, err := def.Version()
+				if err != nil {
+					b.logDebug("[CLI] Version check failed for %s: %v", info.Name, err)
+				}
+				info.Version = version
+			}(&clis[i])
+		}
+		wg.Wait()
+
+		// Synced definitions awaiting approval are listed but not run
+		for _, name := range b.pendingCLIs() {
+			if registry.Known(name) {
+				for i := range clis {
+					if clis[i].Name == name {
+						clis[i].Pending = true
+					}
+				}
+				continue
+			}
+			def := b.config.SyncedCLIs[name]
+			clis = append(clis, cliInfo{Name: name, DisplayName: name, Command: def.Command, Protocol: def.Protocol, Pending: true})
+		}
+
+		b.sendMessage(Message{
+			Type: "cli:list_response",
+			Payload: map[string]interface{}{
+				"deviceId": b.config.DeviceID,
+				"clis":     clis,
+			},
+			Timestamp: time.Now().UnixMilli(),
+		})
+	}()
+}
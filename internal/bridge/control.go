@@ -0,0 +1,151 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/open-agents/bridge/internal/session"
+)
+
+// clientIDFrom returns the client that sent a payload: the clientId the
+// server stamps on messages from an authenticated web connection, or ""
+// when the message does not carry one
+func clientIDFrom(payload map[string]interface{}) string {
+	return getString(payload, "clientId")
+}
+
+// legacyClient is the client messages without a clientId come from when
+// the config accepts them (legacyClients)
+const legacyClient = "web"
+
+// clientID returns the client that sent a payload, legacyClient for one
+// without a clientId if the config accepts those, else ""
+func (b *Bridge) clientID(payload map[string]interface{}) string {
+	if id := clientIDFrom(payload); id != "" || !b.config.LegacyClients {
+		return id
+	}
+	return legacyClient
+}
+
+// requireClient returns the client that sent payload. Messages that don't
+// say who sent them are answered with a session:error (code
+// unauthenticated) rather than acted on for some default client, unless
+// the config accepts them from legacyClient.
+func (b *Bridge) requireClient(sessionID string, payload map[string]interface{}) (string, bool) {
+	if clientID := b.clientID(payload); clientID != "" {
+		return clientID, true
+	}
+	b.logWarn("[Control] Rejected message for session %s without a client identity", sessionID)
+	b.sendMessage(Message{
+		Type: "session:error",
+		Payload: map[string]interface{}{
+			"sessionId": sessionID,
+			"deviceId":  b.config.DeviceID,
+			"code":      "unauthenticated",
+			"error":     "message carries no authenticated client identity (clientId); set legacyClients in the device config to accept it",
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return "", false
+}
+
+// requireControl checks that the sender of payload controls the session,
+// claiming it if nobody does. Rejected input is answered with a
+// session:error (code not_controller) addressed to the sender.
+func (b *Bridge) requireControl(sessionID string, payload map[string]interface{}) bool {
+	clientID, ok := b.requireClient(sessionID, payload)
+	if !ok {
+		return false
+	}
+	ctrl, allowed, claimed := b.sessions.Owners().Claim(sessionID, clientID, session.ClientWeb)
+	if claimed {
+		b.logInfo("[Control] Client %s now controls session %s", clientID, sessionID)
+		b.sendControlChanged(sessionID, &ctrl, nil, "claimed")
+	}
+	if !allowed {
+		b.rejectInput(sessionID, clientID, ctrl)
+	}
+	return allowed
+}
+
+// rejectInput tells a client it does not control the session
+func (b *Bridge) rejectInput(sessionID, clientID string, ctrl session.Controller) {
+	b.logWarn("[Control] Rejected input from %s for session %s (controlled by %s)", clientID, sessionID, ctrl.ClientID)
+	b.sendMessage(Message{
+		Type: "session:error",
+		Payload: map[string]interface{}{
+			"sessionId":  sessionID,
+			"deviceId":   b.config.DeviceID,
+			"clientId":   clientID,
+			"code":       "not_controller",
+			"error":      "session is controlled by another client; take over control to send input",
+			"controller": ctrl,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// handleControlTakeover transfers input rights on a session to the sender
+func (b *Bridge) handleControlTakeover(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	sessionID := getString(payload, "sessionId")
+	clientID, ok := b.requireClient(sessionID, payload)
+	if !ok {
+		return
+	}
+	if b.sessions.Get(sessionID) == nil {
+		b.sendSessionError(sessionID, "session not found")
+		return
+	}
+
+	ctrl, previous := b.sessions.Owners().Takeover(sessionID, clientID, session.ClientWeb)
+	b.logInfo("[Control] Client %s took over session %s", clientID, sessionID)
+	b.sendControlChanged(sessionID, &ctrl, previous, "takeover")
+}
+
+// handleControlRelease gives up input rights; the next client to send input claims them
+func (b *Bridge) handleControlRelease(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	sessionID := getString(payload, "sessionId")
+	clientID, ok := b.requireClient(sessionID, payload)
+	if !ok {
+		return
+	}
+	owners := b.sessions.Owners()
+	previous := owners.Controller(sessionID)
+	if previous == nil {
+		return // nobody in control, nothing to release
+	}
+	if !owners.Release(sessionID, clientID) {
+		b.rejectInput(sessionID, clientID, *previous)
+		return
+	}
+
+	b.logInfo("[Control] Client %s released session %s", clientID, sessionID)
+	b.sendControlChanged(sessionID, nil, previous, "release")
+}
+
+// sendControlChanged notifies every client of a session's new controller (nil = nobody)
+func (b *Bridge) sendControlChanged(sessionID string, ctrl, previous *session.Controller, reason string) {
+	payload := map[string]interface{}{
+		"sessionId":  sessionID,
+		"deviceId":   b.config.DeviceID,
+		"controller": ctrl,
+		"reason":     reason,
+	}
+	if previous != nil {
+		payload["previous"] = previous
+	}
+	b.sendMessage(Message{
+		Type:      "control:changed",
+		Payload:   payload,
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
@@ -0,0 +1,28 @@
+package bridge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRequireClient(t *testing.T) {
+	b, received := connectedBridge(t)
+	if id, ok := b.requireClient("s1", map[string]interface{}{"clientId": "tab-1"}); !ok || id != "tab-1" {
+		t.Errorf("with a clientId: %q, %v", id, ok)
+	}
+	if _, ok := b.requireClient("s1", map[string]interface{}{}); ok {
+		t.Error("accepted a message without a clientId")
+	}
+	if payload := sentPayload(t, received, "session:error"); !strings.Contains(payload, "unauthenticated") {
+		t.Errorf("error = %s", payload)
+	}
+
+	// Servers and clients that don't send one yet can opt in to a shared client
+	b.config.LegacyClients = true
+	if id, ok := b.requireClient("s1", map[string]interface{}{}); !ok || id != legacyClient {
+		t.Errorf("legacy client: %q, %v", id, ok)
+	}
+	if id, _ := b.requireClient("s1", map[string]interface{}{"clientId": "tab-1"}); id != "tab-1" {
+		t.Errorf("a clientId sent is still used, got %q", id)
+	}
+}
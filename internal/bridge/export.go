@@ -0,0 +1,80 @@
+package bridge
+
+import (
+	"strings"
+	"time"
+
+	"github.com/open-agents/bridge/internal/scanner"
+	"github.com/open-agents/bridge/internal/storage"
+	"github.com/open-agents/bridge/internal/transcript"
+)
+
+// handleSessionExport renders a session transcript and sends it back to the client
+func (b *Bridge) handleSessionExport(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	sessionID := getString(payload, "sessionId")
+	format, err := transcript.ParseFormat(getString(payload, "format"))
+	if err != nil {
+		b.sendSessionError(sessionID, err.Error())
+		return
+	}
+	includeThoughts, _ := payload["includeThoughts"].(bool)
+
+	if b.store == nil {
+		b.sendSessionError(sessionID, "session history is not available on this device")
+		return
+	}
+	// Include the reply that is still streaming
+	b.flushReply(sessionID)
+	stored := b.store.GetSession(sessionID)
+	if stored == nil {
+		b.sendSessionError(sessionID, "no history for session "+sessionID)
+		return
+	}
+	h := *stored
+	h.Messages = b.redactHistory(b.store.GetMessages(sessionID, 0))
+
+	var sb strings.Builder
+	if err := transcript.Render(&sb, &h, format, transcript.Options{Thoughts: includeThoughts}); err != nil {
+		b.logError("[Export] Failed to export session %s: %v", sessionID, err)
+		b.sendSessionError(sessionID, "export failed: "+err.Error())
+		return
+	}
+	b.logInfo("[Export] Session %s exported as %s (%d bytes)", sessionID, format, sb.Len())
+
+	b.sendMessage(Message{
+		Type: "session:exported",
+		Payload: map[string]interface{}{
+			"sessionId": sessionID,
+			"deviceId":  b.config.DeviceID,
+			"format":    format,
+			"filename":  transcript.Filename(&h, format),
+			"mimeType":  transcript.MimeType(format),
+			"content":   sb.String(),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// redactHistory returns stored messages as they may leave the device: the
+// findings the redaction config selects are masked in their text and in the
+// tool calls their meta holds. The stored history keeps the original.
+func (b *Bridge) redactHistory(msgs []storage.Message) []storage.Message {
+	if b.scanner.Redaction() == nil {
+		return msgs
+	}
+	out := make([]storage.Message, len(msgs))
+	for i, m := range msgs {
+		m.Content, _ = b.scanner.RedactText(m.Content, scanner.DirOutput)
+		if m.Meta != nil {
+			meta, _ := b.scanner.RedactValue(m.Meta, scanner.DirOutput)
+			m.Meta = meta.(map[string]interface{})
+		}
+		out[i] = m
+	}
+	return out
+}
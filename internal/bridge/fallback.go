@@ -0,0 +1,175 @@
+package bridge
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-agents/bridge/internal/metrics"
+	"github.com/open-agents/bridge/internal/protocol"
+	"github.com/open-agents/bridge/internal/session"
+	"github.com/open-agents/bridge/internal/storage"
+)
+
+// tryFallback moves a failing session to the configured fallback CLI. It
+// returns true when the error is handled by a fallback (started now or already
+// in progress) and should not be reported as a plain session error.
+func (b *Bridge) tryFallback(sessionID string, msg protocol.Message) bool {
+	if len(b.config.ModelFallbacks) == 0 {
+		return false
+	}
+	reason := session.ClassifyError(msg)
+	if reason == "" {
+		return false
+	}
+	sess := b.sessions.Get(sessionID)
+	if sess == nil {
+		return false
+	}
+
+	// Never go back to a CLI this conversation already failed on, or one that is disabled
+	tried := append([]string(nil), sess.FallbackChain...)
+	for cli, enabled := range b.config.CLIEnabled {
+		if !enabled {
+			tried = append(tried, cli)
+		}
+	}
+	target := session.SelectFallback(sess.CLIType, reason, toFallbackConfigs(b.config.ModelFallbacks), tried)
+	if target == "" {
+		return false
+	}
+
+	// One fallback per session, however many error lines the failure produces
+	b.fallbackMu.Lock()
+	if b.fallingBack[sessionID] {
+		b.fallbackMu.Unlock()
+		return true
+	}
+	b.fallingBack[sessionID] = true
+	b.fallbackMu.Unlock()
+
+	// Starting the new CLI takes a while; don't block the old session's reader
+	go b.runFallback(sess, target, reason, fmt.Sprintf("%v", msg.Content))
+	return true
+}
+
+// runFallback replaces sess with a session on the target CLI and replays the
+// last prompt together with a summary of the conversation
+func (b *Bridge) runFallback(sess *session.Session, target, reason, errText string) {
+	oldID := sess.ID
+	newID := oldID + "-fb"
+	b.logInfo("[Fallback] Session %s: %s failed (%s), switching to %s as %s", oldID, sess.CLIType, reason, target, newID)
+
+	b.sendMessage(Message{
+		Type: "session:output",
+		Payload: map[string]interface{}{
+			"sessionId":  oldID,
+			"deviceId":   b.config.DeviceID,
+			"outputType": "stderr",
+			"content":    fmt.Sprintf("[fallback] %s failed (%s), switching to %s", sess.CLIType, reason, target),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+
+	// Close the interrupted turn so it is part of the summary
+	b.flushReply(oldID)
+	var history []storage.Message
+	if b.store != nil {
+		history = append(history, b.store.GetMessages(oldID, 0)...)
+	}
+
+	// The task continues on the new session; don't report the old one as finished
+	jobID, taskID, startedAt := sess.JobID, sess.TaskID, sess.StartedAt
+	sess.JobID, sess.TaskID = "", ""
+	var queued []session.QueuedPrompt
+	if sess.Input != nil {
+		queued = sess.Input.Clear()
+	}
+	ctrl := b.sessions.Owners().Controller(oldID)
+	_ = b.sessions.Stop(oldID)
+	metrics.EndSession(oldID)
+	b.sessionEnded(oldID, "fallback")
+
+	opts := sess.Options
+	opts.CLIType = target
+	opts.SessionID = newID
+	opts.FallbackChain = append(append([]string(nil), sess.FallbackChain...), sess.CLIType)
+	if opts.WorkDir == "" {
+		opts.WorkDir = sess.WorkDir
+	}
+	if opts.PermissionMode == "" {
+		opts.PermissionMode = sess.PermissionMode
+	}
+
+	newSess, err := b.sessions.CreateWithOptions(opts)
+	// The attempt is over; a later failure under this ID may fall back again
+	b.fallbackMu.Lock()
+	delete(b.fallingBack, oldID)
+	b.fallbackMu.Unlock()
+	if err != nil {
+		b.logError("[Fallback] Failed to start %s for session %s: %v", target, oldID, err)
+		b.sendMessage(Message{
+			Type: "session:error",
+			Payload: map[string]interface{}{
+				"sessionId": oldID,
+				"deviceId":  b.config.DeviceID,
+				"error":     fmt.Sprintf("fallback to %s failed: %v (original error: %s)", target, err, errText),
+				"reason":    "fallback_failed",
+			},
+			Timestamp: time.Now().UnixMilli(),
+		})
+		return
+	}
+	newSess.JobID, newSess.TaskID, newSess.StartedAt = jobID, taskID, startedAt
+	b.moveScheduledRun(sess, newSess)
+	if ctrl != nil {
+		b.sessions.Owners().Takeover(newID, ctrl.ClientID, ctrl.Kind)
+	}
+	metrics.StartSession(newID)
+	b.auditSessionStart(newSess, "fallback", map[string]interface{}{"fallbackFrom": oldID, "reason": reason})
+
+	// Carry the conversation over so exports and later fallbacks see all of it
+	if b.store != nil {
+		b.store.ForkSession(oldID, newID, b.config.DeviceID, target, newSess.WorkDir)
+		b.store.AddMessage(newID, storage.Message{
+			ID:      uuid.New().String(),
+			Role:    "system",
+			Content: fmt.Sprintf("Continued from session %s (%s) after %s error: %s", oldID, sess.CLIType, reason, errText),
+		})
+	}
+
+	b.sendMessage(Message{
+		Type: "session:fallback",
+		Payload: map[string]interface{}{
+			"sessionId":    oldID,
+			"newSessionId": newID,
+			"deviceId":     b.config.DeviceID,
+			"fromCliType":  sess.CLIType,
+			"toCliType":    target,
+			"reason":       reason,
+			"error":        errText,
+			"workDir":      newSess.WorkDir,
+			"protocol":     newSess.GetProtocolName(),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+
+	// Prompts queued on the failed session follow the replayed one
+	defer b.requeue(newSess, queued)
+
+	if sess.LastPrompt == "" && len(history) == 0 {
+		return // nothing was asked yet, the new session just waits for input
+	}
+	newSess.LastPrompt = sess.LastPrompt
+	b.checkpointBeforeTurn(newSess, sess.LastPrompt)
+	if tracksTurns(newSess) {
+		newSess.Input.Begin()
+	}
+	if err := newSess.Send(session.FallbackPrompt(sess.CLIType, reason, history, sess.LastPrompt)); err != nil {
+		b.logError("[Fallback] Failed to replay prompt on %s: %v", newID, err)
+		b.sendSessionError(newID, fmt.Sprintf("failed to replay the last prompt: %v", err))
+		if tracksTurns(newSess) {
+			newSess.Input.End()
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package bridge
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/open-agents/bridge/internal/permission"
+	"github.com/open-agents/bridge/internal/rules"
+)
+
+// grantFor returns an active grant covering a request of a session
+func (b *Bridge) grantFor(sessionID, tool, path, command string) (permission.Grant, bool) {
+	workDir := ""
+	if sess := b.sessions.Get(sessionID); sess != nil {
+		workDir = sess.WorkDir
+	}
+	return b.grants.Match(sessionID, workDir, tool, path, command)
+}
+
+// policyRequest describes a permission request of a session to the rules engine
+func (b *Bridge) policyRequest(sessionID, tool, path, command, risk string, input map[string]interface{}) rules.Request {
+	req := rules.Request{
+		Tool:      tool,
+		Path:      path,
+		Command:   command,
+		SessionID: sessionID,
+		Risk:      risk,
+		Findings:  rules.ScanFindings(b.scanner, input),
+	}
+	if sess := b.sessions.Get(sessionID); sess != nil {
+		req.CLIType = sess.CLIType
+		req.WorkDir = sess.WorkDir
+		req.PermissionMode = sess.PermissionMode
+	}
+	return req
+}
+
+// grantEnded tells the web a grant no longer applies
+func (b *Bridge) grantEnded(g permission.Grant, reason string) {
+	b.logInfo("Grant %s ended (%s) after %d uses", g.ID, reason, g.Uses)
+	b.sendMessage(Message{
+		Type: "permission:grant_expired",
+		Payload: map[string]interface{}{
+			"deviceId": b.config.DeviceID,
+			"grant":    g,
+			"reason":   reason,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// grantFromPayload reads a grant request: sessionId or allSessions, tool,
+// pattern, a duration (durationSeconds or a Go duration string) and
+// untilTurnEnd
+func grantFromPayload(payload map[string]interface{}) (permission.Grant, error) {
+	g := permission.Grant{
+		SessionID: getString(payload, "sessionId"),
+		Tool:      getString(payload, "tool"),
+		Pattern:   getString(payload, "pattern"),
+	}
+	g.UntilTurnEnd, _ = payload["untilTurnEnd"].(bool)
+	g.AllSessions, _ = payload["allSessions"].(bool)
+
+	var d time.Duration
+	if secs, ok := payload["durationSeconds"].(float64); ok {
+		d = time.Duration(secs * float64(time.Second))
+	} else if s := getString(payload, "duration"); s != "" {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return g, fmt.Errorf("invalid duration %q", s)
+		}
+		d = parsed
+	}
+	if d < 0 {
+		return g, fmt.Errorf("invalid duration %v", d)
+	}
+	if d > 0 {
+		g.ExpiresAt = time.Now().Add(d)
+	}
+	return g, nil
+}
+
+// handleGrant activates a temporary permission grant
+func (b *Bridge) handleGrant(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+	result := map[string]interface{}{
+		"deviceId":  b.config.DeviceID,
+		"requestId": getString(payload, "requestId"),
+	}
+	fail := func(err error) {
+		b.logWarn("Rejected grant: %v", err)
+		result["error"] = err.Error()
+		b.sendMessage(Message{Type: "permission:grant_error", Payload: result, Timestamp: time.Now().UnixMilli()})
+	}
+
+	clientID, ok := b.requireClient(getString(payload, "sessionId"), payload)
+	if !ok {
+		return
+	}
+	g, err := grantFromPayload(payload)
+	if err != nil {
+		fail(err)
+		return
+	}
+	g.CreatedBy = clientID
+	workDir := ""
+	if g.SessionID != "" {
+		sess := b.sessions.Get(g.SessionID)
+		if sess == nil {
+			fail(fmt.Errorf("session %s not found", g.SessionID))
+			return
+		}
+		if g.UntilTurnEnd && !tracksTurns(sess) {
+			fail(fmt.Errorf("session %s does not report turns; give the grant a duration", g.SessionID))
+			return
+		}
+		workDir = sess.WorkDir
+	}
+	if !b.requireGrantControl(g, payload) {
+		return
+	}
+
+	g, err = b.grants.Add(g, workDir)
+	if err != nil {
+		fail(err)
+		return
+	}
+	b.logInfo("Grant %s: tool=%q pattern=%q session=%q all=%v until=%v turn=%v by %s",
+		g.ID, g.Tool, g.Pattern, g.SessionID, g.AllSessions, g.ExpiresAt.Format(time.RFC3339), g.UntilTurnEnd, g.CreatedBy)
+	result["grant"] = g
+	b.sendMessage(Message{Type: "permission:granted", Payload: result, Timestamp: time.Now().UnixMilli()})
+}
+
+// handleGrantRevoke ends a grant early; the web hears about it through
+// permission:grant_expired with reason "revoked"
+func (b *Bridge) handleGrantRevoke(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+	id := getString(payload, "id")
+	g, ok := b.grants.Get(id)
+	if ok && !b.requireGrantControl(g, payload) {
+		return
+	}
+	if !ok || !b.grants.Revoke(id) {
+		b.sendMessage(Message{
+			Type: "permission:grant_error",
+			Payload: map[string]interface{}{
+				"deviceId":  b.config.DeviceID,
+				"requestId": getString(payload, "requestId"),
+				"id":        id,
+				"error":     "no active grant " + id,
+			},
+			Timestamp: time.Now().UnixMilli(),
+		})
+	}
+}
+
+// requireGrantControl checks that the sender of payload may create or
+// revoke g: it must control the grant's session, or for a grant to all
+// sessions, every session that is controlled by anyone
+func (b *Bridge) requireGrantControl(g permission.Grant, payload map[string]interface{}) bool {
+	if !g.AllSessions {
+		return b.requireControl(g.SessionID, payload)
+	}
+	clientID, ok := b.requireClient("", payload)
+	if !ok {
+		return false
+	}
+	for _, sess := range b.sessions.List() {
+		if ctrl := b.sessions.Owners().Controller(sess.ID); ctrl != nil && ctrl.ClientID != clientID {
+			b.rejectInput(sess.ID, clientID, *ctrl)
+			return false
+		}
+	}
+	return true
+}
+
+// handleGrantList reports the active grants
+func (b *Bridge) handleGrantList(msg Message) {
+	payload, _ := msg.Payload.(map[string]interface{})
+	b.sendMessage(Message{
+		Type: "permission:grant:list_response",
+		Payload: map[string]interface{}{
+			"deviceId":  b.config.DeviceID,
+			"requestId": getString(payload, "requestId"),
+			"grants":    b.grants.List(),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
@@ -0,0 +1,213 @@
+package bridge
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-agents/bridge/internal/protocol"
+	"github.com/open-agents/bridge/internal/session"
+	"github.com/open-agents/bridge/internal/storage"
+)
+
+// pendingText is streamed agent text (reply or thought) not yet stored
+type pendingText struct {
+	typ  string // "" for reply text, storage.TypeThought for thinking
+	text strings.Builder
+}
+
+// beginTurn prepares a session for a new prompt: the prompt is recorded in the
+// local history and the workDir is checkpointed before the CLI sees it
+func (b *Bridge) beginTurn(sess *session.Session, prompt string) {
+	if tracksTurns(sess) {
+		sess.Input.Begin()
+	}
+	b.recordPrompt(sess, prompt)
+	b.checkpointBeforeTurn(sess, prompt)
+
+	// The web shows its own prompts; attached terminals see every prompt
+	b.attach.publish(Message{
+		Type: "chat:prompt",
+		Payload: map[string]interface{}{
+			"sessionId": sess.ID,
+			"content":   prompt,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// recordPrompt stores a user prompt, closing the previous assistant reply first
+func (b *Bridge) recordPrompt(sess *session.Session, prompt string) {
+	sess.LastPrompt = prompt
+	if b.store == nil {
+		return
+	}
+	b.flushReply(sess.ID)
+	if b.store.GetSession(sess.ID) == nil {
+		b.store.CreateSession(sess.ID, b.config.DeviceID, sess.CLIType, sess.WorkDir)
+	}
+	b.store.AddMessage(sess.ID, storage.Message{
+		ID:      uuid.New().String(),
+		Role:    "user",
+		Content: prompt,
+	})
+}
+
+// recordReply accumulates streamed assistant text for the current turn
+func (b *Bridge) recordReply(sessionID, text string) {
+	b.recordStream(sessionID, "", text)
+}
+
+// recordThought accumulates streamed agent thinking for the current turn
+func (b *Bridge) recordThought(sessionID, text string) {
+	b.recordStream(sessionID, storage.TypeThought, text)
+}
+
+func (b *Bridge) recordStream(sessionID, typ, text string) {
+	if b.store == nil || text == "" {
+		return
+	}
+	b.historyMu.Lock()
+	pending, ok := b.pendingReplies[sessionID]
+	b.historyMu.Unlock()
+
+	// Switching between thinking and replying closes the previous block
+	if ok && pending.typ != typ {
+		b.flushReply(sessionID)
+	}
+
+	b.historyMu.Lock()
+	defer b.historyMu.Unlock()
+	pending, ok = b.pendingReplies[sessionID]
+	if !ok {
+		pending = &pendingText{typ: typ}
+		b.pendingReplies[sessionID] = pending
+	}
+	pending.text.WriteString(text)
+}
+
+// flushReply stores the accumulated assistant text of a session, if any
+func (b *Bridge) flushReply(sessionID string) {
+	if b.store == nil {
+		return
+	}
+	b.historyMu.Lock()
+	pending, ok := b.pendingReplies[sessionID]
+	delete(b.pendingReplies, sessionID)
+	b.historyMu.Unlock()
+
+	if !ok || strings.TrimSpace(pending.text.String()) == "" {
+		return
+	}
+	b.store.AddMessage(sessionID, storage.Message{
+		ID:      uuid.New().String(),
+		Role:    "assistant",
+		Type:    pending.typ,
+		Content: pending.text.String(),
+	})
+}
+
+// recordToolCall stores a tool call, merging later updates into the same entry
+func (b *Bridge) recordToolCall(sessionID string, tc protocol.ToolCall) {
+	if b.store == nil || tc.ID == "" {
+		return
+	}
+	// Keep text, tool calls and decisions in the order they happened
+	b.flushReply(sessionID)
+
+	id := "tool:" + tc.ID
+	merge := func(m *storage.Message) {
+		if m.Meta == nil {
+			m.Meta = map[string]interface{}{"toolCallId": tc.ID}
+		}
+		if tc.Name != "" {
+			m.Content = tc.Name
+		}
+		if tc.Status != "" {
+			m.Meta["status"] = tc.Status
+		}
+		if tc.Kind != "" {
+			m.Meta["kind"] = tc.Kind
+		}
+		if len(tc.Input) > 0 {
+			m.Meta["input"] = tc.Input
+		}
+		if len(tc.Content) > 0 {
+			m.Meta["content"] = tc.Content
+		}
+		if tc.Result != nil {
+			m.Meta["result"] = tc.Result
+		}
+	}
+	if b.store.UpdateMessage(sessionID, id, merge) {
+		return
+	}
+	msg := storage.Message{ID: id, Role: "tool", Type: storage.TypeToolCall}
+	merge(&msg)
+	b.store.AddMessage(sessionID, msg)
+}
+
+// recordPermissionRequest stores a permission prompt awaiting a decision
+func (b *Bridge) recordPermissionRequest(sessionID string, req protocol.PermissionRequest) {
+	if b.store == nil {
+		return
+	}
+	b.flushReply(sessionID)
+	b.store.AddMessage(sessionID, storage.Message{
+		ID:      fmt.Sprintf("perm:%v", req.ID),
+		Role:    "system",
+		Type:    storage.TypePermission,
+		Content: req.ToolName,
+		Meta: map[string]interface{}{
+			"description": req.Description,
+			"risk":        req.Risk,
+			"kind":        req.Kind,
+			"input":       req.ToolInput,
+			"options":     req.Options,
+			"status":      "pending",
+		},
+	})
+}
+
+// recordPermissionDecision stores the answer to a permission prompt
+func (b *Bridge) recordPermissionDecision(sessionID, permID string, approved bool, optionID, decidedBy string) {
+	if b.store == nil {
+		return
+	}
+	if optionID != "" {
+		approved = !strings.HasPrefix(optionID, "reject")
+	}
+	status := "denied"
+	if approved {
+		status = "approved"
+	}
+	b.store.UpdateMessage(sessionID, "perm:"+permID, func(m *storage.Message) {
+		if m.Meta == nil {
+			m.Meta = map[string]interface{}{}
+		}
+		m.Meta["status"] = status
+		m.Meta["optionId"] = optionID
+		m.Meta["decidedBy"] = decidedBy
+		m.Meta["decidedAt"] = time.Now().UTC().Format(time.RFC3339)
+	})
+}
+
+// recordUsage stores a token usage report
+func (b *Bridge) recordUsage(sessionID string, usage protocol.UsageStats) {
+	if b.store == nil {
+		return
+	}
+	b.store.AddMessage(sessionID, storage.Message{
+		ID:   uuid.New().String(),
+		Role: "system",
+		Type: storage.TypeUsage,
+		Meta: map[string]interface{}{
+			"inputTokens":   usage.InputTokens,
+			"outputTokens":  usage.OutputTokens,
+			"cacheCreation": usage.CacheCreation,
+			"cacheRead":     usage.CacheRead,
+			"contextSize":   usage.ContextSize,
+		},
+	})
+}
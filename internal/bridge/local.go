@@ -0,0 +1,136 @@
+package bridge
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/open-agents/bridge/internal/control"
+	"github.com/open-agents/bridge/internal/localui"
+	"github.com/open-agents/bridge/internal/storage"
+)
+
+const (
+	// localHistory is how many messages per session a browser gets on connect
+	localHistory = 200
+	// localAlerts is how many security alerts are kept for browsers that connect later
+	localAlerts = 100
+)
+
+// localState holds what local mode replays to browsers that connect late
+type localState struct {
+	mu     sync.Mutex
+	alerts []map[string]interface{}
+}
+
+// ServeLocal makes the bridge serve its own web UI on addr (token auth)
+// instead of connecting to the server. It listens right away, so LocalURL
+// is known before Start; call it before Start.
+func (b *Bridge) ServeLocal(addr string) error {
+	srv, err := localui.New(addr, "", localUI{b})
+	if err != nil {
+		return err
+	}
+	if err := srv.Start(); err != nil {
+		return err
+	}
+	b.localUI = srv
+	b.local = &localState{}
+	b.applyRedaction()
+	return nil
+}
+
+// LocalURL is the address of the local web UI, token included; empty
+// unless the bridge runs in local mode
+func (b *Bridge) LocalURL() string {
+	if b.localUI == nil {
+		return ""
+	}
+	return b.localUI.URL()
+}
+
+// publishLocal sends a message to the browsers of the local UI
+func (b *Bridge) publishLocal(msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if msg.Type == "security:alert" {
+		if payload, ok := msg.Payload.(map[string]interface{}); ok {
+			b.local.mu.Lock()
+			b.local.alerts = append([]map[string]interface{}{payload}, b.local.alerts...)
+			if len(b.local.alerts) > localAlerts {
+				b.local.alerts = b.local.alerts[:localAlerts]
+			}
+			b.local.mu.Unlock()
+		}
+	}
+	b.localUI.Broadcast(data)
+	return nil
+}
+
+// localUI feeds the local web UI's messages into the bridge
+type localUI struct {
+	b *Bridge
+}
+
+// HandleMessage queues a browser's message like one from the server
+func (u localUI) HandleMessage(data []byte) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		u.b.logInfo("[LocalUI] Failed to parse message: %v", err)
+		return
+	}
+	select {
+	case u.b.messageQueue <- msg:
+	case <-u.b.done:
+	}
+}
+
+// Hello is the state a browser starts from: sessions with their recent
+// history, pending permission requests and recent security alerts
+func (u localUI) Hello() []byte {
+	b := u.b
+	api := localAPI{b}
+	sessions := api.Sessions()
+	history := make(map[string][]storage.Message, len(sessions))
+	if b.store != nil {
+		for _, s := range sessions {
+			history[s.ID] = b.store.GetMessages(s.ID, localHistory)
+		}
+	}
+	permissions := api.Permissions()
+	if permissions == nil {
+		permissions = []control.PermissionInfo{}
+	}
+
+	b.local.mu.Lock()
+	alerts := append([]map[string]interface{}{}, b.local.alerts...)
+	b.local.mu.Unlock()
+
+	data, _ := json.Marshal(Message{
+		Type: "local:state",
+		Payload: map[string]interface{}{
+			"deviceId":    b.config.DeviceID,
+			"deviceName":  b.config.DeviceName,
+			"sessions":    sessions,
+			"history":     history,
+			"permissions": permissions,
+			"alerts":      alerts,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return data
+}
+
+// runLocal is Start for local mode: process the UI's messages until Stop
+func (b *Bridge) runLocal() error {
+	b.stateManager.SetState(StateLocal, "local_mode")
+	b.logInfo("[LocalUI] 🏠 Local mode, not connecting to the server")
+
+	go b.messageWorker()
+
+	<-b.done
+	b.logInfo("[Bridge] 🛑 Shutdown signal received, stopping...")
+	return nil
+}
@@ -0,0 +1,324 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-agents/bridge/internal/control"
+	"github.com/open-agents/bridge/internal/metrics"
+	"github.com/open-agents/bridge/internal/permission"
+	"github.com/open-agents/bridge/internal/policy"
+	"github.com/open-agents/bridge/internal/session"
+	"github.com/open-agents/bridge/internal/storage"
+)
+
+// defaultLocalClient identifies local clients that don't name themselves
+const defaultLocalClient = "local"
+
+// startControlServer serves the local control API on the device's socket.
+// The bridge keeps running without it, e.g. when the socket can't be created.
+func (b *Bridge) startControlServer() {
+	srv := control.NewServer(control.PathsFor(b.config.DeviceName), localAPI{b})
+	if err := srv.Start(); err != nil {
+		b.logWarn("[Control] Local control API unavailable: %v", err)
+		return
+	}
+	b.controlServer = srv
+}
+
+// localAPI implements control.Backend on top of the bridge
+type localAPI struct {
+	b *Bridge
+}
+
+func localClientID(id string) string {
+	if id == "" {
+		return defaultLocalClient
+	}
+	return id
+}
+
+func (a localAPI) Status() control.Status {
+	b := a.b
+	history := b.stateManager.GetHistory()
+	transitions := make([]control.Transition, len(history))
+	for i, t := range history {
+		transitions[i] = control.Transition{From: t.From.String(), To: t.To.String(), At: t.Timestamp, Reason: t.Reason}
+	}
+
+	queued := 0
+	for _, sess := range b.sessions.List() {
+		if sess.Input != nil {
+			queued += len(sess.Input.Pending())
+		}
+	}
+
+	return control.Status{
+		DeviceID:   b.config.DeviceID,
+		DeviceName: b.config.DeviceName,
+		ServerURL:  b.config.ServerURL,
+		PID:        os.Getpid(),
+		StartedAt:  b.startedAt,
+		Connection: control.Connection{
+			State:   b.stateManager.GetState().String(),
+			Since:   b.stateManager.GetLastTransitionTime(),
+			History: transitions,
+		},
+		Reconnect:   b.reconnectMetrics.GetStats(),
+		Sessions:    b.sessions.GetStats(),
+		Permissions: len(a.Permissions()),
+		Queued:      queued,
+		Health:      metrics.RunHealthChecks(),
+		LocalUI:     b.LocalURL(),
+	}
+}
+
+func (a localAPI) sessionInfo(sess *session.Session) control.SessionInfo {
+	info := control.SessionInfo{
+		ID:             sess.ID,
+		CLIType:        sess.CLIType,
+		WorkDir:        sess.WorkDir,
+		PermissionMode: sess.PermissionMode,
+		Status:         sess.Status,
+		Protocol:       sess.GetProtocolName(),
+		CreatedAt:      sess.CreatedAt,
+		LastActiveAt:   sess.LastActiveAt,
+		Controller:     a.b.sessions.Owners().Controller(sess.ID),
+		Sandboxed:      sess.Sandboxed,
+		ScheduleRunID:  sess.ScheduleRunID,
+	}
+	if sess.Input != nil {
+		info.Busy = sess.Input.Busy()
+		info.Queue = sess.Input.Pending()
+	}
+	return info
+}
+
+func (a localAPI) Sessions() []control.SessionInfo {
+	sessions := a.b.sessions.List()
+	list := make([]control.SessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		list = append(list, a.sessionInfo(sess))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+func (a localAPI) Session(id string) (control.SessionInfo, bool) {
+	sess := a.b.sessions.Get(id)
+	if sess == nil {
+		return control.SessionInfo{}, false
+	}
+	return a.sessionInfo(sess), true
+}
+
+func (a localAPI) StartSession(req control.StartRequest) (control.SessionInfo, error) {
+	b := a.b
+	if req.CLIType == "" || req.WorkDir == "" {
+		return control.SessionInfo{}, control.BadRequest("cliType and workDir are required")
+	}
+	if req.SessionID == "" {
+		req.SessionID = uuid.New().String()
+	}
+
+	sess, err := b.sessions.CreateWithOptions(session.CreateOptions{
+		CLIType:        req.CLIType,
+		WorkDir:        req.WorkDir,
+		SessionID:      req.SessionID,
+		Cols:           120,
+		Rows:           30,
+		PermissionMode: req.PermissionMode,
+	})
+	if err != nil {
+		return control.SessionInfo{}, policyError(err)
+	}
+	metrics.StartSession(sess.ID)
+	b.auditSessionStart(sess, localClientID(req.ClientID), nil)
+
+	// The local client that started the session controls it
+	ctrl, _, _ := b.sessions.Owners().Claim(sess.ID, localClientID(req.ClientID), session.ClientLocal)
+	b.sendMessage(Message{
+		Type: "session:started",
+		Payload: map[string]interface{}{
+			"sessionId":  sess.ID,
+			"deviceId":   b.config.DeviceID,
+			"cliType":    sess.CLIType,
+			"workDir":    sess.WorkDir,
+			"controller": ctrl,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+
+	if req.Prompt != "" {
+		b.scanInput(sess.ID, req.Prompt)
+		if err := b.submitPrompt(sess, req.Prompt, localClientID(req.ClientID), false); err != nil {
+			return a.sessionInfo(sess), fmt.Errorf("session started but the prompt failed: %w", err)
+		}
+	}
+	return a.sessionInfo(sess), nil
+}
+
+// requireLocalControl claims a session for a local client, failing if
+// another client controls it
+func (a localAPI) requireLocalControl(sessionID, clientID string) error {
+	b := a.b
+	clientID = localClientID(clientID)
+	ctrl, allowed, claimed := b.sessions.Owners().Claim(sessionID, clientID, session.ClientLocal)
+	if claimed {
+		b.logInfo("[Control] Local client %s now controls session %s", clientID, sessionID)
+		b.sendControlChanged(sessionID, &ctrl, nil, "claimed")
+	}
+	if !allowed {
+		return control.Forbidden("not_controller", "session %s is controlled by %s client %s", sessionID, ctrl.Kind, ctrl.ClientID)
+	}
+	return nil
+}
+
+func (a localAPI) SendPrompt(id string, req control.SendRequest) error {
+	b := a.b
+	sess := b.sessions.Get(id)
+	if sess == nil {
+		return control.NotFound("session %s not found", id)
+	}
+	if req.Content == "" {
+		return control.BadRequest("content is required")
+	}
+	if err := a.requireLocalControl(id, req.ClientID); err != nil {
+		return err
+	}
+	if err := b.sessions.CheckPolicy(sess.CLIType, sess.WorkDir, sess.PermissionMode); err != nil {
+		return policyError(err)
+	}
+	b.scanInput(id, req.Content)
+	return b.submitPrompt(sess, req.Content, localClientID(req.ClientID), req.Interrupt)
+}
+
+func (a localAPI) StopSession(id string, req control.StopRequest) error {
+	b := a.b
+	if b.sessions.Get(id) == nil {
+		return control.NotFound("session %s not found", id)
+	}
+	if err := a.requireLocalControl(id, req.ClientID); err != nil {
+		return err
+	}
+	b.flushReply(id)
+	if err := b.sessions.Stop(id); err != nil {
+		return err
+	}
+	b.sessionEnded(id, localClientID(req.ClientID))
+	metrics.EndSession(id)
+	b.sendMessage(Message{
+		Type: "session:stopped",
+		Payload: map[string]interface{}{
+			"sessionId": id,
+			"deviceId":  b.config.DeviceID,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return nil
+}
+
+func (a localAPI) CancelTurn(id string, req control.StopRequest) error {
+	b := a.b
+	sess := b.sessions.Get(id)
+	if sess == nil {
+		return control.NotFound("session %s not found", id)
+	}
+	if err := a.requireLocalControl(id, req.ClientID); err != nil {
+		return err
+	}
+	b.cancelTurn(sess)
+	return nil
+}
+
+func (a localAPI) Permissions() []control.PermissionInfo {
+	var list []control.PermissionInfo
+	for _, p := range a.b.permissions.List() {
+		list = append(list, control.PermissionInfo{
+			ID:          p.ID,
+			Origin:      p.Origin,
+			SessionID:   p.SessionID,
+			ToolName:    p.Tool,
+			Description: p.Description,
+			Risk:        p.Risk,
+			Input:       p.Input,
+			Options:     p.Options,
+			CreatedAt:   p.CreatedAt,
+			Deadline:    p.Deadline,
+		})
+	}
+	return list
+}
+
+// storedPermission returns the history entry of a permission prompt, if any
+func (b *Bridge) storedPermission(sessionID, id string) *storage.Message {
+	if b.store == nil {
+		return nil
+	}
+	msgs := b.store.GetMessages(sessionID, 0)
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].ID == "perm:"+id {
+			return &msgs[i]
+		}
+	}
+	return nil
+}
+
+func (a localAPI) ResolvePermission(id string, d control.Decision) error {
+	b := a.b
+	p, ok := b.permissions.Get(d.SessionID, id)
+	if !ok {
+		return control.NotFound("permission request %s of session %q is not pending", id, d.SessionID)
+	}
+	if p.Origin == permission.OriginACP {
+		if err := a.requireLocalControl(p.SessionID, d.ClientID); err != nil {
+			return err
+		}
+	}
+	optionID := d.OptionID
+	if optionID == "" && p.Origin == permission.OriginACP {
+		optionID = "reject_once"
+		if d.Approved {
+			optionID = "allow_once"
+		}
+	}
+	if _, ok := b.permissions.Remove(p.SessionID, p.ID); !ok {
+		return control.NotFound("permission request %s of session %q is not pending", id, d.SessionID)
+	}
+	b.resolvePermission(p, d.Approved, optionID, localClientID(d.ClientID), d.Remember)
+	return nil
+}
+
+func (a localAPI) Remembered() []permission.Remembered {
+	return a.b.remembered.List()
+}
+
+func (a localAPI) Forget(id string) error {
+	r, ok := a.b.remembered.Forget(id)
+	if !ok {
+		return control.NotFound("no remembered decision %s", id)
+	}
+	a.b.logInfo("[Control] Forgot remembered decision %s: %s %s", r.ID, r.Tool, r.Input)
+	return nil
+}
+
+func (a localAPI) SyncedCLIs() []control.SyncedCLI {
+	return a.b.syncedCLIs()
+}
+
+func (a localAPI) ApproveCLI(name string, approval control.CLIApproval) error {
+	return a.b.approveCLI(name, approval.Fingerprint)
+}
+
+// policyError reports policy violations as 403 with the violation code
+func policyError(err error) error {
+	var v *policy.Violation
+	if errors.As(err, &v) {
+		return control.Forbidden(v.Code, "%s", v.Message)
+	}
+	return err
+}
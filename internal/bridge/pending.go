@@ -0,0 +1,195 @@
+package bridge
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/open-agents/bridge/internal/audit"
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/permission"
+	"github.com/open-agents/bridge/internal/protocol"
+	"github.com/open-agents/bridge/internal/scanner"
+)
+
+// trackHookPermission registers a hook request forwarded to the user
+func (b *Bridge) trackHookPermission(req permission.Request) permission.Pending {
+	timeout := time.Duration(req.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = config.DefaultHookPermissionTimeout
+	}
+	return b.permissions.Add(permission.Pending{
+		ID:             req.ID,
+		RawID:          req.ID,
+		Origin:         permission.OriginHook,
+		SessionID:      req.SessionID,
+		Tool:           permission.ToolName(req.PermissionType),
+		PermissionType: req.PermissionType,
+		Description:    req.Description,
+		Risk:           req.Risk,
+		Input:          req.Detail,
+	}, timeout)
+}
+
+// trackACPPermission registers an ACP request forwarded to the user
+func (b *Bridge) trackACPPermission(sessionID, protocolName string, req protocol.PermissionRequest) permission.Pending {
+	return b.permissions.Add(permission.Pending{
+		ID:          fmt.Sprintf("%v", req.ID),
+		RawID:       req.ID,
+		Origin:      permission.OriginACP,
+		SessionID:   sessionID,
+		Protocol:    protocolName,
+		Tool:        req.ToolName,
+		Description: req.Description,
+		Risk:        req.Risk,
+		Input:       req.ToolInput,
+		Options:     req.Options,
+	}, b.config.PermissionTimeout.Timeout(config.DefaultACPPermissionTimeout))
+}
+
+// permissionRequestMessage builds the permission:request sent to the web;
+// hook and ACP requests keep their own field names
+func (b *Bridge) permissionRequestMessage(p permission.Pending) Message {
+	// The request shows what the agent is about to do, which may hold what
+	// the output scan masks everywhere else
+	p, found := b.redactPending(p)
+	if len(found) > 0 && p.SessionID != "" {
+		b.sendAlerts(p.SessionID, "output", b.outputStream(p.SessionID, protocol.MessageTypeToolCall).stream.Unreported(found))
+	}
+	payload := map[string]interface{}{
+		"id":          p.RawID,
+		"sessionId":   p.SessionID,
+		"deviceId":    b.config.DeviceID,
+		"description": p.Description,
+		"risk":        p.Risk,
+		"origin":      p.Origin,
+		"createdAt":   p.CreatedAt.UnixMilli(),
+		"deadline":    p.Deadline.UnixMilli(),
+	}
+	if p.Origin == permission.OriginHook {
+		payload["permissionType"] = p.PermissionType
+		payload["detail"] = p.Input
+		payload["timeout"] = int(p.Deadline.Sub(p.CreatedAt) / time.Second)
+	} else {
+		payload["toolName"] = p.Tool
+		payload["toolInput"] = p.Input
+		payload["options"] = p.Options
+		payload["protocol"] = p.Protocol
+	}
+	return Message{Type: "permission:request", Payload: payload, Timestamp: time.Now().UnixMilli()}
+}
+
+// redactPending masks the findings the redaction config selects in what a
+// request shows of the agent's intent, its description and input
+func (b *Bridge) redactPending(p permission.Pending) (permission.Pending, []scanner.Alert) {
+	description, found := b.scanner.RedactText(p.Description, scanner.DirOutput)
+	input, inInput := b.scanner.RedactValue(p.Input, scanner.DirOutput)
+	p.Description = description
+	if m, ok := input.(map[string]interface{}); ok {
+		p.Input = m
+	}
+	return p, append(found, inInput...)
+}
+
+// resendPermissions repeats the requests still waiting for an answer; the
+// web may have missed them while the connection was down
+func (b *Bridge) resendPermissions() {
+	pending := b.permissions.List()
+	if len(pending) == 0 {
+		return
+	}
+	b.logInfo("Resending %d pending permission requests", len(pending))
+	for _, p := range pending {
+		msg := b.permissionRequestMessage(p)
+		msg.Payload.(map[string]interface{})["resent"] = true
+		b.sendMessage(msg)
+	}
+}
+
+// permissionTimedOut answers a request nobody answered before its deadline
+// with the configured outcome for its risk level
+func (b *Bridge) permissionTimedOut(p permission.Pending) {
+	approved := b.config.PermissionTimeout.Approve(p.Risk)
+	b.logWarn("Permission %s (%s risk) timed out, approved=%v by default: %s", p.ID, orUnknown(p.Risk), approved, p.Description)
+
+	b.resolvePermission(p, approved, "", "timeout", false)
+	b.sendPermissionExpired(p, "timeout", &approved)
+}
+
+// hookTimedOut decides a hook request whose wait ended before the registry
+// noticed its deadline
+func (b *Bridge) hookTimedOut(req permission.Request) bool {
+	approved := b.config.PermissionTimeout.Approve(req.Risk)
+	if p, ok := b.permissions.Remove(req.SessionID, req.ID); ok {
+		b.logWarn("Permission %s (%s risk) timed out, approved=%v by default: %s", p.ID, orUnknown(p.Risk), approved, p.Description)
+		b.auditPermissionDecision(p.SessionID, p.ID, approved, "", "timeout", "")
+		b.sendPermissionExpired(p, "timeout", &approved)
+	}
+	return approved
+}
+
+// dropPermissions forgets the ACP requests of a session whose agent no
+// longer waits for them
+func (b *Bridge) dropPermissions(sessionID, reason string) {
+	for _, p := range b.permissions.RemoveSession(sessionID) {
+		b.logInfo("Dropped permission %s of session %s (%s)", p.ID, sessionID, reason)
+		b.sendPermissionExpired(p, reason, nil)
+	}
+}
+
+// sessionEnded drops what only applied while a session ran: its grants, the
+// requests its agent was waiting on and its output scans, after reporting
+// what they held back. by is who stopped it.
+func (b *Bridge) sessionEnded(sessionID, by string) {
+	released, alerts := b.flushOutputScan(sessionID, true)
+	b.sendAlerts(sessionID, "output", alerts)
+	b.forwardReleased(sessionID, released)
+	b.auditEvent(audit.SessionStop, sessionID, by, nil)
+	b.grants.EndSession(sessionID)
+	b.dropPermissions(sessionID, "session_ended")
+}
+
+// sendPermissionExpired tells the web a request no longer needs an answer
+func (b *Bridge) sendPermissionExpired(p permission.Pending, reason string, approved *bool) {
+	payload := map[string]interface{}{
+		"deviceId":  b.config.DeviceID,
+		"id":        p.RawID,
+		"sessionId": p.SessionID,
+		"origin":    p.Origin,
+		"reason":    reason,
+	}
+	if approved != nil {
+		payload["approved"] = *approved
+	}
+	b.sendMessage(Message{Type: "permission:expired", Payload: payload, Timestamp: time.Now().UnixMilli()})
+}
+
+// handlePermissionList reports the pending permission requests
+func (b *Bridge) handlePermissionList(msg Message) {
+	payload, _ := msg.Payload.(map[string]interface{})
+	b.sendMessage(Message{
+		Type: "permission:list_response",
+		Payload: map[string]interface{}{
+			"deviceId":    b.config.DeviceID,
+			"requestId":   getString(payload, "requestId"),
+			"permissions": b.pendingPermissions(),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// pendingPermissions lists the requests waiting for an answer as they may
+// leave the device
+func (b *Bridge) pendingPermissions() []permission.Pending {
+	pending := b.permissions.List()
+	for i := range pending {
+		pending[i], _ = b.redactPending(pending[i])
+	}
+	return pending
+}
+
+func orUnknown(risk string) string {
+	if risk == "" {
+		return "unknown"
+	}
+	return risk
+}
@@ -0,0 +1,68 @@
+package bridge
+
+import (
+	"errors"
+	"time"
+
+	"github.com/open-agents/bridge/internal/policy"
+	"github.com/open-agents/bridge/internal/session"
+)
+
+// applyPolicy rebuilds the device policy from the current config
+func (b *Bridge) applyPolicy() {
+	b.sessions.SetPolicy(policy.New(b.config.CLIEnabled, b.config.Policy))
+}
+
+// reportPolicyViolation sends a session:error (code policy_violation) if err
+// is a policy violation and reports whether it was one
+func (b *Bridge) reportPolicyViolation(sessionID string, err error) bool {
+	var v *policy.Violation
+	if !errors.As(err, &v) {
+		return false
+	}
+	b.logWarn("[Policy] Session %s refused: %s", sessionID, v.Message)
+	b.sendMessage(Message{
+		Type: "session:error",
+		Payload: map[string]interface{}{
+			"sessionId": sessionID,
+			"deviceId":  b.config.DeviceID,
+			"code":      "policy_violation",
+			"reason":    v.Code,
+			"error":     v.Message,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return true
+}
+
+// checkSessionPolicy re-checks a running session before it gets more input,
+// so CLIs disabled after the session started stop taking prompts
+func (b *Bridge) checkSessionPolicy(sess *session.Session) bool {
+	err := b.sessions.CheckPolicy(sess.CLIType, sess.WorkDir, sess.PermissionMode)
+	if err == nil {
+		return true
+	}
+	if !b.reportPolicyViolation(sess.ID, err) {
+		b.sendSessionError(sess.ID, err.Error())
+	}
+	return false
+}
+
+// reportTaskPolicyViolation fails a multi-agent task refused by the device policy
+func (b *Bridge) reportTaskPolicyViolation(jobID, taskID string, err error) bool {
+	if !b.reportPolicyViolation(taskID, err) {
+		return false
+	}
+	b.sendMessage(Message{
+		Type: "multiagent:task_error",
+		Payload: map[string]interface{}{
+			"jobId":     jobID,
+			"taskId":    taskID,
+			"deviceId":  b.config.DeviceID,
+			"error":     err.Error(),
+			"errorType": "policy",
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return true
+}
@@ -0,0 +1,154 @@
+package bridge
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-agents/bridge/internal/protocol"
+	"github.com/open-agents/bridge/internal/session"
+)
+
+// interruptGrace is how long an interrupt waits for the agent to confirm the
+// cancel before the new prompt is sent anyway
+const interruptGrace = 15 * time.Second
+
+// tracksTurns reports whether a session reports the end of each turn (ACP).
+// Only those sessions queue input; PTY input goes straight to the terminal.
+func tracksTurns(sess *session.Session) bool {
+	return sess.Input != nil && sess.GetProtocolName() == "acp"
+}
+
+// submitPrompt delivers a prompt now if the agent is idle and queues it
+// otherwise. With interrupt, the current turn is cancelled and the prompt
+// is delivered as soon as the agent stops.
+func (b *Bridge) submitPrompt(sess *session.Session, content, clientID string, interrupt bool) error {
+	if !tracksTurns(sess) {
+		return b.deliverPrompt(sess, content)
+	}
+
+	p := session.QueuedPrompt{ID: uuid.New().String(), Content: content, ClientID: clientID}
+	deliver, position := sess.Input.Submit(p, interrupt)
+	if deliver {
+		return b.deliverPrompt(sess, content)
+	}
+
+	b.logInfo("[Queue] Session %s is busy, prompt %s queued at position %d", sess.ID, p.ID, position)
+	b.sendQueueState(sess, "queued", p.ID)
+	if interrupt {
+		b.interruptTurn(sess, p.ID)
+	}
+	return nil
+}
+
+// deliverPrompt starts a turn with content
+func (b *Bridge) deliverPrompt(sess *session.Session, content string) error {
+	b.beginTurn(sess, content)
+	if err := sess.Send(content); err != nil {
+		// The prompt never reached the agent, so no turn end will follow
+		if tracksTurns(sess) {
+			sess.Input.End()
+		}
+		return err
+	}
+	return nil
+}
+
+// turnEnded delivers the next queued prompt once the agent finished a turn
+func (b *Bridge) turnEnded(sessionID string) {
+	sess := b.sessions.Get(sessionID)
+	if sess == nil || !tracksTurns(sess) {
+		return
+	}
+	next, ok := sess.Input.Next()
+	if !ok {
+		return
+	}
+	b.deliverQueued(sess, next)
+}
+
+func (b *Bridge) deliverQueued(sess *session.Session, p session.QueuedPrompt) {
+	b.logInfo("[Queue] Delivering prompt %s to session %s (queued %v)", p.ID, sess.ID, time.Since(p.QueuedAt).Round(time.Millisecond))
+	b.sendQueueState(sess, "delivered", p.ID)
+	if err := b.deliverPrompt(sess, p.Content); err != nil {
+		b.logError("[Queue] Failed to deliver prompt %s to session %s: %v", p.ID, sess.ID, err)
+		b.sendSessionError(sess.ID, fmt.Sprintf("Failed to send message: %v", err))
+	}
+}
+
+// interruptTurn cancels the current turn so the prompt at the head of the
+// queue goes next. Agents confirm with a cancelled stopReason; if one
+// doesn't, the prompt is sent after interruptGrace regardless.
+func (b *Bridge) interruptTurn(sess *session.Session, promptID string) {
+	b.logInfo("[Queue] Interrupting session %s for prompt %s", sess.ID, promptID)
+	if err := sess.Protocol.SendMessage(protocol.Message{
+		Type:    protocol.MessageTypeCancel,
+		Content: "interrupted",
+	}); err != nil {
+		b.logWarn("[Queue] Cancel failed for session %s: %v", sess.ID, err)
+	}
+
+	time.AfterFunc(interruptGrace, func() {
+		if p, ok := sess.Input.Take(promptID); ok {
+			b.logWarn("[Queue] Session %s did not stop within %v, sending prompt %s anyway", sess.ID, interruptGrace, promptID)
+			b.deliverQueued(sess, p)
+		}
+	})
+}
+
+// requeue moves prompts that were waiting on another session (e.g. before a fallback)
+func (b *Bridge) requeue(sess *session.Session, prompts []session.QueuedPrompt) {
+	for _, p := range prompts {
+		if err := b.submitPrompt(sess, p.Content, p.ClientID, false); err != nil {
+			b.logError("[Queue] Failed to send prompt %s to session %s: %v", p.ID, sess.ID, err)
+		}
+	}
+}
+
+// sendQueueState tells clients what is waiting for a session
+func (b *Bridge) sendQueueState(sess *session.Session, reason, promptID string) {
+	b.sendMessage(Message{
+		Type: "session:queue",
+		Payload: map[string]interface{}{
+			"sessionId": sess.ID,
+			"deviceId":  b.config.DeviceID,
+			"reason":    reason,
+			"promptId":  promptID,
+			"busy":      sess.Input.Busy(),
+			"items":     sess.Input.Pending(),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// handleSessionUnqueue drops one queued prompt, or all of them when no promptId is given
+func (b *Bridge) handleSessionUnqueue(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	sessionID := getString(payload, "sessionId")
+	promptID := getString(payload, "promptId")
+	sess := b.sessions.Get(sessionID)
+	if sess == nil {
+		b.sendSessionError(sessionID, "session not found")
+		return
+	}
+	if !b.requireControl(sessionID, payload) || sess.Input == nil {
+		return
+	}
+
+	if promptID == "" {
+		if dropped := sess.Input.Clear(); len(dropped) > 0 {
+			b.logInfo("[Queue] Cleared %d queued prompt(s) of session %s", len(dropped), sessionID)
+		}
+		b.sendQueueState(sess, "cleared", "")
+		return
+	}
+	if !sess.Input.Remove(promptID) {
+		b.sendSessionError(sessionID, "prompt "+promptID+" is not queued")
+		return
+	}
+	b.sendQueueState(sess, "removed", promptID)
+}
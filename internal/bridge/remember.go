@@ -0,0 +1,151 @@
+package bridge
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-agents/bridge/internal/permission"
+	"github.com/open-agents/bridge/internal/protocol"
+)
+
+// permissionKey identifies a request for remembered decisions, scoped to the
+// project and CLI of its session and to the ACP tool kind, if any. Without a
+// bridge session to take the project and CLI from (a hook's own session ID,
+// say) there is none: an answer kept for it would apply everywhere.
+func (b *Bridge) permissionKey(sessionID, kind, tool string, input map[string]interface{}) (permission.Key, bool) {
+	sess := b.sessions.Get(sessionID)
+	if sess == nil || sess.WorkDir == "" || sess.CLIType == "" {
+		return permission.Key{}, false
+	}
+	key := permission.KeyFor(sess.WorkDir, sess.CLIType, tool, input)
+	key.Kind = kind
+	return key, true
+}
+
+// rememberedFor returns the remembered answer to a request, if its session
+// has one
+func (b *Bridge) rememberedFor(sessionID, kind, tool string, input map[string]interface{}) (permission.Remembered, bool) {
+	key, ok := b.permissionKey(sessionID, kind, tool, input)
+	if !ok {
+		return permission.Remembered{}, false
+	}
+	return b.remembered.Lookup(key)
+}
+
+// acpTool names the tool of an ACP request: a bridge tool when its kind and
+// input say what it does, else the agent's own name for it
+func acpTool(toolName, kind string, input map[string]interface{}) string {
+	if tool, _, _ := permission.Classify(kind, input); tool != "" && tool != permission.UnknownTool {
+		return tool
+	}
+	return toolName
+}
+
+// answerACP answers an ACP request without asking anyone; decidedBy names
+// the grant or remembered decision that did
+func (b *Bridge) answerACP(sessionID string, req protocol.PermissionRequest, approved bool, decidedBy string) bool {
+	sess := b.sessions.Get(sessionID)
+	if sess == nil || sess.Protocol == nil {
+		return false
+	}
+	optionID := protocol.PickOption(req.Options, approved, false)
+	b.logInfo("[Bridge] Permission %v answered %s by %s: %s", req.ID, optionID, decidedBy, req.Description)
+
+	b.recordPermissionRequest(sessionID, req)
+	b.recordPermissionDecision(sessionID, fmt.Sprintf("%v", req.ID), approved, optionID, decidedBy)
+	b.auditPermissionDecision(sessionID, fmt.Sprintf("%v", req.ID), approved, optionID, decidedBy, "")
+	sess.Protocol.SendMessage(protocol.Message{
+		Type:    protocol.MessageTypePermission,
+		Content: protocol.PermissionResponse{ID: req.ID, OptionID: optionID},
+	})
+	return true
+}
+
+// isAlwaysOption reports whether an ACP option answers for later requests
+// too, like allow_always and reject_always
+func isAlwaysOption(optionID string) bool {
+	return strings.Contains(strings.ToLower(optionID), "always")
+}
+
+// rememberDecision stores an "always" answer to a pending request so later
+// requests of the same kind are answered without asking
+func (b *Bridge) rememberDecision(idStr, sessionID string, approved bool, optionID, decidedBy string) {
+	var key permission.Key
+	var ok bool
+	if sessionID != "" {
+		m := b.storedPermission(sessionID, idStr)
+		if m == nil {
+			b.logWarn("Cannot remember permission %s: request not found", idStr)
+			return
+		}
+		input, _ := m.Meta["input"].(map[string]interface{})
+		kind, _ := m.Meta["kind"].(string)
+		key, ok = b.permissionKey(sessionID, kind, acpTool(m.Content, kind, input), input)
+		if optionID != "" {
+			approved = !strings.HasPrefix(optionID, "reject")
+		}
+	} else {
+		req, pending := b.permHandler.Lookup(idStr)
+		if !pending {
+			b.logWarn("Cannot remember permission %s: request not pending", idStr)
+			return
+		}
+		key, ok = b.permissionKey(req.SessionID, "", permission.ToolName(req.PermissionType), req.Detail)
+	}
+	if !ok {
+		b.logWarn("Cannot remember permission %s: its session's project and CLI are unknown; later requests will be asked", idStr)
+		return
+	}
+
+	r := b.remembered.Remember(key, approved, decidedBy)
+	b.logInfo("Remembered decision %s: %s %s approved=%v", r.ID, key.Tool, key.Input, approved)
+	b.sendMessage(Message{
+		Type: "permission:remembered",
+		Payload: map[string]interface{}{
+			"deviceId": b.config.DeviceID,
+			"decision": r,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// handleRememberedList reports the remembered decisions
+func (b *Bridge) handleRememberedList(msg Message) {
+	payload, _ := msg.Payload.(map[string]interface{})
+	b.sendMessage(Message{
+		Type: "permission:remembered:list_response",
+		Payload: map[string]interface{}{
+			"deviceId":  b.config.DeviceID,
+			"requestId": getString(payload, "requestId"),
+			"decisions": b.remembered.List(),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// handleRememberedForget revokes a remembered decision
+func (b *Bridge) handleRememberedForget(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+	id := getString(payload, "id")
+	result := map[string]interface{}{
+		"deviceId":  b.config.DeviceID,
+		"requestId": getString(payload, "requestId"),
+		"id":        id,
+	}
+	if r, ok := b.remembered.Forget(id); ok {
+		b.logInfo("Forgot remembered decision %s: %s %s", r.ID, r.Tool, r.Input)
+		result["ok"] = true
+	} else {
+		result["ok"] = false
+		result["error"] = "no remembered decision " + id
+	}
+	b.sendMessage(Message{
+		Type:      "permission:remembered:forgotten",
+		Payload:   result,
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
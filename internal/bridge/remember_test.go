@@ -0,0 +1,56 @@
+package bridge
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/permission"
+	"github.com/open-agents/bridge/internal/session"
+)
+
+func TestACPToolKeepsUnclassifiedNames(t *testing.T) {
+	path := map[string]interface{}{"path": "/repo/main.go"}
+	if got := acpTool("Read main.go", "read", path); got != "fs_read" {
+		t.Errorf("read: got %q", got)
+	}
+	// Without a kind a path alone must not pass for a read, or a remembered
+	// read would answer a delete of the same file
+	if got := acpTool("Delete main.go", "", path); got != "Delete main.go" {
+		t.Errorf("path only: got %q", got)
+	}
+	if got := acpTool("Delete main.go", "delete", path); got != "fs_delete" {
+		t.Errorf("delete: got %q", got)
+	}
+}
+
+func TestRememberSkipsUnknownSessions(t *testing.T) {
+	b := &Bridge{
+		config:      &config.Config{DeviceID: "dev"},
+		sessions:    session.NewManager(),
+		permHandler: permission.NewHandler(),
+		remembered:  permission.NewMemory(filepath.Join(t.TempDir(), "remembered.json")),
+	}
+	// A hook reports the CLI's own session ID, which the bridge doesn't know
+	req := permission.Request{ID: "h1", SessionID: "cli-session", PermissionType: "command:exec",
+		Detail: map[string]interface{}{"command": "rm -rf build"}}
+	if _, ok := b.permissionKey(req.SessionID, "", "Bash", req.Detail); ok {
+		t.Fatal("key for an unknown session")
+	}
+
+	submitted := make(chan struct{})
+	b.permHandler.OnRequest(func(permission.Request) { close(submitted) })
+	b.permHandler.SetTimeout(time.Second)
+	go b.permHandler.Submit(req)
+	<-submitted
+	b.rememberDecision(req.ID, "", true, "", "web")
+	if got := b.remembered.List(); len(got) != 0 {
+		t.Errorf("remembered %v", got)
+	}
+	// An entry without a project from an earlier version isn't applied either
+	b.remembered.Remember(permission.KeyFor("", "", "Bash", req.Detail), true, "web")
+	if _, ok := b.rememberedFor(req.SessionID, "", "Bash", req.Detail); ok {
+		t.Error("applied a decision to an unknown session")
+	}
+}
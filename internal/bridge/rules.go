@@ -0,0 +1,99 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/open-agents/bridge/internal/audit"
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/rules"
+)
+
+// rulesFromPayload reads the rules of a rules:sync or rules:simulate
+func rulesFromPayload(rulesData []interface{}) []config.AutoApprovalRule {
+	var newRules []config.AutoApprovalRule
+	for _, r := range rulesData {
+		if ruleMap, ok := r.(map[string]interface{}); ok {
+			rule := config.AutoApprovalRule{
+				ID:          getString(ruleMap, "id"),
+				Pattern:     getString(ruleMap, "pattern"),
+				Tool:        getString(ruleMap, "tool"),
+				Action:      getString(ruleMap, "action"),
+				Expression:  getString(ruleMap, "expression"),
+				Description: getString(ruleMap, "description"),
+			}
+			if p, ok := ruleMap["priority"].(float64); ok {
+				rule.Priority = int(p)
+			}
+			newRules = append(newRules, rule)
+		}
+	}
+	return newRules
+}
+
+// handleRulesSimulate previews what syncing a set of rules would change:
+// past hook requests from the audit log (or the cases sent along) are
+// replayed through the current and the proposed rules
+func (b *Bridge) handleRulesSimulate(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+	requestID := getString(payload, "requestId")
+	rulesData, _ := payload["rules"].([]interface{})
+	proposed := rulesFromPayload(rulesData)
+	current := b.config.Rules
+
+	go func() {
+		reply := map[string]interface{}{"deviceId": b.config.DeviceID, "requestId": requestID}
+		cases, skipped, source, err := b.simulationCases(payload)
+		if err != nil {
+			reply["error"] = err.Error()
+		} else {
+			impact := rules.Simulate(current, proposed, cases)
+			impact.Skipped = skipped
+			reply["source"] = source
+			reply["impact"] = impact
+			b.logInfo("Simulated %d rules against %d requests from %s: %d would change", len(proposed), len(cases), source, len(impact.Changed))
+		}
+		b.sendMessage(Message{Type: "rules:simulate_response", Payload: reply, Timestamp: time.Now().UnixMilli()})
+	}()
+}
+
+// simulationCases returns the requests to replay: the payload's "cases" if
+// it has any, otherwise the hook requests of the audit log made since
+// "since" (Unix milliseconds, optional)
+func (b *Bridge) simulationCases(payload map[string]interface{}) ([]rules.Case, int, string, error) {
+	if raw, ok := payload["cases"]; ok {
+		var cases []rules.Case
+		data, _ := json.Marshal(raw)
+		if err := json.Unmarshal(data, &cases); err != nil {
+			return nil, 0, "", fmt.Errorf("invalid cases: %v", err)
+		}
+		return cases, 0, "cases", nil
+	}
+
+	if b.audit == nil {
+		return nil, 0, "", fmt.Errorf("the audit log is not available")
+	}
+	entries, err := audit.Read(b.audit.Path())
+	if err != nil {
+		return nil, 0, "", err
+	}
+	cases, skipped := rules.CasesFromAudit(entries, b.scanner)
+	if since, ok := payload["since"].(float64); ok {
+		cases = casesSince(cases, time.UnixMilli(int64(since)))
+	}
+	return cases, skipped, "audit", nil
+}
+
+func casesSince(cases []rules.Case, since time.Time) []rules.Case {
+	var kept []rules.Case
+	for _, c := range cases {
+		if !c.Request.Time.Before(since) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,263 @@
+package bridge
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/open-agents/bridge/internal/audit"
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/protocol"
+	"github.com/open-agents/bridge/internal/scanner"
+)
+
+// outputHoldDelay is how long text a stream held back for the next chunk
+// waits for it before it is passed on as it is
+const outputHoldDelay = 300 * time.Millisecond
+
+// outputScan is the scan of one type of a session's output
+type outputScan struct {
+	stream  *scanner.Stream
+	release *time.Timer // passes on what the stream held back if no chunk follows
+}
+
+// scanOutput scans the text of a session's output as one stream per message
+// type, so a secret split between two chunks is still found, and returns
+// the message as it may leave the device: with redaction configured, the
+// findings it selects are masked in text and in tool calls. Streamed text
+// that could still turn into a secret is held back; what other streams held
+// is returned first, as released, so output keeps its order, and the end of
+// a turn flushes them all.
+func (b *Bridge) scanOutput(sessionID string, msg protocol.Message) (protocol.Message, []protocol.Message, []scanner.Alert) {
+	if msg.Type == protocol.MessageTypeStatus {
+		if _, ended := msg.Meta["stopReason"]; ended && msg.Content == protocol.StatusIdle {
+			released, alerts := b.flushOutputScan(sessionID, false)
+			return msg, released, alerts
+		}
+		return msg, nil, nil
+	}
+
+	released, alerts := b.releaseOutput(sessionID, msg.Type)
+	switch content := msg.Content.(type) {
+	case string:
+		scan := b.outputStream(sessionID, msg.Type)
+		var masked string
+		var found []scanner.Alert
+		if msg.Type == protocol.MessageTypeContent || msg.Type == protocol.MessageTypeThought {
+			masked, found = scan.stream.Write(content)
+			if scan.stream.Holding() {
+				b.holdOutput(sessionID, msg.Type, scan)
+			}
+		} else {
+			// Errors and activity come whole; nothing follows to wait for
+			masked, found = scan.stream.WriteMessage(content)
+		}
+		msg.Content = masked
+		return msg, released, append(alerts, found...)
+
+	case protocol.ToolCall:
+		if b.scanner.Redaction() == nil {
+			return msg, released, alerts
+		}
+		var inToolCall, found []scanner.Alert
+		if content.Input != nil {
+			var input interface{}
+			input, found = b.scanner.RedactValue(content.Input, scanner.DirOutput)
+			content.Input = input.(map[string]interface{})
+			inToolCall = append(inToolCall, found...)
+		}
+		content.Result, found = b.scanner.RedactValue(content.Result, scanner.DirOutput)
+		inToolCall = append(inToolCall, found...)
+		if content.Content != nil {
+			var blocks interface{}
+			blocks, found = b.scanner.RedactValue(content.Content, scanner.DirOutput)
+			content.Content = blocks.([]interface{})
+			inToolCall = append(inToolCall, found...)
+		}
+		msg.Content = content
+		return msg, released, append(alerts, b.outputStream(sessionID, msg.Type).stream.Unreported(inToolCall)...)
+	}
+	return msg, released, alerts
+}
+
+// outputStream returns the scan of one type of a session's output
+func (b *Bridge) outputStream(sessionID string, typ protocol.MessageType) *outputScan {
+	b.outputScanMu.Lock()
+	defer b.outputScanMu.Unlock()
+	if b.outputScans == nil {
+		b.outputScans = make(map[string]map[protocol.MessageType]*outputScan)
+	}
+	scans := b.outputScans[sessionID]
+	if scans == nil {
+		scans = make(map[protocol.MessageType]*outputScan)
+		b.outputScans[sessionID] = scans
+	}
+	scan := scans[typ]
+	if scan == nil {
+		scan = &outputScan{stream: b.scanner.NewStream(scanner.DirOutput)}
+		scans[typ] = scan
+	}
+	return scan
+}
+
+// holdOutput (re)starts the wait for the chunk after text a stream held
+// back; when none comes in time the text is passed on as it is
+func (b *Bridge) holdOutput(sessionID string, typ protocol.MessageType, scan *outputScan) {
+	b.outputScanMu.Lock()
+	defer b.outputScanMu.Unlock()
+	if scan.release != nil {
+		scan.release.Stop()
+	}
+	scan.release = time.AfterFunc(outputHoldDelay, func() {
+		text, alerts := scan.stream.Release()
+		b.sendAlerts(sessionID, "output", alerts)
+		b.forwardReleased(sessionID, []protocol.Message{{Type: typ, Content: text}})
+	})
+}
+
+// releaseOutput passes on what the streams of a session's output other than
+// typ held back, before a message of typ goes out
+func (b *Bridge) releaseOutput(sessionID string, typ protocol.MessageType) ([]protocol.Message, []scanner.Alert) {
+	b.outputScanMu.Lock()
+	var holding []protocol.MessageType
+	for t, scan := range b.outputScans[sessionID] {
+		if t != typ && scan.stream.Holding() {
+			holding = append(holding, t)
+		}
+	}
+	scans := b.outputScans[sessionID]
+	b.outputScanMu.Unlock()
+
+	var released []protocol.Message
+	var alerts []scanner.Alert
+	for _, t := range holding {
+		text, found := scans[t].stream.Release()
+		alerts = append(alerts, found...)
+		if text != "" {
+			released = append(released, protocol.Message{Type: t, Content: text})
+		}
+	}
+	return released, alerts
+}
+
+// flushOutputScan returns what a session's output scans held back for a next
+// chunk, the text to pass on and the findings not reported yet; with done
+// the scans are dropped
+func (b *Bridge) flushOutputScan(sessionID string, done bool) ([]protocol.Message, []scanner.Alert) {
+	b.outputScanMu.Lock()
+	scans := b.outputScans[sessionID]
+	if done {
+		delete(b.outputScans, sessionID)
+	}
+	for _, scan := range scans {
+		if scan.release != nil {
+			scan.release.Stop()
+		}
+	}
+	b.outputScanMu.Unlock()
+
+	var released []protocol.Message
+	var alerts []scanner.Alert
+	for typ, scan := range scans {
+		text, found := scan.stream.Flush()
+		alerts = append(alerts, found...)
+		if text != "" {
+			released = append(released, protocol.Message{Type: typ, Content: text})
+		}
+	}
+	return released, alerts
+}
+
+// forwardReleased passes on text the output scans held back. It was
+// recorded to history when it arrived, so only the masked text goes on.
+func (b *Bridge) forwardReleased(sessionID string, released []protocol.Message) {
+	for _, out := range released {
+		b.forwardSessionOutput(sessionID, protocol.Message{Type: out.Type, Content: ""}, out)
+	}
+}
+
+// sendAlerts raises security alerts for a session's input or output
+func (b *Bridge) sendAlerts(sessionID, direction string, alerts []scanner.Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	for _, a := range alerts {
+		b.sendMessage(Message{
+			Type: "security:alert",
+			Payload: map[string]interface{}{
+				"sessionId":   sessionID,
+				"deviceId":    b.config.DeviceID,
+				"category":    a.Category,
+				"level":       a.Level,
+				"ruleId":      a.RuleID,
+				"title":       a.Title,
+				"description": a.Description,
+				"match":       a.Match,
+				"direction":   direction,
+				"plugin":      a.Plugin,
+				"findingId":   a.FindingID,
+				"redacted":    a.Redacted,
+				"confidence":  a.Confidence,
+			},
+			Timestamp: time.Now().UnixMilli(),
+		})
+	}
+	b.auditAlerts(sessionID, direction, alerts)
+	b.logWarn("[Scanner] ⚠️ %d %s alert(s) in session %s", len(alerts), direction, sessionID)
+}
+
+// applyRedaction sets which findings are masked in content sent off the
+// device. In local mode nothing leaves it, so nothing is masked.
+func (b *Bridge) applyRedaction() {
+	if b.localUI != nil {
+		b.scanner.SetRedaction(nil)
+		return
+	}
+	b.scanner.SetRedaction(b.config.Redaction)
+}
+
+// handleScannerRedactionSync sets the findings masked in outbound content:
+// "plugins" maps a plugin name, or "*", to the lowest level masked; none
+// turns redaction off
+func (b *Bridge) handleScannerRedactionSync(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+	var cfg *scanner.RedactConfig
+	if plugins, ok := payload["plugins"].(map[string]interface{}); ok && len(plugins) > 0 {
+		cfg = &scanner.RedactConfig{Plugins: make(map[string]scanner.AlertLevel, len(plugins))}
+		for name, level := range plugins {
+			if l, _ := level.(string); l != "" {
+				cfg.Plugins[name] = scanner.AlertLevel(l)
+			}
+		}
+	}
+
+	b.config.Redaction = cfg
+	b.applyRedaction()
+	config.Save(b.config)
+	b.auditEvent(audit.ConfigSync, "", "web", map[string]interface{}{"sections": []string{"redaction"}, "redaction": cfg})
+	b.logInfo("[Scanner] Redaction of outbound content: %v", redactionSummary(cfg))
+
+	b.sendMessage(Message{
+		Type: "scanner:redaction:synced",
+		Payload: map[string]interface{}{
+			"deviceId":  b.config.DeviceID,
+			"redaction": cfg,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+func redactionSummary(cfg *scanner.RedactConfig) string {
+	if cfg == nil || len(cfg.Plugins) == 0 {
+		return "off"
+	}
+	parts := make([]string, 0, len(cfg.Plugins))
+	for name, level := range cfg.Plugins {
+		parts = append(parts, name+"="+string(level))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
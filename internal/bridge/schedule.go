@@ -0,0 +1,292 @@
+package bridge
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/metrics"
+	"github.com/open-agents/bridge/internal/scanner"
+	"github.com/open-agents/bridge/internal/schedule"
+	"github.com/open-agents/bridge/internal/session"
+)
+
+// scheduleSummaryLen is how much of the agent's last reply a run report carries
+const scheduleSummaryLen = 500
+
+// initSchedules opens the run records and loads the configured schedules
+func (b *Bridge) initSchedules() {
+	b.scheduleRuns = schedule.NewRunStore(filepath.Join(config.ConfigDir(), "schedules", "runs"))
+	for _, run := range b.scheduleRuns.Interrupted() {
+		b.logWarn("[Schedule] Run %s of %s was interrupted by a bridge restart", run.ID, run.ScheduleID)
+	}
+	b.scheduler = schedule.NewScheduler(b.startScheduledRun)
+	b.applySchedules()
+}
+
+// applySchedules hands the configured schedules to the scheduler
+func (b *Bridge) applySchedules() {
+	for _, err := range b.scheduler.Set(b.config.Schedules) {
+		b.logError("[Schedule] Skipping invalid schedule: %v", err)
+	}
+}
+
+// startScheduledRun starts a session for a schedule that came due and sends
+// its prompt. The run finishes when the agent ends the turn, the session
+// fails, or the schedule's timeout passes.
+func (b *Bridge) startScheduledRun(sc schedule.Schedule, dueAt time.Time) {
+	run := schedule.Run{
+		ID:         uuid.New().String(),
+		ScheduleID: sc.ID,
+		CLIType:    sc.CLIType,
+		WorkDir:    sc.WorkDir,
+		DueAt:      dueAt,
+		StartedAt:  time.Now(),
+		Status:     schedule.RunRunning,
+	}
+
+	if prev, ok := b.scheduleRuns.Running(sc.ID); ok {
+		b.logWarn("[Schedule] %s: run %s is still going, skipping this one", sc.ID, prev.ID)
+		run.Status = schedule.RunSkipped
+		run.FinishedAt = run.StartedAt
+		run.Error = "previous run " + prev.ID + " was still running"
+		b.scheduleRuns.Save(run)
+		b.reportScheduleRun(run)
+		return
+	}
+
+	run.SessionID = fmt.Sprintf("sched-%s-%s", sc.ID, dueAt.Format("20060102-150405"))
+	b.scheduleRuns.Save(run)
+	b.logInfo("[Schedule] %s: starting run %s as session %s", sc.ID, run.ID, run.SessionID)
+
+	sess, err := b.sessions.CreateWithOptions(session.CreateOptions{
+		CLIType:        sc.CLIType,
+		WorkDir:        sc.WorkDir,
+		SessionID:      run.SessionID,
+		Cols:           120,
+		Rows:           30,
+		PermissionMode: sc.PermissionMode,
+	})
+	if err != nil {
+		b.finishScheduledRun(run.ID, schedule.RunFailed, "", fmt.Sprintf("failed to start session: %v", err))
+		return
+	}
+	sess.ScheduleRunID = run.ID
+	metrics.StartSession(sess.ID)
+	b.auditSessionStart(sess, "schedule", map[string]interface{}{"scheduleId": sc.ID, "runId": run.ID})
+	b.reportScheduleRun(run)
+
+	if err := b.deliverPrompt(sess, sc.Prompt); err != nil {
+		b.finishScheduledRun(run.ID, schedule.RunFailed, "", fmt.Sprintf("failed to send prompt: %v", err))
+		return
+	}
+
+	time.AfterFunc(sc.Timeout(), func() {
+		b.finishScheduledRun(run.ID, schedule.RunTimeout, "", fmt.Sprintf("no result within %v", sc.Timeout()))
+	})
+}
+
+// scheduledTurnEnded finishes the run of a scheduled session whose agent
+// ended its turn (ACP) or whose CLI exited (PTY)
+func (b *Bridge) scheduledTurnEnded(sessionID string, meta map[string]interface{}) {
+	sess := b.sessions.Get(sessionID)
+	if sess == nil || sess.ScheduleRunID == "" {
+		return
+	}
+	stopReason, _ := meta["stopReason"].(string)
+	switch {
+	case stopReason == "error":
+		// Failed prompts are reported by the error itself, which may also start a fallback
+	case stopReason == "cancelled":
+		b.finishScheduledRun(sess.ScheduleRunID, schedule.RunFailed, stopReason, "the turn was cancelled")
+	case stopReason != "":
+		b.finishScheduledRun(sess.ScheduleRunID, schedule.RunCompleted, stopReason, "")
+	default:
+		if code, ok := meta["exit_code"].(int); ok {
+			if code == 0 {
+				b.finishScheduledRun(sess.ScheduleRunID, schedule.RunCompleted, "exit", "")
+			} else {
+				b.finishScheduledRun(sess.ScheduleRunID, schedule.RunFailed, "exit", fmt.Sprintf("CLI exited with code %d", code))
+			}
+		}
+	}
+}
+
+// scheduledRunFailed finishes the run of a scheduled session that reported an
+// error no fallback took over
+func (b *Bridge) scheduledRunFailed(sessionID string, errText string) {
+	if sess := b.sessions.Get(sessionID); sess != nil && sess.ScheduleRunID != "" {
+		b.finishScheduledRun(sess.ScheduleRunID, schedule.RunFailed, "", errText)
+	}
+}
+
+// moveScheduledRun hands a run over to the session that replaced its own
+func (b *Bridge) moveScheduledRun(from, to *session.Session) {
+	if from.ScheduleRunID == "" {
+		return
+	}
+	to.ScheduleRunID, from.ScheduleRunID = from.ScheduleRunID, ""
+	b.scheduleRuns.Update(to.ScheduleRunID, func(r *schedule.Run) {
+		r.SessionID = to.ID
+		r.CLIType = to.CLIType
+	})
+}
+
+// finishScheduledRun records the outcome of a run, stops its session unless
+// a client has taken it over, and reports the result
+func (b *Bridge) finishScheduledRun(runID, status, stopReason, errText string) {
+	current, ok := b.scheduleRuns.Get(runID)
+	if !ok || current.Done() {
+		return
+	}
+	b.flushReply(current.SessionID)
+	summary := b.lastReply(current.SessionID)
+
+	finished := false
+	run, _ := b.scheduleRuns.Update(runID, func(r *schedule.Run) {
+		if r.Done() {
+			return
+		}
+		finished = true
+		r.Status = status
+		r.StopReason = stopReason
+		r.Error = errText
+		r.Summary = summary
+		r.FinishedAt = time.Now()
+	})
+	if !finished {
+		return
+	}
+	b.logInfo("[Schedule] %s: run %s %s after %v", run.ScheduleID, run.ID, status, run.FinishedAt.Sub(run.StartedAt).Round(time.Second))
+
+	if sess := b.sessions.Get(run.SessionID); sess != nil && sess.ScheduleRunID == runID {
+		sess.ScheduleRunID = ""
+		if b.sessions.Owners().Controller(sess.ID) == nil {
+			_ = b.sessions.Stop(sess.ID)
+			metrics.EndSession(sess.ID)
+			b.sessionEnded(sess.ID, "schedule")
+		}
+	}
+	b.reportScheduleRun(run)
+}
+
+// lastReply returns the end of the agent's last reply stored for a session
+func (b *Bridge) lastReply(sessionID string) string {
+	if b.store == nil {
+		return ""
+	}
+	msgs := b.store.GetMessages(sessionID, 0)
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == "assistant" && msgs[i].Type == "" {
+			text := []rune(msgs[i].Content)
+			if len(text) > scheduleSummaryLen {
+				text = text[len(text)-scheduleSummaryLen:]
+			}
+			return string(text)
+		}
+	}
+	return ""
+}
+
+// redactRun returns a run as it may leave the device: the findings the
+// redaction config selects are masked in its summary of the agent's reply
+// and in its error. The run store keeps the original.
+func (b *Bridge) redactRun(run schedule.Run) schedule.Run {
+	run.Summary, _ = b.scanner.RedactText(run.Summary, scanner.DirOutput)
+	run.Error, _ = b.scanner.RedactText(run.Error, scanner.DirOutput)
+	return run
+}
+
+func (b *Bridge) redactRuns(runs []schedule.Run) []schedule.Run {
+	for i := range runs {
+		runs[i] = b.redactRun(runs[i])
+	}
+	return runs
+}
+
+// connected reports whether the WebSocket to the server is up
+func (b *Bridge) connected() bool {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+	return b.conn != nil
+}
+
+// reportScheduleRun sends a run to the server. Finished runs are marked
+// reported once sent; while offline they wait for reportScheduleRuns.
+func (b *Bridge) reportScheduleRun(run schedule.Run) {
+	if !b.connected() {
+		if run.Done() {
+			b.logInfo("[Schedule] Offline, run %s will be reported on reconnect", run.ID)
+		}
+		return
+	}
+	err := b.sendMessage(Message{
+		Type: "schedule:run",
+		Payload: map[string]interface{}{
+			"deviceId": b.config.DeviceID,
+			"run":      b.redactRun(run),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	if err == nil && run.Done() {
+		b.scheduleRuns.Update(run.ID, func(r *schedule.Run) { r.Reported = true })
+	}
+}
+
+// reportScheduleRuns sends the runs that finished while the bridge was offline
+func (b *Bridge) reportScheduleRuns() {
+	runs := b.scheduleRuns.Unreported()
+	if len(runs) == 0 {
+		return
+	}
+	b.logInfo("[Schedule] Reporting %d run(s) that finished while offline", len(runs))
+	for _, run := range runs {
+		b.reportScheduleRun(run)
+	}
+}
+
+// handleScheduleList replies with the schedules, their next run times and recent runs
+func (b *Bridge) handleScheduleList(msg Message) {
+	payload, _ := msg.Payload.(map[string]interface{})
+	scheduleID := getString(payload, "scheduleId")
+	limit := 20
+	if l, ok := payload["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+
+	b.sendMessage(Message{
+		Type: "schedule:list_response",
+		Payload: map[string]interface{}{
+			"deviceId":  b.config.DeviceID,
+			"requestId": getString(payload, "requestId"),
+			"schedules": b.scheduler.List(),
+			"runs":      b.redactRuns(b.scheduleRuns.List(scheduleID, limit)),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// handleScheduleRunNow starts a run of a schedule immediately
+func (b *Bridge) handleScheduleRunNow(msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+	scheduleID := getString(payload, "scheduleId")
+	sc, ok := b.scheduler.Get(scheduleID)
+	if !ok {
+		b.sendMessage(Message{
+			Type: "schedule:error",
+			Payload: map[string]interface{}{
+				"deviceId":   b.config.DeviceID,
+				"scheduleId": scheduleID,
+				"error":      "schedule not found",
+			},
+			Timestamp: time.Now().UnixMilli(),
+		})
+		return
+	}
+	go b.startScheduledRun(sc, time.Now())
+}
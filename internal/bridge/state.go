@@ -14,6 +14,7 @@ const (
 	StateConnected
 	StateReconnecting
 	StateFailed
+	StateLocal // serving the local web UI, no server connection
 )
 
 // String returns the string representation of the connection state
@@ -29,6 +30,8 @@ func (s ConnectionState) String() string {
 		return "reconnecting"
 	case StateFailed:
 		return "failed"
+	case StateLocal:
+		return "local"
 	default:
 		return "unknown"
 	}
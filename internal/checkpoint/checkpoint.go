@@ -0,0 +1,361 @@
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Checkpoint is a snapshot of a session's workDir taken before a prompt turn
+type Checkpoint struct {
+	ID        string    `json:"id"` // commit hash in the shadow repository
+	SessionID string    `json:"sessionId"`
+	Turn      int       `json:"turn"`
+	WorkDir   string    `json:"workDir"`
+	Label     string    `json:"label"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RollbackResult describes what a rollback changed in the workDir
+type RollbackResult struct {
+	Checkpoint Checkpoint `json:"checkpoint"`
+	SafetyID   string     `json:"safetyCheckpointId"` // snapshot taken right before rolling back
+	Restored   []string   `json:"restored"`
+	Removed    []string   `json:"removed"`
+}
+
+// Store keeps workDir snapshots in shadow git repositories.
+// The user's own repository (if any) is never touched: every workDir gets a
+// separate bare repository under the store directory and files are committed
+// with an explicit --work-tree, so changes made through ACP fs writes,
+// terminal commands or PTY CLIs are all captured the same way.
+type Store struct {
+	dir     string
+	timeout time.Duration
+	mu      sync.Mutex
+}
+
+// DefaultTimeout bounds how long taking a snapshot may hold up a prompt
+const DefaultTimeout = 10 * time.Second
+
+// NewStore creates a checkpoint store rooted at dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir, timeout: DefaultTimeout}
+}
+
+// SetTimeout overrides how long a snapshot may take before it is given up
+func (s *Store) SetTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeout = timeout
+}
+
+// Available reports whether the git binary needed for checkpoints is installed
+func Available() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// Create snapshots workDir for the given session. If nothing changed since the
+// session's last checkpoint, the last checkpoint is returned with created=false.
+func (s *Store) Create(sessionID, workDir, label string) (cp *Checkpoint, created bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createLocked(sessionID, workDir, label)
+}
+
+func (s *Store) createLocked(sessionID, workDir, label string) (*Checkpoint, bool, error) {
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve workDir: %w", err)
+	}
+	if err := checkWorkDir(absWorkDir); err != nil {
+		return nil, false, err
+	}
+
+	gitDir, err := s.ensureRepo(absWorkDir)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Staging reads the whole tree, which in a large workDir can take longer
+	// than a prompt should wait
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	if _, err := s.gitContext(ctx, gitDir, absWorkDir, "add", "-A", "."); err != nil {
+		if ctx.Err() != nil {
+			// A killed git leaves its lock behind, which would fail every later snapshot
+			os.Remove(filepath.Join(gitDir, "index.lock"))
+			return nil, false, fmt.Errorf("staging workDir took longer than %v", s.timeout)
+		}
+		return nil, false, fmt.Errorf("failed to stage workDir: %w", err)
+	}
+	tree, err := s.git(gitDir, absWorkDir, "write-tree")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to write tree: %w", err)
+	}
+
+	index := s.loadIndex(sessionID)
+	args := []string{"commit-tree", tree, "-m", label}
+	if len(index) > 0 {
+		last := index[len(index)-1]
+		lastTree, err := s.git(gitDir, absWorkDir, "rev-parse", last.ID+"^{tree}")
+		if err == nil && lastTree == tree && last.WorkDir == absWorkDir {
+			return &last, false, nil
+		}
+		if err == nil {
+			args = append(args, "-p", last.ID)
+		}
+	}
+
+	commit, err := s.git(gitDir, absWorkDir, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+
+	cp := Checkpoint{
+		ID:        commit,
+		SessionID: sessionID,
+		Turn:      len(index) + 1,
+		WorkDir:   absWorkDir,
+		Label:     label,
+		CreatedAt: time.Now(),
+	}
+
+	// Keep every checkpoint reachable so git gc never drops it
+	ref := fmt.Sprintf("refs/open-agents/%s/turn-%d", refSafe(sessionID), cp.Turn)
+	if _, err := s.git(gitDir, absWorkDir, "update-ref", ref, commit); err != nil {
+		return nil, false, fmt.Errorf("failed to update ref: %w", err)
+	}
+
+	index = append(index, cp)
+	if err := s.saveIndex(sessionID, index); err != nil {
+		return nil, false, err
+	}
+	return &cp, true, nil
+}
+
+// List returns all checkpoints of a session, oldest first
+func (s *Store) List(sessionID string) []Checkpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadIndex(sessionID)
+}
+
+// Get returns a checkpoint by ID. An empty ID selects the latest checkpoint.
+func (s *Store) Get(sessionID, checkpointID string) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getLocked(sessionID, checkpointID)
+}
+
+func (s *Store) getLocked(sessionID, checkpointID string) (*Checkpoint, error) {
+	index := s.loadIndex(sessionID)
+	if len(index) == 0 {
+		return nil, fmt.Errorf("no checkpoints for session %s", sessionID)
+	}
+	if checkpointID == "" {
+		cp := index[len(index)-1]
+		return &cp, nil
+	}
+	for _, cp := range index {
+		if cp.ID == checkpointID || (len(checkpointID) >= 7 && strings.HasPrefix(cp.ID, checkpointID)) {
+			c := cp
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("checkpoint %s not found for session %s", checkpointID, sessionID)
+}
+
+// Rollback restores the session's workDir to the given checkpoint.
+// A safety checkpoint of the current state is taken first so a rollback
+// can itself be rolled back.
+func (s *Store) Rollback(sessionID, checkpointID string) (*RollbackResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target, err := s.getLocked(sessionID, checkpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	safety, _, err := s.createLocked(sessionID, target.WorkDir, fmt.Sprintf("before rollback to %s", shortID(target.ID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to take safety checkpoint: %w", err)
+	}
+
+	gitDir := s.repoPath(target.WorkDir)
+	result := &RollbackResult{Checkpoint: *target, SafetyID: safety.ID}
+	if safety.ID == target.ID {
+		return result, nil
+	}
+
+	// Files created after the checkpoint have to be removed explicitly
+	out, err := s.git(gitDir, target.WorkDir, "diff-tree", "-r", "--no-renames", "--name-status", target.ID, safety.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff checkpoints: %w", err)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		status, path := fields[0], fields[1]
+		if status == "A" {
+			if err := os.Remove(filepath.Join(target.WorkDir, path)); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+			result.Removed = append(result.Removed, path)
+		} else {
+			result.Restored = append(result.Restored, path)
+		}
+	}
+
+	if len(result.Restored) > 0 {
+		if _, err := s.git(gitDir, target.WorkDir, "checkout", "-f", target.ID, "--", "."); err != nil {
+			return nil, fmt.Errorf("failed to restore files: %w", err)
+		}
+	}
+	// Re-sync the shadow index with the restored state
+	if _, err := s.git(gitDir, target.WorkDir, "read-tree", target.ID); err != nil {
+		return nil, fmt.Errorf("failed to reset shadow index: %w", err)
+	}
+
+	return result, nil
+}
+
+// ensureRepo initializes the shadow repository for a workDir
+func (s *Store) ensureRepo(absWorkDir string) (string, error) {
+	gitDir := s.repoPath(absWorkDir)
+	if _, err := os.Stat(filepath.Join(gitDir, "HEAD")); err == nil {
+		return gitDir, nil
+	}
+	if err := os.MkdirAll(gitDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create checkpoint repo: %w", err)
+	}
+	cmd := exec.Command("git", "init", "--quiet", "--bare", gitDir)
+	cmd.Env = gitEnv()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("git init failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	// Record which directory this repository shadows, for humans poking around
+	os.WriteFile(filepath.Join(gitDir, "description"), []byte(absWorkDir+"\n"), 0600)
+	return gitDir, nil
+}
+
+func (s *Store) repoPath(absWorkDir string) string {
+	sum := sha1.Sum([]byte(absWorkDir))
+	return filepath.Join(s.dir, "repos", hex.EncodeToString(sum[:8])+".git")
+}
+
+// git runs a git command against the shadow repository and returns trimmed stdout
+func (s *Store) git(gitDir, workDir string, args ...string) (string, error) {
+	return s.gitContext(context.Background(), gitDir, workDir, args...)
+}
+
+// gitContext is git, killed when ctx is done
+func (s *Store) gitContext(ctx context.Context, gitDir, workDir string, args ...string) (string, error) {
+	full := append([]string{
+		"--git-dir=" + gitDir,
+		"--work-tree=" + workDir,
+		"-c", "user.name=open-agents",
+		"-c", "user.email=bridge@open-agents.local",
+		"-c", "core.autocrlf=false",
+		"-c", "gc.auto=0",
+	}, args...)
+	cmd := exec.CommandContext(ctx, "git", full...)
+	cmd.Dir = workDir
+	cmd.Env = gitEnv()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (s *Store) indexPath(sessionID string) string {
+	return filepath.Join(s.dir, "sessions", refSafe(sessionID)+".json")
+}
+
+func (s *Store) loadIndex(sessionID string) []Checkpoint {
+	data, err := os.ReadFile(s.indexPath(sessionID))
+	if err != nil {
+		return nil
+	}
+	var index []Checkpoint
+	if json.Unmarshal(data, &index) != nil {
+		return nil
+	}
+	return index
+}
+
+func (s *Store) saveIndex(sessionID string, index []Checkpoint) error {
+	path := s.indexPath(sessionID)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create checkpoint index dir: %w", err)
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// checkWorkDir refuses to snapshot directories that are far too broad
+func checkWorkDir(absWorkDir string) error {
+	info, err := os.Stat(absWorkDir)
+	if err != nil {
+		return fmt.Errorf("workDir not accessible: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("workDir is not a directory: %s", absWorkDir)
+	}
+	if absWorkDir == filepath.Dir(absWorkDir) {
+		return fmt.Errorf("refusing to checkpoint filesystem root")
+	}
+	if home, err := os.UserHomeDir(); err == nil && filepath.Clean(home) == absWorkDir {
+		return fmt.Errorf("refusing to checkpoint home directory")
+	}
+	return nil
+}
+
+// gitEnv strips GIT_* variables so the user's environment can't redirect the shadow repo
+func gitEnv() []string {
+	env := make([]string, 0, len(os.Environ()))
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, "GIT_") {
+			env = append(env, e)
+		}
+	}
+	return append(env, "GIT_TERMINAL_PROMPT=0")
+}
+
+var unsafeRefChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func refSafe(id string) string {
+	safe := unsafeRefChars.ReplaceAllString(id, "_")
+	safe = strings.TrimLeft(safe, ".")
+	if safe == "" {
+		safe = "_"
+	}
+	return safe
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,117 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateAndRollback(t *testing.T) {
+	if !Available() {
+		t.Skip("git not installed")
+	}
+
+	store := NewStore(t.TempDir())
+	workDir := t.TempDir()
+
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(workDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("main.go", "package main\n")
+	write("docs/readme.md", "hello\n")
+
+	cp1, created, err := store.Create("sess-1", workDir, "turn 1")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !created || cp1.Turn != 1 {
+		t.Fatalf("expected new checkpoint for turn 1, got created=%v turn=%d", created, cp1.Turn)
+	}
+
+	// Unchanged tree reuses the last checkpoint
+	cpSame, created, err := store.Create("sess-1", workDir, "turn 2")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created || cpSame.ID != cp1.ID {
+		t.Errorf("expected unchanged workDir to reuse checkpoint %s, got %s (created=%v)", cp1.ID, cpSame.ID, created)
+	}
+
+	// Simulate an agent turn: modify, delete and add files
+	write("main.go", "package main\n\nfunc broken(\n")
+	os.Remove(filepath.Join(workDir, "docs/readme.md"))
+	write("junk/new.txt", "oops\n")
+
+	result, err := store.Rollback("sess-1", cp1.ID)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(workDir, "main.go"))
+	if string(data) != "package main\n" {
+		t.Errorf("main.go not restored, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "docs/readme.md")); err != nil {
+		t.Errorf("deleted file not restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "junk/new.txt")); !os.IsNotExist(err) {
+		t.Errorf("file added after checkpoint should be removed")
+	}
+	if len(result.Removed) != 1 || result.Removed[0] != "junk/new.txt" {
+		t.Errorf("Removed = %v, want [junk/new.txt]", result.Removed)
+	}
+	if result.SafetyID == "" || result.SafetyID == cp1.ID {
+		t.Errorf("expected a distinct safety checkpoint, got %q", result.SafetyID)
+	}
+
+	// The rollback itself can be undone
+	if _, err := store.Rollback("sess-1", result.SafetyID); err != nil {
+		t.Fatalf("Rollback to safety checkpoint failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "junk/new.txt")); err != nil {
+		t.Errorf("undoing rollback should bring back new.txt: %v", err)
+	}
+
+	if got := len(store.List("sess-1")); got < 2 {
+		t.Errorf("List returned %d checkpoints, want at least 2", got)
+	}
+}
+
+func TestGetUnknownCheckpoint(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if _, err := store.Get("missing", ""); err == nil {
+		t.Error("expected error for session without checkpoints")
+	}
+}
+
+func TestCreateGivesUpAfterTimeout(t *testing.T) {
+	if !Available() {
+		t.Skip("git not installed")
+	}
+
+	store := NewStore(t.TempDir())
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "main.go"), []byte("package main\n"), 0644)
+
+	store.SetTimeout(time.Nanosecond)
+	if _, _, err := store.Create("sess-1", workDir, "turn 1"); err == nil || !strings.Contains(err.Error(), "longer than") {
+		t.Fatalf("Create = %v, want a timeout", err)
+	}
+	if got := len(store.List("sess-1")); got != 0 {
+		t.Errorf("List returned %d checkpoints after a timeout", got)
+	}
+
+	// The next snapshot is not held up by the one given up
+	store.SetTimeout(DefaultTimeout)
+	if _, created, err := store.Create("sess-1", workDir, "turn 1"); err != nil || !created {
+		t.Fatalf("Create after timeout: created=%v err=%v", created, err)
+	}
+}
@@ -0,0 +1,87 @@
+package clidef
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Fingerprint identifies the exact content of a definition; any change to
+// what would be run gives a different fingerprint
+func (d Definition) Fingerprint() string {
+	data, _ := json.Marshal(d) // map keys are sorted
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// Approvals records which synced definitions were approved on the device.
+// Definitions pushed by the server are not used until approved here, and an
+// approval only holds for the fingerprint it was given for.
+type Approvals struct {
+	mu    sync.Mutex
+	file  string
+	items map[string]string // CLI type -> fingerprint
+}
+
+// NewApprovals opens the approvals in file
+func NewApprovals(file string) *Approvals {
+	a := &Approvals{file: file, items: make(map[string]string)}
+	if data, err := os.ReadFile(file); err == nil {
+		json.Unmarshal(data, &a.items)
+	}
+	return a
+}
+
+// Approved reports whether def was approved for cliType
+func (a *Approvals) Approved(cliType string, def Definition) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fp, ok := a.items[cliType]
+	return ok && fp == def.Fingerprint()
+}
+
+// Approve approves def for cliType, replacing an earlier approval
+func (a *Approvals) Approve(cliType string, def Definition) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.items[cliType] = def.Fingerprint()
+	return a.save()
+}
+
+// Filter returns the definitions of synced that were approved and the
+// sorted names of those that were not
+func (a *Approvals) Filter(synced map[string]Definition) (approved map[string]Definition, pending []string) {
+	approved = make(map[string]Definition)
+	for name, def := range synced {
+		if a.Approved(name, def) {
+			approved[name] = def
+		} else {
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(pending)
+	return approved, pending
+}
+
+// save writes the file; callers hold mu
+func (a *Approvals) save() error {
+	if a.file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(a.items, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.file), 0700); err != nil {
+		return err
+	}
+	tmp := a.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, a.file)
+}
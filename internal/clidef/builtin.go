@@ -0,0 +1,92 @@
+package clidef
+
+// Builtins returns the default definitions of the supported CLIs
+func Builtins() map[string]Definition {
+	return map[string]Definition{
+		"claude": {
+			DisplayName: "Claude Code",
+			// Claude Code ACP via npx
+			Command: "npx",
+			Args:    []string{"@zed-industries/claude-code-acp"},
+			// Unset CLAUDECODE to allow nested sessions
+			Env: map[string]string{"CLAUDECODE": ""},
+			PermissionModes: map[string]Mode{
+				"accept-all":   {Args: []string{"--dangerously-skip-permissions"}, Env: map[string]string{"CLAUDE_PERMISSION_MODE": "accept-all"}},
+				"accept-edits": {Env: map[string]string{"CLAUDE_PERMISSION_MODE": "accept-edits"}},
+				"plan":         {Args: []string{"--plan"}, Env: map[string]string{"CLAUDE_PERMISSION_MODE": "plan"}},
+			},
+			StatePaths: []string{"~/.claude", "~/.claude.json"},
+		},
+		"qwen": {
+			DisplayName: "Qwen Code",
+			Command:     "qwen-code",
+			Args:        []string{"--experimental-acp"},
+			PermissionModes: map[string]Mode{
+				"accept-all":   {Env: map[string]string{"QWEN_PERMISSION_MODE": "accept-all"}},
+				"accept-edits": {Env: map[string]string{"QWEN_PERMISSION_MODE": "accept-edits"}},
+				"plan":         {Env: map[string]string{"QWEN_PERMISSION_MODE": "plan"}},
+			},
+			VersionCommand: []string{"qwen-code", "--version"},
+			StatePaths:     []string{"~/.qwen"},
+		},
+		"goose": {
+			DisplayName: "Goose",
+			Command:     "goose",
+			Args:        []string{"acp"},
+			PermissionModes: map[string]Mode{
+				"accept-all":   {Env: map[string]string{"GOOSE_MODE": "auto"}},
+				"accept-edits": {Env: map[string]string{"GOOSE_MODE": "auto-edit"}},
+				"plan":         {Env: map[string]string{"GOOSE_MODE": "plan"}},
+			},
+			VersionCommand: []string{"goose", "--version"},
+			StatePaths:     []string{"~/.config/goose", "~/.local/share/goose", "~/.local/state/goose"},
+		},
+		"gemini": {
+			DisplayName: "Gemini CLI",
+			Command:     "gemini-cli",
+			Args:        []string{"--acp"},
+			PermissionModes: map[string]Mode{
+				"accept-all":   {Env: map[string]string{"GEMINI_PERMISSION_MODE": "accept-all"}},
+				"accept-edits": {Env: map[string]string{"GEMINI_PERMISSION_MODE": "accept-edits"}},
+				"plan":         {Env: map[string]string{"GEMINI_PERMISSION_MODE": "plan"}},
+			},
+			VersionCommand: []string{"gemini-cli", "--version"},
+			StatePaths:     []string{"~/.gemini"},
+		},
+		"kiro": {
+			DisplayName:    "Kiro CLI",
+			Command:        "kiro",
+			Args:           []string{"chat"},
+			VersionCommand: []string{"kiro", "--version"},
+			StatePaths:     []string{"~/.kiro"},
+		},
+		"cline": {
+			DisplayName:    "Cline CLI",
+			Command:        "cline",
+			VersionCommand: []string{"cline", "--version"},
+			StatePaths:     []string{"~/.cline"},
+		},
+		"codex": {
+			DisplayName:    "Codex CLI",
+			Command:        "codex",
+			VersionCommand: []string{"codex", "--version"},
+			StatePaths:     []string{"~/.codex"},
+		},
+		"aider": {
+			// Aider - AI pair programming in terminal
+			// Installation: pip install aider-chat
+			// Uses its own protocol, not ACP
+			DisplayName: "Aider",
+			Command:     "aider",
+			Args:        []string{"--no-auto-commits", "--pretty"},
+			Protocol:    ProtocolPTY,
+			PermissionModes: map[string]Mode{
+				// Aider doesn't distinguish between edits and commands; --yes accepts both
+				"accept-all":   {Args: []string{"--yes"}},
+				"accept-edits": {Args: []string{"--yes"}},
+			},
+			VersionCommand: []string{"aider", "--version"},
+			StatePaths:     []string{"~/.aider"},
+		},
+	}
+}
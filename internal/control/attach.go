@@ -0,0 +1,199 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Event is one message of a session's stream, as the web client receives it
+type Event struct {
+	Type      string                 `json:"type"`
+	Payload   map[string]interface{} `json:"payload"`
+	Timestamp int64                  `json:"timestamp"`
+}
+
+// Events sent on an attach stream in addition to the session's own messages
+const (
+	EventAttached = "attach:ready" // first event: scrollback and controller
+	EventError    = "attach:error" // input or resize was refused
+	EventDetached = "attach:detached"
+)
+
+// AttachRequest attaches a terminal to a session
+type AttachRequest struct {
+	ClientID string `json:"clientId,omitempty"`
+	Takeover bool   `json:"takeover,omitempty"` // take control from another client
+}
+
+// Attachment is a terminal attached to a session. Events is closed when the
+// session ends or the attachment is closed.
+type Attachment interface {
+	Events() <-chan Event
+	Input(data []byte) error
+	Resize(cols, rows int) error
+	Close()
+}
+
+// attachFrame is what an attached terminal sends: keystrokes or its size
+type attachFrame struct {
+	Type string `json:"type"` // "input" or "resize"
+	Data []byte `json:"data,omitempty"`
+	Cols int    `json:"cols,omitempty"`
+	Rows int    `json:"rows,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{
+	// Only the socket owner holds the token, so any origin is fine
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// handleAttach serves GET /v1/sessions/<id>/attach as a WebSocket
+func (s *Server) handleAttach(w http.ResponseWriter, r *http.Request, id string) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	req := AttachRequest{
+		ClientID: r.URL.Query().Get("clientId"),
+		Takeover: r.URL.Query().Get("takeover") == "true",
+	}
+	att, err := s.backend.Attach(id, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		att.Close()
+		return
+	}
+	defer conn.Close()
+
+	var writeMu sync.Mutex
+	send := func(ev Event) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(ev)
+	}
+
+	// Frames from the terminal; a read error means it went away
+	go func() {
+		defer att.Close()
+		for {
+			var f attachFrame
+			if err := conn.ReadJSON(&f); err != nil {
+				return
+			}
+			var err error
+			switch f.Type {
+			case "input":
+				err = att.Input(f.Data)
+			case "resize":
+				err = att.Resize(f.Cols, f.Rows)
+			}
+			if err != nil {
+				send(Event{Type: EventError, Payload: errorPayload(err), Timestamp: time.Now().UnixMilli()})
+			}
+		}
+	}()
+
+	for ev := range att.Events() {
+		if send(ev) != nil {
+			att.Close()
+			for range att.Events() {
+				// drain until the backend closes the stream
+			}
+			return
+		}
+	}
+	writeMu.Lock()
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	writeMu.Unlock()
+}
+
+func errorPayload(err error) map[string]interface{} {
+	payload := map[string]interface{}{"error": err.Error()}
+	if e, ok := err.(*Error); ok {
+		payload["error"] = e.Message
+		payload["code"] = e.Code
+	}
+	return payload
+}
+
+// Attached is the client end of an attach stream
+type Attached struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// Attach opens the event stream of a session. Output, status and control
+// changes arrive as events; keystrokes and resizes go back with Input and Resize.
+func (c *Client) Attach(id string, req AttachRequest) (*Attached, error) {
+	q := url.Values{}
+	if req.ClientID != "" {
+		q.Set("clientId", req.ClientID)
+	}
+	if req.Takeover {
+		q.Set("takeover", "true")
+	}
+	dialer := websocket.Dialer{
+		NetDialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", c.socket)
+		},
+		HandshakeTimeout: 10 * time.Second,
+	}
+	header := http.Header{"Authorization": {"Bearer " + c.token}}
+	u := "ws://bridge/v1/sessions/" + url.PathEscape(id) + "/attach?" + q.Encode()
+	conn, resp, err := dialer.Dial(u, header)
+	if err != nil {
+		if resp != nil && resp.StatusCode >= 400 {
+			e := &Error{Status: resp.StatusCode}
+			if json.NewDecoder(resp.Body).Decode(e) != nil || e.Message == "" {
+				e.Message = resp.Status
+			}
+			resp.Body.Close()
+			return nil, e
+		}
+		return nil, err
+	}
+	return &Attached{conn: conn}, nil
+}
+
+// Next waits for the next event; it fails once the stream has ended
+func (a *Attached) Next() (Event, error) {
+	var ev Event
+	err := a.conn.ReadJSON(&ev)
+	return ev, err
+}
+
+// Input sends keystrokes to the session's terminal
+func (a *Attached) Input(data []byte) error {
+	return a.write(attachFrame{Type: "input", Data: data})
+}
+
+// Resize tells the session the size of the attached terminal
+func (a *Attached) Resize(cols, rows int) error {
+	return a.write(attachFrame{Type: "resize", Cols: cols, Rows: rows})
+}
+
+func (a *Attached) write(f attachFrame) error {
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	return a.conn.WriteJSON(f)
+}
+
+// Close detaches
+func (a *Attached) Close() error {
+	a.writeMu.Lock()
+	a.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	a.writeMu.Unlock()
+	return a.conn.Close()
+}
@@ -0,0 +1,176 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/open-agents/bridge/internal/permission"
+)
+
+// ErrNotRunning means no bridge is serving the device's control socket
+var ErrNotRunning = errors.New("bridge is not running")
+
+// Client talks to a running bridge over its control socket
+type Client struct {
+	http   *http.Client
+	socket string
+	token  string
+}
+
+// Dial connects to the bridge of a device. It returns ErrNotRunning when the
+// socket or token is missing or nobody is listening.
+func Dial(paths Paths) (*Client, error) {
+	data, err := os.ReadFile(paths.Token)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotRunning
+		}
+		return nil, fmt.Errorf("read control token: %w", err)
+	}
+	conn, err := net.DialTimeout("unix", paths.Socket, 2*time.Second)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	conn.Close()
+
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", paths.Socket)
+	}
+	return &Client{
+		http: &http.Client{
+			Transport: &http.Transport{DialContext: dial},
+			Timeout:   30 * time.Second,
+		},
+		socket: paths.Socket,
+		token:  strings.TrimSpace(string(data)),
+	}, nil
+}
+
+// Status returns the daemon status
+func (c *Client) Status() (Status, error) {
+	var st Status
+	err := c.do(http.MethodGet, "/v1/status", nil, &st)
+	return st, err
+}
+
+// Sessions lists the running sessions
+func (c *Client) Sessions() ([]SessionInfo, error) {
+	var list []SessionInfo
+	err := c.do(http.MethodGet, "/v1/sessions", nil, &list)
+	return list, err
+}
+
+// Session returns one running session
+func (c *Client) Session(id string) (SessionInfo, error) {
+	var info SessionInfo
+	err := c.do(http.MethodGet, "/v1/sessions/"+url.PathEscape(id), nil, &info)
+	return info, err
+}
+
+// StartSession starts a session
+func (c *Client) StartSession(req StartRequest) (SessionInfo, error) {
+	var info SessionInfo
+	err := c.do(http.MethodPost, "/v1/sessions", req, &info)
+	return info, err
+}
+
+// Send sends a prompt to a session
+func (c *Client) Send(id string, req SendRequest) error {
+	return c.do(http.MethodPost, "/v1/sessions/"+url.PathEscape(id)+"/send", req, nil)
+}
+
+// Stop stops a session
+func (c *Client) Stop(id string, req StopRequest) error {
+	return c.do(http.MethodPost, "/v1/sessions/"+url.PathEscape(id)+"/stop", req, nil)
+}
+
+// Cancel cancels the current turn of a session and drops its queued prompts
+func (c *Client) Cancel(id string, req StopRequest) error {
+	return c.do(http.MethodPost, "/v1/sessions/"+url.PathEscape(id)+"/cancel", req, nil)
+}
+
+// Permissions lists the permission requests waiting for an answer
+func (c *Client) Permissions() ([]PermissionInfo, error) {
+	var list []PermissionInfo
+	err := c.do(http.MethodGet, "/v1/permissions", nil, &list)
+	return list, err
+}
+
+// Resolve answers a permission request
+func (c *Client) Resolve(id string, d Decision) error {
+	return c.do(http.MethodPost, "/v1/permissions/"+url.PathEscape(id), d, nil)
+}
+
+// Remembered lists the remembered permission decisions
+func (c *Client) Remembered() ([]permission.Remembered, error) {
+	var list []permission.Remembered
+	err := c.do(http.MethodGet, "/v1/remembered", nil, &list)
+	return list, err
+}
+
+// Forget revokes a remembered permission decision
+func (c *Client) Forget(id string) error {
+	return c.do(http.MethodDelete, "/v1/remembered/"+url.PathEscape(id), nil, nil)
+}
+
+// SyncedCLIs lists the CLI definitions pushed by the server
+func (c *Client) SyncedCLIs() ([]SyncedCLI, error) {
+	var list []SyncedCLI
+	err := c.do(http.MethodGet, "/v1/clis/synced", nil, &list)
+	return list, err
+}
+
+// ApproveCLI approves a synced CLI definition
+func (c *Client) ApproveCLI(name string, a CLIApproval) error {
+	return c.do(http.MethodPost, "/v1/clis/synced/"+url.PathEscape(name)+"/approve", a, nil)
+}
+
+// do sends a request; API errors are returned as *Error
+func (c *Client) do(method, path string, body, out interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	// The host is ignored; requests go to the socket
+	req, err := http.NewRequest(method, "http://bridge"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		e := &Error{Status: resp.StatusCode}
+		if json.NewDecoder(resp.Body).Decode(e) != nil || e.Message == "" {
+			e.Message = resp.Status
+		}
+		return e
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
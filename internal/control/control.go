@@ -0,0 +1,194 @@
+// Package control is the local control API of a running bridge: an HTTP API
+// on a per-device unix socket, authenticated with a token only the user can
+// read. The CLI uses it to inspect and drive the daemon.
+package control
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/open-agents/bridge/internal/clidef"
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/metrics"
+	"github.com/open-agents/bridge/internal/permission"
+	"github.com/open-agents/bridge/internal/session"
+)
+
+// DefaultDevice names the socket of a bridge started without a device name
+const DefaultDevice = "default"
+
+// Paths locates the socket and token of one device's bridge
+type Paths struct {
+	Socket string
+	Token  string
+}
+
+// PathsFor returns the control paths of a device under ConfigDir()/run
+func PathsFor(device string) Paths {
+	if device == "" {
+		device = DefaultDevice
+	}
+	dir := filepath.Join(config.ConfigDir(), "run")
+	return Paths{
+		Socket: filepath.Join(dir, device+".sock"),
+		Token:  filepath.Join(dir, device+".token"),
+	}
+}
+
+// Status is the overall state of the daemon
+type Status struct {
+	DeviceID    string                 `json:"deviceId"`
+	DeviceName  string                 `json:"deviceName,omitempty"`
+	ServerURL   string                 `json:"serverUrl"`
+	PID         int                    `json:"pid"`
+	StartedAt   time.Time              `json:"startedAt"`
+	Connection  Connection             `json:"connection"`
+	Reconnect   map[string]interface{} `json:"reconnect"`
+	Sessions    map[string]int         `json:"sessions"`
+	Permissions int                    `json:"pendingPermissions"`
+	Queued      int                    `json:"queuedPrompts"`
+	Health      *metrics.HealthReport  `json:"health,omitempty"`
+	LocalUI     string                 `json:"localUi,omitempty"` // web UI URL in local mode, token included
+}
+
+// Connection is the WebSocket state and its recent transitions
+type Connection struct {
+	State   string       `json:"state"`
+	Since   time.Time    `json:"since"`
+	History []Transition `json:"history,omitempty"`
+}
+
+// Transition is one connection state change
+type Transition struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// SessionInfo describes a running session
+type SessionInfo struct {
+	ID             string                 `json:"id"`
+	CLIType        string                 `json:"cliType"`
+	WorkDir        string                 `json:"workDir"`
+	PermissionMode string                 `json:"permissionMode,omitempty"`
+	Status         string                 `json:"status"`
+	Protocol       string                 `json:"protocol"`
+	CreatedAt      time.Time              `json:"createdAt"`
+	LastActiveAt   time.Time              `json:"lastActiveAt"`
+	Controller     *session.Controller    `json:"controller,omitempty"`
+	Busy           bool                   `json:"busy"`
+	Queue          []session.QueuedPrompt `json:"queue,omitempty"`
+	Sandboxed      bool                   `json:"sandboxed,omitempty"`
+	ScheduleRunID  string                 `json:"scheduleRunId,omitempty"`
+}
+
+// PermissionInfo describes a permission request waiting for an answer
+type PermissionInfo struct {
+	ID          string                 `json:"id"`
+	Origin      string                 `json:"origin"` // "acp" or "hook"
+	SessionID   string                 `json:"sessionId,omitempty"`
+	ToolName    string                 `json:"toolName,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Risk        string                 `json:"risk,omitempty"`
+	Input       map[string]interface{} `json:"input,omitempty"`
+	Options     []string               `json:"options,omitempty"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	Deadline    time.Time              `json:"deadline"` // answered by default then
+}
+
+// StartRequest starts a session, optionally with a first prompt
+type StartRequest struct {
+	CLIType        string `json:"cliType"`
+	WorkDir        string `json:"workDir"`
+	PermissionMode string `json:"permissionMode,omitempty"`
+	SessionID      string `json:"sessionId,omitempty"`
+	Prompt         string `json:"prompt,omitempty"`
+	ClientID       string `json:"clientId,omitempty"`
+}
+
+// SendRequest sends a prompt to a session
+type SendRequest struct {
+	Content   string `json:"content"`
+	Interrupt bool   `json:"interrupt,omitempty"` // cancel the current turn and send now
+	ClientID  string `json:"clientId,omitempty"`
+}
+
+// StopRequest stops a session or cancels its current turn
+type StopRequest struct {
+	ClientID string `json:"clientId,omitempty"`
+}
+
+// Decision answers a permission request
+type Decision struct {
+	SessionID string `json:"sessionId,omitempty"` // the request's session; IDs repeat across sessions
+	Approved  bool   `json:"approved"`
+	OptionID  string `json:"optionId,omitempty"` // ACP option; defaults to allow_once / reject_once
+	ClientID  string `json:"clientId,omitempty"`
+	Remember  bool   `json:"remember,omitempty"` // answer later requests of the same kind alike
+}
+
+// SyncedCLI is a CLI definition pushed by the server. It is only used once
+// approved on the device, and only while it stays unchanged.
+type SyncedCLI struct {
+	Name        string            `json:"name"`
+	Definition  clidef.Definition `json:"definition"`
+	Fingerprint string            `json:"fingerprint"`
+	Approved    bool              `json:"approved"`
+	Overridden  bool              `json:"overridden,omitempty"` // the local config defines the same CLI type
+}
+
+// CLIApproval approves a synced CLI definition. Fingerprint must be the one
+// the user was shown, so a definition changed since is not approved.
+type CLIApproval struct {
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Backend is what the daemon exposes through the control API
+type Backend interface {
+	Status() Status
+	Sessions() []SessionInfo
+	Session(id string) (SessionInfo, bool)
+	StartSession(req StartRequest) (SessionInfo, error)
+	SendPrompt(id string, req SendRequest) error
+	StopSession(id string, req StopRequest) error
+	CancelTurn(id string, req StopRequest) error
+	Attach(id string, req AttachRequest) (Attachment, error)
+	Permissions() []PermissionInfo
+	ResolvePermission(id string, d Decision) error
+	Remembered() []permission.Remembered
+	Forget(id string) error
+	SyncedCLIs() []SyncedCLI
+	ApproveCLI(name string, a CLIApproval) error
+}
+
+// Error is a failed request with the HTTP status it is reported with
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s (%s)", e.Message, e.Code)
+	}
+	return e.Message
+}
+
+// NotFound reports a missing session or permission request
+func NotFound(format string, args ...interface{}) *Error {
+	return &Error{Status: http.StatusNotFound, Code: "not_found", Message: fmt.Sprintf(format, args...)}
+}
+
+// BadRequest reports an invalid request
+func BadRequest(format string, args ...interface{}) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: "bad_request", Message: fmt.Sprintf(format, args...)}
+}
+
+// Forbidden reports a request refused by policy or session control
+func Forbidden(code, format string, args ...interface{}) *Error {
+	return &Error{Status: http.StatusForbidden, Code: code, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,316 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/open-agents/bridge/internal/clidef"
+	"github.com/open-agents/bridge/internal/permission"
+)
+
+type fakeBackend struct {
+	sessions  map[string]SessionInfo
+	sent      []SendRequest
+	cancelled []string
+	attached  *fakeAttachment
+	resolved  map[string]Decision
+	forgotten []string
+	approved  []string
+}
+
+func (f *fakeBackend) Status() Status {
+	return Status{DeviceID: "dev-1", Connection: Connection{State: "connected"}}
+}
+
+func (f *fakeBackend) Sessions() []SessionInfo {
+	var list []SessionInfo
+	for _, s := range f.sessions {
+		list = append(list, s)
+	}
+	return list
+}
+
+func (f *fakeBackend) Session(id string) (SessionInfo, bool) {
+	s, ok := f.sessions[id]
+	return s, ok
+}
+
+func (f *fakeBackend) StartSession(req StartRequest) (SessionInfo, error) {
+	if req.CLIType == "" {
+		return SessionInfo{}, BadRequest("cliType is required")
+	}
+	info := SessionInfo{ID: "s2", CLIType: req.CLIType, WorkDir: req.WorkDir}
+	f.sessions[info.ID] = info
+	return info, nil
+}
+
+func (f *fakeBackend) SendPrompt(id string, req SendRequest) error {
+	if _, ok := f.sessions[id]; !ok {
+		return NotFound("session %s not found", id)
+	}
+	f.sent = append(f.sent, req)
+	return nil
+}
+
+func (f *fakeBackend) StopSession(id string, req StopRequest) error {
+	if _, ok := f.sessions[id]; !ok {
+		return NotFound("session %s not found", id)
+	}
+	delete(f.sessions, id)
+	return nil
+}
+
+func (f *fakeBackend) CancelTurn(id string, req StopRequest) error {
+	if _, ok := f.sessions[id]; !ok {
+		return NotFound("session %s not found", id)
+	}
+	f.cancelled = append(f.cancelled, id)
+	return nil
+}
+
+func (f *fakeBackend) Attach(id string, req AttachRequest) (Attachment, error) {
+	if _, ok := f.sessions[id]; !ok {
+		return nil, NotFound("session %s not found", id)
+	}
+	f.attached = &fakeAttachment{events: make(chan Event, 8), input: make(chan string, 8)}
+	f.attached.events <- Event{Type: EventAttached, Payload: map[string]interface{}{"clientId": req.ClientID}}
+	return f.attached, nil
+}
+
+type fakeAttachment struct {
+	events chan Event
+	input  chan string
+	once   sync.Once
+}
+
+func (a *fakeAttachment) Events() <-chan Event { return a.events }
+
+func (a *fakeAttachment) Input(data []byte) error {
+	if string(data) == "forbidden" {
+		return Forbidden("not_controller", "read-only")
+	}
+	a.input <- string(data)
+	return nil
+}
+
+func (a *fakeAttachment) Resize(cols, rows int) error {
+	a.input <- fmt.Sprintf("%dx%d", cols, rows)
+	return nil
+}
+
+func (a *fakeAttachment) Close() { a.once.Do(func() { close(a.events) }) }
+
+func (f *fakeBackend) Permissions() []PermissionInfo {
+	return []PermissionInfo{{ID: "7", Origin: "acp", SessionID: "s1", ToolName: "bash"}}
+}
+
+func (f *fakeBackend) ResolvePermission(id string, d Decision) error {
+	f.resolved[id] = d
+	return nil
+}
+
+func (f *fakeBackend) Remembered() []permission.Remembered {
+	return []permission.Remembered{{ID: "r1", Key: permission.Key{Tool: "execute_bash", Input: "npm test"}, Approved: true}}
+}
+
+func (f *fakeBackend) Forget(id string) error {
+	if id != "r1" {
+		return NotFound("no remembered decision %s", id)
+	}
+	f.forgotten = append(f.forgotten, id)
+	return nil
+}
+
+func (f *fakeBackend) SyncedCLIs() []SyncedCLI {
+	return []SyncedCLI{{Name: "opencode", Definition: clidef.Definition{Command: "opencode"}, Fingerprint: "abc"}}
+}
+
+func (f *fakeBackend) ApproveCLI(name string, a CLIApproval) error {
+	if name != "opencode" || a.Fingerprint != "abc" {
+		return Forbidden("fingerprint_mismatch", "definition of %s changed", name)
+	}
+	f.approved = append(f.approved, name)
+	return nil
+}
+
+func startServer(t *testing.T) (*fakeBackend, Paths) {
+	t.Helper()
+	dir := t.TempDir()
+	paths := Paths{Socket: filepath.Join(dir, "dev.sock"), Token: filepath.Join(dir, "dev.token")}
+	backend := &fakeBackend{
+		sessions: map[string]SessionInfo{"s1": {ID: "s1", CLIType: "claude"}},
+		resolved: map[string]Decision{},
+	}
+	srv := NewServer(paths, backend)
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	return backend, paths
+}
+
+func TestClientServer(t *testing.T) {
+	backend, paths := startServer(t)
+	c, err := Dial(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := c.Status()
+	if err != nil || st.DeviceID != "dev-1" || st.Connection.State != "connected" {
+		t.Fatalf("status = %+v, %v", st, err)
+	}
+
+	info, err := c.StartSession(StartRequest{CLIType: "goose", WorkDir: "/tmp"})
+	if err != nil || info.ID != "s2" {
+		t.Fatalf("start = %+v, %v", info, err)
+	}
+	if list, err := c.Sessions(); err != nil || len(list) != 2 {
+		t.Fatalf("sessions = %v, %v", list, err)
+	}
+
+	if err := c.Send("s1", SendRequest{Content: "hi", Interrupt: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.sent) != 1 || backend.sent[0].Content != "hi" || !backend.sent[0].Interrupt {
+		t.Fatalf("sent = %+v", backend.sent)
+	}
+
+	if err := c.Cancel("s1", StopRequest{}); err != nil || len(backend.cancelled) != 1 {
+		t.Fatalf("cancel: %v, cancelled = %v", err, backend.cancelled)
+	}
+
+	if err := c.Resolve("7", Decision{Approved: true}); err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := backend.resolved["7"]; !ok || !d.Approved {
+		t.Fatalf("resolved = %+v", backend.resolved)
+	}
+
+	if list, err := c.Remembered(); err != nil || len(list) != 1 || list[0].Input != "npm test" {
+		t.Fatalf("remembered = %+v, %v", list, err)
+	}
+	if err := c.Forget("r1"); err != nil || len(backend.forgotten) != 1 {
+		t.Fatalf("forget: %v, forgotten = %v", err, backend.forgotten)
+	}
+	if err := c.Forget("nope"); !errors.As(err, new(*Error)) {
+		t.Fatalf("forget unknown: %v", err)
+	}
+
+	if list, err := c.SyncedCLIs(); err != nil || len(list) != 1 || list[0].Definition.Command != "opencode" {
+		t.Fatalf("synced clis = %+v, %v", list, err)
+	}
+	if err := c.ApproveCLI("opencode", CLIApproval{Fingerprint: "stale"}); !errors.As(err, new(*Error)) {
+		t.Fatalf("approve with a stale fingerprint: %v", err)
+	}
+	if err := c.ApproveCLI("opencode", CLIApproval{Fingerprint: "abc"}); err != nil || len(backend.approved) != 1 {
+		t.Fatalf("approve: %v, approved = %v", err, backend.approved)
+	}
+
+	if err := c.Stop("s1", StopRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Session("s1")
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusNotFound {
+		t.Fatalf("stopped session lookup: %v", err)
+	}
+	if _, err := c.StartSession(StartRequest{}); !errors.As(err, &e) || e.Status != http.StatusBadRequest {
+		t.Fatalf("invalid start: %v", err)
+	}
+}
+
+func TestAttach(t *testing.T) {
+	backend, paths := startServer(t)
+	c, err := Dial(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var e *Error
+	if _, err := c.Attach("missing", AttachRequest{}); !errors.As(err, &e) || e.Status != http.StatusNotFound {
+		t.Fatalf("attach to missing session: %v", err)
+	}
+
+	a, err := c.Attach("s1", AttachRequest{ClientID: "term-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if ev, err := a.Next(); err != nil || ev.Type != EventAttached || ev.Payload["clientId"] != "term-1" {
+		t.Fatalf("first event = %+v, %v", ev, err)
+	}
+
+	backend.attached.events <- Event{Type: "chat:response", Payload: map[string]interface{}{"content": "hello"}}
+	if ev, err := a.Next(); err != nil || ev.Payload["content"] != "hello" {
+		t.Fatalf("output event = %+v, %v", ev, err)
+	}
+
+	a.Input([]byte("ls\r\x1b[A"))
+	a.Resize(100, 40)
+	for _, want := range []string{"ls\r\x1b[A", "100x40"} {
+		select {
+		case got := <-backend.attached.input:
+			if got != want {
+				t.Errorf("input = %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no %q from the terminal", want)
+		}
+	}
+
+	a.Input([]byte("forbidden"))
+	if ev, err := a.Next(); err != nil || ev.Type != EventError || ev.Payload["code"] != "not_controller" {
+		t.Fatalf("refused input = %+v, %v", ev, err)
+	}
+
+	backend.attached.Close()
+	if _, err := a.Next(); err == nil {
+		t.Fatal("stream still open after the session ended")
+	}
+}
+
+func TestRejectsWrongToken(t *testing.T) {
+	_, paths := startServer(t)
+	c, err := Dial(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.token = "guess"
+	_, err = c.Status()
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusUnauthorized {
+		t.Fatalf("wrong token: %v", err)
+	}
+}
+
+func TestTokenFileIsPrivate(t *testing.T) {
+	_, paths := startServer(t)
+	fi, err := os.Stat(paths.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		t.Errorf("token mode = %v", fi.Mode().Perm())
+	}
+}
+
+func TestNotRunning(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Dial(Paths{Socket: filepath.Join(dir, "x.sock"), Token: filepath.Join(dir, "x.token")}); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("err = %v, want ErrNotRunning", err)
+	}
+}
+
+func TestSecondServerRefused(t *testing.T) {
+	_, paths := startServer(t)
+	if err := NewServer(paths, &fakeBackend{}).Start(); err == nil {
+		t.Fatal("second server started on a live socket")
+	}
+}
@@ -0,0 +1,283 @@
+package control
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Server serves the control API of a backend on a unix socket
+type Server struct {
+	paths   Paths
+	backend Backend
+	token   string
+	http    *http.Server
+}
+
+// NewServer creates a control server for backend
+func NewServer(paths Paths, backend Backend) *Server {
+	return &Server{paths: paths, backend: backend}
+}
+
+// Start writes a fresh token and listens on the socket. It fails if another
+// bridge is already serving the same device.
+func (s *Server) Start() error {
+	if err := os.MkdirAll(filepath.Dir(s.paths.Socket), 0700); err != nil {
+		return err
+	}
+
+	// A socket left behind by a crashed bridge is removed; a live one means
+	// the device is already running
+	if _, err := os.Stat(s.paths.Socket); err == nil {
+		if conn, err := net.DialTimeout("unix", s.paths.Socket, time.Second); err == nil {
+			conn.Close()
+			return fmt.Errorf("a bridge is already running for this device (%s)", s.paths.Socket)
+		}
+		os.Remove(s.paths.Socket)
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.paths.Token, []byte(token+"\n"), 0600); err != nil {
+		return fmt.Errorf("write control token: %w", err)
+	}
+	s.token = token
+
+	ln, err := net.Listen("unix", s.paths.Socket)
+	if err != nil {
+		return err
+	}
+	os.Chmod(s.paths.Socket, 0600)
+
+	s.http = &http.Server{Handler: s.routes(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := s.http.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[Control] Server stopped: %v", err)
+		}
+	}()
+	log.Printf("[Control] Listening on %s", s.paths.Socket)
+	return nil
+}
+
+// Stop closes the socket and removes it and the token
+func (s *Server) Stop() {
+	if s.http != nil {
+		s.http.Close()
+	}
+	os.Remove(s.paths.Socket)
+	os.Remove(s.paths.Token)
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/status", s.handleStatus)
+	mux.HandleFunc("/v1/sessions", s.handleSessions)
+	mux.HandleFunc("/v1/sessions/", s.handleSession)
+	mux.HandleFunc("/v1/permissions", s.handlePermissions)
+	mux.HandleFunc("/v1/permissions/", s.handlePermission)
+	mux.HandleFunc("/v1/remembered", s.handleRemembered)
+	mux.HandleFunc("/v1/remembered/", s.handleForget)
+	mux.HandleFunc("/v1/clis/synced", s.handleSyncedCLIs)
+	mux.HandleFunc("/v1/clis/synced/", s.handleApproveCLI)
+	return s.authenticate(mux)
+}
+
+// authenticate requires the token from the token file as a bearer token
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			writeError(w, &Error{Status: http.StatusUnauthorized, Code: "unauthorized", Message: "invalid control token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.backend.Status())
+}
+
+// handleSessions lists sessions (GET) or starts one (POST)
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.backend.Sessions())
+	case http.MethodPost:
+		var req StartRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		info, err := s.backend.StartSession(req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, info)
+	default:
+		allow(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+// handleSession serves /v1/sessions/<id>[/send|/stop|/cancel|/attach]
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/sessions/"), "/")
+	if id == "" {
+		writeError(w, NotFound("no session given"))
+		return
+	}
+
+	switch action {
+	case "":
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		info, ok := s.backend.Session(id)
+		if !ok {
+			writeError(w, NotFound("session %s not found", id))
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+	case "send":
+		var req SendRequest
+		if !allow(w, r, http.MethodPost) || !decode(w, r, &req) {
+			return
+		}
+		respond(w, s.backend.SendPrompt(id, req))
+	case "stop":
+		var req StopRequest
+		if !allow(w, r, http.MethodPost) || !decode(w, r, &req) {
+			return
+		}
+		respond(w, s.backend.StopSession(id, req))
+	case "cancel":
+		var req StopRequest
+		if !allow(w, r, http.MethodPost) || !decode(w, r, &req) {
+			return
+		}
+		respond(w, s.backend.CancelTurn(id, req))
+	case "attach":
+		s.handleAttach(w, r, id)
+	default:
+		writeError(w, NotFound("unknown session action %q", action))
+	}
+}
+
+func (s *Server) handlePermissions(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.backend.Permissions())
+}
+
+// handlePermission answers /v1/permissions/<id>
+func (s *Server) handlePermission(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/permissions/")
+	var d Decision
+	if !allow(w, r, http.MethodPost) || !decode(w, r, &d) {
+		return
+	}
+	respond(w, s.backend.ResolvePermission(id, d))
+}
+
+func (s *Server) handleRemembered(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.backend.Remembered())
+}
+
+// handleForget revokes /v1/remembered/<id>
+func (s *Server) handleForget(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodDelete) {
+		return
+	}
+	respond(w, s.backend.Forget(strings.TrimPrefix(r.URL.Path, "/v1/remembered/")))
+}
+
+func (s *Server) handleSyncedCLIs(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.backend.SyncedCLIs())
+}
+
+// handleApproveCLI approves /v1/clis/synced/<name>/approve
+func (s *Server) handleApproveCLI(w http.ResponseWriter, r *http.Request) {
+	name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/clis/synced/"), "/")
+	if name == "" || action != "approve" {
+		writeError(w, NotFound("unknown CLI action"))
+		return
+	}
+	var a CLIApproval
+	if !allow(w, r, http.MethodPost) || !decode(w, r, &a) {
+		return
+	}
+	respond(w, s.backend.ApproveCLI(name, a))
+}
+
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, &Error{Status: http.StatusMethodNotAllowed, Code: "method_not_allowed", Message: r.Method + " not allowed"})
+	return false
+}
+
+// decode reads a JSON body; an empty body leaves v unchanged
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, BadRequest("invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+func respond(w http.ResponseWriter, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Status: http.StatusInternalServerError, Message: err.Error()}
+	}
+	writeJSON(w, e.Status, e)
+}
@@ -0,0 +1,165 @@
+package limits
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits describes resource limits applied to an agent CLI process and
+// every command it runs through the bridge. Zero values mean "no limit".
+type Limits struct {
+	MemoryMB          int `json:"memoryMb,omitempty"`          // memory ceiling for the whole session
+	CPUPercent        int `json:"cpuPercent,omitempty"`        // 100 = one full core
+	MaxProcs          int `json:"maxProcs,omitempty"`          // max processes/threads
+	MaxOpenFiles      int `json:"maxOpenFiles,omitempty"`      // max open file descriptors per process
+	CommandTimeoutSec int `json:"commandTimeoutSec,omitempty"` // wall-clock limit for terminal commands
+}
+
+// IsZero reports whether no limit is set
+func (l *Limits) IsZero() bool {
+	return l == nil || (l.MemoryMB == 0 && l.CPUPercent == 0 && l.MaxProcs == 0 &&
+		l.MaxOpenFiles == 0 && l.CommandTimeoutSec == 0)
+}
+
+// CommandTimeout returns the wall-clock limit for terminal commands (0 = none)
+func (l *Limits) CommandTimeout() time.Duration {
+	if l == nil {
+		return 0
+	}
+	return time.Duration(l.CommandTimeoutSec) * time.Second
+}
+
+// String returns a compact description for logs
+func (l *Limits) String() string {
+	if l.IsZero() {
+		return "none"
+	}
+	var parts []string
+	if l.MemoryMB > 0 {
+		parts = append(parts, fmt.Sprintf("mem=%dMB", l.MemoryMB))
+	}
+	if l.CPUPercent > 0 {
+		parts = append(parts, fmt.Sprintf("cpu=%d%%", l.CPUPercent))
+	}
+	if l.MaxProcs > 0 {
+		parts = append(parts, fmt.Sprintf("procs=%d", l.MaxProcs))
+	}
+	if l.MaxOpenFiles > 0 {
+		parts = append(parts, fmt.Sprintf("files=%d", l.MaxOpenFiles))
+	}
+	if l.CommandTimeoutSec > 0 {
+		parts = append(parts, fmt.Sprintf("cmdTimeout=%ds", l.CommandTimeoutSec))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Merge layers limits: later non-zero fields override earlier ones.
+// Returns nil if the result has no limits.
+func Merge(layers ...*Limits) *Limits {
+	out := &Limits{}
+	for _, l := range layers {
+		if l == nil {
+			continue
+		}
+		if l.MemoryMB != 0 {
+			out.MemoryMB = l.MemoryMB
+		}
+		if l.CPUPercent != 0 {
+			out.CPUPercent = l.CPUPercent
+		}
+		if l.MaxProcs != 0 {
+			out.MaxProcs = l.MaxProcs
+		}
+		if l.MaxOpenFiles != 0 {
+			out.MaxOpenFiles = l.MaxOpenFiles
+		}
+		if l.CommandTimeoutSec != 0 {
+			out.CommandTimeoutSec = l.CommandTimeoutSec
+		}
+	}
+	if out.IsZero() {
+		return nil
+	}
+	return out
+}
+
+// Narrow applies a session's requested limits on top of the configured
+// ones. A session may only tighten them: each requested field is capped at
+// the configured value, and only adds a limit where none is configured.
+// Returns nil if the result has no limits.
+func Narrow(configured, requested *Limits) *Limits {
+	out := &Limits{}
+	if configured != nil {
+		*out = *configured
+	}
+	if requested != nil {
+		out.MemoryMB = narrower(out.MemoryMB, requested.MemoryMB)
+		out.CPUPercent = narrower(out.CPUPercent, requested.CPUPercent)
+		out.MaxProcs = narrower(out.MaxProcs, requested.MaxProcs)
+		out.MaxOpenFiles = narrower(out.MaxOpenFiles, requested.MaxOpenFiles)
+		out.CommandTimeoutSec = narrower(out.CommandTimeoutSec, requested.CommandTimeoutSec)
+	}
+	if out.IsZero() {
+		return nil
+	}
+	return out
+}
+
+// narrower returns the stricter of two limits, where zero means no limit
+func narrower(configured, requested int) int {
+	if requested <= 0 || (configured > 0 && requested > configured) {
+		return configured
+	}
+	return requested
+}
+
+// Enforcement modes
+const (
+	ModeNone   = "none"
+	ModeCgroup = "cgroup"
+	ModeRlimit = "rlimit"
+)
+
+// Group applies one session's limits to all processes added to it.
+// On Linux it uses a cgroup v2 subtree when one can be delegated to the
+// bridge, and per-process rlimits otherwise.
+type Group struct {
+	name   string
+	limits Limits
+	mode   string
+	path   string // cgroup directory (cgroup mode only)
+
+	// last seen event counters, used to report new limit hits only once
+	mu        sync.Mutex
+	oomKills  int
+	pidsMaxed int
+}
+
+// Limits returns the limits this group enforces
+func (g *Group) Limits() *Limits {
+	if g == nil {
+		return nil
+	}
+	l := g.limits
+	return &l
+}
+
+// Mode returns how the limits are enforced (cgroup, rlimit or none)
+func (g *Group) Mode() string {
+	if g == nil {
+		return ModeNone
+	}
+	return g.mode
+}
+
+// LimitError is reported when a process was stopped by a resource limit
+type LimitError struct {
+	Limit  string // memory, pids, cpu, wall_clock
+	Detail string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit exceeded: %s", e.Limit, e.Detail)
+}
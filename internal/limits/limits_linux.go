@@ -0,0 +1,299 @@
+//go:build linux
+
+package limits
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/open-agents/bridge/internal/logger"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// rlimit resource numbers missing from the syscall package
+const rlimitNproc = 6
+
+// clone3 is what starts a process directly in a cgroup (Linux 5.7+)
+const sysClone3 = 435
+
+var (
+	delegateOnce sync.Once
+	delegateDir  string // cgroup under which session groups are created ("" = unavailable)
+)
+
+// NewGroup prepares a limit group for a session
+func NewGroup(name string, l *Limits) (*Group, error) {
+	if l.IsZero() {
+		return &Group{name: name, mode: ModeNone}, nil
+	}
+	g := &Group{name: name, limits: *l, mode: ModeRlimit}
+
+	if needsCgroup(l) {
+		if parent := cgroupDelegate(); parent != "" && !clone3Supported() {
+			logger.Warn("[Limits] Kernel cannot start processes in a cgroup, using rlimits for %s", name)
+		} else if parent != "" {
+			path := filepath.Join(parent, "session-"+sanitize(name))
+			if err := g.setupCgroup(path); err != nil {
+				logger.Warn("[Limits] cgroup setup failed for %s, using rlimits: %v", name, err)
+			} else {
+				g.mode = ModeCgroup
+				g.path = path
+			}
+		}
+	}
+
+	logger.Info("[Limits] Group %s: %s (mode: %s)", name, l.String(), g.mode)
+	return g, nil
+}
+
+// Prepare makes cmd start inside the group's cgroup, so that nothing it
+// forks can run outside the limits. The returned function is called once
+// cmd has been started.
+func (g *Group) Prepare(cmd *exec.Cmd) (func(), error) {
+	if g == nil || g.mode != ModeCgroup {
+		return func() {}, nil
+	}
+	dir, err := os.Open(g.path)
+	if err != nil {
+		return nil, fmt.Errorf("open cgroup: %w", err)
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(dir.Fd())
+	return func() { dir.Close() }, nil
+}
+
+// Add applies the per-process limits to a process started after Prepare.
+// Under rlimits it should be held (as the sandbox does) until Add returns.
+func (g *Group) Add(pid int) error {
+	if g == nil || g.mode == ModeNone || pid <= 0 {
+		return nil
+	}
+
+	// Open file limits are per-process in both modes
+	if g.limits.MaxOpenFiles > 0 {
+		if err := prlimit(pid, syscall.RLIMIT_NOFILE, uint64(g.limits.MaxOpenFiles)); err != nil {
+			return fmt.Errorf("RLIMIT_NOFILE on pid %d: %w", pid, err)
+		}
+	}
+
+	if g.mode == ModeCgroup {
+		// Prepare already started it in the cgroup
+		return nil
+	}
+
+	// rlimit fallback: approximations of the cgroup limits
+	if g.limits.MemoryMB > 0 {
+		// RLIMIT_DATA covers heap and private mappings without counting reserved address space
+		if err := prlimit(pid, syscall.RLIMIT_DATA, uint64(g.limits.MemoryMB)*1024*1024); err != nil {
+			return fmt.Errorf("RLIMIT_DATA on pid %d: %w", pid, err)
+		}
+	}
+	if g.limits.MaxProcs > 0 {
+		// Note: RLIMIT_NPROC counts all processes of the user, not just this session
+		if err := prlimit(pid, rlimitNproc, uint64(g.limits.MaxProcs)); err != nil {
+			return fmt.Errorf("RLIMIT_NPROC on pid %d: %w", pid, err)
+		}
+	}
+	if g.limits.CPUPercent > 0 && g.limits.CPUPercent < 100 {
+		// No CPU share without cgroups; lower the scheduling priority instead
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, 10); err != nil {
+			return fmt.Errorf("setpriority on pid %d: %w", pid, err)
+		}
+	}
+	return nil
+}
+
+// Exceeded reports a limit hit that happened since the last call, or nil
+func (g *Group) Exceeded() *LimitError {
+	if g == nil || g.mode != ModeCgroup {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if n := readEventCounter(filepath.Join(g.path, "memory.events"), "oom_kill"); n > g.oomKills {
+		g.oomKills = n
+		return &LimitError{Limit: "memory", Detail: fmt.Sprintf("process killed after exceeding %d MB", g.limits.MemoryMB)}
+	}
+	if n := readEventCounter(filepath.Join(g.path, "pids.events"), "max"); n > g.pidsMaxed {
+		g.pidsMaxed = n
+		return &LimitError{Limit: "pids", Detail: fmt.Sprintf("process limit of %d reached", g.limits.MaxProcs)}
+	}
+	return nil
+}
+
+// ExitReason reports the limit that killed a process under rlimits, which
+// leave no event counters for Exceeded to read, going by the signal it died
+// of: SIGXCPU is sent for the CPU time limit, and a SIGKILL with a memory
+// limit set is taken as the memory limit. Callers rule out kills of their
+// own (stop, wall-clock timeout) first. Returns nil in cgroup mode.
+func (g *Group) ExitReason(state *os.ProcessState) *LimitError {
+	if g == nil || g.mode != ModeRlimit || state == nil {
+		return nil
+	}
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return nil
+	}
+	switch {
+	case ws.Signal() == syscall.SIGXCPU:
+		return &LimitError{Limit: "cpu", Detail: "process killed after using up its CPU time"}
+	case ws.Signal() == syscall.SIGKILL && g.limits.MemoryMB > 0:
+		return &LimitError{Limit: "memory", Detail: fmt.Sprintf("process killed with a %d MB memory limit set", g.limits.MemoryMB)}
+	}
+	return nil
+}
+
+// Close kills anything left in the group and removes the cgroup
+func (g *Group) Close() {
+	if g == nil || g.mode != ModeCgroup {
+		return
+	}
+	// cgroup.kill exists since Linux 5.14; older kernels just leave stragglers
+	os.WriteFile(filepath.Join(g.path, "cgroup.kill"), []byte("1"), 0644)
+	if err := os.Remove(g.path); err != nil {
+		logger.Debug("[Limits] Failed to remove cgroup %s: %v", g.path, err)
+	}
+}
+
+func needsCgroup(l *Limits) bool {
+	return l.MemoryMB > 0 || l.CPUPercent > 0 || l.MaxProcs > 0
+}
+
+func (g *Group) setupCgroup(path string) error {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	if g.limits.MemoryMB > 0 {
+		bytes := strconv.FormatInt(int64(g.limits.MemoryMB)*1024*1024, 10)
+		if err := os.WriteFile(filepath.Join(path, "memory.max"), []byte(bytes), 0644); err != nil {
+			os.Remove(path)
+			return fmt.Errorf("memory.max: %w", err)
+		}
+		// Without swap limit the memory ceiling is easy to sidestep
+		os.WriteFile(filepath.Join(path, "memory.swap.max"), []byte("0"), 0644)
+	}
+	if g.limits.CPUPercent > 0 {
+		const period = 100000
+		quota := period * g.limits.CPUPercent / 100
+		if err := os.WriteFile(filepath.Join(path, "cpu.max"), []byte(fmt.Sprintf("%d %d", quota, period)), 0644); err != nil {
+			os.Remove(path)
+			return fmt.Errorf("cpu.max: %w", err)
+		}
+	}
+	if g.limits.MaxProcs > 0 {
+		if err := os.WriteFile(filepath.Join(path, "pids.max"), []byte(strconv.Itoa(g.limits.MaxProcs)), 0644); err != nil {
+			os.Remove(path)
+			return fmt.Errorf("pids.max: %w", err)
+		}
+	}
+	return nil
+}
+
+// cgroupDelegate finds (once) a cgroup v2 directory where the bridge may
+// create session groups with the memory, cpu and pids controllers enabled.
+func cgroupDelegate() string {
+	delegateOnce.Do(func() {
+		if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+			logger.Info("[Limits] cgroup v2 not available, using rlimits")
+			return
+		}
+		own, err := ownCgroup()
+		if err != nil {
+			logger.Info("[Limits] Cannot determine own cgroup: %v", err)
+			return
+		}
+		dir := filepath.Join(cgroupRoot, own)
+		if err := enableControllers(dir); err != nil {
+			// "No internal processes": move the bridge into a leaf first
+			leaf := filepath.Join(dir, "bridge")
+			if mkErr := os.MkdirAll(leaf, 0755); mkErr != nil {
+				logger.Info("[Limits] cgroup %s not delegated to us: %v", dir, mkErr)
+				return
+			}
+			if mvErr := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0644); mvErr != nil {
+				logger.Info("[Limits] Cannot move bridge into %s: %v", leaf, mvErr)
+				return
+			}
+			if err := enableControllers(dir); err != nil {
+				logger.Info("[Limits] Cannot enable cgroup controllers in %s: %v", dir, err)
+				return
+			}
+		}
+		delegateDir = dir
+		logger.Info("[Limits] Using cgroup v2 delegate %s", dir)
+	})
+	return delegateDir
+}
+
+// clone3Supported probes for clone3 with an empty argument, which the
+// kernel rejects with EINVAL if it has the call and ENOSYS if it doesn't
+// (or a seccomp filter hides it)
+func clone3Supported() bool {
+	_, _, errno := syscall.RawSyscall(sysClone3, 0, 0, 0)
+	return errno != syscall.ENOSYS
+}
+
+func enableControllers(dir string) error {
+	return os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+memory +cpu +pids"), 0644)
+}
+
+func ownCgroup() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "0::") {
+			return strings.TrimPrefix(scanner.Text(), "0::"), nil
+		}
+	}
+	return "", fmt.Errorf("no cgroup v2 entry")
+}
+
+func readEventCounter(path, key string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			n, _ := strconv.Atoi(fields[1])
+			return n
+		}
+	}
+	return 0
+}
+
+func prlimit(pid int, resource int, value uint64) error {
+	rlim := syscall.Rlimit{Cur: value, Max: value}
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource),
+		uintptr(unsafe.Pointer(&rlim)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '.' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,45 @@
+//go:build !linux
+
+package limits
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/open-agents/bridge/internal/logger"
+)
+
+// NewGroup prepares a limit group for a session.
+// Only the wall-clock command timeout is enforced outside Linux.
+func NewGroup(name string, l *Limits) (*Group, error) {
+	if l.IsZero() {
+		return &Group{name: name, mode: ModeNone}, nil
+	}
+	if l.MemoryMB > 0 || l.CPUPercent > 0 || l.MaxProcs > 0 || l.MaxOpenFiles > 0 {
+		logger.Warn("[Limits] Process resource limits are only supported on Linux; only command timeouts apply")
+	}
+	return &Group{name: name, limits: *l, mode: ModeNone}, nil
+}
+
+// Prepare is a no-op outside Linux
+func (g *Group) Prepare(cmd *exec.Cmd) (func(), error) {
+	return func() {}, nil
+}
+
+// Add is a no-op outside Linux
+func (g *Group) Add(pid int) error {
+	return nil
+}
+
+// Exceeded always returns nil outside Linux
+func (g *Group) Exceeded() *LimitError {
+	return nil
+}
+
+// ExitReason always returns nil outside Linux
+func (g *Group) ExitReason(state *os.ProcessState) *LimitError {
+	return nil
+}
+
+// Close is a no-op outside Linux
+func (g *Group) Close() {}
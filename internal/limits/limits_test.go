@@ -0,0 +1,173 @@
+package limits
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	global := &Limits{MemoryMB: 4096, MaxProcs: 512, CommandTimeoutSec: 600}
+	perCLI := &Limits{MemoryMB: 2048}
+	session := &Limits{CommandTimeoutSec: 30}
+
+	got := Merge(global, perCLI, session)
+	want := Limits{MemoryMB: 2048, MaxProcs: 512, CommandTimeoutSec: 30}
+	if got == nil || *got != want {
+		t.Errorf("Merge = %+v, want %+v", got, want)
+	}
+	if got.CommandTimeout() != 30*time.Second {
+		t.Errorf("CommandTimeout = %v, want 30s", got.CommandTimeout())
+	}
+
+	if Merge(nil, &Limits{}) != nil {
+		t.Error("Merge of empty layers should be nil")
+	}
+}
+
+func TestNarrow(t *testing.T) {
+	configured := &Limits{MemoryMB: 2048, MaxProcs: 512, CommandTimeoutSec: 600}
+	requested := &Limits{MemoryMB: 8192, MaxProcs: 64, CPUPercent: 50, CommandTimeoutSec: 3600}
+
+	got := Narrow(configured, requested)
+	want := Limits{MemoryMB: 2048, MaxProcs: 64, CPUPercent: 50, CommandTimeoutSec: 600}
+	if got == nil || *got != want {
+		t.Errorf("Narrow = %+v, want %+v", got, want)
+	}
+
+	if got := Narrow(configured, nil); got == nil || *got != *configured {
+		t.Errorf("Narrow without a request = %+v, want %+v", got, configured)
+	}
+	if got := Narrow(nil, &Limits{MemoryMB: 1024}); got == nil || got.MemoryMB != 1024 {
+		t.Errorf("Narrow with nothing configured = %+v, want the requested limit", got)
+	}
+	if Narrow(nil, &Limits{MemoryMB: -1}) != nil {
+		t.Error("a negative request should not add a limit")
+	}
+}
+
+func TestExitReason(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("rlimits are only applied on Linux")
+	}
+	g := &Group{name: "test", limits: Limits{MemoryMB: 256}, mode: ModeRlimit}
+	run := func(script string) *os.ProcessState {
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Run()
+		return cmd.ProcessState
+	}
+
+	if le := g.ExitReason(run("kill -XCPU $$")); le == nil || le.Limit != "cpu" {
+		t.Errorf("SIGXCPU exit = %v, want a cpu limit", le)
+	}
+	if le := g.ExitReason(run("kill -KILL $$")); le == nil || le.Limit != "memory" {
+		t.Errorf("SIGKILL exit with a memory limit = %v, want a memory limit", le)
+	}
+	if le := g.ExitReason(run("exit 3")); le != nil {
+		t.Errorf("plain exit = %v, want nil", le)
+	}
+
+	noMem := &Group{name: "test", limits: Limits{MaxProcs: 64}, mode: ModeRlimit}
+	if le := noMem.ExitReason(run("kill -KILL $$")); le != nil {
+		t.Errorf("SIGKILL exit without a memory limit = %v, want nil", le)
+	}
+	cgroup := &Group{name: "test", limits: Limits{MemoryMB: 256}, mode: ModeCgroup}
+	if le := cgroup.ExitReason(run("kill -KILL $$")); le != nil {
+		t.Errorf("cgroup mode = %v, want nil (event counters report its hits)", le)
+	}
+}
+
+func TestNilGroupIsNoop(t *testing.T) {
+	var g *Group
+	if err := g.Add(os.Getpid()); err != nil {
+		t.Errorf("nil group Add returned %v", err)
+	}
+	if g.Exceeded() != nil || g.ExitReason(nil) != nil {
+		t.Error("nil group should never report limits")
+	}
+	if g.Limits().CommandTimeout() != 0 {
+		t.Error("nil group should have no command timeout")
+	}
+	g.Close()
+}
+
+func TestOpenFilesLimitApplied(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("rlimits are only applied on Linux")
+	}
+
+	cmd := exec.Command("sleep", "5")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	defer cmd.Process.Kill()
+
+	g, err := NewGroup("test-nofile", &Limits{MaxOpenFiles: 123})
+	if err != nil {
+		t.Fatalf("NewGroup failed: %v", err)
+	}
+	defer g.Close()
+
+	if err := g.Add(cmd.Process.Pid); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(cmd.Process.Pid) + "/limits")
+	if err != nil {
+		t.Fatalf("read limits: %v", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "Max open files") {
+			if fields := strings.Fields(line); len(fields) < 5 || fields[3] != "123" {
+				t.Errorf("unexpected open files limit: %q", line)
+			}
+			return
+		}
+	}
+	t.Error("Max open files not found in /proc limits")
+}
+
+func TestPrepareStartsInCgroup(t *testing.T) {
+	g, err := NewGroup("test-prepare", &Limits{MaxProcs: 64})
+	if err != nil {
+		t.Fatalf("NewGroup failed: %v", err)
+	}
+	defer g.Close()
+	if g.Mode() != ModeCgroup {
+		t.Skip("no delegated cgroup v2 subtree")
+	}
+
+	cmd := exec.Command("cat", "/proc/self/cgroup")
+	started, err := g.Prepare(cmd)
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	out, err := cmd.Output()
+	started()
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	// The process must be in the group from the start, not moved there later
+	if !strings.Contains(string(out), "session-test-prepare") {
+		t.Errorf("started in %q", out)
+	}
+}
+
+func TestAddReportsFailure(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("rlimits are only applied on Linux")
+	}
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot run true: %v", err)
+	}
+	// The process is gone, so no limit can be applied to it
+	g := &Group{name: "test", limits: Limits{MemoryMB: 256}, mode: ModeRlimit}
+	if err := g.Add(cmd.Process.Pid); err == nil {
+		t.Error("Add to an exited process succeeded")
+	}
+}
@@ -0,0 +1,245 @@
+// Package localui serves a minimal web UI for the bridge on localhost. The
+// UI speaks the same WebSocket message protocol as the cloud service, so the
+// bridge can be used with no server at all.
+package localui
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+//go:embed static
+var static embed.FS
+
+// DefaultAddr is where the UI listens unless told otherwise
+const DefaultAddr = "127.0.0.1:7420"
+
+const (
+	// clientBuffer is how many messages a browser may lag behind before it
+	// is disconnected; it reloads the state when it reconnects
+	clientBuffer = 1024
+	pingInterval = 30 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+// Handler is the bridge side of the UI
+type Handler interface {
+	// HandleMessage receives a message sent by a browser
+	HandleMessage(data []byte)
+	// Hello returns the first message for a new browser: the current state
+	Hello() []byte
+}
+
+// Server serves the UI and its WebSocket
+type Server struct {
+	addr    string
+	token   string
+	handler Handler
+	http    *http.Server
+	url     string
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+}
+
+type client struct {
+	conn *websocket.Conn
+	send chan []byte
+	once sync.Once
+}
+
+func (c *client) close() {
+	c.once.Do(func() { close(c.send) })
+}
+
+// New creates a UI server on addr. An empty token is replaced by a random one.
+func New(addr, token string, handler Handler) (*Server, error) {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	if token == "" {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		token = hex.EncodeToString(buf)
+	}
+	return &Server{addr: addr, token: token, handler: handler, clients: make(map[*client]struct{})}, nil
+}
+
+// Start listens on the address; it fails if the port is taken
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	if tcp, ok := ln.Addr().(*net.TCPAddr); ok && !tcp.IP.IsLoopback() {
+		log.Printf("[LocalUI] ⚠️ Listening on %s, which is reachable from other machines", ln.Addr())
+	}
+
+	// The token rides in the fragment so it never reaches logs or referrers
+	s.url = fmt.Sprintf("http://%s/#token=%s", ln.Addr(), url.QueryEscape(s.token))
+	s.http = &http.Server{Handler: s.routes(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := s.http.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[LocalUI] Server stopped: %v", err)
+		}
+	}()
+	log.Printf("[LocalUI] Listening on http://%s", ln.Addr())
+	return nil
+}
+
+// Stop disconnects every browser and closes the listener
+func (s *Server) Stop() {
+	if s.http != nil {
+		s.http.Close()
+	}
+	s.mu.Lock()
+	for c := range s.clients {
+		c.close()
+	}
+	s.clients = make(map[*client]struct{})
+	s.mu.Unlock()
+}
+
+// URL is the address to open in a browser, token included
+func (s *Server) URL() string {
+	return s.url
+}
+
+// Clients returns the number of connected browsers
+func (s *Server) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// Broadcast sends a message to every connected browser. A browser that
+// can't keep up is disconnected rather than stalling the bridge.
+func (s *Server) Broadcast(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		select {
+		case c.send <- data:
+		default:
+			log.Printf("[LocalUI] Dropping a browser that fell behind")
+			delete(s.clients, c)
+			c.close()
+		}
+	}
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	assets, _ := fs.Sub(static, "static")
+	files := http.FileServer(http.FS(assets))
+	mux.HandleFunc("/ws", s.handleWS)
+	mux.Handle("/", securityHeaders(files))
+	return mux
+}
+
+func securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Content-Security-Policy", "default-src 'self'; script-src 'self'; style-src 'self'; connect-src 'self' ws: wss:; frame-ancestors 'none'")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", "no-referrer")
+		h.Set("Cache-Control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin rejects pages served from other sites; the token alone would
+// do, but a browser should never let them try
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // not a browser
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// handleWS serves GET /ws?token=... as the bridge's message stream
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	upgrader := websocket.Upgrader{CheckOrigin: sameOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &client{conn: conn, send: make(chan []byte, clientBuffer)}
+	c.send <- s.handler.Hello()
+	s.mu.Lock()
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+	log.Printf("[LocalUI] Browser connected from %s", r.RemoteAddr)
+
+	go s.writeLoop(c)
+	s.readLoop(c)
+
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+	c.close()
+	log.Printf("[LocalUI] Browser disconnected from %s", r.RemoteAddr)
+}
+
+func (s *Server) readLoop(c *client) {
+	c.conn.SetReadLimit(16 << 20)
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		s.handler.HandleMessage(data)
+	}
+}
+
+func (s *Server) writeLoop(c *client) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	defer c.conn.Close()
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package localui
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type fakeHandler struct {
+	received chan string
+}
+
+func (f *fakeHandler) HandleMessage(data []byte) {
+	f.received <- string(data)
+}
+
+func (f *fakeHandler) Hello() []byte {
+	return []byte(`{"type":"local:state"}`)
+}
+
+func startServer(t *testing.T) (*Server, *fakeHandler) {
+	t.Helper()
+	h := &fakeHandler{received: make(chan string, 10)}
+	s, err := New("127.0.0.1:0", "secret", h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+	return s, h
+}
+
+func wsURL(t *testing.T, s *Server, token string) string {
+	t.Helper()
+	u, err := url.Parse(s.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "ws://" + u.Host + "/ws?token=" + url.QueryEscape(token)
+}
+
+func TestURLCarriesToken(t *testing.T) {
+	s, _ := startServer(t)
+	if !strings.HasPrefix(s.URL(), "http://127.0.0.1:") || !strings.HasSuffix(s.URL(), "/#token=secret") {
+		t.Errorf("URL = %q", s.URL())
+	}
+
+	generated, err := New("", "", &fakeHandler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(generated.token) < 32 || generated.addr != DefaultAddr {
+		t.Errorf("defaults: token %q, addr %q", generated.token, generated.addr)
+	}
+}
+
+func TestServesUI(t *testing.T) {
+	s, _ := startServer(t)
+	resp, err := http.Get(strings.Split(s.URL(), "#")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "app.js") {
+		t.Fatalf("GET / = %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Content-Security-Policy") == "" {
+		t.Error("missing Content-Security-Policy")
+	}
+}
+
+func TestRejectsBadTokenAndForeignOrigin(t *testing.T) {
+	s, _ := startServer(t)
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL(t, s, "wrong"), nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("bad token: err %v, resp %v", err, resp)
+	}
+
+	header := http.Header{"Origin": {"http://evil.example"}}
+	_, resp, err = websocket.DefaultDialer.Dial(wsURL(t, s, "secret"), header)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("foreign origin: err %v, resp %v", err, resp)
+	}
+}
+
+func TestHelloBroadcastAndMessages(t *testing.T) {
+	s, h := startServer(t)
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(t, s, "secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != `{"type":"local:state"}` {
+		t.Fatalf("hello = %q, %v", data, err)
+	}
+
+	// The client is registered once the hello is queued
+	for i := 0; s.Clients() == 0 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Broadcast([]byte(`{"type":"chat:response"}`))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != `{"type":"chat:response"}` {
+		t.Fatalf("broadcast = %q, %v", data, err)
+	}
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"session:send"}`))
+	select {
+	case got := <-h.received:
+		if got != `{"type":"session:send"}` {
+			t.Errorf("handler got %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not delivered to the handler")
+	}
+
+	s.Stop()
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Error("connection still open after Stop")
+	}
+}
@@ -0,0 +1,501 @@
+// Local web UI for the bridge. It speaks the same message protocol as the
+// cloud dashboard over a WebSocket to the bridge itself.
+(function () {
+  'use strict';
+
+  const token = new URLSearchParams(location.hash.slice(1)).get('token') || '';
+  const clientId = sessionStorage.getItem('clientId') || 'local-ui-' + Math.random().toString(36).slice(2, 10);
+  sessionStorage.setItem('clientId', clientId);
+
+  const state = {
+    ws: null,
+    retry: 0,
+    deviceId: '',
+    sessions: new Map(), // id -> {info, entries, busy, stopped}
+    permissions: new Map(), // id -> permission request payload
+    alerts: [],
+    selected: null,
+  };
+
+  const $ = (id) => document.getElementById(id);
+
+  function el(tag, cls, text) {
+    const e = document.createElement(tag);
+    if (cls) e.className = cls;
+    if (text !== undefined) e.textContent = text;
+    return e;
+  }
+
+  function button(label, cls, onClick) {
+    const b = el('button', cls, label);
+    b.type = 'button';
+    b.addEventListener('click', onClick);
+    return b;
+  }
+
+  // --- connection ---
+
+  function connect() {
+    if (!token) {
+      setConn('no token: open the URL printed by "open-agents start --local"', 'offline');
+      return;
+    }
+    const proto = location.protocol === 'https:' ? 'wss:' : 'ws:';
+    const ws = new WebSocket(proto + '//' + location.host + '/ws?token=' + encodeURIComponent(token));
+    state.ws = ws;
+    ws.onopen = () => {
+      state.retry = 0;
+      setConn('connected', 'online');
+      send('cli:list', {});
+    };
+    ws.onmessage = (ev) => {
+      let msg;
+      try { msg = JSON.parse(ev.data); } catch (e) { return; }
+      handle(msg);
+    };
+    ws.onclose = () => {
+      setConn('disconnected', 'offline');
+      const delay = Math.min(1000 * 2 ** state.retry++, 15000);
+      setTimeout(connect, delay);
+    };
+  }
+
+  function setConn(text, cls) {
+    const c = $('conn');
+    c.textContent = text;
+    c.className = 'badge ' + (cls || '');
+  }
+
+  function send(type, payload) {
+    if (!state.ws || state.ws.readyState !== WebSocket.OPEN) return false;
+    payload = Object.assign({ clientId: clientId, deviceId: state.deviceId }, payload);
+    state.ws.send(JSON.stringify({ type: type, payload: payload, timestamp: Date.now() }));
+    return true;
+  }
+
+  // --- state ---
+
+  function session(id) {
+    let s = state.sessions.get(id);
+    if (!s) {
+      s = { info: { id: id }, entries: [], busy: false, stopped: false };
+      state.sessions.set(id, s);
+    }
+    return s;
+  }
+
+  function addEntry(sessionId, entry) {
+    const s = session(sessionId);
+    const last = s.entries[s.entries.length - 1];
+    // Streamed text arrives in chunks; keep appending to the same entry
+    if (last && entry.merge && last.kind === entry.kind) {
+      last.text += entry.text;
+    } else {
+      s.entries.push(entry);
+    }
+    if (sessionId === state.selected) renderLog();
+  }
+
+  // stripANSI makes raw terminal output readable as text
+  function stripANSI(text) {
+    return String(text)
+      .replace(/\x1b\][^\x07\x1b]*(\x07|\x1b\\)/g, '')
+      .replace(/\x1b\[[0-9;?]*[ -\/]*[@-~]/g, '')
+      .replace(/\x1b[@-Z\\-_]/g, '')
+      .replace(/\r(?!\n)/g, '');
+  }
+
+  function permKey(id) {
+    return String(id);
+  }
+
+  // JSON-RPC answers carry the ID type of the request; IDs listed in the
+  // state snapshot are strings, numeric ones go back as numbers
+  function rawPermID(p) {
+    if (typeof p.id === 'string' && p.origin === 'acp' && /^\d+$/.test(p.id)) return Number(p.id);
+    return p.id;
+  }
+
+  function loadHistory(sessionId, messages) {
+    const s = session(sessionId);
+    s.entries = [];
+    (messages || []).forEach((m) => {
+      switch (m.type || '') {
+        case 'thought':
+          s.entries.push({ kind: 'thought', text: m.content });
+          break;
+        case 'tool_call':
+          s.entries.push({ kind: 'tool', id: m.id, text: toolLine(m.content, m.meta && m.meta.status) });
+          break;
+        case 'permission':
+          s.entries.push({ kind: 'system', text: '🔐 ' + m.content + ' (' + ((m.meta && m.meta.status) || '') + ')' });
+          break;
+        case 'usage':
+          break;
+        default:
+          if (m.role === 'user') s.entries.push({ kind: 'user', text: m.content });
+          else if (m.role === 'assistant') s.entries.push({ kind: 'assistant', text: m.content });
+          else s.entries.push({ kind: 'system', text: m.content });
+      }
+    });
+  }
+
+  function toolLine(name, status) {
+    return '⚙ ' + (name || 'tool') + (status ? ' [' + status + ']' : '');
+  }
+
+  // --- messages from the bridge ---
+
+  function handle(msg) {
+    const p = msg.payload || {};
+    const sid = p.sessionId;
+    switch (msg.type) {
+      case 'local:state':
+        state.deviceId = p.deviceId || '';
+        $('device').textContent = p.deviceName || '';
+        state.sessions.clear();
+        (p.sessions || []).forEach((info) => {
+          const s = session(info.id);
+          s.info = info;
+          s.busy = !!info.busy;
+          loadHistory(info.id, (p.history || {})[info.id]);
+        });
+        state.permissions.clear();
+        (p.permissions || []).forEach((perm) => state.permissions.set(permKey(perm.id), perm));
+        state.alerts = p.alerts || [];
+        if (state.selected && !state.sessions.has(state.selected)) state.selected = null;
+        renderAll();
+        break;
+
+      case 'cli:list_response':
+        renderCLIs(p.clis || []);
+        break;
+
+      case 'session:started': {
+        const s = session(sid);
+        s.info = Object.assign(s.info, { id: sid, cliType: p.cliType, workDir: p.workDir, controller: p.controller });
+        s.stopped = false;
+        if (!state.selected || p.controller && p.controller.clientId === clientId) state.selected = sid;
+        renderAll();
+        break;
+      }
+
+      case 'session:stopped':
+        if (state.sessions.has(sid)) {
+          const s = session(sid);
+          s.stopped = true;
+          s.busy = false;
+          addEntry(sid, { kind: 'system', text: 'Session stopped' });
+        }
+        for (const [id, perm] of state.permissions) {
+          if (perm.sessionId === sid) state.permissions.delete(id);
+        }
+        renderAll();
+        break;
+
+      case 'chat:response':
+        if (p.protocol === 'pty') addEntry(sid, { kind: 'terminal', text: stripANSI(p.content), merge: true });
+        else if (typeof p.content === 'string') addEntry(sid, { kind: 'assistant', text: p.content, merge: true });
+        break;
+
+      case 'session:output':
+        addEntry(sid, { kind: p.outputType === 'stderr' ? 'system' : 'terminal', text: stripANSI(p.content), merge: p.outputType !== 'stderr' });
+        break;
+
+      case 'chat:thought':
+        addEntry(sid, { kind: 'thought', text: String(p.content), merge: true });
+        break;
+
+      case 'tool:call': {
+        const tc = p.toolCall || {};
+        const s = session(sid);
+        const existing = tc.id && s.entries.find((e) => e.kind === 'tool' && e.id === tc.id);
+        if (existing) {
+          existing.text = toolLine(tc.name || existing.name, tc.status);
+          if (sid === state.selected) renderLog();
+        } else {
+          addEntry(sid, { kind: 'tool', id: tc.id, name: tc.name, text: toolLine(tc.name, tc.status) });
+        }
+        break;
+      }
+
+      case 'agent:plan': {
+        const plan = Array.isArray(p.plan) ? p.plan : (p.plan && p.plan.entries) || [];
+        const lines = plan.map((e) => (e.status === 'completed' ? '☑ ' : '☐ ') + (e.content || JSON.stringify(e)));
+        addEntry(sid, { kind: 'plan', text: lines.join('\n') || JSON.stringify(p.plan) });
+        break;
+      }
+
+      case 'permission:request': {
+        // Hook requests (from the permission server) name a permissionType instead of a tool
+        const hook = !!p.permissionType;
+        const perm = Object.assign({ origin: hook ? 'hook' : 'acp' }, p);
+        if (hook) {
+          perm.toolName = p.permissionType;
+          perm.toolInput = p.detail;
+          perm.options = ['approve', 'deny'];
+        }
+        state.permissions.set(permKey(p.id), perm);
+        if (sid && state.sessions.has(sid)) addEntry(sid, { kind: 'permission', permID: permKey(p.id) });
+        renderPermissions();
+        break;
+      }
+
+      case 'permission:expired': {
+        // Timed out or no longer awaited by the agent
+        const perm = state.permissions.get(permKey(p.id));
+        state.permissions.delete(permKey(p.id));
+        if (perm && perm.sessionId && p.reason === 'timeout') {
+          addEntry(perm.sessionId, { kind: 'system', text: '🔐 ' + (perm.toolName || 'permission') + ': timed out, ' + (p.approved ? 'approved' : 'denied') });
+        }
+        renderPermissions();
+        break;
+      }
+
+      case 'agent:status': {
+        const s = session(sid);
+        s.busy = p.status === 'busy';
+        renderSessions();
+        renderHead();
+        break;
+      }
+
+      case 'session:queue':
+        if (p.reason === 'queued') addEntry(sid, { kind: 'system', text: 'Queued (' + (p.items || []).length + ' waiting)' });
+        break;
+
+      case 'session:cancelled':
+        addEntry(sid, { kind: 'system', text: 'Turn cancelled' });
+        break;
+
+      case 'session:error':
+        if (p.clientId && p.clientId !== clientId) break; // addressed to another client
+        if (sid) addEntry(sid, { kind: 'error', text: '✖ ' + p.error });
+        else alert(p.error);
+        break;
+
+      case 'session:fallback': {
+        addEntry(sid, { kind: 'error', text: '✖ ' + (p.error || p.reason) });
+        addEntry(sid, { kind: 'system', text: 'Continuing with ' + p.toCliType + ' in session ' + String(p.newSessionId).slice(0, 8) });
+        const next = session(p.newSessionId);
+        next.info = Object.assign(next.info, { id: p.newSessionId, cliType: p.toCliType, workDir: p.workDir, controller: session(sid).info.controller });
+        session(sid).stopped = true;
+        if (state.selected === sid) state.selected = p.newSessionId;
+        renderAll();
+        break;
+      }
+
+      case 'session:usage':
+        if (p.usage) session(sid).usage = p.usage;
+        if (sid === state.selected) renderHead();
+        break;
+
+      case 'control:changed':
+        session(sid).info.controller = p.controller;
+        renderSessions();
+        renderHead();
+        break;
+
+      case 'security:alert':
+        state.alerts.unshift(p);
+        state.alerts = state.alerts.slice(0, 100);
+        renderAlerts();
+        break;
+    }
+  }
+
+  // --- rendering ---
+
+  function renderAll() {
+    renderSessions();
+    renderPermissions();
+    renderAlerts();
+    renderHead();
+    renderLog();
+  }
+
+  function renderCLIs(clis) {
+    const select = $('cli');
+    const current = select.value;
+    select.textContent = '';
+    clis.filter((c) => c.enabled).forEach((c) => {
+      const o = el('option', '', (c.displayName || c.name) + (c.installed ? '' : ' (not installed)'));
+      o.value = c.name;
+      o.disabled = !c.installed;
+      select.appendChild(o);
+    });
+    if (current) select.value = current;
+  }
+
+  function renderSessions() {
+    const list = $('sessions');
+    list.textContent = '';
+    if (state.sessions.size === 0) list.appendChild(el('li', 'empty', 'No sessions'));
+    state.sessions.forEach((s, id) => {
+      const li = el('li', 'session' + (id === state.selected ? ' selected' : ''));
+      const dot = el('span', 'dot ' + (s.stopped ? '' : s.busy ? 'busy' : 'idle'));
+      li.appendChild(dot);
+      li.appendChild(el('strong', '', s.info.cliType || 'session'));
+      li.appendChild(document.createTextNode(' ' + id.slice(0, 8) + (s.stopped ? ' (stopped)' : '')));
+      li.appendChild(el('div', 'meta', s.info.workDir || ''));
+      li.addEventListener('click', () => {
+        state.selected = id;
+        renderAll();
+      });
+      list.appendChild(li);
+    });
+  }
+
+  function renderPermissions() {
+    const list = $('permissions');
+    list.textContent = '';
+    $('perm-count').textContent = state.permissions.size ? '(' + state.permissions.size + ')' : '';
+    if (state.permissions.size === 0) list.appendChild(el('li', 'empty', 'None pending'));
+    state.permissions.forEach((p) => list.appendChild(permissionCard(p, 'li')));
+    if (state.selected) renderLog();
+  }
+
+  function permissionCard(p, tag) {
+    const card = el(tag, 'permission');
+    card.appendChild(el('strong', '', '🔐 ' + (p.toolName || 'Permission') + (p.risk ? ' (' + p.risk + ' risk)' : '')));
+    if (p.sessionId) card.appendChild(el('div', 'meta', 'session ' + p.sessionId.slice(0, 8)));
+    if (p.description) card.appendChild(el('div', 'meta', p.description));
+    const input = p.toolInput || p.input;
+    if (input && input.command) card.appendChild(el('div', 'meta', '$ ' + input.command));
+    else if (input && (input.path || input.file_path)) card.appendChild(el('div', 'meta', input.path || input.file_path));
+
+    const buttons = el('div', 'buttons');
+    const options = p.options && p.options.length ? p.options : p.origin === 'hook' ? ['approve', 'approve_always', 'deny', 'deny_always'] : ['allow_once', 'reject_once'];
+    options.forEach((opt) => {
+      const approve = !/reject|deny|cancel/i.test(opt);
+      buttons.appendChild(button(opt.replace(/_/g, ' '), approve ? 'primary' : 'danger', () => answer(p, approve, opt)));
+    });
+    card.appendChild(buttons);
+    return card;
+  }
+
+  function answer(p, approved, optionId) {
+    const payload = { id: rawPermID(p), approved: approved, sessionId: p.sessionId };
+    if (p.origin !== 'hook') payload.optionId = optionId;
+    else if (/always/.test(optionId)) payload.remember = true;
+    if (send('permission:response', payload)) {
+      state.permissions.delete(permKey(p.id));
+      if (p.sessionId) addEntry(p.sessionId, { kind: 'system', text: '🔐 ' + (p.toolName || 'permission') + ': ' + optionId });
+      renderPermissions();
+    }
+  }
+
+  function renderAlerts() {
+    const list = $('alerts');
+    list.textContent = '';
+    $('alert-count').textContent = state.alerts.length ? '(' + state.alerts.length + ')' : '';
+    if (state.alerts.length === 0) list.appendChild(el('li', 'empty', 'No alerts'));
+    state.alerts.forEach((a) => {
+      const li = el('li', 'alert ' + (a.level || ''));
+      li.appendChild(el('strong', '', '⚠ ' + (a.title || a.ruleId)));
+      li.appendChild(el('div', 'meta', [a.level, a.category, a.direction, a.sessionId && 'session ' + a.sessionId.slice(0, 8)].filter(Boolean).join(' · ')));
+      if (a.description) li.appendChild(el('div', 'meta', a.description));
+      if (a.match) li.appendChild(el('div', 'meta', a.match));
+      li.addEventListener('click', () => {
+        if (a.sessionId && state.sessions.has(a.sessionId)) {
+          state.selected = a.sessionId;
+          renderAll();
+        }
+      });
+      list.appendChild(li);
+    });
+  }
+
+  function controlledElsewhere(s) {
+    const c = s && s.info.controller;
+    return !!(c && c.clientId && c.clientId !== clientId);
+  }
+
+  function renderHead() {
+    const s = state.selected && state.sessions.get(state.selected);
+    const live = !!(s && !s.stopped);
+    $('chat-title').textContent = s ? (s.info.cliType || 'session') + ' · ' + state.selected.slice(0, 8) + ' · ' + (s.info.workDir || '') : 'Select or start a session';
+    let status = s ? (s.stopped ? 'stopped' : s.busy ? 'working…' : 'idle') : '';
+    if (s && s.usage) status += ' · ' + (s.usage.inputTokens + s.usage.outputTokens) + ' tokens';
+    if (controlledElsewhere(s)) status += ' · controlled by ' + s.info.controller.kind + ' client ' + s.info.controller.clientId;
+    $('chat-status').textContent = status;
+    $('takeover').hidden = !(live && controlledElsewhere(s));
+    $('cancel').disabled = !live;
+    $('stop').disabled = !live;
+    $('prompt').disabled = !live;
+    document.querySelector('#send button[type=submit]').disabled = !live;
+    $('interrupt').disabled = !live;
+  }
+
+  function renderLog() {
+    const log = $('log');
+    const atBottom = log.scrollHeight - log.scrollTop - log.clientHeight < 40;
+    log.textContent = '';
+    const s = state.selected && state.sessions.get(state.selected);
+    if (!s) return;
+    s.entries.forEach((e) => {
+      if (e.kind === 'permission') {
+        const p = state.permissions.get(e.permID);
+        if (p) log.appendChild(permissionCard(p, 'div')).classList.add('entry');
+        return;
+      }
+      log.appendChild(el('div', 'entry ' + e.kind, e.text));
+    });
+    if (atBottom) log.scrollTop = log.scrollHeight;
+  }
+
+  // --- user actions ---
+
+  function newSessionID() {
+    if (window.crypto && crypto.randomUUID) return crypto.randomUUID();
+    return 'xxxxxxxx-xxxx-4xxx-yxxx-xxxxxxxxxxxx'.replace(/[xy]/g, (c) => {
+      const r = Math.random() * 16 | 0;
+      return (c === 'x' ? r : (r & 0x3 | 0x8)).toString(16);
+    });
+  }
+
+  $('start').addEventListener('submit', (ev) => {
+    ev.preventDefault();
+    const workDir = $('workdir').value.trim();
+    if (!workDir) return;
+    localStorage.setItem('workDir', workDir);
+    send('session:start', {
+      sessionId: newSessionID(),
+      cliType: $('cli').value,
+      workDir: workDir,
+      permissionMode: $('mode').value,
+    });
+  });
+  $('workdir').value = localStorage.getItem('workDir') || '';
+
+  function submitPrompt(mode) {
+    const text = $('prompt').value;
+    if (!text.trim() || !state.selected) return;
+    const payload = { sessionId: state.selected, content: text };
+    if (mode) payload.mode = mode;
+    if (send('session:send', payload)) {
+      addEntry(state.selected, { kind: 'user', text: text });
+      $('prompt').value = '';
+    }
+  }
+
+  $('send').addEventListener('submit', (ev) => {
+    ev.preventDefault();
+    submitPrompt('');
+  });
+  $('prompt').addEventListener('keydown', (ev) => {
+    if (ev.key === 'Enter' && !ev.shiftKey && !ev.isComposing) {
+      ev.preventDefault();
+      submitPrompt('');
+    }
+  });
+  $('interrupt').addEventListener('click', () => submitPrompt('interrupt'));
+  $('cancel').addEventListener('click', () => send('session:cancel', { sessionId: state.selected }));
+  $('stop').addEventListener('click', () => {
+    if (confirm('Stop this session?')) send('session:stop', { sessionId: state.selected });
+  });
+  $('takeover').addEventListener('click', () => send('control:takeover', { sessionId: state.selected }));
+
+  renderAll();
+  connect();
+})();
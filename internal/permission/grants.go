@@ -0,0 +1,262 @@
+package permission
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/rules"
+)
+
+// MaxGrantDuration bounds how long a grant can last
+const MaxGrantDuration = 24 * time.Hour
+
+// Reasons a grant ends
+const (
+	GrantExpired      = "expired"
+	GrantTurnEnded    = "turn_ended"
+	GrantSessionEnded = "session_ended"
+	GrantRevoked      = "revoked"
+)
+
+// Grant is a temporary approval, such as "fs_write under src/ for 15
+// minutes" or "any command in this session until the turn ends"
+type Grant struct {
+	ID           string    `json:"id"`
+	SessionID    string    `json:"sessionId,omitempty"`   // the session the grant is for
+	AllSessions  bool      `json:"allSessions,omitempty"` // or, said explicitly, every session
+	Tool         string    `json:"tool,omitempty"`        // empty or "*": every tool
+	Pattern      string    `json:"pattern,omitempty"`     // rule pattern; relative paths are under the session's workDir
+	ExpiresAt    time.Time `json:"expiresAt,omitempty"`
+	UntilTurnEnd bool      `json:"untilTurnEnd,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	CreatedBy    string    `json:"createdBy,omitempty"`
+	Uses         int       `json:"uses"`
+
+	// The grant as an approving rule, compiled when it is added: engine
+	// matches commands and other tools, pathEngine the file tools with the
+	// pattern resolved as a path (nil when it cannot be)
+	engine     *rules.Engine
+	pathEngine *rules.Engine
+}
+
+// Grants holds the active grants in memory; they don't outlive the bridge
+type Grants struct {
+	mu      sync.Mutex
+	items   map[string]*Grant
+	timers  map[string]*time.Timer
+	onEnd   func(g Grant, reason string)
+	stopped bool
+}
+
+// NewGrants creates an empty set of grants. onEnd is called, without locks
+// held, whenever a grant ends.
+func NewGrants(onEnd func(g Grant, reason string)) *Grants {
+	return &Grants{items: make(map[string]*Grant), timers: make(map[string]*time.Timer), onEnd: onEnd}
+}
+
+// Add validates and activates a grant. It must be for one session or say
+// AllSessions, and it must end: at a time at most MaxGrantDuration away, at
+// the end of its session's turn, or both. workDir is the session's; relative
+// path patterns are resolved against it.
+func (gs *Grants) Add(g Grant, workDir string) (Grant, error) {
+	now := time.Now()
+	if g.SessionID == "" && !g.AllSessions {
+		return Grant{}, fmt.Errorf("a grant needs a session or allSessions")
+	}
+	if g.SessionID != "" && g.AllSessions {
+		return Grant{}, fmt.Errorf("a grant is for one session or for all sessions, not both")
+	}
+	if g.ExpiresAt.IsZero() && !g.UntilTurnEnd {
+		return Grant{}, fmt.Errorf("a grant needs a duration or untilTurnEnd")
+	}
+	if !g.ExpiresAt.IsZero() && (!g.ExpiresAt.After(now) || g.ExpiresAt.Sub(now) > MaxGrantDuration) {
+		return Grant{}, fmt.Errorf("a grant must expire within %v", MaxGrantDuration)
+	}
+	if g.UntilTurnEnd && g.SessionID == "" {
+		return Grant{}, fmt.Errorf("untilTurnEnd needs a session")
+	}
+	g.ID = uuid.New().String()
+	g.CreatedAt = now
+	g.Uses = 0
+	if err := g.compile(workDir); err != nil {
+		return Grant{}, err
+	}
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if gs.stopped {
+		return Grant{}, fmt.Errorf("grants are stopped")
+	}
+	gs.items[g.ID] = &g
+	if !g.ExpiresAt.IsZero() {
+		id := g.ID
+		gs.timers[id] = time.AfterFunc(g.ExpiresAt.Sub(now), func() { gs.end(id, GrantExpired) })
+	}
+	return g, nil
+}
+
+// Match returns an active grant covering a request, counting its use.
+// workDir is the requesting session's.
+func (gs *Grants) Match(sessionID, workDir, tool, filePath, command string) (Grant, bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	ids := make([]string, 0, len(gs.items))
+	for id := range gs.items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	now := time.Now()
+	for _, id := range ids {
+		g := gs.items[id]
+		if !g.AllSessions && g.SessionID != sessionID {
+			continue
+		}
+		if !g.ExpiresAt.IsZero() && !now.Before(g.ExpiresAt) {
+			continue // its timer is about to fire
+		}
+		if !g.covers(workDir, tool, filePath, command) {
+			continue
+		}
+		g.Uses++
+		return *g, true
+	}
+	return Grant{}, false
+}
+
+// compile turns the grant into its approving rules. A relative path
+// pattern needs the session's workDir; without one the grant does not cover
+// file tools, which is an error when that is all it is for.
+func (g *Grant) compile(workDir string) error {
+	rule := config.AutoApprovalRule{ID: g.ID, Tool: g.Tool, Pattern: g.Pattern, Action: rules.ActionApprove}
+	g.engine = rules.NewEngine([]config.AutoApprovalRule{rule})
+	if g.Pattern == "" || g.Pattern == "*" {
+		g.pathEngine = g.engine
+		return nil
+	}
+
+	pattern := filepath.ToSlash(g.Pattern)
+	if !path.IsAbs(pattern) {
+		if workDir == "" {
+			if strings.HasPrefix(g.Tool, "fs_") {
+				return fmt.Errorf("pattern %q is relative; a grant for all sessions needs an absolute path", g.Pattern)
+			}
+			return nil
+		}
+		pattern = path.Join(filepath.ToSlash(workDir), pattern)
+	}
+	pattern = path.Clean(pattern)
+	if strings.HasSuffix(g.Pattern, "/") {
+		pattern += "/**"
+	}
+	rule.Pattern = pattern
+	g.pathEngine = rules.NewEngine([]config.AutoApprovalRule{rule})
+	return nil
+}
+
+// covers reports whether a request is within the grant, matching it like an
+// auto-approval rule: every part of a command must be covered
+func (g *Grant) covers(workDir, tool, filePath, command string) bool {
+	engine := g.engine
+	if strings.HasPrefix(tool, "fs_") || strings.HasPrefix(g.Tool, "fs_") {
+		engine = g.pathEngine
+	}
+	if engine == nil {
+		return false
+	}
+	if filePath != "" && workDir != "" && !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(workDir, filePath)
+	}
+	d := engine.Decide(rules.Request{Tool: tool, Path: filePath, Command: command, WorkDir: workDir})
+	return d.Action == rules.ActionApprove
+}
+
+// Get returns an active grant
+func (gs *Grants) Get(id string) (Grant, bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if g, ok := gs.items[id]; ok {
+		return *g, true
+	}
+	return Grant{}, false
+}
+
+// List returns the active grants, oldest first
+func (gs *Grants) List() []Grant {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	list := make([]Grant, 0, len(gs.items))
+	for _, g := range gs.items {
+		list = append(list, *g)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Revoke ends a grant early
+func (gs *Grants) Revoke(id string) bool {
+	return gs.end(id, GrantRevoked)
+}
+
+// EndTurn ends the grants that last until a session's turn ends
+func (gs *Grants) EndTurn(sessionID string) {
+	gs.endWhere(GrantTurnEnded, func(g *Grant) bool { return g.UntilTurnEnd && g.SessionID == sessionID })
+}
+
+// EndSession ends the grants of a session
+func (gs *Grants) EndSession(sessionID string) {
+	gs.endWhere(GrantSessionEnded, func(g *Grant) bool { return sessionID != "" && g.SessionID == sessionID })
+}
+
+// Stop drops every grant without reporting them
+func (gs *Grants) Stop() {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	for _, t := range gs.timers {
+		t.Stop()
+	}
+	gs.items = make(map[string]*Grant)
+	gs.timers = make(map[string]*time.Timer)
+	gs.stopped = true
+}
+
+func (gs *Grants) endWhere(reason string, match func(*Grant) bool) {
+	gs.mu.Lock()
+	var ids []string
+	for id, g := range gs.items {
+		if match(g) {
+			ids = append(ids, id)
+		}
+	}
+	gs.mu.Unlock()
+	sort.Strings(ids)
+	for _, id := range ids {
+		gs.end(id, reason)
+	}
+}
+
+func (gs *Grants) end(id, reason string) bool {
+	gs.mu.Lock()
+	g, ok := gs.items[id]
+	if ok {
+		delete(gs.items, id)
+		if t := gs.timers[id]; t != nil {
+			t.Stop()
+			delete(gs.timers, id)
+		}
+	}
+	gs.mu.Unlock()
+
+	if ok && gs.onEnd != nil {
+		gs.onEnd(*g, reason)
+	}
+	return ok
+}
@@ -0,0 +1,131 @@
+package permission
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type endLog struct {
+	mu    sync.Mutex
+	ended map[string]string
+}
+
+func (l *endLog) record(g Grant, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ended[g.ID] = reason
+}
+
+func (l *endLog) reason(id string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ended[id]
+}
+
+func TestGrantsMatch(t *testing.T) {
+	log := &endLog{ended: map[string]string{}}
+	gs := NewGrants(log.record)
+	defer gs.Stop()
+	in := time.Now().Add(15 * time.Minute)
+
+	src, err := gs.Add(Grant{SessionID: "s1", Tool: "fs_write", Pattern: "src/", ExpiresAt: in}, "/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := gs.Add(Grant{AllSessions: true, Tool: "fs_write", Pattern: "/repo/docs/", ExpiresAt: in}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	turn, err := gs.Add(Grant{SessionID: "s1", Tool: "execute_bash", UntilTurnEnd: true}, "/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		session, tool, path, command string
+		want                         string
+	}{
+		{"s1", "fs_write", "/repo/src/a/b.go", "", src.ID},
+		{"s1", "fs_write", "src/main.go", "", src.ID},
+		{"s2", "fs_write", "/repo/src/main.go", "", ""}, // another session's grant
+		{"s2", "fs_write", "docs/intro.md", "", docs.ID},
+		{"s1", "fs_write", "/repo/src/../go.mod", "", ""},
+		{"s1", "fs_write", "/repo/README.md", "", ""},
+		{"s1", "fs_read", "/repo/src/a.go", "", ""},
+		{"s1", "execute_bash", "", "make && ./run", turn.ID},
+		{"s1", "execute_bash", "", "ls > /etc/passwd", ""}, // the redirect writes a file
+		{"s2", "execute_bash", "", "make", ""},
+	}
+	for _, tt := range tests {
+		g, ok := gs.Match(tt.session, "/repo", tt.tool, tt.path, tt.command)
+		if ok != (tt.want != "") || ok && g.ID != tt.want {
+			t.Errorf("%s %s %s%s: got %q, %v, want %q", tt.session, tt.tool, tt.path, tt.command, g.ID, ok, tt.want)
+		}
+	}
+
+	gs.EndTurn("s2")
+	if _, ok := gs.Match("s1", "/repo", "execute_bash", "", "make"); !ok {
+		t.Error("another session's turn ended the grant")
+	}
+	gs.EndTurn("s1")
+	if _, ok := gs.Match("s1", "/repo", "execute_bash", "", "make"); ok {
+		t.Error("turn grant outlived the turn")
+	}
+	if log.reason(turn.ID) != GrantTurnEnded {
+		t.Errorf("turn grant ended with %q", log.reason(turn.ID))
+	}
+
+	if g, ok := gs.Get(src.ID); !ok || g.Uses != 2 {
+		t.Errorf("Get(%s) = %+v, %v; want it with 2 uses", src.ID, g, ok)
+	}
+	if !gs.Revoke(src.ID) || gs.Revoke(src.ID) {
+		t.Error("revoke should succeed once")
+	}
+	if log.reason(src.ID) != GrantRevoked || len(gs.List()) != 1 {
+		t.Errorf("after revoke: %q, %v", log.reason(src.ID), gs.List())
+	}
+}
+
+func TestGrantsExpire(t *testing.T) {
+	log := &endLog{ended: map[string]string{}}
+	gs := NewGrants(log.record)
+	defer gs.Stop()
+
+	g, err := gs.Add(Grant{AllSessions: true, ExpiresAt: time.Now().Add(30 * time.Millisecond)}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gs.Match("s1", "/repo", "execute_bash", "", "rm -rf build"); !ok {
+		t.Fatal("grant for everything did not match")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for log.reason(g.ID) == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if log.reason(g.ID) != GrantExpired {
+		t.Fatalf("grant ended with %q", log.reason(g.ID))
+	}
+	if _, ok := gs.Match("s1", "/repo", "execute_bash", "", "ls"); ok {
+		t.Error("expired grant matched")
+	}
+}
+
+func TestGrantsValidate(t *testing.T) {
+	gs := NewGrants(nil)
+	defer gs.Stop()
+	in := time.Now().Add(time.Minute)
+	for _, g := range []Grant{
+		{SessionID: "s1", Tool: "fs_write"}, // never ends
+		{SessionID: "s1", ExpiresAt: time.Now().Add(-time.Minute)},
+		{SessionID: "s1", ExpiresAt: time.Now().Add(MaxGrantDuration + time.Hour)},
+		{AllSessions: true, UntilTurnEnd: true},                               // no session
+		{ExpiresAt: in},                                                       // no scope
+		{SessionID: "s1", AllSessions: true, ExpiresAt: in},                   // both scopes
+		{AllSessions: true, Tool: "fs_write", Pattern: "src/", ExpiresAt: in}, // relative path without a workDir
+	} {
+		if _, err := gs.Add(g, ""); err == nil {
+			t.Errorf("%+v: expected an error", g)
+		}
+	}
+}
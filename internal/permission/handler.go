@@ -26,13 +26,17 @@ type Response struct {
 // Handler manages pending permission requests
 type Handler struct {
 	pending  map[string]chan Response
+	requests map[string]Request
 	mu       sync.Mutex
 	onRequest func(Request)
+	onTimeout func(Request) bool
+	timeout  time.Duration
 }
 
 func NewHandler() *Handler {
 	return &Handler{
-		pending: make(map[string]chan Response),
+		pending:  make(map[string]chan Response),
+		requests: make(map[string]Request),
 	}
 }
 
@@ -41,16 +45,34 @@ func (h *Handler) OnRequest(callback func(Request)) {
 	h.onRequest = callback
 }
 
+// OnTimeout sets the callback deciding requests nobody answered in time;
+// without one they are denied
+func (h *Handler) OnTimeout(callback func(Request) bool) {
+	h.onTimeout = callback
+}
+
+// SetTimeout overrides how long requests wait for an answer
+func (h *Handler) SetTimeout(timeout time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timeout = timeout
+}
+
 // Submit adds a new permission request and waits for response
 func (h *Handler) Submit(req Request) (bool, error) {
 	h.mu.Lock()
 	ch := make(chan Response, 1)
 	h.pending[req.ID] = ch
+	if h.timeout > 0 {
+		req.Timeout = int(h.timeout / time.Second)
+	}
+	h.requests[req.ID] = req
 	h.mu.Unlock()
 
 	defer func() {
 		h.mu.Lock()
 		delete(h.pending, req.ID)
+		delete(h.requests, req.ID)
 		h.mu.Unlock()
 	}()
 
@@ -67,6 +89,9 @@ func (h *Handler) Submit(req Request) (bool, error) {
 	case resp := <-ch:
 		return resp.Approved, nil
 	case <-time.After(timeout):
+		if h.onTimeout != nil {
+			return h.onTimeout(req), nil
+		}
 		return false, nil
 	}
 }
@@ -77,18 +102,30 @@ func (h *Handler) Resolve(resp Response) {
 	defer h.mu.Unlock()
 
 	if ch, ok := h.pending[resp.ID]; ok {
-		ch <- resp
+		select {
+		case ch <- resp:
+		default: // already answered
+		}
 	}
 }
 
+// Lookup returns a pending request
+func (h *Handler) Lookup(id string) (Request, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	req, ok := h.requests[id]
+	return req, ok
+}
+
 // GetPending returns all pending requests
 func (h *Handler) GetPending() []Request {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	var reqs []Request
-	for id := range h.pending {
-		reqs = append(reqs, Request{ID: id})
+	for _, req := range h.requests {
+		reqs = append(reqs, req)
 	}
 	return reqs
 }
@@ -0,0 +1,171 @@
+package permission
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Key is the kind of request a remembered decision answers: one tool with
+// one normalized input, in one project and for one CLI
+type Key struct {
+	WorkDir string `json:"workDir,omitempty"`
+	CLIType string `json:"cliType,omitempty"`
+	Kind    string `json:"kind,omitempty"` // ACP tool kind
+	Tool    string `json:"tool"`
+	Input   string `json:"input"`
+}
+
+// KeyFor normalizes a request into its key. Commands are reformatted, so
+// spacing and quoting style don't matter; paths are made absolute against
+// workDir and cleaned; other inputs are compared as canonical JSON.
+func KeyFor(workDir, cliType, tool string, input map[string]any) Key {
+	if workDir != "" {
+		workDir = filepath.ToSlash(filepath.Clean(workDir))
+	}
+	key := Key{WorkDir: workDir, CLIType: cliType, Tool: tool}
+
+	switch {
+	case tool == "execute_bash":
+		command, _ := input["command"].(string)
+		key.Input = normalizeCommand(command)
+		return key
+	case strings.HasPrefix(tool, "fs_"):
+		for _, k := range pathKeys {
+			if p, ok := input[k].(string); ok && p != "" {
+				p = filepath.ToSlash(p)
+				if !path.IsAbs(p) && workDir != "" {
+					p = path.Join(workDir, p)
+				}
+				key.Input = path.Clean(p)
+				return key
+			}
+		}
+	}
+	data, _ := json.Marshal(input) // map keys are sorted
+	key.Input = string(data)
+	return key
+}
+
+func normalizeCommand(command string) string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return strings.Join(strings.Fields(command), " ")
+	}
+	var sb strings.Builder
+	if err := syntax.NewPrinter().Print(&sb, file); err != nil {
+		return strings.Join(strings.Fields(command), " ")
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// Remembered is an "always" answer applied to later requests with its key
+type Remembered struct {
+	ID string `json:"id"`
+	Key
+	Approved   bool      `json:"approved"`
+	CreatedAt  time.Time `json:"createdAt"`
+	CreatedBy  string    `json:"createdBy,omitempty"` // client that answered
+	LastUsedAt time.Time `json:"lastUsedAt,omitempty"`
+	Uses       int       `json:"uses"`
+}
+
+// Memory persists remembered decisions in a JSON file
+type Memory struct {
+	mu    sync.Mutex
+	file  string
+	items []Remembered
+}
+
+// NewMemory opens the remembered decisions in file
+func NewMemory(file string) *Memory {
+	m := &Memory{file: file}
+	if data, err := os.ReadFile(file); err == nil {
+		json.Unmarshal(data, &m.items)
+	}
+	return m
+}
+
+// Remember stores a decision for key, replacing an earlier one
+func (m *Memory) Remember(key Key, approved bool, createdBy string) Remembered {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := Remembered{ID: uuid.New().String(), Key: key, Approved: approved, CreatedAt: time.Now(), CreatedBy: createdBy}
+	for i := range m.items {
+		if m.items[i].Key == key {
+			m.items[i] = r
+			m.save()
+			return r
+		}
+	}
+	m.items = append(m.items, r)
+	m.save()
+	return r
+}
+
+// Lookup returns the decision remembered for key and counts its use
+func (m *Memory) Lookup(key Key) (Remembered, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.items {
+		if m.items[i].Key == key {
+			m.items[i].Uses++
+			m.items[i].LastUsedAt = time.Now()
+			m.save()
+			return m.items[i], true
+		}
+	}
+	return Remembered{}, false
+}
+
+// List returns the remembered decisions, oldest first
+func (m *Memory) List() []Remembered {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Remembered, len(m.items))
+	copy(list, m.items)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Forget removes a remembered decision
+func (m *Memory) Forget(id string) (Remembered, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, r := range m.items {
+		if r.ID == id {
+			m.items = append(m.items[:i], m.items[i+1:]...)
+			m.save()
+			return r, true
+		}
+	}
+	return Remembered{}, false
+}
+
+// save writes the file; callers hold mu
+func (m *Memory) save() {
+	if m.file == "" {
+		return
+	}
+	data, err := json.MarshalIndent(m.items, "", "  ")
+	if err != nil {
+		return
+	}
+	os.MkdirAll(filepath.Dir(m.file), 0700)
+	tmp := m.file + ".tmp"
+	if os.WriteFile(tmp, data, 0600) == nil {
+		os.Rename(tmp, m.file)
+	}
+}
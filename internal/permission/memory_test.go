@@ -0,0 +1,121 @@
+package permission
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyForNormalizes(t *testing.T) {
+	same := [][2]Key{
+		{
+			KeyFor("/repo/", "claude", "execute_bash", map[string]any{"command": "npm   test"}),
+			KeyFor("/repo", "claude", "execute_bash", map[string]any{"command": "npm test "}),
+		},
+		{
+			KeyFor("/repo", "claude", "execute_bash", map[string]any{"command": "go test ./... &&  go vet ./..."}),
+			KeyFor("/repo", "claude", "execute_bash", map[string]any{"command": "go test ./...&&go vet ./..."}),
+		},
+		{
+			KeyFor("/repo", "claude", "fs_write", map[string]any{"path": "src/a.go"}),
+			KeyFor("/repo", "claude", "fs_write", map[string]any{"file_path": "/repo/src/./a.go"}),
+		},
+		{
+			KeyFor("/repo", "goose", "use_aws", map[string]any{"b": 1, "a": "x"}),
+			KeyFor("/repo", "goose", "use_aws", map[string]any{"a": "x", "b": 1}),
+		},
+	}
+	for _, p := range same {
+		if p[0] != p[1] {
+			t.Errorf("keys differ: %+v and %+v", p[0], p[1])
+		}
+	}
+
+	different := [][2]Key{
+		{
+			KeyFor("/repo", "claude", "execute_bash", map[string]any{"command": "npm test"}),
+			KeyFor("/repo", "claude", "execute_bash", map[string]any{"command": "npm test; rm -rf /"}),
+		},
+		{
+			KeyFor("/repo", "claude", "execute_bash", map[string]any{"command": `echo "a  b"`}),
+			KeyFor("/repo", "claude", "execute_bash", map[string]any{"command": `echo "a b"`}),
+		},
+		{
+			KeyFor("/repo", "claude", "execute_bash", map[string]any{"command": "npm test"}),
+			KeyFor("/other", "claude", "execute_bash", map[string]any{"command": "npm test"}),
+		},
+		{
+			KeyFor("/repo", "claude", "execute_bash", map[string]any{"command": "npm test"}),
+			KeyFor("/repo", "codex", "execute_bash", map[string]any{"command": "npm test"}),
+		},
+		{
+			KeyFor("/repo", "claude", "fs_read", map[string]any{"path": "a.go"}),
+			KeyFor("/repo", "claude", "fs_write", map[string]any{"path": "a.go"}),
+		},
+	}
+	for _, p := range different {
+		if p[0] == p[1] {
+			t.Errorf("keys should differ: %+v", p[0])
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		kind  string
+		input map[string]any
+		tool  string
+	}{
+		{"", map[string]any{"command": "ls"}, "execute_bash"},
+		{"execute", map[string]any{"command": "ls"}, "execute_bash"},
+		{"read", map[string]any{"file_path": "/a", "offset": 10}, "fs_read"},
+		{"", map[string]any{"file_path": "/a", "content": "x"}, "fs_write"},
+		{"", map[string]any{"path": "/a", "old_string": "x", "new_string": "y"}, "fs_write"},
+		{"edit", map[string]any{"path": "/a"}, "fs_write"},
+		{"delete", map[string]any{"path": "/a"}, "fs_delete"},
+		{"move", map[string]any{"path": "/a", "destination": "/b"}, "fs_move"},
+		// a path alone says nothing about what the tool does with it
+		{"", map[string]any{"path": "/a"}, UnknownTool},
+		{"other", map[string]any{"path": "/a"}, UnknownTool},
+		{"read", map[string]any{"path": "/a", "content": "x"}, UnknownTool},
+		{"", map[string]any{"url": "https://example.com"}, ""},
+	}
+	for _, tt := range tests {
+		if tool, _, _ := Classify(tt.kind, tt.input); tool != tt.tool {
+			t.Errorf("%s %v: got %q, want %q", tt.kind, tt.input, tool, tt.tool)
+		}
+	}
+	if ToolName("command:exec") != "execute_bash" || ToolName("tool:web_fetch") != "web_fetch" {
+		t.Error("ToolName does not invert the hook's permission types")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "remembered.json")
+	m := NewMemory(file)
+	key := KeyFor("/repo", "claude", "execute_bash", map[string]any{"command": "npm test"})
+
+	if _, ok := m.Lookup(key); ok {
+		t.Fatal("empty memory matched")
+	}
+	first := m.Remember(key, true, "web-1")
+	second := m.Remember(key, false, "local")
+	if list := m.List(); len(list) != 1 || list[0].ID != second.ID || list[0].Approved {
+		t.Fatalf("remembering again should replace: %+v", list)
+	}
+
+	// Decisions survive a restart, with their use counts
+	m = NewMemory(file)
+	r, ok := m.Lookup(key)
+	if !ok || r.ID != second.ID || r.Approved || r.Uses != 1 {
+		t.Fatalf("lookup = %+v, %v", r, ok)
+	}
+	if _, ok := m.Forget(first.ID); ok {
+		t.Error("forgot a replaced decision")
+	}
+	if _, ok := m.Forget(second.ID); !ok {
+		t.Error("forget failed")
+	}
+	if _, ok := NewMemory(file).Lookup(key); ok {
+		t.Error("forgotten decision still applies")
+	}
+}
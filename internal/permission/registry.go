@@ -0,0 +1,150 @@
+package permission
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Origins of a pending request
+const (
+	OriginHook = "hook" // the hook server; answered through Handler
+	OriginACP  = "acp"  // an ACP session; answered with one of Options
+)
+
+// Pending is a permission request waiting for an answer
+type Pending struct {
+	ID             string         `json:"id"`
+	RawID          any            `json:"-"` // ACP requests are answered with the JSON-RPC ID as sent
+	Origin         string         `json:"origin"`
+	SessionID      string         `json:"sessionId,omitempty"`
+	Protocol       string         `json:"protocol,omitempty"`
+	Tool           string         `json:"toolName,omitempty"`
+	PermissionType string         `json:"permissionType,omitempty"` // hook requests
+	Description    string         `json:"description,omitempty"`
+	Risk           string         `json:"risk,omitempty"`
+	Input          map[string]any `json:"toolInput,omitempty"`
+	Options        []string       `json:"options,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	Deadline       time.Time      `json:"deadline"`
+}
+
+// Registry holds the pending permission requests of every origin until they
+// are answered, dropped or time out. Requests are kept by session and ID:
+// ACP IDs are the agent's JSON-RPC IDs, which repeat across sessions.
+type Registry struct {
+	mu        sync.Mutex
+	items     map[pendingKey]*Pending
+	timers    map[pendingKey]*time.Timer
+	onTimeout func(p Pending)
+}
+
+type pendingKey struct {
+	sessionID, id string
+}
+
+// NewRegistry creates an empty registry. onTimeout is called, without locks
+// held, with a request whose deadline passed; it is no longer pending then.
+func NewRegistry(onTimeout func(p Pending)) *Registry {
+	return &Registry{items: make(map[pendingKey]*Pending), timers: make(map[pendingKey]*time.Timer), onTimeout: onTimeout}
+}
+
+// Add registers a request that times out after timeout, replacing a
+// request of the same session with the same ID
+func (r *Registry) Add(p Pending, timeout time.Duration) Pending {
+	p.CreatedAt = time.Now()
+	p.Deadline = p.CreatedAt.Add(timeout)
+
+	key := pendingKey{p.SessionID, p.ID}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t := r.timers[key]; t != nil {
+		t.Stop()
+	}
+	r.items[key] = &p
+	r.timers[key] = time.AfterFunc(timeout, func() {
+		if expired, ok := r.Remove(key.sessionID, key.id); ok && r.onTimeout != nil {
+			r.onTimeout(expired)
+		}
+	})
+	return p
+}
+
+// Get returns a pending request of a session
+func (r *Registry) Get(sessionID, id string) (Pending, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.items[pendingKey{sessionID, id}]; ok {
+		return *p, true
+	}
+	return Pending{}, false
+}
+
+// Remove takes a request out of the registry; only one caller gets ok for
+// each request, so it can be answered exactly once
+func (r *Registry) Remove(sessionID, id string) (Pending, bool) {
+	key := pendingKey{sessionID, id}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.items[key]
+	if !ok {
+		return Pending{}, false
+	}
+	delete(r.items, key)
+	if t := r.timers[key]; t != nil {
+		t.Stop()
+		delete(r.timers, key)
+	}
+	return *p, true
+}
+
+// RemoveSession drops the ACP requests of a session that ended; nobody is
+// left to answer them
+func (r *Registry) RemoveSession(sessionID string) []Pending {
+	var dropped []Pending
+	for _, p := range r.List() {
+		if p.Origin == OriginACP && p.SessionID == sessionID {
+			if p, ok := r.Remove(p.SessionID, p.ID); ok {
+				dropped = append(dropped, p)
+			}
+		}
+	}
+	return dropped
+}
+
+// List returns the pending requests, oldest first
+func (r *Registry) List() []Pending {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]Pending, 0, len(r.items))
+	for _, p := range r.items {
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		if list[i].ID != list[j].ID {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].SessionID < list[j].SessionID
+	})
+	return list
+}
+
+// Len returns the number of pending requests
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.items)
+}
+
+// Stop cancels every deadline
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.timers {
+		t.Stop()
+	}
+	r.timers = make(map[pendingKey]*time.Timer)
+}
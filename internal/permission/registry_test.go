@@ -0,0 +1,81 @@
+package permission
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	timedOut := make(chan Pending, 1)
+	r := NewRegistry(func(p Pending) { timedOut <- p })
+	defer r.Stop()
+
+	r.Add(Pending{ID: "h1", Origin: OriginHook, SessionID: "s1", Risk: "low"}, time.Minute)
+	r.Add(Pending{ID: "a1", Origin: OriginACP, SessionID: "s1", RawID: float64(1)}, time.Minute)
+	r.Add(Pending{ID: "a2", Origin: OriginACP, SessionID: "s2"}, 20*time.Millisecond)
+	// Agents number their requests alike; another session's doesn't replace it
+	r.Add(Pending{ID: "a1", Origin: OriginACP, SessionID: "s3", RawID: float64(1)}, time.Minute)
+
+	p, ok := r.Get("s1", "a1")
+	if !ok || p.RawID != float64(1) || p.Deadline.Sub(p.CreatedAt) != time.Minute {
+		t.Fatalf("get = %+v, %v", p, ok)
+	}
+	if p, ok := r.Get("s3", "a1"); !ok || p.SessionID != "s3" {
+		t.Fatalf("get of the other session's request = %+v, %v", p, ok)
+	}
+	if _, ok := r.Get("", "a1"); ok {
+		t.Error("a request was found without its session")
+	}
+
+	select {
+	case p := <-timedOut:
+		if p.ID != "a2" {
+			t.Fatalf("timed out %s", p.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("deadline passed without a timeout")
+	}
+	if _, ok := r.Remove("s2", "a2"); ok {
+		t.Error("a timed out request can be answered again")
+	}
+
+	// Only the session's ACP requests go with it; hooks still wait
+	if dropped := r.RemoveSession("s1"); len(dropped) != 1 || dropped[0].ID != "a1" {
+		t.Errorf("dropped %+v", dropped)
+	}
+	if _, ok := r.Remove("s1", "h1"); !ok {
+		t.Error("hook request was not pending")
+	}
+	if _, ok := r.Remove("s1", "h1"); ok {
+		t.Error("removed twice")
+	}
+	if _, ok := r.Remove("s3", "a1"); !ok {
+		t.Error("the other session's request was not pending")
+	}
+	if r.Len() != 0 {
+		t.Errorf("left %+v", r.List())
+	}
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	h := NewHandler()
+	h.SetTimeout(time.Second)
+	h.OnTimeout(func(req Request) bool { return req.Risk == "low" })
+
+	var seen Request
+	h.OnRequest(func(req Request) { seen = req })
+	if approved, _ := h.Submit(Request{ID: "1", Risk: "low", Timeout: 60}); !approved {
+		t.Error("low-risk default should approve")
+	}
+	if seen.Timeout != 1 {
+		t.Errorf("request timeout = %d, want the handler's", seen.Timeout)
+	}
+
+	h.OnRequest(func(req Request) {
+		h.Resolve(Response{ID: req.ID, Approved: true})
+		h.Resolve(Response{ID: req.ID, Approved: false}) // must not block
+	})
+	if approved, _ := h.Submit(Request{ID: "2", Risk: "high"}); !approved {
+		t.Error("first answer should win")
+	}
+}
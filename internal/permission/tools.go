@@ -0,0 +1,68 @@
+package permission
+
+import "strings"
+
+// pathKeys name the file of a tool input
+var pathKeys = []string{"path", "file_path", "filePath", "abs_path"}
+
+// readOnlyKeys are the only keys a file read has; anything else may write
+var readOnlyKeys = map[string]bool{
+	"path": true, "file_path": true, "filePath": true, "abs_path": true,
+	"offset": true, "limit": true, "line": true, "start_line": true, "end_line": true,
+}
+
+// UnknownTool is the tool of a call Classify cannot tell apart from others
+const UnknownTool = "unknown"
+
+// Classify maps an agent's tool call onto the bridge's tools. kind is the
+// ACP tool kind ("read", "edit", "delete", "move", "execute", ...) or empty
+// when the agent sent none. A command is execute_bash; a file path is
+// fs_read only when the kind says it is a read, fs_write for an edit or a
+// path with anything else, fs_delete and fs_move for those kinds, and
+// unknown otherwise, so that what was decided for reading a file does not
+// carry over to other tools on the same path.
+func Classify(kind string, input map[string]any) (tool, path, command string) {
+	if c, ok := input["command"].(string); ok {
+		if kind == "" || kind == "execute" {
+			return "execute_bash", "", c
+		}
+		return UnknownTool, "", c
+	}
+	for _, key := range pathKeys {
+		if p, ok := input[key].(string); ok {
+			readOnly := true
+			for k := range input {
+				if !readOnlyKeys[k] {
+					readOnly = false
+				}
+			}
+			switch {
+			case kind == "read" && readOnly:
+				return "fs_read", p, ""
+			case kind == "edit", kind == "" && !readOnly:
+				return "fs_write", p, ""
+			case kind == "delete":
+				return "fs_delete", p, ""
+			case kind == "move":
+				return "fs_move", p, ""
+			}
+			return UnknownTool, p, ""
+		}
+	}
+	return "", "", ""
+}
+
+// ToolName returns the tool a hook request's permission type was made from
+func ToolName(permissionType string) string {
+	switch permissionType {
+	case "file:read":
+		return "fs_read"
+	case "file:write":
+		return "fs_write"
+	case "command:exec":
+		return "execute_bash"
+	case "aws:api":
+		return "use_aws"
+	}
+	return strings.TrimPrefix(permissionType, "tool:")
+}
@@ -0,0 +1,157 @@
+// Package policy decides which sessions a device may run: enabled CLIs,
+// allowed workDir roots, per-CLI session caps and allowed permission modes.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Violation codes
+const (
+	CodeCLIDisabled    = "cli_disabled"
+	CodeWorkDir        = "workdir_not_allowed"
+	CodeMaxSessions    = "max_sessions"
+	CodePermissionMode = "permission_mode_not_allowed"
+)
+
+// DefaultPermissionMode is the mode of sessions that don't request one
+const DefaultPermissionMode = "default"
+
+// Config is the "policy" section of the device config
+type Config struct {
+	WorkDirRoots           []string       `json:"workDirRoots,omitempty"`           // sessions must run under one of these (~ expands to home); empty = anywhere
+	MaxSessionsPerCLI      map[string]int `json:"maxSessionsPerCli,omitempty"`      // active sessions per CLI type, "*" for CLIs without their own entry; 0 = unlimited
+	AllowedPermissionModes []string       `json:"allowedPermissionModes,omitempty"` // empty = all modes
+}
+
+// Policy is the effective device policy. A nil Policy allows everything.
+type Policy struct {
+	CLIEnabled map[string]bool // a CLI set to false is disabled; CLIs not listed are allowed
+	Config
+}
+
+// New builds the policy from the synced CLI switches and the policy config
+func New(cliEnabled map[string]bool, cfg *Config) *Policy {
+	p := &Policy{CLIEnabled: cliEnabled}
+	if cfg != nil {
+		p.Config = *cfg
+	}
+	return p
+}
+
+// Violation is a request the policy refuses
+type Violation struct {
+	Code    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return "policy: " + v.Message
+}
+
+// Check validates the CLI, workDir and permission mode of a session
+func (p *Policy) Check(cliType, workDir, permissionMode string) error {
+	if p == nil {
+		return nil
+	}
+	if enabled, ok := p.CLIEnabled[cliType]; ok && !enabled {
+		return &Violation{CodeCLIDisabled, fmt.Sprintf("CLI %s is disabled on this device", cliType)}
+	}
+	if !p.AllowsWorkDir(workDir) {
+		return &Violation{CodeWorkDir, fmt.Sprintf("workDir %s is outside the allowed roots %v", workDir, p.WorkDirRoots)}
+	}
+	if !p.AllowsPermissionMode(permissionMode) {
+		if permissionMode == "" {
+			permissionMode = DefaultPermissionMode
+		}
+		return &Violation{CodePermissionMode, fmt.Sprintf("permission mode %s is not allowed (allowed: %s)", permissionMode, strings.Join(p.AllowedPermissionModes, ", "))}
+	}
+	return nil
+}
+
+// CheckCapacity validates that another session of cliType may start while
+// active sessions of that CLI are running
+func (p *Policy) CheckCapacity(cliType string, active int) error {
+	if p == nil {
+		return nil
+	}
+	limit, ok := p.MaxSessionsPerCLI[cliType]
+	if !ok {
+		limit = p.MaxSessionsPerCLI["*"]
+	}
+	if limit > 0 && active >= limit {
+		return &Violation{CodeMaxSessions, fmt.Sprintf("CLI %s already has %d active session(s), the maximum on this device", cliType, active)}
+	}
+	return nil
+}
+
+// AllowsPermissionMode reports whether sessions may use a permission mode
+func (p *Policy) AllowsPermissionMode(mode string) bool {
+	if p == nil || len(p.AllowedPermissionModes) == 0 {
+		return true
+	}
+	if mode == "" {
+		mode = DefaultPermissionMode
+	}
+	for _, allowed := range p.AllowedPermissionModes {
+		if allowed == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsWorkDir reports whether dir lies under one of the allowed roots.
+// Symlinks are resolved so a link inside a root can't point outside it.
+func (p *Policy) AllowsWorkDir(dir string) bool {
+	if p == nil || len(p.WorkDirRoots) == 0 {
+		return true
+	}
+	resolved := resolve(dir)
+	if resolved == "" {
+		return false
+	}
+	for _, root := range p.WorkDirRoots {
+		if r := resolve(expandHome(root)); r != "" && within(resolved, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the absolute, symlink-free form of path ("" if it can't be made absolute)
+func resolve(path string) string {
+	if path == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = real
+	}
+	return abs
+}
+
+func within(path, root string) bool {
+	if runtime.GOOS == "windows" {
+		path, root = strings.ToLower(path), strings.ToLower(root)
+	}
+	return path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(home, strings.TrimPrefix(p, "~"))
+	}
+	return p
+}
@@ -0,0 +1,104 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func code(err error) string {
+	var v *Violation
+	if errors.As(err, &v) {
+		return v.Code
+	}
+	return ""
+}
+
+func TestNilPolicyAllowsEverything(t *testing.T) {
+	var p *Policy
+	if err := p.Check("anything", "/", "accept-all"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CheckCapacity("anything", 100); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCLIEnabled(t *testing.T) {
+	p := New(map[string]bool{"claude": true, "codex": false}, nil)
+	if err := p.Check("claude", ".", ""); err != nil {
+		t.Errorf("enabled CLI refused: %v", err)
+	}
+	if c := code(p.Check("codex", ".", "")); c != CodeCLIDisabled {
+		t.Errorf("disabled CLI: code = %q", c)
+	}
+	// CLIs the admin never listed stay usable
+	if err := p.Check("goose", ".", ""); err != nil {
+		t.Errorf("unlisted CLI refused: %v", err)
+	}
+}
+
+func TestWorkDirRoots(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "project")
+	outside := t.TempDir()
+	if err := os.Mkdir(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "escape")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	p := New(nil, &Config{WorkDirRoots: []string{root}})
+	for dir, want := range map[string]bool{
+		root:                      true,
+		project:                   true,
+		outside:                   false,
+		link:                      false, // symlink out of the root
+		root + "-sibling":         false, // prefix but not a child
+		filepath.Join(root, ".."): false,
+	} {
+		if got := p.AllowsWorkDir(dir); got != want {
+			t.Errorf("AllowsWorkDir(%s) = %v, want %v", dir, got, want)
+		}
+	}
+	if c := code(p.Check("claude", outside, "")); c != CodeWorkDir {
+		t.Errorf("code = %q", c)
+	}
+}
+
+func TestPermissionModes(t *testing.T) {
+	p := New(nil, &Config{AllowedPermissionModes: []string{"default", "plan"}})
+	if err := p.Check("claude", ".", ""); err != nil {
+		t.Errorf("empty mode should count as default: %v", err)
+	}
+	if err := p.Check("claude", ".", "plan"); err != nil {
+		t.Error(err)
+	}
+	for _, mode := range []string{"accept-all", "sandbox"} {
+		if c := code(p.Check("claude", ".", mode)); c != CodePermissionMode {
+			t.Errorf("%s: code = %q", mode, c)
+		}
+	}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	p := New(nil, &Config{MaxSessionsPerCLI: map[string]int{"*": 2, "claude": 1, "codex": 0}})
+	if err := p.CheckCapacity("claude", 0); err != nil {
+		t.Error(err)
+	}
+	if c := code(p.CheckCapacity("claude", 1)); c != CodeMaxSessions {
+		t.Errorf("claude at cap: code = %q", c)
+	}
+	if err := p.CheckCapacity("gemini", 1); err != nil {
+		t.Errorf("default cap applied too early: %v", err)
+	}
+	if c := code(p.CheckCapacity("gemini", 2)); c != CodeMaxSessions {
+		t.Errorf("gemini at default cap: code = %q", c)
+	}
+	if err := p.CheckCapacity("codex", 10); err != nil {
+		t.Errorf("0 should mean unlimited: %v", err)
+	}
+}
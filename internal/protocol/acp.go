@@ -2,6 +2,10 @@ package protocol
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,7 +18,9 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/open-agents/bridge/internal/limits"
 	"github.com/open-agents/bridge/internal/logger"
+	"github.com/open-agents/bridge/internal/sandbox"
 )
 
 // terminalState stores the state of a terminal command
@@ -41,9 +47,15 @@ type ACPAdapter struct {
 	workDir    string
 	terminals  map[string]*terminalState // terminalId -> state
 	terminalMu sync.RWMutex
+	limits     *limits.Group // resource limits for the CLI and terminal commands
+	sandbox    *sandbox.Spec // sandbox of the CLI process (nil = unconfined)
+	cmdSandbox *sandbox.Spec // sandbox for terminal commands (nil = unconfined)
 	// Token usage tracking (estimated)
 	inputTokens  atomic.Int64
 	outputTokens atomic.Int64
+	// Turn tracking: request ID of the session/prompt in flight (0 = idle) and its last reported status
+	turnID     atomic.Int64
+	turnStatus atomic.Value // AgentStatus
 	// Signal channels for initialization sequencing
 	initDone chan struct{} // closed when initialize response received
 }
@@ -72,6 +84,9 @@ func (a *ACPAdapter) Connect(config AdapterConfig) error {
 
 	// Store work directory for session/new
 	a.workDir = config.WorkDir
+	a.limits = config.Limits
+	a.sandbox = config.Sandbox
+	a.cmdSandbox = config.CommandSandbox
 
 	// Start CLI process
 	a.cmd = exec.Command(config.Command, config.Args...)
@@ -117,11 +132,11 @@ func (a *ACPAdapter) Connect(config AdapterConfig) error {
 	}
 
 	// Start process
-	if err := a.cmd.Start(); err != nil {
+	if err := startConfined(a.cmd, a.sandbox, a.limits, a.cmd.Start); err != nil {
 		return fmt.Errorf("failed to start process: %w", err)
 	}
 
-	logger.Info("[ACP] Process started (PID: %d)", a.cmd.Process.Pid)
+	logger.Info("[ACP] Process started (PID: %d, sandboxed: %v)", a.cmd.Process.Pid, a.sandbox != nil)
 	a.connected.Store(true)
 
 	// Start reading messages
@@ -237,10 +252,16 @@ initialized:
 
 		log.Printf("[ACP] Sending prompt to session %s: %s", a.sessionID, content)
 
+		// The turn lasts until the agent answers this request with a stopReason
+		id := a.nextRequestID()
+		a.turnID.Store(id)
+		a.turnStatus.Store(StatusIdle)
+		a.setTurnStatus(StatusThinking)
+
 		// ACP session/prompt expects prompt as an array of content objects
 		req := map[string]interface{}{
 			"jsonrpc": "2.0",
-			"id":      a.nextRequestID(),
+			"id":      id,
 			"method":  "session/prompt",
 			"params": map[string]interface{}{
 				"sessionId": a.sessionID,
@@ -253,7 +274,11 @@ initialized:
 			},
 		}
 
-		return a.sendJSONRPC(req)
+		if err := a.sendJSONRPC(req); err != nil {
+			a.finishTurn(id, "error")
+			return err
+		}
+		return nil
 
 	case MessageTypePermission:
 		// Handle permission response
@@ -388,14 +413,46 @@ func (a *ACPAdapter) monitorProcess() {
 	// Wait for process to exit
 	err := a.cmd.Wait()
 
-	// Mark as disconnected
-	a.connected.Store(false)
+	// Mark as disconnected; still connected means nobody asked it to stop
+	unexpected := a.connected.Swap(false)
 
 	if err != nil {
 		log.Printf("[ACP] Process exited with error: %v", err)
 	} else {
 		log.Printf("[ACP] Process exited normally")
 	}
+
+	le := a.limits.Exceeded()
+	if le == nil && unexpected {
+		le = a.limits.ExitReason(a.cmd.ProcessState)
+	}
+	if le != nil {
+		a.emitLimitError(le)
+	} else if unexpected && err != nil {
+		a.emitMessage(Message{
+			Type:    MessageTypeError,
+			Content: fmt.Sprintf("agent process exited unexpectedly: %v", err),
+			Meta: map[string]interface{}{
+				"protocol":  "acp",
+				"source":    "exit",
+				"exit_code": a.cmd.ProcessState.ExitCode(),
+			},
+		})
+	}
+}
+
+// emitLimitError reports a resource limit hit as an error message
+func (a *ACPAdapter) emitLimitError(le *limits.LimitError) {
+	logger.Warn("[ACP] %v", le)
+	a.emitMessage(Message{
+		Type:    MessageTypeError,
+		Content: le.Error(),
+		Meta: map[string]interface{}{
+			"protocol": "acp",
+			"source":   "limit",
+			"limit":    le.Limit,
+		},
+	})
 }
 
 // readMessages reads JSON-RPC messages from stdout
@@ -528,6 +585,7 @@ func (a *ACPAdapter) processSessionUpdate(update map[string]interface{}) {
 
 		// Track output tokens
 		a.outputTokens.Add(estimateTokens(text))
+		a.setTurnStatus(StatusStreaming)
 
 		a.emitMessage(Message{
 			Type:    MessageTypeContent,
@@ -546,6 +604,7 @@ func (a *ACPAdapter) processSessionUpdate(update map[string]interface{}) {
 
 		// Track output tokens for thinking as well
 		a.outputTokens.Add(estimateTokens(text))
+		a.setTurnStatus(StatusThinking)
 
 		a.emitMessage(Message{
 			Type:    MessageTypeThought,
@@ -569,12 +628,19 @@ func (a *ACPAdapter) processSessionUpdate(update map[string]interface{}) {
 		if status == "" {
 			status = "pending"
 		}
+		rawInput, _ := update["rawInput"].(map[string]interface{})
+		kind, _ := update["kind"].(string)
+		content, _ := update["content"].([]interface{})
+		a.setTurnStatus(StatusToolExecuting)
 		a.emitMessage(Message{
 			Type: MessageTypeToolCall,
 			Content: ToolCall{
-				ID:     toolCallID,
-				Name:   title,
-				Status: status,
+				ID:      toolCallID,
+				Name:    title,
+				Input:   rawInput,
+				Status:  status,
+				Kind:    kind,
+				Content: content,
 			},
 			Meta: map[string]interface{}{
 				"protocol": "acp",
@@ -587,12 +653,24 @@ func (a *ACPAdapter) processSessionUpdate(update map[string]interface{}) {
 			toolCallID, _ = update["id"].(string) // fallback
 		}
 		status, _ := update["status"].(string)
+		title, _ := update["title"].(string)
+		rawInput, _ := update["rawInput"].(map[string]interface{})
+		kind, _ := update["kind"].(string)
+		content, _ := update["content"].([]interface{})
+		result := update["result"]
+		if result == nil {
+			result = update["rawOutput"]
+		}
 		a.emitMessage(Message{
 			Type: MessageTypeToolCall,
 			Content: ToolCall{
-				ID:     toolCallID,
-				Status: status,
-				Result: update["result"],
+				ID:      toolCallID,
+				Name:    title,
+				Input:   rawInput,
+				Status:  status,
+				Result:  result,
+				Kind:    kind,
+				Content: content,
 			},
 			Meta: map[string]interface{}{
 				"protocol": "acp",
@@ -600,32 +678,67 @@ func (a *ACPAdapter) processSessionUpdate(update map[string]interface{}) {
 		})
 
 	case "end_turn":
-		// Send status update
-		a.emitMessage(Message{
-			Type:    MessageTypeStatus,
-			Content: StatusIdle,
-			Meta: map[string]interface{}{
-				"protocol": "acp",
-			},
-		})
+		a.finishTurn(a.turnID.Load(), "end_turn")
+	}
+}
 
-		// Send usage statistics
-		inputTokens := a.inputTokens.Load()
-		outputTokens := a.outputTokens.Load()
-		a.emitMessage(Message{
-			Type: MessageTypeUsage,
-			Content: UsageStats{
-				InputTokens:   int(inputTokens),
-				OutputTokens:  int(outputTokens),
-				CacheCreation: 0, // Not available from ACP
-				CacheRead:     0, // Not available from ACP
-				ContextSize:   int(inputTokens + outputTokens),
-			},
-			Meta: map[string]interface{}{
-				"protocol": "acp",
-			},
-		})
+// setTurnStatus reports a status change of the turn in flight (repeats are dropped)
+func (a *ACPAdapter) setTurnStatus(status AgentStatus) {
+	if a.turnID.Load() == 0 {
+		return
+	}
+	if prev, _ := a.turnStatus.Swap(status).(AgentStatus); prev == status {
+		return
 	}
+	a.emitMessage(Message{
+		Type:    MessageTypeStatus,
+		Content: status,
+		Meta: map[string]interface{}{
+			"protocol": "acp",
+		},
+	})
+}
+
+// finishTurn ends the turn started by prompt request id: the agent goes idle
+// and usage is reported. Late answers to an older prompt are ignored.
+func (a *ACPAdapter) finishTurn(id int64, stopReason string) {
+	if id == 0 || !a.turnID.CompareAndSwap(id, 0) {
+		return
+	}
+	a.turnStatus.Store(StatusIdle)
+
+	// Send status update
+	a.emitMessage(Message{
+		Type:    MessageTypeStatus,
+		Content: StatusIdle,
+		Meta: map[string]interface{}{
+			"protocol":   "acp",
+			"stopReason": stopReason,
+		},
+	})
+
+	// Send usage statistics
+	inputTokens := a.inputTokens.Load()
+	outputTokens := a.outputTokens.Load()
+	a.emitMessage(Message{
+		Type: MessageTypeUsage,
+		Content: UsageStats{
+			InputTokens:   int(inputTokens),
+			OutputTokens:  int(outputTokens),
+			CacheCreation: 0, // Not available from ACP
+			CacheRead:     0, // Not available from ACP
+			ContextSize:   int(inputTokens + outputTokens),
+		},
+		Meta: map[string]interface{}{
+			"protocol": "acp",
+		},
+	})
+}
+
+// rpcID returns the numeric ID of a JSON-RPC message (0 if absent)
+func rpcID(msg map[string]interface{}) int64 {
+	id, _ := msg["id"].(float64)
+	return int64(id)
 }
 
 // handlePermissionRequest processes permission requests
@@ -634,6 +747,7 @@ func (a *ACPAdapter) handlePermissionRequest(msg map[string]interface{}) {
 	if !ok {
 		return
 	}
+	a.setTurnStatus(StatusPermissionPending)
 
 	// ACP permission request format
 	// id is at root level for JSON-RPC request
@@ -647,6 +761,7 @@ func (a *ACPAdapter) handlePermissionRequest(msg map[string]interface{}) {
 	toolCall, _ := params["toolCall"].(map[string]interface{})
 	toolCallID, _ := toolCall["toolCallId"].(string)
 	title, _ := toolCall["title"].(string)
+	kind, _ := toolCall["kind"].(string)
 	rawInput, _ := toolCall["rawInput"].(map[string]interface{})
 
 	// Options - array of objects with optionId
@@ -684,6 +799,7 @@ func (a *ACPAdapter) handlePermissionRequest(msg map[string]interface{}) {
 		Content: PermissionRequest{
 			ID:          id,
 			ToolName:    title,
+			Kind:        kind,
 			ToolInput:   rawInput,
 			Description: title,
 			Risk:        risk,
@@ -767,6 +883,20 @@ func (a *ACPAdapter) handleFileWrite(msg map[string]interface{}) {
 	content, _ := params["content"].(string)
 	log.Printf("[ACP] File write request: id=%v, path=%s", reqID, path)
 
+	// The bridge writes on the agent's behalf, so enforce the sandbox here too
+	if a.sandbox != nil && !a.sandbox.CanWrite(path) {
+		log.Printf("[ACP] Write outside sandbox refused: %s", path)
+		a.sendJSONRPC(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      reqID,
+			"error": map[string]interface{}{
+				"code":    -32603,
+				"message": fmt.Sprintf("%s: read-only outside the sandboxed workDir", path),
+			},
+		})
+		return
+	}
+
 	// Create directory if needed
 	dir := filepath.Dir(path)
 	if dir != "" && dir != "." {
@@ -775,6 +905,7 @@ func (a *ACPAdapter) handleFileWrite(msg map[string]interface{}) {
 
 	// Write file content
 	err := os.WriteFile(path, []byte(content), 0644)
+	a.emitFileWrite(path, content, err)
 	if err != nil {
 		// Send error response
 		errResp := map[string]interface{}{
@@ -798,6 +929,16 @@ func (a *ACPAdapter) handleFileWrite(msg map[string]interface{}) {
 	a.sendJSONRPC(response)
 }
 
+// emitFileWrite reports a file written on the agent's behalf
+func (a *ACPAdapter) emitFileWrite(path, content string, err error) {
+	sum := sha256.Sum256([]byte(content))
+	meta := map[string]interface{}{"path": path, "bytes": len(content), "sha256": hex.EncodeToString(sum[:])}
+	if err != nil {
+		meta["error"] = err.Error()
+	}
+	a.emitMessage(Message{Type: MessageTypeActivity, Content: ActivityFileWrite, Meta: meta})
+}
+
 // handleTerminalCreate processes terminal creation and command execution requests
 func (a *ACPAdapter) handleTerminalCreate(msg map[string]interface{}) {
 	params, ok := msg["params"].(map[string]interface{})
@@ -857,6 +998,12 @@ func (a *ACPAdapter) handleTerminalCreate(msg map[string]interface{}) {
 	}
 	a.sendJSONRPC(response)
 
+	a.emitMessage(Message{
+		Type:    MessageTypeActivity,
+		Content: ActivityCommand,
+		Meta:    map[string]interface{}{"terminalId": terminalID, "command": command, "cwd": a.workDir},
+	})
+
 	// Execute the command in background
 	go a.executeTerminalCommand(terminalID, command, env, outputByteLimit)
 }
@@ -865,12 +1012,31 @@ func (a *ACPAdapter) handleTerminalCreate(msg map[string]interface{}) {
 func (a *ACPAdapter) executeTerminalCommand(terminalID, command string, env []string, outputLimit int) {
 	log.Printf("[ACP] Executing command: %s", command)
 
+	// Wall-clock limit for terminal commands
+	ctx := context.Background()
+	if timeout := a.limits.Limits().CommandTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	// Execute command
-	cmd := exec.Command("sh", "-c", command)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Env = env
 	cmd.Dir = a.workDir
-
-	output, err := cmd.CombinedOutput()
+	// Don't hang on grandchildren keeping the output pipe open after a kill
+	cmd.WaitDelay = 5 * time.Second
+
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	err := startConfined(cmd, a.cmdSandbox, a.limits, cmd.Start)
+	if err == nil {
+		err = cmd.Wait()
+	} else {
+		buf.WriteString(err.Error())
+	}
+	output := buf.Bytes()
 
 	// Truncate output if needed
 	truncated := false
@@ -890,6 +1056,19 @@ func (a *ACPAdapter) executeTerminalCommand(terminalID, command string, env []st
 		}
 	}
 
+	// Report resource limit hits
+	if ctx.Err() == context.DeadlineExceeded {
+		signal = "SIGKILL"
+		a.emitLimitError(&limits.LimitError{
+			Limit:  "wall_clock",
+			Detail: fmt.Sprintf("command killed after %v: %s", a.limits.Limits().CommandTimeout(), command),
+		})
+	} else if le := a.limits.Exceeded(); le != nil {
+		a.emitLimitError(le)
+	} else if le := a.limits.ExitReason(cmd.ProcessState); le != nil {
+		a.emitLimitError(le)
+	}
+
 	// Store result in terminal state
 	a.terminalMu.Lock()
 	if state, ok := a.terminals[terminalID]; ok {
@@ -903,6 +1082,11 @@ func (a *ACPAdapter) executeTerminalCommand(terminalID, command string, env []st
 	a.terminalMu.Unlock()
 
 	log.Printf("[ACP] Command completed: terminalId=%s, len=%d, exitCode=%d", terminalID, len(output), exitCode)
+	exit := map[string]interface{}{"terminalId": terminalID, "command": command, "exitCode": exitCode}
+	if signal != "" {
+		exit["signal"] = signal
+	}
+	a.emitMessage(Message{Type: MessageTypeActivity, Content: ActivityCommandExit, Meta: exit})
 
 	// Clean up old terminals after a delay
 	go func() {
@@ -913,6 +1097,17 @@ func (a *ACPAdapter) executeTerminalCommand(terminalID, command string, env []st
 	}()
 }
 
+// exitStatus builds the ACP exit status object for a finished terminal
+func exitStatus(state *terminalState) map[string]interface{} {
+	status := map[string]interface{}{
+		"exitCode": state.exitCode,
+	}
+	if state.signal != "" {
+		status["signal"] = state.signal
+	}
+	return status
+}
+
 // handleTerminalWaitForExit handles terminal/wait_for_exit requests
 func (a *ACPAdapter) handleTerminalWaitForExit(msg map[string]interface{}) {
 	params, ok := msg["params"].(map[string]interface{})
@@ -957,9 +1152,7 @@ func (a *ACPAdapter) handleTerminalWaitForExit(msg map[string]interface{}) {
 		"jsonrpc": "2.0",
 		"id":      reqID,
 		"result": map[string]interface{}{
-			"exitStatus": map[string]interface{}{
-				"exitCode": state.exitCode,
-			},
+			"exitStatus": exitStatus(state),
 		},
 	}
 	a.sendJSONRPC(response)
@@ -1011,11 +1204,9 @@ func (a *ACPAdapter) handleTerminalOutputRequest(msg map[string]interface{}) {
 		"jsonrpc": "2.0",
 		"id":      reqID,
 		"result": map[string]interface{}{
-			"output":    state.output,
-			"truncated": state.truncated,
-			"exitStatus": map[string]interface{}{
-				"exitCode": state.exitCode,
-			},
+			"output":     state.output,
+			"truncated":  state.truncated,
+			"exitStatus": exitStatus(state),
 		},
 	}
 	a.sendJSONRPC(response)
@@ -1056,6 +1247,13 @@ func (a *ACPAdapter) handleTerminalRelease(msg map[string]interface{}) {
 func (a *ACPAdapter) handleResponse(msg map[string]interface{}) {
 	result, _ := msg["result"].(map[string]interface{})
 
+	// Handle session/prompt response (the turn is over)
+	if stopReason, ok := result["stopReason"].(string); ok {
+		logger.Info("[ACP] Turn finished: %s", stopReason)
+		a.finishTurn(rpcID(msg), stopReason)
+		return
+	}
+
 	// Handle session/new response (contains sessionId)
 	if sessionID, ok := result["sessionId"].(string); ok {
 		a.sessionID = sessionID
@@ -1117,9 +1315,13 @@ func (a *ACPAdapter) handleError(msg map[string]interface{}) {
 		Content: message,
 		Meta: map[string]interface{}{
 			"protocol": "acp",
+			"source":   "rpc",
 			"code":     int(code),
 		},
 	})
+
+	// A failed session/prompt ends the turn
+	a.finishTurn(rpcID(msg), "error")
 }
 
 // emitMessage sends a message to the callback
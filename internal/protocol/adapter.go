@@ -1,5 +1,13 @@
 package protocol
 
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/open-agents/bridge/internal/limits"
+	"github.com/open-agents/bridge/internal/sandbox"
+)
+
 // Adapter defines the interface that all protocol adapters must implement
 type Adapter interface {
 	// Protocol information
@@ -23,6 +31,13 @@ type Adapter interface {
 	SupportsToolCalls() bool
 }
 
+// Terminal is implemented by adapters that drive a terminal: they take raw
+// keystrokes and follow the size of the terminal showing them
+type Terminal interface {
+	WriteRaw(data []byte) error
+	Resize(cols, rows int) error
+}
+
 // AdapterConfig contains configuration for protocol adapters
 type AdapterConfig struct {
 	WorkDir    string
@@ -33,4 +48,41 @@ type AdapterConfig struct {
 	Rows       int
 	CustomArgs []string
 	CustomEnv  map[string]string
+	Limits     *limits.Group // Resource limits for the CLI process and its commands (nil = none)
+	Protocol   string        // "acp", "pty", or "" / "auto" to try ACP then PTY
+
+	Sandbox        *sandbox.Spec // Namespace sandbox for the CLI process (nil = none)
+	CommandSandbox *sandbox.Spec // Namespace sandbox for terminal commands (nil = none)
+}
+
+// startConfined starts cmd via start, inside the sandbox if spec is set, and
+// under the resource limits: in the group's cgroup from the first
+// instruction, or with its rlimits applied before the sandboxed program is
+// released. If the limits can't be applied the process is killed.
+func startConfined(cmd *exec.Cmd, spec *sandbox.Spec, group *limits.Group, start func() error) error {
+	release := func() {}
+	if spec != nil {
+		r, err := sandbox.Wrap(cmd, *spec)
+		if err != nil {
+			return fmt.Errorf("sandbox: %w", err)
+		}
+		release = r
+	}
+	defer release()
+
+	started, err := group.Prepare(cmd)
+	if err != nil {
+		return fmt.Errorf("resource limits: %w", err)
+	}
+	err = start()
+	started()
+	if err != nil {
+		return err
+	}
+	if err := group.Add(cmd.Process.Pid); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("resource limits: %w", err)
+	}
+	return nil
 }
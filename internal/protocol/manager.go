@@ -22,6 +22,16 @@ func NewManager() *Manager {
 // Connect attempts to connect using the best available protocol
 // For ACP-capable CLIs, we always prefer ACP and don't fallback to PTY
 func (m *Manager) Connect(config AdapterConfig) error {
+	// CLIs that declare their protocol skip auto-detection
+	switch config.Protocol {
+	case "acp":
+		logger.Info("[Protocol] Using ACP protocol for %s (configured)", config.Command)
+		return m.tryACP(config)
+	case "pty":
+		logger.Info("[Protocol] Using PTY protocol for %s (configured)", config.Command)
+		return m.tryPTY(config)
+	}
+
 	logger.Info("[Protocol] Auto-detecting protocol for %s", config.Command)
 
 	// Try ACP first - this is the preferred protocol for Claude Code
@@ -0,0 +1,31 @@
+package protocol
+
+import "strings"
+
+// PickOption chooses the agent's permission option for a decision. Agents
+// name their options differently, so the standard IDs are matched loosely.
+func PickOption(options []string, approved, always bool) string {
+	want, words := "reject_once", []string{"reject", "deny", "cancel"}
+	if approved {
+		want, words = "allow_once", []string{"allow", "approve", "proceed", "accept"}
+	}
+	if always {
+		want = strings.Replace(want, "_once", "_always", 1)
+	}
+	var loose string
+	for _, o := range options {
+		if o == want {
+			return o
+		}
+		lower := strings.ToLower(o)
+		for _, w := range words {
+			if loose == "" && strings.Contains(lower, w) && strings.Contains(lower, "always") == always {
+				loose = o
+			}
+		}
+	}
+	if loose != "" {
+		return loose
+	}
+	return want
+}
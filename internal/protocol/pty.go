@@ -8,6 +8,7 @@ import (
 	"sync/atomic"
 
 	"github.com/creack/pty"
+	"github.com/open-agents/bridge/internal/limits"
 	"github.com/open-agents/bridge/internal/logger"
 )
 
@@ -18,6 +19,7 @@ type PTYAdapter struct {
 	connected atomic.Bool
 	callback  func(Message)
 	mu        sync.Mutex
+	limits    *limits.Group
 }
 
 // NewPTYAdapter creates a new PTY adapter
@@ -60,16 +62,21 @@ func (a *PTYAdapter) Connect(config AdapterConfig) error {
 		rows = 30
 	}
 
-	ptmx, err := pty.StartWithSize(a.cmd, &pty.Winsize{
-		Cols: uint16(cols),
-		Rows: uint16(rows),
+	a.limits = config.Limits
+	var ptmx *os.File
+	err := startConfined(a.cmd, config.Sandbox, a.limits, func() (err error) {
+		ptmx, err = pty.StartWithSize(a.cmd, &pty.Winsize{
+			Cols: uint16(cols),
+			Rows: uint16(rows),
+		})
+		return err
 	})
 	if err != nil {
 		return err
 	}
 	a.ptmx = ptmx
 
-	logger.Info("[PTY] Process started (PID: %d), size: %dx%d", a.cmd.Process.Pid, cols, rows)
+	logger.Info("[PTY] Process started (PID: %d, sandboxed: %v), size: %dx%d", a.cmd.Process.Pid, config.Sandbox != nil, cols, rows)
 	a.connected.Store(true)
 
 	// Read output
@@ -78,7 +85,8 @@ func (a *PTYAdapter) Connect(config AdapterConfig) error {
 	// Wait for exit
 	go func() {
 		err := a.cmd.Wait()
-		a.connected.Store(false)
+		// Still connected means nobody asked it to stop
+		unexpected := a.connected.Swap(false)
 
 		exitCode := 0
 		if err != nil {
@@ -89,6 +97,23 @@ func (a *PTYAdapter) Connect(config AdapterConfig) error {
 
 		logger.Info("[PTY] Process exited with code %d", exitCode)
 
+		le := a.limits.Exceeded()
+		if le == nil && unexpected {
+			le = a.limits.ExitReason(a.cmd.ProcessState)
+		}
+		if le != nil && a.callback != nil {
+			logger.Warn("[PTY] %v", le)
+			a.callback(Message{
+				Type:    MessageTypeError,
+				Content: le.Error(),
+				Meta: map[string]interface{}{
+					"protocol": "pty",
+					"source":   "limit",
+					"limit":    le.Limit,
+				},
+			})
+		}
+
 		if a.callback != nil {
 			a.callback(Message{
 				Type:    MessageTypeStatus,
@@ -162,6 +187,29 @@ func (a *PTYAdapter) SendMessage(msg Message) error {
 	return err
 }
 
+// WriteRaw writes keystrokes to the terminal as they are, without a newline
+func (a *PTYAdapter) WriteRaw(data []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.ptmx == nil || !a.connected.Load() {
+		return io.ErrClosedPipe
+	}
+	_, err := a.ptmx.Write(data)
+	return err
+}
+
+// Resize changes the terminal size; the CLI receives SIGWINCH and redraws
+func (a *PTYAdapter) Resize(cols, rows int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.ptmx == nil || !a.connected.Load() {
+		return nil
+	}
+	return pty.Setsize(a.ptmx, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)})
+}
+
 func (a *PTYAdapter) ReceiveMessage() (Message, error) {
 	// Not used in callback mode
 	return Message{}, nil
@@ -16,6 +16,14 @@ const (
 	MessageTypePing          MessageType = "ping"           // Ping message for connection verification
 	MessageTypePong          MessageType = "pong"           // Pong response to ping
 	MessageTypeAuthRequired  MessageType = "auth_required"  // Authentication required
+	MessageTypeActivity      MessageType = "activity"       // Action taken for the agent (file write, command); Content names it
+)
+
+// Activities reported with MessageTypeActivity
+const (
+	ActivityFileWrite    = "fs_write"      // Meta: path, bytes, sha256, error
+	ActivityCommand      = "terminal"      // Meta: terminalId, command, cwd
+	ActivityCommandExit  = "terminal_exit" // Meta: terminalId, command, exitCode, signal
 )
 
 // AgentStatus represents the current state of the agent
@@ -41,6 +49,7 @@ type Message struct {
 type PermissionRequest struct {
 	ID          interface{}            `json:"id"` // Can be string or number (JSON-RPC 2.0)
 	ToolName    string                 `json:"tool_name"`
+	Kind        string                 `json:"kind,omitempty"` // ACP tool kind: read, edit, delete, move, execute, ...
 	ToolInput   map[string]interface{} `json:"tool_input"`
 	Description string                 `json:"description"`
 	Risk        string                 `json:"risk"` // "low", "medium", "high"
@@ -55,11 +64,13 @@ type PermissionResponse struct {
 
 // ToolCall represents a tool invocation
 type ToolCall struct {
-	ID     string                 `json:"id"`
-	Name   string                 `json:"name"`
-	Input  map[string]interface{} `json:"input"`
-	Status string                 `json:"status"` // "pending", "in_progress", "completed", "failed"
-	Result interface{}            `json:"result,omitempty"`
+	ID      string                 `json:"id"`
+	Name    string                 `json:"name"`
+	Input   map[string]interface{} `json:"input"`
+	Status  string                 `json:"status"` // "pending", "in_progress", "completed", "failed"
+	Result  interface{}            `json:"result,omitempty"`
+	Kind    string                 `json:"kind,omitempty"`    // "read", "edit", "execute", ...
+	Content []interface{}          `json:"content,omitempty"` // ACP tool call content (text blocks, diffs)
 }
 
 // UsageStats represents token usage statistics
@@ -1,62 +1,485 @@
 package rules
 
 import (
+	"fmt"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/scanner"
+)
+
+// Actions a rule can take
+const (
+	ActionApprove = "auto-approve"
+	ActionAsk     = "ask"
+	ActionDeny    = "deny"
 )
 
 type Engine struct {
-	rules []config.AutoApprovalRule
+	mu    sync.RWMutex
+	rules []rule
+}
+
+// rule is a configured rule with its expression compiled
+type rule struct {
+	config.AutoApprovalRule
+	expr *Expr
+	err  error // the expression does not compile
 }
 
 func NewEngine(rules []config.AutoApprovalRule) *Engine {
-	return &Engine{rules: rules}
+	e := &Engine{}
+	e.UpdateRules(rules)
+	return e
 }
 
+// UpdateRules replaces the rules. Rules are matched by descending priority,
+// in config order among equal priorities.
 func (e *Engine) UpdateRules(rules []config.AutoApprovalRule) {
-	e.rules = rules
+	compiled := make([]rule, 0, len(rules))
+	for _, r := range rules {
+		c := rule{AutoApprovalRule: r}
+		if strings.TrimSpace(r.Expression) != "" {
+			c.expr, c.err = CompileExpr(r.Expression)
+		}
+		compiled = append(compiled, c)
+	}
+	sort.SliceStable(compiled, func(i, j int) bool { return compiled[i].Priority > compiled[j].Priority })
+
+	e.mu.Lock()
+	e.rules = compiled
+	e.mu.Unlock()
+}
+
+// Errors lists the rules whose expressions do not compile. Such a rule
+// turns any request it would otherwise be matched against into "ask".
+func (e *Engine) Errors() []error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var errs []error
+	for _, r := range e.rules {
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %v", r.ID, r.err))
+		}
+	}
+	return errs
+}
+
+// Request is a permission request with the context policies can refer to
+type Request struct {
+	Tool           string    `json:"tool"`
+	Path           string    `json:"path,omitempty"`
+	Command        string    `json:"command,omitempty"`
+	SessionID      string    `json:"sessionId,omitempty"`
+	CLIType        string    `json:"cliType,omitempty"`
+	WorkDir        string    `json:"workDir,omitempty"`
+	PermissionMode string    `json:"permissionMode,omitempty"`
+	Risk           string    `json:"risk,omitempty"`
+	Findings       []Finding `json:"findings,omitempty"`
+	Time           time.Time `json:"time"` // defaults to now
+}
+
+// Finding is a scanner alert about the request
+type Finding struct {
+	RuleID     string  `json:"ruleId"`
+	Category   string  `json:"category"`
+	Level      string  `json:"level"`
+	Title      string  `json:"title"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// ScanFindings runs the scanner over the string values of a tool input
+func ScanFindings(s *scanner.Scanner, input map[string]interface{}) []Finding {
+	keys := make([]string, 0, len(input))
+	for k := range input {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var findings []Finding
+	for _, k := range keys {
+		text, ok := input[k].(string)
+		if !ok {
+			continue
+		}
+		for _, a := range s.Scan(text) {
+			findings = append(findings, Finding{RuleID: a.RuleID, Category: string(a.Category), Level: string(a.Level), Title: a.Title, Confidence: a.Confidence})
+		}
+	}
+	return findings
+}
+
+// Decision is the outcome of evaluating a request, with the reasons for it
+type Decision struct {
+	Action  string  `json:"action"`
+	RuleID  string  `json:"ruleId,omitempty"` // approving rules are joined with commas
+	Reason  string  `json:"reason"`
+	Matches []Match `json:"matches,omitempty"` // one per command segment
+}
+
+// Match is how one segment of a command was decided
+type Match struct {
+	Segment string `json:"segment"`
+	Action  string `json:"action"`
+	RuleID  string `json:"ruleId,omitempty"`
+	Reason  string `json:"reason"`
 }
 
 // Evaluate checks permission request against rules
 // Returns: action ("auto-approve", "ask", "deny"), matched rule ID
 func (e *Engine) Evaluate(tool, path, command string) (string, string) {
-	for _, rule := range e.rules {
-		if e.matchRule(rule, tool, path, command) {
-			return rule.Action, rule.ID
+	d := e.Decide(Request{Tool: tool, Path: path, Command: command})
+	return d.Action, d.RuleID
+}
+
+// Decide evaluates a request and explains the decision.
+//
+// Commands are parsed as bash and every segment (each simple command and
+// each file redirected to or from) is matched on its own: a deny on any
+// segment denies, and auto-approval needs a rule approving every segment.
+// A rule matches when its tool, pattern and expression all hold; an
+// expression that fails to compile or evaluate makes the decision "ask".
+func (e *Engine) Decide(req Request) Decision {
+	e.mu.RLock()
+	rules := e.rules
+	e.mu.RUnlock()
+
+	env := req.vars()
+	if req.Tool == "execute_bash" && req.Command != "" {
+		return decideCommand(rules, req.Command, env)
+	}
+	m := first(rules, target{tool: req.Tool, path: req.Path, literal: true}, env)
+	return Decision{Action: m.Action, RuleID: m.RuleID, Reason: m.Reason}
+}
+
+// target is one thing a rule is matched against
+type target struct {
+	tool    string
+	path    string // fs_* tools
+	literal bool   // path is known, not an expansion
+	words   []Word // execute_bash
+}
+
+func decideCommand(rules []rule, command string, env map[string]interface{}) Decision {
+	segments, err := ParseCommand(command)
+	if err != nil || len(segments) == 0 {
+		// What can't be parsed is never approved, but deny rules still apply
+		for _, r := range rules {
+			if r.Action != ActionDeny || !toolMatches(r, "execute_bash") || r.Pattern == "" || !strings.Contains(command, r.Pattern) {
+				continue
+			}
+			if r.expr != nil {
+				if ok, err := r.expr.Eval(targetVars(env, target{tool: "execute_bash"})); err != nil || !ok {
+					continue
+				}
+			}
+			return Decision{Action: ActionDeny, RuleID: r.ID, Reason: fmt.Sprintf("command cannot be parsed and contains %q: %s", r.Pattern, explain(r))}
 		}
+		reason := "command is empty"
+		if err != nil {
+			reason = "command cannot be parsed: " + err.Error()
+		}
+		return Decision{Action: ActionAsk, Reason: reason}
 	}
-	return "ask", ""
+
+	d := Decision{Action: ActionApprove}
+	var ids []string
+	var unapproved *Match
+	for _, seg := range segments {
+		m := first(rules, segmentTarget(seg), env)
+		m.Segment = seg.String()
+		d.Matches = append(d.Matches, m)
+		switch m.Action {
+		case ActionDeny:
+			if d.Action != ActionDeny {
+				d.Action, d.RuleID = ActionDeny, m.RuleID
+				d.Reason = fmt.Sprintf("%q: %s", m.Segment, m.Reason)
+			}
+		case ActionApprove:
+			if !contains(ids, m.RuleID) {
+				ids = append(ids, m.RuleID)
+			}
+		default:
+			if d.Action == ActionApprove {
+				d.Action = ActionAsk
+				unapproved = &d.Matches[len(d.Matches)-1]
+			}
+		}
+	}
+
+	switch d.Action {
+	case ActionAsk:
+		d.Reason = fmt.Sprintf("%q is not approved: %s", unapproved.Segment, unapproved.Reason)
+	case ActionApprove:
+		d.RuleID = strings.Join(ids, ",")
+		reasons := make([]string, len(d.Matches))
+		for i, m := range d.Matches {
+			reasons[i] = fmt.Sprintf("%q: %s", m.Segment, m.Reason)
+		}
+		d.Reason = strings.Join(reasons, "; ")
+	}
+	return d
+}
+
+// segmentTarget matches redirections like the file tools they amount to
+func segmentTarget(seg Segment) target {
+	switch seg.Kind {
+	case SegmentRead:
+		return target{tool: "fs_read", path: seg.Path.Text, literal: seg.Path.Literal}
+	case SegmentWrite:
+		return target{tool: "fs_write", path: seg.Path.Text, literal: seg.Path.Literal}
+	}
+	return target{tool: "execute_bash", words: seg.Words}
+}
+
+// first returns how the first rule matching t decides it
+func first(rules []rule, t target, env map[string]interface{}) Match {
+	var vars map[string]interface{}
+	for _, r := range rules {
+		if !r.matchStatic(t) {
+			continue
+		}
+		if r.err != nil {
+			return Match{Action: ActionAsk, RuleID: r.ID, Reason: fmt.Sprintf("rule %s: invalid expression: %v", r.ID, r.err)}
+		}
+		if r.expr != nil {
+			if vars == nil {
+				vars = targetVars(env, t)
+			}
+			ok, err := r.expr.Eval(vars)
+			if err != nil {
+				return Match{Action: ActionAsk, RuleID: r.ID, Reason: fmt.Sprintf("rule %s: expression failed: %v", r.ID, err)}
+			}
+			if !ok {
+				continue
+			}
+		}
+		return Match{Action: r.Action, RuleID: r.ID, Reason: explain(r)}
+	}
+	return Match{Action: ActionAsk, Reason: "no rule matched"}
 }
 
-func (e *Engine) matchRule(rule config.AutoApprovalRule, tool, path, command string) bool {
-	if rule.Tool != "" && rule.Tool != "*" && rule.Tool != tool {
+// explain says why a rule matched
+func explain(r rule) string {
+	var why []string
+	if r.Tool != "" && r.Tool != "*" {
+		why = append(why, "tool "+r.Tool)
+	}
+	if r.Pattern != "" && r.Pattern != "*" {
+		why = append(why, fmt.Sprintf("pattern %q", r.Pattern))
+	}
+	if r.expr != nil {
+		why = append(why, "expression `"+r.Expression+"`")
+	}
+	if len(why) == 0 {
+		why = append(why, "matches everything")
+	}
+	reason := fmt.Sprintf("rule %s (%s): %s", r.ID, r.Action, strings.Join(why, ", "))
+	if r.Priority != 0 {
+		reason += fmt.Sprintf(", priority %d", r.Priority)
+	}
+	if r.Description != "" {
+		reason += " - " + r.Description
+	}
+	return reason
+}
+
+func toolMatches(r rule, tool string) bool {
+	return r.Tool == "" || r.Tool == "*" || r.Tool == tool
+}
+
+// matchStatic matches a rule's tool and pattern
+func (r rule) matchStatic(t target) bool {
+	if !toolMatches(r, t.tool) {
 		return false
 	}
 
-	if rule.Pattern == "" || rule.Pattern == "*" {
+	if r.Pattern == "" || r.Pattern == "*" {
 		return true
 	}
 
 	// File operations: match path
-	if strings.HasPrefix(tool, "fs_") && path != "" {
-		if matched, _ := filepath.Match(rule.Pattern, path); matched {
-			return true
+	if strings.HasPrefix(t.tool, "fs_") && t.path != "" {
+		return t.literal && matchPath(r.Pattern, t.path)
+	}
+
+	// Commands: match the words of one simple command
+	if t.tool == "execute_bash" && len(t.words) > 0 {
+		return matchWords(patternWords(r.Pattern), t.words, r.Action == ActionDeny)
+	}
+
+	return false
+}
+
+// vars returns the expression variables shared by every target of a request
+func (req Request) vars() map[string]interface{} {
+	now := req.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	workDir := ""
+	if req.WorkDir != "" {
+		workDir = filepath.ToSlash(filepath.Clean(req.WorkDir))
+	}
+	findings := make([]interface{}, len(req.Findings))
+	for i, f := range req.Findings {
+		findings[i] = map[string]interface{}{"ruleId": f.RuleID, "category": f.Category, "level": f.Level, "title": f.Title, "confidence": f.Confidence}
+	}
+	return map[string]interface{}{
+		"command":        req.Command,
+		"sessionId":      req.SessionID,
+		"cliType":        req.CLIType,
+		"workDir":        workDir,
+		"permissionMode": req.PermissionMode,
+		"risk":           req.Risk,
+		"findings":       findings,
+		"hour":           int64(now.Hour()),
+		"minute":         int64(now.Minute()),
+		"weekday":        int64(now.Weekday()),
+	}
+}
+
+// targetVars adds the variables of one target: tool, path (cleaned, and
+// made absolute against workDir), argv (without leading VAR=value words),
+// program and dynamic (some word or the path is only known when the
+// command runs)
+func targetVars(env map[string]interface{}, t target) map[string]interface{} {
+	vars := make(map[string]interface{}, len(env)+5)
+	for k, v := range env {
+		vars[k] = v
+	}
+	// A path known only when the command runs has none to match
+	p := ""
+	if t.path != "" && t.literal {
+		p = filepath.ToSlash(t.path)
+		if workDir, _ := env["workDir"].(string); !path.IsAbs(p) && workDir != "" {
+			p = path.Join(workDir, p)
+		}
+		p = path.Clean(p)
+	}
+	words := t.words
+	for len(words) > 0 && isAssignment(words[0]) {
+		words = words[1:]
+	}
+	argv := make([]interface{}, len(words))
+	program := ""
+	dynamic := t.path != "" && !t.literal
+	for i, w := range words {
+		argv[i] = w.Text
+		dynamic = dynamic || !w.Literal
+	}
+	if len(words) > 0 {
+		program = words[0].Text
+	}
+	vars["tool"] = t.tool
+	vars["path"] = p
+	vars["argv"] = argv
+	vars["program"] = program
+	vars["dynamic"] = dynamic
+	return vars
+}
+
+// isAssignment reports whether a word is a leading VAR=value
+func isAssignment(w Word) bool {
+	i := strings.IndexByte(w.Text, '=')
+	if !w.Literal || i <= 0 {
+		return false
+	}
+	for j, c := range w.Text[:i] {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || j > 0 && c >= '0' && c <= '9' || j == i-1 && c == '+') {
+			return false
 		}
-		if strings.Contains(rule.Pattern, "**") {
-			if matched, _ := filepath.Match(strings.ReplaceAll(rule.Pattern, "**", "*"), path); matched {
-				return true
+	}
+	return true
+}
+
+// matchPath matches a cleaned path against a doublestar glob: * stays within
+// one path element, ** spans any number of them
+func matchPath(pattern, p string) bool {
+	pattern = filepath.ToSlash(pattern)
+	p = path.Clean(filepath.ToSlash(p))
+	matched, _ := doublestar.Match(pattern, p)
+	return matched
+}
+
+// patternWords splits a command pattern like a command line, so quoting in
+// patterns works as it does in commands
+func patternWords(pattern string) []string {
+	segments, err := ParseCommand(pattern)
+	if err == nil && len(segments) == 1 && segments[0].Kind == SegmentCommand {
+		words := segments[0].Words
+		argv := make([]string, len(words))
+		for i, w := range words {
+			if !w.Literal {
+				return strings.Fields(pattern)
 			}
+			argv[i] = w.Text
 		}
+		return argv
 	}
+	return strings.Fields(pattern)
+}
 
-	// Commands: match command string
-	if tool == "execute_bash" && command != "" {
-		if strings.Contains(command, rule.Pattern) || strings.HasPrefix(command, rule.Pattern) {
+// matchWords reports whether a command starts with the pattern's words, each
+// a glob over one word. Deny patterns match anywhere in the command and on
+// the program's base name too, so `rm -rf` also catches `sudo /bin/rm -rf /`.
+func matchWords(pattern []string, words []Word, anywhere bool) bool {
+	if len(pattern) == 0 {
+		return false
+	}
+	for start := 0; start+len(pattern) <= len(words); start++ {
+		if matchWordsAt(pattern, words[start:], anywhere) {
 			return true
 		}
+		if !anywhere {
+			break
+		}
 	}
+	return false
+}
 
+func matchWordsAt(pattern []string, words []Word, lenient bool) bool {
+	for i, p := range pattern {
+		w := words[i]
+		if !w.Literal {
+			// The value is only known when the command runs
+			if p != "*" {
+				return false
+			}
+			continue
+		}
+		if matchWord(p, w.Text) {
+			continue
+		}
+		if lenient && i == 0 && strings.Contains(w.Text, "/") && matchWord(p, path.Base(w.Text)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// matchWord matches one word against a glob in which * and ? also match /
+func matchWord(pattern, word string) bool {
+	const sep = "\x00"
+	matched, err := path.Match(strings.ReplaceAll(pattern, "/", sep), strings.ReplaceAll(word, "/", sep))
+	if err != nil {
+		return pattern == word
+	}
+	return matched
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
 	return false
 }
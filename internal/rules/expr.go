@@ -0,0 +1,990 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Expr is a compiled policy expression. The language is a small subset of
+// CEL: literals (strings, numbers, booleans, null, lists, maps), variables,
+// field access and indexing, ! - * / % + - < <= > >= == != in && || ?:,
+// the functions size, int and string, the string methods startsWith,
+// endsWith, contains, matches (regexp), glob (doublestar path match),
+// lowerAscii and upperAscii, and the macros has(x.f), list.exists(v, p) and
+// list.all(v, p). An expression must evaluate to a boolean.
+type Expr struct {
+	src  string
+	root node
+}
+
+// CompileExpr parses an expression
+func CompileExpr(src string) (*Expr, error) {
+	p := &parser{src: src}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+func (x *Expr) String() string {
+	return x.src
+}
+
+// Eval evaluates the expression against variables
+func (x *Expr) Eval(vars map[string]interface{}) (bool, error) {
+	v, err := x.root.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression is %s, not bool", typeName(v))
+	}
+	return b, nil
+}
+
+// --- lexer ---
+
+const (
+	tokEOF = iota
+	tokIdent
+	tokInt
+	tokFloat
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+	val  interface{}
+}
+
+type parser struct {
+	src  string
+	toks []token
+	i    int
+}
+
+// Operators, longest first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "!", "<", ">", "+", "-", "*", "/", "%", "?", ":", ".", ",", "(", ")", "[", "]", "{", "}"}
+
+func (p *parser) lex() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(s) && (s[i] == '_' || isLetter(s[i]) || isDigit(s[i])) {
+				i++
+			}
+			p.toks = append(p.toks, token{kind: tokIdent, text: s[start:i], pos: start})
+		case isDigit(c):
+			start := i
+			for i < len(s) && isDigit(s[i]) {
+				i++
+			}
+			kind := tokInt
+			if i+1 < len(s) && s[i] == '.' && isDigit(s[i+1]) {
+				kind = tokFloat
+				for i++; i < len(s) && isDigit(s[i]); i++ {
+				}
+			}
+			text := s[start:i]
+			var val interface{}
+			var err error
+			if kind == tokInt {
+				val, err = strconv.ParseInt(text, 10, 64)
+			} else {
+				val, err = strconv.ParseFloat(text, 64)
+			}
+			if err != nil {
+				return fmt.Errorf("bad number %q at offset %d", text, start)
+			}
+			p.toks = append(p.toks, token{kind: kind, text: text, pos: start, val: val})
+		case c == '"' || c == '\'':
+			start := i
+			var sb strings.Builder
+			for i++; ; i++ {
+				if i >= len(s) {
+					return fmt.Errorf("unterminated string at offset %d", start)
+				}
+				if s[i] == c {
+					i++
+					break
+				}
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					case 'r':
+						sb.WriteByte('\r')
+					default:
+						sb.WriteByte(s[i])
+					}
+					continue
+				}
+				sb.WriteByte(s[i])
+			}
+			p.toks = append(p.toks, token{kind: tokString, text: s[start:i], pos: start, val: sb.String()})
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return fmt.Errorf("unexpected %q at offset %d", c, i)
+			}
+			p.toks = append(p.toks, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	p.toks = append(p.toks, token{kind: tokEOF, text: "end of expression", pos: len(s)})
+	return nil
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// --- parser ---
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (p *parser) accept(op string) bool {
+	if p.isOp(op) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q, found %q at offset %d", op, t.text, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) expr() (node, error) {
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return cond, nil
+	}
+	then, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &condNode{cond, then, els}, nil
+}
+
+// Binary operators by precedence, loosest first
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) binary(level int) (node, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		op := ""
+		for _, o := range precedence[level] {
+			if (t.kind == tokOp || t.kind == tokIdent) && t.text == o {
+				op = o
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		p.next()
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if p.accept("!") {
+		operand, err := p.unary()
+		return &notNode{operand}, err
+	}
+	if p.accept("-") {
+		operand, err := p.unary()
+		return &negNode{operand}, err
+	}
+	return p.member()
+}
+
+func (p *parser) member() (node, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, fmt.Errorf("expected a name after '.', found %q at offset %d", t.text, t.pos)
+			}
+			if !p.isOp("(") {
+				n = &fieldNode{target: n, name: t.text}
+				continue
+			}
+			p.next()
+			if t.text == "exists" || t.text == "all" {
+				n, err = p.macro(n, t.text)
+			} else {
+				var args []node
+				args, err = p.args(")")
+				n = &callNode{name: t.text, target: n, args: args}
+			}
+			if err != nil {
+				return nil, err
+			}
+		case p.accept("["):
+			index, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{target: n, index: index}
+		default:
+			return n, nil
+		}
+	}
+}
+
+// macro parses the rest of list.exists(v, pred) or list.all(v, pred)
+func (p *parser) macro(list node, name string) (node, error) {
+	v := p.next()
+	if v.kind != tokIdent {
+		return nil, fmt.Errorf("%s() needs a variable name, found %q at offset %d", name, v.text, v.pos)
+	}
+	if err := p.expect(","); err != nil {
+		return nil, err
+	}
+	pred, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return &macroNode{all: name == "all", list: list, v: v.text, pred: pred}, nil
+}
+
+func (p *parser) args(end string) ([]node, error) {
+	var args []node
+	if p.accept(end) {
+		return args, nil
+	}
+	for {
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(end) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokInt, tokFloat, tokString:
+		return &litNode{t.val}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &litNode{true}, nil
+		case "false":
+			return &litNode{false}, nil
+		case "null":
+			return &litNode{nil}, nil
+		}
+		if !p.accept("(") {
+			return &varNode{t.text}, nil
+		}
+		if t.text == "has" {
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			field, ok := arg.(*fieldNode)
+			if !ok {
+				return nil, fmt.Errorf("has() needs a field, like has(x.f), at offset %d", t.pos)
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return &hasNode{field}, nil
+		}
+		args, err := p.args(")")
+		return &callNode{name: t.text, args: args}, err
+	case tokOp:
+		switch t.text {
+		case "(":
+			n, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "[":
+			items, err := p.args("]")
+			return &listNode{items}, err
+		case "{":
+			m := &mapNode{}
+			if p.accept("}") {
+				return m, nil
+			}
+			for {
+				k, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				v, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				m.keys, m.values = append(m.keys, k), append(m.values, v)
+				if p.accept("}") {
+					return m, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
+
+// --- evaluation ---
+
+type node interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+type litNode struct{ v interface{} }
+
+func (n *litNode) eval(map[string]interface{}) (interface{}, error) { return n.v, nil }
+
+type varNode struct{ name string }
+
+func (n *varNode) eval(vars map[string]interface{}) (interface{}, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undeclared variable %q", n.name)
+	}
+	return normalize(v), nil
+}
+
+type listNode struct{ items []node }
+
+func (n *listNode) eval(vars map[string]interface{}) (interface{}, error) {
+	list := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+type mapNode struct{ keys, values []node }
+
+func (n *mapNode) eval(vars map[string]interface{}) (interface{}, error) {
+	m := make(map[string]interface{}, len(n.keys))
+	for i := range n.keys {
+		k, err := n.keys[i].eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		ks, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("map keys must be strings, not %s", typeName(k))
+		}
+		v, err := n.values[i].eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		m[ks] = v
+	}
+	return m, nil
+}
+
+type fieldNode struct {
+	target node
+	name   string
+}
+
+func (n *fieldNode) eval(vars map[string]interface{}) (interface{}, error) {
+	t, err := n.target.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := t.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s has no field %q", typeName(t), n.name)
+	}
+	v, ok := m[n.name]
+	if !ok {
+		return nil, fmt.Errorf("no such field %q", n.name)
+	}
+	return normalize(v), nil
+}
+
+type hasNode struct{ field *fieldNode }
+
+func (n *hasNode) eval(vars map[string]interface{}) (interface{}, error) {
+	t, err := n.field.target.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := t.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("has() on %s", typeName(t))
+	}
+	_, ok = m[n.field.name]
+	return ok, nil
+}
+
+type indexNode struct{ target, index node }
+
+func (n *indexNode) eval(vars map[string]interface{}) (interface{}, error) {
+	t, err := n.target.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	i, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch t := t.(type) {
+	case []interface{}:
+		idx, ok := i.(int64)
+		if !ok {
+			return nil, fmt.Errorf("list index must be int, not %s", typeName(i))
+		}
+		if idx < 0 || idx >= int64(len(t)) {
+			return nil, fmt.Errorf("index %d out of range (size %d)", idx, len(t))
+		}
+		return normalize(t[idx]), nil
+	case map[string]interface{}:
+		k, ok := i.(string)
+		if !ok {
+			return nil, fmt.Errorf("map key must be string, not %s", typeName(i))
+		}
+		v, ok := t[k]
+		if !ok {
+			return nil, fmt.Errorf("no such key %q", k)
+		}
+		return normalize(v), nil
+	}
+	return nil, fmt.Errorf("cannot index %s", typeName(t))
+}
+
+type notNode struct{ operand node }
+
+func (n *notNode) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("! needs bool, not %s", typeName(v))
+	}
+	return !b, nil
+}
+
+type negNode struct{ operand node }
+
+func (n *negNode) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case int64:
+		return -v, nil
+	case float64:
+		return -v, nil
+	}
+	return nil, fmt.Errorf("- needs a number, not %s", typeName(v))
+}
+
+type condNode struct{ cond, then, els node }
+
+func (n *condNode) eval(vars map[string]interface{}) (interface{}, error) {
+	c, err := n.cond.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := c.(bool)
+	if !ok {
+		return nil, fmt.Errorf("?: needs a bool condition, not %s", typeName(c))
+	}
+	if b {
+		return n.then.eval(vars)
+	}
+	return n.els.eval(vars)
+}
+
+type macroNode struct {
+	all  bool
+	list node
+	v    string
+	pred node
+}
+
+func (n *macroNode) eval(vars map[string]interface{}) (interface{}, error) {
+	l, err := n.list.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := l.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("exists/all need a list, not %s", typeName(l))
+	}
+	scope := make(map[string]interface{}, len(vars)+1)
+	for k, v := range vars {
+		scope[k] = v
+	}
+	for _, item := range list {
+		scope[n.v] = item
+		r, err := n.pred.eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := r.(bool)
+		if !ok {
+			return nil, fmt.Errorf("exists/all predicate is %s, not bool", typeName(r))
+		}
+		if b != n.all {
+			return b, nil // exists: found one; all: found a counterexample
+		}
+	}
+	return n.all, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(vars map[string]interface{}) (interface{}, error) {
+	l, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit logic
+	if n.op == "&&" || n.op == "||" {
+		lb, ok := l.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s needs bool, not %s", n.op, typeName(l))
+		}
+		if lb == (n.op == "||") {
+			return lb, nil
+		}
+		r, err := n.right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := r.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s needs bool, not %s", n.op, typeName(r))
+		}
+		return rb, nil
+	}
+
+	r, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "in":
+		switch c := r.(type) {
+		case []interface{}:
+			for _, item := range c {
+				if equal(l, normalize(item)) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			k, ok := l.(string)
+			if !ok {
+				return false, nil
+			}
+			_, found := c[k]
+			return found, nil
+		}
+		return nil, fmt.Errorf("in needs a list or map, not %s", typeName(r))
+	case "<", "<=", ">", ">=":
+		c, err := compare(l, r)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	case "+":
+		switch lv := l.(type) {
+		case string:
+			if rv, ok := r.(string); ok {
+				return lv + rv, nil
+			}
+		case []interface{}:
+			if rv, ok := r.([]interface{}); ok {
+				return append(append([]interface{}{}, lv...), rv...), nil
+			}
+		}
+	}
+	return arith(n.op, l, r)
+}
+
+func arith(op string, l, r interface{}) (interface{}, error) {
+	li, lInt := l.(int64)
+	ri, rInt := r.(int64)
+	if lInt && rInt {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "/", "%":
+			if ri == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if op == "/" {
+				return li / ri, nil
+			}
+			return li % ri, nil
+		}
+	}
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if !lok || !rok || op == "%" {
+		return nil, fmt.Errorf("no operator %s for %s and %s", op, typeName(l), typeName(r))
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	}
+	return lf / rf, nil
+}
+
+type callNode struct {
+	name   string
+	target node // nil for global functions
+	args   []node
+}
+
+func (n *callNode) eval(vars map[string]interface{}) (interface{}, error) {
+	var args []interface{}
+	if n.target != nil {
+		t, err := n.target.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, t)
+	}
+	for _, a := range n.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	switch n.name {
+	case "size":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("size() takes one argument")
+		}
+		switch v := args[0].(type) {
+		case string:
+			return int64(len([]rune(v))), nil
+		case []interface{}:
+			return int64(len(v)), nil
+		case map[string]interface{}:
+			return int64(len(v)), nil
+		}
+		return nil, fmt.Errorf("size() of %s", typeName(args[0]))
+	case "int":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("int() takes one argument")
+		}
+		switch v := args[0].(type) {
+		case int64:
+			return v, nil
+		case float64:
+			return int64(v), nil
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		}
+		return nil, fmt.Errorf("int() of %s", typeName(args[0]))
+	case "string":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("string() takes one argument")
+		}
+		return fmt.Sprint(args[0]), nil
+	}
+
+	// String methods
+	if n.target == nil {
+		return nil, fmt.Errorf("unknown function %s()", n.name)
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s() needs a string, not %s", n.name, typeName(args[0]))
+	}
+	switch n.name {
+	case "lowerAscii", "upperAscii":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s() takes no arguments", n.name)
+		}
+		if n.name == "lowerAscii" {
+			return strings.ToLower(s), nil
+		}
+		return strings.ToUpper(s), nil
+	case "startsWith", "endsWith", "contains", "matches", "glob":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s() takes one argument", n.name)
+		}
+		arg, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() needs a string argument, not %s", n.name, typeName(args[1]))
+		}
+		switch n.name {
+		case "startsWith":
+			return strings.HasPrefix(s, arg), nil
+		case "endsWith":
+			return strings.HasSuffix(s, arg), nil
+		case "contains":
+			return strings.Contains(s, arg), nil
+		case "glob":
+			return matchPath(arg, s), nil
+		}
+		re, err := compileRegexp(arg)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(s), nil
+	}
+	return nil, fmt.Errorf("unknown method %s()", n.name)
+}
+
+var regexpCache sync.Map
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexpCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexpCache.Store(pattern, re)
+	return re, nil
+}
+
+// normalize converts Go values handed in as variables to the expression's types
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			m[k] = s
+		}
+		return m
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, m := range v {
+			list[i] = m
+		}
+		return list
+	}
+	return v
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func equal(l, r interface{}) bool {
+	if lf, ok := toFloat(l); ok {
+		rf, ok := toFloat(r)
+		return ok && lf == rf
+	}
+	switch lv := l.(type) {
+	case []interface{}:
+		rv, ok := r.([]interface{})
+		if !ok || len(lv) != len(rv) {
+			return false
+		}
+		for i := range lv {
+			if !equal(normalize(lv[i]), normalize(rv[i])) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		rv, ok := r.(map[string]interface{})
+		if !ok || len(lv) != len(rv) {
+			return false
+		}
+		for k, v := range lv {
+			w, ok := rv[k]
+			if !ok || !equal(normalize(v), normalize(w)) {
+				return false
+			}
+		}
+		return true
+	}
+	return l == r
+}
+
+func compare(l, r interface{}) (int, error) {
+	if ls, ok := l.(string); ok {
+		if rs, ok := r.(string); ok {
+			return strings.Compare(ls, rs), nil
+		}
+	}
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if !lok || !rok {
+		return 0, fmt.Errorf("cannot compare %s and %s", typeName(l), typeName(r))
+	}
+	switch {
+	case lf < rf:
+		return -1, nil
+	case lf > rf:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
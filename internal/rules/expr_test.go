@@ -0,0 +1,75 @@
+package rules
+
+import "testing"
+
+func TestExprEval(t *testing.T) {
+	vars := map[string]interface{}{
+		"tool":     "fs_write",
+		"path":     "/repo/src/main.go",
+		"workDir":  "/repo",
+		"risk":     "medium",
+		"hour":     int64(14),
+		"argv":     []interface{}{"git", "push", "origin"},
+		"findings": []interface{}{map[string]interface{}{"level": "high", "category": "secret"}},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`tool == "fs_write" && path.startsWith(workDir + "/src") && risk != "high"`, true},
+		{`tool == 'fs_read' || risk == "high"`, false},
+		{`!(hour < 9 || hour >= 18)`, true},
+		{`hour * 2 + 1 == 29 && 7 / 2 == 3 && 7 % 2 == 1 && -hour < 0`, true},
+		{`1.5 < 2 && 2 == 2.0`, true},
+		{`risk in ["low", "medium"]`, true},
+		{`"secret" in {"secret": 1}`, true},
+		{`argv[0] == "git" && argv[1] in ["push", "pull"] && size(argv) == 3`, true},
+		{`argv.exists(a, a.startsWith("ori"))`, true},
+		{`argv.all(a, size(a) > 3)`, false},
+		{`findings.exists(f, f.level == "high")`, true},
+		{`has(findings[0].level) && !has(findings[0].ruleId)`, true},
+		{`path.endsWith(".go") && path.contains("/src/") && path.matches("^/repo/.*\\.go$")`, true},
+		{`path.glob("/repo/**/*.go") && !path.glob("/repo/*.go")`, true},
+		{`"A".lowerAscii() == "a" && "a".upperAscii() == "A"`, true},
+		{`risk == "high" ? false : true`, true},
+		{`[1, 2] + [3] == [1, 2, 3] && int("4") == 4 && string(5) == "5"`, true},
+		{`null == null && "b" > "a"`, true},
+		{`false && undefined`, false}, // short-circuits
+		{`true || 1 / 0 == 1`, true},
+	}
+	for _, tt := range tests {
+		x, err := CompileExpr(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		got, err := x.Eval(vars)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	for _, src := range []string{`tool ==`, `"open`, `(a`, `a.exists(1, true)`, `has(a)`, `a # b`, `a b`} {
+		if _, err := CompileExpr(src); err == nil {
+			t.Errorf("%s: expected a compile error", src)
+		}
+	}
+
+	vars := map[string]interface{}{"n": int64(1), "s": "x", "l": []interface{}{}}
+	for _, src := range []string{`missing == 1`, `n`, `n + s == 1`, `l[0] == 1`, `s.startsWith(1)`, `n / 0 == 1`, `s.matches("(") `, `n && true`, `s.nope()`} {
+		x, err := CompileExpr(src)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if _, err := x.Eval(vars); err == nil {
+			t.Errorf("%s: expected an evaluation error", src)
+		}
+	}
+}
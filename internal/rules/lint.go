@@ -0,0 +1,217 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-agents/bridge/internal/config"
+)
+
+// Kinds of issue Lint reports
+const (
+	IssueInvalid     = "invalid"     // the rule cannot work as written
+	IssueUnreachable = "unreachable" // the rule can never match
+	IssueShadowed    = "shadowed"    // an earlier rule matches everything it does
+	IssueBroad       = "broad"       // the rule approves far more than it likely means to
+	IssueUnused      = "unused"      // the rule decided none of the replayed requests
+)
+
+// Issue is a problem found with one rule
+type Issue struct {
+	RuleID  string `json:"ruleId"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// interpreters run whatever code they are given, so approving them by name
+// approves anything
+var interpreters = map[string]bool{
+	"bash": true, "sh": true, "zsh": true, "fish": true, "dash": true,
+	"python": true, "python3": true, "node": true, "deno": true, "perl": true, "ruby": true, "php": true,
+	"eval": true, "exec": true, "env": true, "sudo": true, "xargs": true, "su": true, "doas": true,
+}
+
+// Lint checks rules, in the order they are matched, for ones that cannot
+// work, that can never decide anything because an earlier rule always wins,
+// and for approvals much broader than they look
+func Lint(rules []config.AutoApprovalRule) []Issue {
+	sorted := NewEngine(rules).sorted()
+	var issues []Issue
+	add := func(r rule, kind, format string, args ...interface{}) {
+		issues = append(issues, Issue{RuleID: r.ID, Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	seen := map[string]bool{}
+	for i, r := range sorted {
+		if r.ID == "" {
+			add(r, IssueInvalid, "has no id")
+		} else if seen[r.ID] {
+			add(r, IssueInvalid, "id is used by another rule")
+		}
+		seen[r.ID] = true
+
+		switch r.Action {
+		case ActionApprove, ActionAsk, ActionDeny:
+		default:
+			add(r, IssueInvalid, "unknown action %q; requests it matches will ask", r.Action)
+		}
+		if r.err != nil {
+			add(r, IssueInvalid, "invalid expression, requests it matches will ask: %v", r.err)
+		}
+
+		if !patternApplies(r) {
+			add(r, IssueUnreachable, "tool %s is not matched against patterns; only fs_* paths and execute_bash commands are", r.Tool)
+			continue
+		}
+		if by, ok := coveredBy(sorted[:i], r); ok {
+			switch {
+			case matchesAll(by):
+				add(r, IssueUnreachable, "rule %s (%s) before it matches every %srequest", by.ID, by.Action, toolPrefix(r.Tool))
+			case by.Action == r.Action:
+				add(r, IssueShadowed, "rule %s before it matches everything it does, with the same action", by.ID)
+			default:
+				add(r, IssueShadowed, "rule %s before it matches everything it does, so it never gets to %s", by.ID, r.Action)
+			}
+			continue
+		}
+
+		if r.Action == ActionApprove && r.expr == nil && r.err == nil {
+			if why := broad(r); why != "" {
+				add(r, IssueBroad, "%s", why)
+			}
+		}
+	}
+	return issues
+}
+
+// patternApplies reports whether a rule's pattern can match its tool:
+// matchStatic only matches patterns against paths and commands
+func patternApplies(r rule) bool {
+	if isCatchAll(r.Pattern) || r.Tool == "" || r.Tool == "*" {
+		return true
+	}
+	return strings.HasPrefix(r.Tool, "fs_") || r.Tool == "execute_bash"
+}
+
+// coveredBy returns the first of earlier that matches every request r does.
+// An earlier rule with a broken expression is not counted; it has its own
+// issue.
+func coveredBy(earlier []rule, r rule) (rule, bool) {
+	for _, e := range earlier {
+		if covers(e, r) {
+			return e, true
+		}
+	}
+	return rule{}, false
+}
+
+// covers reports whether a matches every request b matches
+func covers(a, b rule) bool {
+	if a.err != nil {
+		return false
+	}
+	// a must hold for every tool b does
+	if a.Tool != "" && a.Tool != "*" && a.Tool != b.Tool {
+		return false
+	}
+	if a.expr != nil && a.Expression != b.Expression {
+		return false
+	}
+	if isCatchAll(a.Pattern) {
+		return true
+	}
+	if isCatchAll(b.Pattern) {
+		return false
+	}
+	if a.Pattern == b.Pattern && (a.Action == ActionDeny || b.Action != ActionDeny) {
+		return true
+	}
+
+	tool := b.Tool
+	if tool == "" || tool == "*" {
+		// b's pattern is matched against whatever tool comes; only the
+		// same pattern is sure to match the same requests
+		return false
+	}
+	switch {
+	case strings.HasPrefix(tool, "fs_"):
+		return pathCovers(a.Pattern, b.Pattern)
+	case tool == "execute_bash":
+		// Deny patterns match anywhere in a command, others only at its start
+		if b.Action == ActionDeny && a.Action != ActionDeny {
+			return false
+		}
+		return wordsCover(patternWords(a.Pattern), patternWords(b.Pattern))
+	}
+	return false
+}
+
+// pathCovers reports whether glob a matches every path glob b does
+func pathCovers(a, b string) bool {
+	if !hasMeta(b) {
+		return matchPath(a, b)
+	}
+	if strings.HasSuffix(a, "/**") {
+		return strings.HasPrefix(b, strings.TrimSuffix(a, "**"))
+	}
+	return false
+}
+
+// wordsCover reports whether command pattern a matches every command b does
+func wordsCover(a, b []string) bool {
+	if len(a) == 0 || len(a) > len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] == b[i] || a[i] == "*" || !hasMeta(b[i]) && matchWord(a[i], b[i]) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// broad explains why an approval is broader than it looks, if it is
+func broad(r rule) string {
+	switch {
+	case (r.Tool == "" || r.Tool == "*") && isCatchAll(r.Pattern):
+		return "approves every request of every tool"
+	case isCatchAll(r.Pattern):
+		return fmt.Sprintf("approves every %s request", r.Tool)
+	case r.Tool == "execute_bash":
+		words := patternWords(r.Pattern)
+		if len(words) == 0 {
+			break
+		}
+		if words[0] == "*" || words[0] == "**" {
+			return "approves any program"
+		}
+		if interpreters[words[0]] && (len(words) == 1 || words[1] == "*") {
+			return fmt.Sprintf("%s runs whatever it is given, so this approves any command", words[0])
+		}
+	case strings.HasPrefix(r.Tool, "fs_"):
+		if p := strings.TrimPrefix(r.Pattern, "/"); strings.HasPrefix(p, "**") || strings.HasPrefix(p, "*/**") {
+			return fmt.Sprintf("%s matches files anywhere on the machine", r.Pattern)
+		}
+	}
+	return ""
+}
+
+func isCatchAll(pattern string) bool {
+	return pattern == "" || pattern == "*"
+}
+
+func matchesAll(r rule) bool {
+	return isCatchAll(r.Pattern) && r.expr == nil && r.err == nil
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[{\\")
+}
+
+func toolPrefix(tool string) string {
+	if tool == "" || tool == "*" {
+		return ""
+	}
+	return tool + " "
+}
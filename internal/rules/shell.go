@@ -0,0 +1,307 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Segment kinds
+const (
+	SegmentCommand = "command" // a simple command that runs
+	SegmentRead    = "read"    // a file a redirection reads
+	SegmentWrite   = "write"   // a file a redirection writes
+)
+
+// maxNesting bounds how deep `sh -c` and eval arguments are parsed
+const maxNesting = 4
+
+// Word is one shell word. Literal words are known before the command runs
+// (quotes removed); other words hold their source text.
+type Word struct {
+	Text    string
+	Literal bool
+}
+
+// Segment is one part of a command line that needs approval on its own: a
+// simple command anywhere in it (pipelines, && and ; chains, subshells,
+// command substitutions, sh -c strings) or a file redirected to or from
+type Segment struct {
+	Kind   string
+	Words  []Word // command words, environment assignments first (SegmentCommand)
+	Path   Word   // redirection target (SegmentRead, SegmentWrite)
+	Source string // text of the segment in the command line
+
+	offset uint
+}
+
+// Argv returns the command's words as text
+func (s Segment) Argv() []string {
+	argv := make([]string, len(s.Words))
+	for i, w := range s.Words {
+		argv[i] = w.Text
+	}
+	return argv
+}
+
+func (s Segment) String() string {
+	if s.Kind == SegmentCommand {
+		return s.Source
+	}
+	return s.Kind + " " + s.Path.Text
+}
+
+// ParseCommand splits a bash command line into the segments that need approval
+func ParseCommand(command string) ([]Segment, error) {
+	return parseCommand(command, 0)
+}
+
+func parseCommand(command string, depth int) ([]Segment, error) {
+	parser := syntax.NewParser(syntax.Variant(syntax.LangBash))
+	file, err := parser.Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []Segment
+	var nestedErr error
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.Stmt:
+			for _, r := range n.Redirs {
+				if seg, ok := redirectSegment(command, r); ok {
+					segments = append(segments, seg)
+				}
+			}
+		case *syntax.CallExpr:
+			if len(n.Args) == 0 {
+				return true // assignments only; substitutions in them are walked
+			}
+			seg := Segment{Kind: SegmentCommand, Source: source(command, n), offset: n.Pos().Offset()}
+			for _, a := range n.Assigns {
+				seg.Words = append(seg.Words, assignWord(command, a))
+			}
+			for _, w := range n.Args {
+				seg.Words = append(seg.Words, word(command, w))
+			}
+			segments = append(segments, seg)
+
+			// Strings run by a shell or eval are command lines of their own
+			if inner, ok := innerCommand(seg.Words[len(n.Assigns):]); ok {
+				if depth >= maxNesting {
+					nestedErr = fmt.Errorf("commands nested more than %d levels deep", maxNesting)
+					return false
+				}
+				nested, err := parseCommand(inner, depth+1)
+				if err != nil {
+					nestedErr = err
+					return false
+				}
+				for i := range nested {
+					nested[i].offset = seg.offset
+				}
+				segments = append(segments, nested...)
+			}
+		case *syntax.DeclClause:
+			seg := Segment{Kind: SegmentCommand, Source: source(command, n), offset: n.Pos().Offset()}
+			seg.Words = append(seg.Words, Word{Text: n.Variant.Value, Literal: true})
+			for _, a := range n.Args {
+				seg.Words = append(seg.Words, assignWord(command, a))
+			}
+			segments = append(segments, seg)
+		}
+		return true
+	})
+	if nestedErr != nil {
+		return nil, nestedErr
+	}
+
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].offset < segments[j].offset })
+	return segments, nil
+}
+
+// innerCommand returns the command line a shell runs for `sh -c '...'`
+// or `eval ...`, if its text is known
+func innerCommand(words []Word) (string, bool) {
+	if len(words) < 2 || !words[0].Literal {
+		return "", false
+	}
+	switch path.Base(words[0].Text) {
+	case "eval":
+		parts := make([]string, 0, len(words)-1)
+		for _, w := range words[1:] {
+			parts = append(parts, w.Text)
+		}
+		return strings.Join(parts, " "), true
+	case "sh", "bash", "zsh", "dash", "ksh":
+		for i, w := range words[1 : len(words)-1] {
+			if w.Literal && strings.HasPrefix(w.Text, "-") && !strings.HasPrefix(w.Text, "--") && strings.Contains(w.Text, "c") {
+				return words[i+2].Text, true
+			}
+		}
+	}
+	return "", false
+}
+
+// redirectSegment describes the file a redirection reads or writes. File
+// descriptor duplication, here-documents and the null and standard devices
+// touch no file.
+func redirectSegment(command string, r *syntax.Redirect) (Segment, bool) {
+	if r.Word == nil {
+		return Segment{}, false
+	}
+	target := word(command, r.Word)
+	seg := Segment{Path: target, Source: source(command, r), offset: r.Pos().Offset()}
+
+	switch r.Op {
+	case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll, syntax.RdrInOut:
+		seg.Kind = SegmentWrite
+	case syntax.RdrIn:
+		seg.Kind = SegmentRead
+	case syntax.DplIn, syntax.DplOut:
+		if target.Literal && (target.Text == "-" || isDigits(target.Text)) {
+			return Segment{}, false
+		}
+		seg.Kind = SegmentWrite
+		if r.Op == syntax.DplIn {
+			seg.Kind = SegmentRead
+		}
+	default: // here-documents and here-strings
+		return Segment{}, false
+	}
+
+	if target.Literal {
+		switch target.Text {
+		case "/dev/null", "/dev/stdin", "/dev/stdout", "/dev/stderr", "/dev/tty":
+			return Segment{}, false
+		}
+	}
+	return seg, true
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func source(command string, n syntax.Node) string {
+	start, end := n.Pos().Offset(), n.End().Offset()
+	if start > end || int(end) > len(command) {
+		return ""
+	}
+	return command[start:end]
+}
+
+// word resolves a word if its value is known before the command runs
+func word(command string, w *syntax.Word) Word {
+	if text, ok := literal(w); ok {
+		return Word{Text: text, Literal: true}
+	}
+	return Word{Text: source(command, w)}
+}
+
+func assignWord(command string, a *syntax.Assign) Word {
+	if a.Name == nil || a.Index != nil || a.Array != nil {
+		return Word{Text: source(command, a)}
+	}
+	if a.Naked {
+		return Word{Text: a.Name.Value, Literal: true}
+	}
+	value := ""
+	if a.Value != nil {
+		text, ok := literal(a.Value)
+		if !ok {
+			return Word{Text: source(command, a)}
+		}
+		value = text
+	}
+	op := "="
+	if a.Append {
+		op = "+="
+	}
+	return Word{Text: a.Name.Value + op + value, Literal: true}
+}
+
+// literal returns a word's value with quoting removed, if it has no
+// expansions. A leading ~ (the home directory of the user or of ~name) and
+// unquoted glob characters expand too.
+func literal(w *syntax.Word) (string, bool) {
+	var sb strings.Builder
+	for i, part := range w.Parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			if i == 0 && strings.HasPrefix(p.Value, "~") || hasGlob(p.Value) {
+				return "", false
+			}
+			sb.WriteString(unescape(p.Value, ""))
+		case *syntax.SglQuoted:
+			if p.Dollar {
+				return "", false // $'...' escapes
+			}
+			sb.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			if p.Dollar {
+				return "", false
+			}
+			for _, inner := range p.Parts {
+				lit, ok := inner.(*syntax.Lit)
+				if !ok {
+					return "", false
+				}
+				sb.WriteString(unescape(lit.Value, "\"\\$`"))
+			}
+		default:
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+// hasGlob reports whether unquoted text has a glob character the shell
+// expands, one not escaped with a backslash
+func hasGlob(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+// unescape removes backslashes the shell drops: before any character
+// outside quotes (only), or before one of special inside double quotes
+func unescape(s, special string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			next := s[i+1]
+			if next == '\n' {
+				i++ // line continuation
+				continue
+			}
+			if special == "" || strings.IndexByte(special, next) >= 0 {
+				sb.WriteByte(next)
+				i++
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,167 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-agents/bridge/internal/audit"
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/scanner"
+)
+
+// Case is a permission request to replay, with how it was answered when it
+// was made, if that is known
+type Case struct {
+	ID          string  `json:"id,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Request     Request `json:"request"`
+	Approved    *bool   `json:"approved,omitempty"`
+	DecidedBy   string  `json:"decidedBy,omitempty"` // "rule:<id>", "timeout", a client, ...
+}
+
+// Change is a request the proposed rules decide differently
+type Change struct {
+	Case
+	Before Decision `json:"before"`
+	After  Decision `json:"after"`
+}
+
+// Impact is what replacing the rules would have done to past requests
+type Impact struct {
+	Cases   int            `json:"cases"`
+	Skipped int            `json:"skipped,omitempty"` // requests rules never see, such as ACP ones
+	Before  map[string]int `json:"before"`            // requests per action under the current rules
+	After   map[string]int `json:"after"`             // and under the proposed ones
+	Changed []Change       `json:"changed"`
+	Hits    map[string]int `json:"hits"` // requests each proposed rule decided
+	Issues  []Issue        `json:"issues"`
+}
+
+// Simulate replays cases through the current and the proposed rules and
+// reports the requests whose outcome changes, how often each proposed rule
+// decides, and what Lint finds in the proposed rules. Proposed rules that
+// decide none of the cases are reported as unused.
+func Simulate(current, proposed []config.AutoApprovalRule, cases []Case) Impact {
+	before, after := NewEngine(current), NewEngine(proposed)
+	im := Impact{
+		Cases:   len(cases),
+		Before:  map[string]int{},
+		After:   map[string]int{},
+		Changed: []Change{},
+		Hits:    map[string]int{},
+	}
+	for _, c := range cases {
+		b, a := before.Decide(c.Request), after.Decide(c.Request)
+		im.Before[b.Action]++
+		im.After[a.Action]++
+		for _, id := range decidingRules(a) {
+			im.Hits[id]++
+		}
+		if b.Action != a.Action {
+			im.Changed = append(im.Changed, Change{Case: c, Before: b, After: a})
+		}
+	}
+
+	im.Issues = Lint(proposed)
+	if len(cases) > 0 {
+		for _, r := range after.sorted() {
+			if r.ID != "" && im.Hits[r.ID] == 0 && !flagged(im.Issues, r.ID) {
+				im.Issues = append(im.Issues, Issue{RuleID: r.ID, Kind: IssueUnused, Message: fmt.Sprintf("decides none of the %d replayed requests", len(cases))})
+			}
+		}
+	}
+	return im
+}
+
+// decidingRules lists the rules a decision came from, once each
+func decidingRules(d Decision) []string {
+	var ids []string
+	add := func(id string) {
+		if id != "" && !contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	for _, m := range d.Matches {
+		add(m.RuleID)
+	}
+	if len(d.Matches) == 0 {
+		for _, id := range strings.Split(d.RuleID, ",") {
+			add(id)
+		}
+	}
+	return ids
+}
+
+// flagged reports whether Lint already explains why a rule never decides
+func flagged(issues []Issue, id string) bool {
+	for _, is := range issues {
+		if is.RuleID == id && is.Kind != IssueBroad {
+			return true
+		}
+	}
+	return false
+}
+
+// CasesFromAudit pairs the hook permission requests of an audit log with
+// their decisions and the sessions they came from. ACP requests are counted
+// as skipped: rules are not applied to them. With a scanner, the findings
+// policies can refer to are computed again from the recorded input.
+func CasesFromAudit(entries []audit.Entry, s *scanner.Scanner) (cases []Case, skipped int) {
+	type session struct{ cliType, workDir, permissionMode string }
+	sessions := map[string]session{}
+	open := map[string]int{} // request id -> index in cases, until decided
+
+	for _, e := range entries {
+		str := func(k string) string {
+			if v, ok := e.Data[k]; ok && v != nil {
+				return fmt.Sprint(v)
+			}
+			return ""
+		}
+		switch e.Type {
+		case audit.SessionStart:
+			sessions[e.SessionID] = session{str("cliType"), str("workDir"), str("permissionMode")}
+
+		case audit.PermissionRequest:
+			if str("origin") != "hook" { // permission.OriginHook; permission imports this package
+				skipped++
+				continue
+			}
+			input, _ := e.Data["input"].(map[string]interface{})
+			req := Request{
+				Tool:      str("tool"),
+				SessionID: e.SessionID,
+				Risk:      str("risk"),
+				Time:      e.Time,
+			}
+			req.Path, _ = input["path"].(string)
+			req.Command, _ = input["command"].(string)
+			if sess, ok := sessions[e.SessionID]; ok {
+				req.CLIType, req.WorkDir, req.PermissionMode = sess.cliType, sess.workDir, sess.permissionMode
+			}
+			if s != nil {
+				req.Findings = ScanFindings(s, input)
+			}
+			open[str("id")] = len(cases)
+			cases = append(cases, Case{ID: str("id"), Description: str("description"), Request: req})
+
+		case audit.PermissionDecision:
+			i, ok := open[str("id")]
+			if !ok {
+				continue
+			}
+			delete(open, str("id"))
+			approved, _ := e.Data["approved"].(bool)
+			cases[i].Approved = &approved
+			cases[i].DecidedBy = e.Actor
+		}
+	}
+	return cases, skipped
+}
+
+// sorted returns the rules in the order they are matched
+func (e *Engine) sorted() []rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rules
+}
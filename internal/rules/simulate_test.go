@@ -0,0 +1,103 @@
+package rules
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/open-agents/bridge/internal/audit"
+	"github.com/open-agents/bridge/internal/config"
+)
+
+func TestLint(t *testing.T) {
+	issues := Lint([]config.AutoApprovalRule{
+		{ID: "git", Tool: "execute_bash", Pattern: "git", Action: ActionApprove},
+		{ID: "git-status", Tool: "execute_bash", Pattern: "git status", Action: ActionApprove},
+		{ID: "git-push", Tool: "execute_bash", Pattern: "git push", Action: ActionDeny},
+		{ID: "src", Tool: "fs_write", Pattern: "/repo/**", Action: ActionApprove},
+		{ID: "src-go", Tool: "fs_write", Pattern: "/repo/src/*.go", Action: ActionDeny},
+		{ID: "python", Tool: "execute_bash", Pattern: "python *", Action: ActionApprove},
+		{ID: "aws", Tool: "use_aws", Pattern: "s3", Action: ActionApprove},
+		{ID: "bad", Tool: "execute_bash", Pattern: "make", Action: ActionApprove, Expression: "risk =="},
+		{ID: "ask-all", Action: ActionAsk},
+		{ID: "after", Tool: "fs_read", Pattern: "/tmp/*", Action: ActionApprove},
+		{ID: "first", Action: ActionDeny, Priority: 10, Expression: `risk == "high"`},
+	})
+
+	got := map[string]string{}
+	for _, is := range issues {
+		got[is.RuleID] = is.Kind
+	}
+	want := map[string]string{
+		"git-status": IssueShadowed,    // same action as "git"
+		"src-go":     IssueShadowed,    // never gets to deny
+		"python":     IssueBroad,       // an interpreter
+		"aws":        IssueUnreachable, // patterns never match use_aws
+		"bad":        IssueInvalid,
+		"after":      IssueUnreachable, // ask-all matches everything first
+	}
+	for id, kind := range want {
+		if got[id] != kind {
+			t.Errorf("%s: issue %q, want %q (%+v)", id, got[id], kind, issues)
+		}
+	}
+	for _, id := range []string{"git", "git-push", "ask-all", "first"} {
+		if got[id] != "" {
+			t.Errorf("%s: unexpected issue %q", id, got[id])
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := audit.Open(path, []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Append(audit.Entry{Type: audit.SessionStart, SessionID: "s1", Data: map[string]any{"cliType": "claude", "workDir": "/repo"}})
+	request := func(id, tool string, input map[string]any) {
+		l.Append(audit.Entry{Type: audit.PermissionRequest, SessionID: "s1", Data: map[string]any{"id": id, "origin": "hook", "tool": tool, "input": input}})
+	}
+	request("1", "execute_bash", map[string]any{"command": "go test ./..."})
+	l.Append(audit.Entry{Type: audit.PermissionDecision, SessionID: "s1", Actor: "web-1", Data: map[string]any{"id": "1", "approved": true}})
+	request("2", "execute_bash", map[string]any{"command": "rm -rf build"})
+	request("3", "fs_write", map[string]any{"path": "/repo/main.go"})
+	l.Append(audit.Entry{Type: audit.PermissionRequest, SessionID: "s1", Data: map[string]any{"id": "4", "origin": "acp", "tool": "Bash"}})
+	l.Close()
+
+	entries, err := audit.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases, skipped := CasesFromAudit(entries, nil)
+	if len(cases) != 3 || skipped != 1 {
+		t.Fatalf("cases = %+v, skipped %d", cases, skipped)
+	}
+	if c := cases[0]; c.Approved == nil || !*c.Approved || c.DecidedBy != "web-1" || c.Request.WorkDir != "/repo" || c.Request.Time.IsZero() {
+		t.Errorf("case 1 = %+v", c)
+	}
+
+	current := []config.AutoApprovalRule{{ID: "go", Tool: "execute_bash", Pattern: "go", Action: ActionApprove}}
+	proposed := []config.AutoApprovalRule{
+		{ID: "tests", Tool: "execute_bash", Pattern: "go test", Action: ActionApprove, Expression: `workDir == "/repo"`},
+		{ID: "rm", Tool: "execute_bash", Pattern: "rm -rf", Action: ActionDeny},
+		{ID: "docs", Tool: "fs_write", Pattern: "/repo/docs/**", Action: ActionApprove},
+	}
+	im := Simulate(current, proposed, cases)
+	if len(im.Changed) != 1 || im.Changed[0].ID != "2" || im.Changed[0].Before.Action != ActionAsk || im.Changed[0].After.Action != ActionDeny {
+		t.Errorf("changed = %+v", im.Changed)
+	}
+	if im.Hits["tests"] != 1 || im.Hits["rm"] != 1 || im.After[ActionAsk] != 1 {
+		t.Errorf("hits = %v, after = %v", im.Hits, im.After)
+	}
+	if len(im.Issues) != 1 || im.Issues[0].RuleID != "docs" || im.Issues[0].Kind != IssueUnused {
+		t.Errorf("issues = %+v", im.Issues)
+	}
+
+	// Replayed requests keep the time they were made, for hour and weekday
+	night := []config.AutoApprovalRule{{ID: "night", Action: ActionDeny, Expression: "hour < 6"}}
+	c := Case{Request: Request{Tool: "fs_read", Path: "/etc/hosts", Time: time.Date(2026, 1, 1, 3, 0, 0, 0, time.Local)}}
+	if im := Simulate(nil, night, []Case{c}); len(im.Changed) != 1 {
+		t.Errorf("night: %+v", im)
+	}
+}
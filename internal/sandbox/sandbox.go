@@ -0,0 +1,198 @@
+// Package sandbox runs agent CLIs and their commands inside Linux namespaces
+// with a read-write view of the session workDir and a read-only view of the
+// rest of the system.
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Network access inside the sandbox
+const (
+	NetworkNone     = "none"     // no network at all
+	NetworkLoopback = "loopback" // private loopback interface only
+	NetworkFull     = "full"     // host network
+)
+
+// InitArg is the argv[1] marker for the re-exec'd sandbox helper
+const InitArg = "__sandbox-init"
+
+// specEnv carries the JSON-encoded Spec to the helper process
+const specEnv = "OPEN_AGENTS_SANDBOX_SPEC"
+
+// Config is the user-facing sandbox configuration
+type Config struct {
+	Network        string   `json:"network,omitempty"`        // network for the CLI process (default: full, CLIs need their model API)
+	CommandNetwork string   `json:"commandNetwork,omitempty"` // network for agent-run terminal commands (default: none)
+	Writable       []string `json:"writable,omitempty"`       // extra read-write paths (~ expands to home)
+}
+
+// Spec describes one sandboxed process
+type Spec struct {
+	WorkDir  string   `json:"workDir"`
+	Network  string   `json:"network"`
+	Writable []string `json:"writable,omitempty"`
+	Hidden   []string `json:"hidden,omitempty"` // directories shown empty, such as the bridge's own credentials
+}
+
+// Spec returns the sandbox for the CLI process of a session
+func (c *Config) Spec(workDir string, extraWritable ...string) (Spec, error) {
+	network := c.Network
+	if network == "" {
+		network = NetworkFull
+	}
+	return newSpec(workDir, network, append(c.Writable, extraWritable...))
+}
+
+// CommandSpec returns the sandbox for terminal commands run on the agent's behalf
+func (c *Config) CommandSpec(workDir string) (Spec, error) {
+	network := c.CommandNetwork
+	if network == "" {
+		network = NetworkNone
+	}
+	return newSpec(workDir, network, c.Writable)
+}
+
+// Merge returns a copy of c narrowed by override, the sandbox a session asks
+// for. A session may only tighten the device's sandbox: its networks apply
+// when they allow less than the device's, and its writable paths only where
+// they lie inside a path the device already makes writable.
+func (c *Config) Merge(override *Config) *Config {
+	merged := &Config{}
+	if c != nil {
+		*merged = *c
+		merged.Writable = append([]string(nil), c.Writable...)
+	}
+	if override == nil {
+		return merged
+	}
+	if narrowerNetwork(override.Network, merged.Network, NetworkFull) {
+		merged.Network = override.Network
+	}
+	if narrowerNetwork(override.CommandNetwork, merged.CommandNetwork, NetworkNone) {
+		merged.CommandNetwork = override.CommandNetwork
+	}
+	if len(override.Writable) > 0 {
+		var writable []string
+		for _, p := range override.Writable {
+			p = filepath.Clean(expandHome(p))
+			if !filepath.IsAbs(p) {
+				continue
+			}
+			for _, root := range merged.Writable {
+				if root = expandHome(root); root != "" && within(p, filepath.Clean(root)) {
+					writable = append(writable, p)
+					break
+				}
+			}
+		}
+		merged.Writable = writable
+	}
+	return merged
+}
+
+// networkRank orders the network settings from least to most access
+var networkRank = map[string]int{NetworkNone: 0, NetworkLoopback: 1, NetworkFull: 2}
+
+// narrowerNetwork reports whether requested allows less network access than
+// current, or def when current is unset
+func narrowerNetwork(requested, current, def string) bool {
+	if current == "" {
+		current = def
+	}
+	r, ok := networkRank[requested]
+	c, known := networkRank[current]
+	return ok && known && r < c
+}
+
+func newSpec(workDir, network string, writable []string) (Spec, error) {
+	switch network {
+	case NetworkNone, NetworkLoopback, NetworkFull:
+	default:
+		return Spec{}, fmt.Errorf("invalid sandbox network %q (want none, loopback or full)", network)
+	}
+
+	dir, err := filepath.Abs(workDir)
+	if err != nil {
+		return Spec{}, err
+	}
+	if dir == string(filepath.Separator) {
+		return Spec{}, fmt.Errorf("refusing to sandbox with the filesystem root as workDir")
+	}
+	if home, _ := os.UserHomeDir(); home != "" && dir == filepath.Clean(home) {
+		return Spec{}, fmt.Errorf("refusing to sandbox with the home directory as workDir")
+	}
+
+	spec := Spec{WorkDir: dir, Network: network}
+	for _, p := range writable {
+		if p = expandHome(p); p == "" || !filepath.IsAbs(p) {
+			continue
+		}
+		spec.Writable = append(spec.Writable, filepath.Clean(p))
+	}
+	return spec, nil
+}
+
+// CanWrite reports whether path lies inside one of the spec's writable trees.
+// The bridge uses it for writes it performs on the agent's behalf (ACP fs/write).
+func (s *Spec) CanWrite(path string) bool {
+	if s == nil {
+		return true
+	}
+	path, ok := resolvePath(path)
+	if !ok {
+		return false
+	}
+	for _, root := range append([]string{s.WorkDir}, s.Writable...) {
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		if within(path, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath resolves the symlinks of path through its longest existing
+// ancestor, so that missing directories below a symlinked one still resolve
+// to where a write would land. It fails for a path through a dangling or
+// unreadable link, whose target cannot be checked.
+func resolvePath(path string) (string, bool) {
+	path = filepath.Clean(path)
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return resolved, true
+		}
+		if _, err := os.Lstat(dir); err == nil || !os.IsNotExist(err) {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return path, true
+		}
+		missing = append(missing, filepath.Base(dir))
+	}
+}
+
+func within(path, root string) bool {
+	return path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(home, strings.TrimPrefix(p, "~"))
+	}
+	return p
+}
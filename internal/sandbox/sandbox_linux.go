@@ -0,0 +1,382 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// gateEnv names the fd the helper waits on before starting the target, so the
+// bridge can apply resource limits to the helper before anything is forked.
+const gateEnv = "OPEN_AGENTS_SANDBOX_GATE"
+
+// statfs flag bits (ST_*) missing from the syscall package
+const (
+	stNosuid     = 0x2
+	stNodev      = 0x4
+	stNoexec     = 0x8
+	stNoatime    = 0x400
+	stNodiratime = 0x800
+	stRelatime   = 0x1000
+)
+
+const (
+	siocgifflags = 0x8913
+	siocsifflags = 0x8914
+)
+
+// Wrap rewrites cmd so it runs inside a sandbox described by spec. The
+// returned release function must be called once cmd has been started (or has
+// failed to start); until then the sandboxed program is held back.
+func Wrap(cmd *exec.Cmd, spec Spec) (func(), error) {
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	gateR, gateW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	gateFD := 3 + len(cmd.ExtraFiles)
+	cmd.ExtraFiles = append(cmd.ExtraFiles, gateR)
+	cmd.Args = append([]string{"open-agents", InitArg, "--", cmd.Path}, cmd.Args[1:]...)
+	// /proc/self/exe survives the bridge binary being replaced by an update
+	cmd.Path = "/proc/self/exe"
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(withoutEnv(env, specEnv, gateEnv),
+		specEnv+"="+string(data),
+		gateEnv+"="+strconv.Itoa(gateFD))
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID)
+	if spec.Network != NetworkFull {
+		flags |= syscall.CLONE_NEWNET
+	}
+	cmd.SysProcAttr.Cloneflags |= flags
+	uid, gid := os.Getuid(), os.Getgid()
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+	cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			gateR.Close()
+			gateW.Write([]byte{1})
+			gateW.Close()
+		})
+	}
+	return release, nil
+}
+
+// Init is the entry point of the re-exec'd helper. It finishes setting up the
+// namespaces, waits for the bridge to release it, then runs the target program
+// as its child and exits with the target's status. It never returns.
+func Init() {
+	args := os.Args[2:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fail(fmt.Errorf("no command given"))
+	}
+
+	var spec Spec
+	if err := json.Unmarshal([]byte(os.Getenv(specEnv)), &spec); err != nil {
+		fail(fmt.Errorf("invalid spec: %w", err))
+	}
+	gateFD, _ := strconv.Atoi(os.Getenv(gateEnv))
+	os.Unsetenv(specEnv)
+	os.Unsetenv(gateEnv)
+
+	wd, _ := os.Getwd()
+	if err := setupMounts(spec); err != nil {
+		fail(err)
+	}
+	// Re-enter the workDir so relative paths resolve through the writable bind
+	if wd != "" {
+		os.Chdir(wd)
+	}
+	if spec.Network == NetworkLoopback {
+		if err := loopbackUp(); err != nil {
+			fail(fmt.Errorf("loopback: %w", err))
+		}
+	}
+
+	if gateFD > 0 {
+		gate := os.NewFile(uintptr(gateFD), "gate")
+		buf := make([]byte, 1)
+		if n, _ := gate.Read(buf); n != 1 {
+			os.Exit(126)
+		}
+		gate.Close()
+	}
+
+	child := exec.Command(args[0], args[1:]...)
+	child.Stdin, child.Stdout, child.Stderr = os.Stdin, os.Stdout, os.Stderr
+	// Catch (rather than ignore) terminal signals so the child keeps default handlers;
+	// it shares our process group and receives them from the tty directly.
+	sigs := make(chan os.Signal, 8)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGHUP)
+	if err := child.Start(); err != nil {
+		fail(err)
+	}
+	go func() {
+		for sig := range sigs {
+			if sig == syscall.SIGTERM || sig == syscall.SIGHUP {
+				child.Process.Signal(sig)
+			}
+		}
+	}()
+
+	// As PID 1 of the namespace, reap everything; exiting tears down the rest
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &status, 0, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+		if pid != child.Process.Pid {
+			continue
+		}
+		if status.Signaled() {
+			os.Exit(128 + int(status.Signal()))
+		}
+		os.Exit(status.ExitStatus())
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "[Sandbox] %v\n", err)
+	os.Exit(126)
+}
+
+// setupMounts binds the writable trees onto themselves and makes every other
+// mount read-only
+func setupMounts(spec Spec) error {
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+
+	var writable []string
+	for i, p := range append([]string{spec.WorkDir}, spec.Writable...) {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			if i == 0 {
+				return fmt.Errorf("workDir: %w", err)
+			}
+			continue // optional paths that don't exist are skipped
+		}
+		if err := syscall.Mount(resolved, resolved, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("bind %s: %w", resolved, err)
+		}
+		writable = append(writable, resolved)
+	}
+
+	mounts, err := mountPoints()
+	if err != nil {
+		return err
+	}
+	for _, mp := range mounts {
+		if skipRemount(mp, writable) {
+			continue
+		}
+		if err := remountReadOnly(mp); err != nil {
+			// Mount points hidden by a later mount can't be addressed and don't matter
+			if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOENT) {
+				continue
+			}
+			return fmt.Errorf("remount %s read-only: %w", mp, err)
+		}
+	}
+
+	// Private /tmp, unless it would hide a writable tree
+	tmpInUse := false
+	for _, w := range writable {
+		if within(w, "/tmp") {
+			tmpInUse = true
+		}
+	}
+	if !tmpInUse {
+		if err := syscall.Mount("tmpfs", "/tmp", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+			fmt.Fprintf(os.Stderr, "[Sandbox] private /tmp unavailable: %v\n", err)
+		}
+	}
+
+	// Hidden directories, such as the bridge's control socket and keys, are
+	// covered with an empty one; a pathname socket is reachable from any
+	// namespace that can see it. A directory holding a writable tree stays.
+	for _, h := range spec.Hidden {
+		resolved, err := filepath.EvalSymlinks(h)
+		if err != nil {
+			continue // nothing to hide
+		}
+		if holdsWritable(resolved, writable) {
+			fmt.Fprintf(os.Stderr, "[Sandbox] %s holds a writable path and stays visible\n", resolved)
+			continue
+		}
+		if err := syscall.Mount("tmpfs", resolved, "tmpfs", syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "mode=0500"); err != nil {
+			return fmt.Errorf("hide %s: %w", resolved, err)
+		}
+	}
+
+	// Fresh /proc so the agent only sees its own processes (best effort)
+	syscall.Mount("proc", "/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "")
+	return nil
+}
+
+func holdsWritable(dir string, writable []string) bool {
+	for _, w := range writable {
+		if within(w, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func skipRemount(mp string, writable []string) bool {
+	for _, pseudo := range []string{"/proc", "/dev", "/sys"} {
+		if within(mp, pseudo) {
+			return true
+		}
+	}
+	for _, w := range writable {
+		if within(mp, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// remountReadOnly adds MS_RDONLY to a mount, keeping the flags a user
+// namespace is not allowed to change
+func remountReadOnly(mp string) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(mp, &st); err != nil {
+		return err
+	}
+	stFlags := int64(st.Flags)
+	flags := uintptr(syscall.MS_REMOUNT | syscall.MS_BIND | syscall.MS_RDONLY)
+	for _, f := range []struct {
+		st int64
+		ms uintptr
+	}{
+		{stNosuid, syscall.MS_NOSUID},
+		{stNodev, syscall.MS_NODEV},
+		{stNoexec, syscall.MS_NOEXEC},
+		{stNoatime, syscall.MS_NOATIME},
+		{stNodiratime, syscall.MS_NODIRATIME},
+		{stRelatime, syscall.MS_RELATIME},
+	} {
+		if stFlags&f.st != 0 {
+			flags |= f.ms
+		}
+	}
+	if stFlags&(stNoatime|stRelatime) == 0 {
+		flags |= syscall.MS_STRICTATIME
+	}
+	return syscall.Mount("", mp, "", flags, "")
+}
+
+func mountPoints() ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		mounts = append(mounts, unescapeMountPath(fields[4]))
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes (\040 etc.) used in mountinfo
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// loopbackUp brings up "lo" in the new network namespace
+func loopbackUp() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	var ifr struct {
+		name  [syscall.IFNAMSIZ]byte
+		flags uint16
+		_     [22]byte
+	}
+	copy(ifr.name[:], "lo")
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), siocgifflags, uintptr(unsafe.Pointer(&ifr))); errno != 0 {
+		return errno
+	}
+	ifr.flags |= syscall.IFF_UP
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), siocsifflags, uintptr(unsafe.Pointer(&ifr))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func withoutEnv(env []string, names ...string) []string {
+	out := make([]string, 0, len(env))
+	for _, e := range env {
+		keep := true
+		for _, name := range names {
+			if strings.HasPrefix(e, name+"=") {
+				keep = false
+			}
+		}
+		if keep {
+			out = append(out, e)
+		}
+	}
+	return out
+}
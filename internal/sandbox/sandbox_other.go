@@ -0,0 +1,21 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// Wrap is not supported outside Linux; sandboxed sessions fail to start
+func Wrap(cmd *exec.Cmd, spec Spec) (func(), error) {
+	return nil, fmt.Errorf("sandbox mode is not supported on %s", runtime.GOOS)
+}
+
+// Init is never reached outside Linux
+func Init() {
+	fmt.Fprintln(os.Stderr, "[Sandbox] not supported on", runtime.GOOS)
+	os.Exit(126)
+}
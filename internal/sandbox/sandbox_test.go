@@ -0,0 +1,228 @@
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Wrapped commands re-exec the test binary as the sandbox helper
+	if len(os.Args) > 1 && os.Args[1] == InitArg {
+		Init()
+	}
+	os.Exit(m.Run())
+}
+
+func TestSpecValidation(t *testing.T) {
+	cfg := &Config{}
+	if _, err := cfg.Spec("/"); err == nil {
+		t.Error("root workDir should be refused")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if _, err := cfg.Spec(home); err == nil {
+			t.Error("home workDir should be refused")
+		}
+	}
+	if _, err := (&Config{Network: "wifi"}).Spec(t.TempDir()); err == nil {
+		t.Error("unknown network should be refused")
+	}
+
+	spec, err := cfg.Spec(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Network != NetworkFull {
+		t.Errorf("CLI network = %q, want full", spec.Network)
+	}
+	cmdSpec, _ := cfg.CommandSpec(t.TempDir())
+	if cmdSpec.Network != NetworkNone {
+		t.Errorf("command network = %q, want none", cmdSpec.Network)
+	}
+}
+
+func TestCanWrite(t *testing.T) {
+	dir := t.TempDir()
+	extra := t.TempDir()
+	spec, err := (&Config{Writable: []string{extra}}).Spec(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]bool{
+		filepath.Join(dir, "a.txt"):        true,
+		filepath.Join(dir, "sub", "b.txt"): true,
+		filepath.Join(extra, "c.txt"):      true,
+		dir + "-sibling/d.txt":             false,
+		"/etc/passwd":                      false,
+		filepath.Join(dir, "..", "e.txt"):  false,
+	}
+	for path, want := range cases {
+		if got := spec.CanWrite(path); got != want {
+			t.Errorf("CanWrite(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestCanWriteThroughSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "missing"), filepath.Join(dir, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := (&Config{}).Spec(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]bool{
+		filepath.Join(dir, "link", "a.txt"):                false,
+		filepath.Join(dir, "link", "new", "deep", "b.txt"): false,
+		filepath.Join(dir, "dangling"):                     false,
+		filepath.Join(dir, "dangling", "c.txt"):            false,
+		filepath.Join(dir, "new", "deep", "d.txt"):         true,
+	}
+	for path, want := range cases {
+		if got := spec.CanWrite(path); got != want {
+			t.Errorf("CanWrite(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestMergeOnlyNarrows(t *testing.T) {
+	shared := t.TempDir()
+	device := &Config{Network: NetworkLoopback, Writable: []string{shared}}
+
+	got := device.Merge(&Config{
+		Network:        NetworkFull,
+		CommandNetwork: NetworkFull,
+		Writable:       []string{"/etc", filepath.Join(shared, "sub")},
+	})
+	if got.Network != NetworkLoopback {
+		t.Errorf("Network = %q, want the device's loopback", got.Network)
+	}
+	if got.CommandNetwork != "" {
+		t.Errorf("CommandNetwork = %q, want the device default", got.CommandNetwork)
+	}
+	if len(got.Writable) != 1 || got.Writable[0] != filepath.Join(shared, "sub") {
+		t.Errorf("Writable = %v, want only the path inside the device's", got.Writable)
+	}
+
+	got = device.Merge(&Config{Network: NetworkNone})
+	if got.Network != NetworkNone {
+		t.Errorf("Network = %q, want none", got.Network)
+	}
+	if len(got.Writable) != 1 || got.Writable[0] != shared {
+		t.Errorf("Writable = %v, want the device's", got.Writable)
+	}
+
+	if got := (*Config)(nil).Merge(&Config{Network: NetworkFull, Writable: []string{"/"}}); got.Network != "" || len(got.Writable) != 0 {
+		t.Errorf("Merge without a device config = %+v, want the defaults", got)
+	}
+}
+
+func runSandboxed(t *testing.T, spec Spec, script string) (string, error) {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Dir = spec.WorkDir
+	release, err := Wrap(cmd, spec)
+	if err != nil {
+		t.Fatalf("Wrap: %v", err)
+	}
+	var out strings.Builder
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Start(); err != nil {
+		release()
+		t.Skipf("user namespaces unavailable: %v", err)
+	}
+	release()
+	err = cmd.Wait()
+	return out.String(), err
+}
+
+func TestSandboxFilesystem(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is Linux only")
+	}
+	dir := t.TempDir()
+	outside := t.TempDir()
+	spec, err := (&Config{}).CommandSpec(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runSandboxed(t, spec, "echo hi > inside.txt && touch "+outside+"/escape.txt")
+	if err == nil {
+		t.Fatalf("write outside workDir should fail, output: %s", out)
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "inside.txt")); statErr != nil {
+		t.Errorf("write inside workDir failed: %v (output: %s)", statErr, out)
+	}
+	if _, statErr := os.Stat(filepath.Join(outside, "escape.txt")); statErr == nil {
+		t.Error("file was created outside the sandbox")
+	}
+}
+
+func TestSandboxHidesBridgeState(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is Linux only")
+	}
+	state := t.TempDir()
+	os.MkdirAll(filepath.Join(state, "run"), 0700)
+	token := filepath.Join(state, "run", "default.token")
+	os.WriteFile(token, []byte("secret-token"), 0600)
+
+	spec, _ := (&Config{}).CommandSpec(t.TempDir())
+	spec.Hidden = []string{state, filepath.Join(state, "run")}
+	if out, err := runSandboxed(t, spec, "cat "+token); err == nil || strings.Contains(out, "secret-token") {
+		t.Errorf("token readable in the sandbox: %v, %s", err, out)
+	}
+
+	// A config directory holding the workDir stays; its run/ is still hidden
+	spec, _ = (&Config{}).CommandSpec(filepath.Join(state, "work"))
+	os.MkdirAll(spec.WorkDir, 0700)
+	spec.Hidden = []string{state, filepath.Join(state, "run")}
+	out, err := runSandboxed(t, spec, "touch inside.txt && cat "+token)
+	if err == nil || strings.Contains(out, "secret-token") {
+		t.Errorf("token readable with the workDir in the config directory: %v, %s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(spec.WorkDir, "inside.txt")); err != nil {
+		t.Errorf("workDir inside the config directory not writable: %v (%s)", err, out)
+	}
+}
+
+func TestSandboxExitCode(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is Linux only")
+	}
+	spec, _ := (&Config{}).CommandSpec(t.TempDir())
+	_, err := runSandboxed(t, spec, "exit 7")
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 7 {
+		t.Errorf("exit = %v, want status 7", err)
+	}
+}
+
+func TestSandboxNetwork(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is Linux only")
+	}
+	spec, _ := (&Config{CommandNetwork: NetworkLoopback}).CommandSpec(t.TempDir())
+	out, err := runSandboxed(t, spec, "cat /proc/net/dev")
+	if err != nil {
+		t.Fatalf("cat /proc/net/dev: %v (%s)", err, out)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		name, _, found := strings.Cut(strings.TrimSpace(line), ":")
+		if found && name != "lo" {
+			t.Errorf("unexpected interface %q in loopback sandbox", name)
+		}
+	}
+}
@@ -78,7 +78,7 @@ func (c *CodeShieldScanner) Scan(text string, dir Direction) []Alert {
 	}
 	var alerts []Alert
 	for _, r := range compiledCodeRules {
-		if loc := r.pattern.FindStringIndex(text); loc != nil {
+		for _, loc := range r.pattern.FindAllStringIndex(text, maxMatches) {
 			match := text[loc[0]:loc[1]]
 			if len(match) > 80 {
 				match = match[:80] + "..."
@@ -90,6 +90,8 @@ func (c *CodeShieldScanner) Scan(text string, dir Direction) []Alert {
 				Title:       r.title,
 				Description: r.desc,
 				Match:       match,
+				Start:       loc[0],
+				End:         loc[1],
 			})
 		}
 	}
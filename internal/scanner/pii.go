@@ -46,7 +46,11 @@ func init() {
 func (p *PIIScanner) Scan(text string, dir Direction) []Alert {
 	var alerts []Alert
 	for _, r := range compiledPIIRules {
-		if loc := r.pattern.FindStringIndex(text); loc != nil {
+		for _, loc := range r.pattern.FindAllStringIndex(text, maxMatches) {
+			confidence := checkConfidence(piiChecks, r, text[loc[0]:loc[1]])
+			if confidence == 0 {
+				continue
+			}
 			alerts = append(alerts, Alert{
 				Category:    CategoryPII,
 				Level:       r.level,
@@ -54,6 +58,9 @@ func (p *PIIScanner) Scan(text string, dir Direction) []Alert {
 				Title:       r.title,
 				Description: r.desc,
 				Match:       Redact(text[loc[0]:loc[1]]),
+				Confidence:  confidence,
+				Start:       loc[0],
+				End:         loc[1],
 			})
 		}
 	}
@@ -47,7 +47,7 @@ func (d *DangerousCmdScanner) Scan(text string, dir Direction) []Alert {
 	}
 	var alerts []Alert
 	for _, r := range compiledCmdRules {
-		if loc := r.pattern.FindStringIndex(text); loc != nil {
+		for _, loc := range r.pattern.FindAllStringIndex(text, maxMatches) {
 			match := text[loc[0]:loc[1]]
 			if len(match) > 60 {
 				match = match[:60] + "..."
@@ -59,6 +59,8 @@ func (d *DangerousCmdScanner) Scan(text string, dir Direction) []Alert {
 				Title:       r.title,
 				Description: r.desc,
 				Match:       match,
+				Start:       loc[0],
+				End:         loc[1],
 			})
 		}
 	}
@@ -86,7 +88,8 @@ func (p *PathScanner) Scan(text string, dir Direction) []Alert {
 		if strings.HasPrefix(path, "~/") {
 			short = path[2:]
 		}
-		if strings.Contains(text, path) || strings.Contains(text, short) {
+		// short is a suffix of path, so it finds both
+		if i := strings.Index(text, short); i >= 0 {
 			alerts = append(alerts, Alert{
 				Category:    CategoryPathAccess,
 				Level:       AlertWarning,
@@ -94,6 +97,8 @@ func (p *PathScanner) Scan(text string, dir Direction) []Alert {
 				Title:       "Sensitive Path Access",
 				Description: fmt.Sprintf("Access to sensitive path %s detected.", path),
 				Match:       path,
+				Start:       i,
+				End:         i + len(short),
 			})
 		}
 	}
@@ -156,7 +161,7 @@ func (c *CustomRuleScanner) Scan(text string, dir Direction) []Alert {
 	}
 	var alerts []Alert
 	for _, r := range c.rules {
-		if loc := r.pattern.FindStringIndex(text); loc != nil {
+		for _, loc := range r.pattern.FindAllStringIndex(text, maxMatches) {
 			alerts = append(alerts, Alert{
 				Category:    CategorySensitiveData,
 				Level:       r.level,
@@ -164,6 +169,8 @@ func (c *CustomRuleScanner) Scan(text string, dir Direction) []Alert {
 				Title:       r.title,
 				Description: r.desc,
 				Match:       Redact(text[loc[0]:loc[1]]),
+				Start:       loc[0],
+				End:         loc[1],
 			})
 		}
 	}
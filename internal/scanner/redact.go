@@ -0,0 +1,206 @@
+package scanner
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactConfig selects the findings masked in content sent off the device.
+// Plugins maps a plugin name, or "*" for the others, to the lowest level
+// masked: "critical", or "warning" for both levels. Nil masks nothing.
+type RedactConfig struct {
+	Plugins map[string]AlertLevel `json:"plugins,omitempty"`
+}
+
+// Selects reports whether an alert's match is masked
+func (c *RedactConfig) Selects(a Alert) bool {
+	if c == nil {
+		return false
+	}
+	level, ok := c.Plugins[a.Plugin]
+	if !ok {
+		level = c.Plugins["*"]
+	}
+	switch level {
+	case AlertWarning:
+		return true
+	case AlertCritical:
+		return a.Level == AlertCritical
+	}
+	return false
+}
+
+// SetRedaction sets which findings are masked; nil masks nothing
+func (s *Scanner) SetRedaction(c *RedactConfig) {
+	s.mu.Lock()
+	s.redact = c
+	s.mu.Unlock()
+}
+
+// Redaction returns which findings are masked
+func (s *Scanner) Redaction() *RedactConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.redact
+}
+
+// Mask is how a masked value appears: Redact's mask and the finding ID the
+// alert about it carries
+func Mask(value, findingID string) string {
+	return Redact(value) + "[finding:" + findingID + "]"
+}
+
+// RedactText masks the findings the redaction config selects in text. It
+// returns the masked text and the alerts about what was masked, once each.
+func (s *Scanner) RedactText(text string, dir Direction) (string, []Alert) {
+	cfg := s.Redaction()
+	if cfg == nil {
+		return text, nil
+	}
+	all := s.scan(text, dir)
+	var masked []Alert
+	seen := make(map[string]bool)
+	for i := range all {
+		if !cfg.Selects(all[i]) {
+			continue
+		}
+		all[i].Redacted = true
+		if !seen[all[i].FindingID] {
+			seen[all[i].FindingID] = true
+			masked = append(masked, all[i])
+		}
+	}
+	if len(masked) == 0 {
+		return text, nil
+	}
+	extendKeys(text, all)
+	return maskSpans(text, 0, all), masked
+}
+
+// RedactValue masks selected findings in every string of a JSON-like value
+// (maps, slices and strings). What changes is copied; v itself is left as it
+// is.
+func (s *Scanner) RedactValue(v interface{}, dir Direction) (interface{}, []Alert) {
+	if s.Redaction() == nil {
+		return v, nil
+	}
+	var alerts []Alert
+	seen := make(map[string]bool)
+	var walk func(v interface{}) (interface{}, bool)
+	walk = func(v interface{}) (interface{}, bool) {
+		switch v := v.(type) {
+		case string:
+			masked, found := s.RedactText(v, dir)
+			for _, a := range found {
+				if !seen[a.FindingID] {
+					seen[a.FindingID] = true
+					alerts = append(alerts, a)
+				}
+			}
+			return masked, len(found) > 0
+		case map[string]interface{}:
+			var out map[string]interface{}
+			for k, e := range v {
+				m, changed := walk(e)
+				if !changed {
+					continue
+				}
+				if out == nil {
+					out = make(map[string]interface{}, len(v))
+					for k2, e2 := range v {
+						out[k2] = e2
+					}
+				}
+				out[k] = m
+			}
+			if out == nil {
+				return v, false
+			}
+			return out, true
+		case []interface{}:
+			var out []interface{}
+			for i, e := range v {
+				m, changed := walk(e)
+				if !changed {
+					continue
+				}
+				if out == nil {
+					out = append([]interface{}(nil), v...)
+				}
+				out[i] = m
+			}
+			if out == nil {
+				return v, false
+			}
+			return out, true
+		}
+		return v, false
+	}
+	out, _ := walk(v)
+	return out, alerts
+}
+
+// keyEnd is the END line of a private key block
+var keyEnd = regexp.MustCompile(`-----END[ A-Z]*PRIVATE KEY-----`)
+
+// extendKeys makes the masks of redacted private keys go on to the next END
+// line, or to the end of text, where the pattern stopped at the BEGIN line:
+// the body has characters the pattern doesn't expect (escaped newlines, say)
+// or has not arrived yet. It returns the finding ID of a key still open at
+// the end of text.
+func extendKeys(text string, alerts []Alert) (open string) {
+	for i := range alerts {
+		a := &alerts[i]
+		if !a.Redacted || a.RuleID != "private_key" || keyEnd.MatchString(text[a.Start:a.End]) {
+			continue
+		}
+		if loc := keyEnd.FindStringIndex(text[a.End:]); loc != nil {
+			a.maskTo = a.End + loc[1]
+		} else {
+			a.maskTo = len(text)
+			open = a.FindingID
+		}
+	}
+	return open
+}
+
+// maskSpans replaces what the redacted alerts cover in text[base:]. A match
+// that started before base, in text already sent, has the rest of it masked.
+func maskSpans(text string, base int, alerts []Alert) string {
+	var spans []Alert
+	for _, a := range alerts {
+		if a.Redacted && a.maskEnd() > base && a.maskEnd() <= len(text) {
+			spans = append(spans, a)
+		}
+	}
+	if len(spans) == 0 {
+		return text[base:]
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+
+	var b strings.Builder
+	pos := base
+	for _, a := range spans {
+		start, end := a.Start, a.maskEnd()
+		if start < base {
+			start = base
+		}
+		if start < pos {
+			// Overlaps the previous mask, which then hides it too
+			if end > pos {
+				pos = end
+			}
+			continue
+		}
+		b.WriteString(text[pos:start])
+		if a.Start >= base {
+			b.WriteString(Mask(text[a.Start:a.End], a.FindingID))
+		} else {
+			b.WriteString("****[finding:" + a.FindingID + "]")
+		}
+		pos = end
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
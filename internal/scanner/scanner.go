@@ -1,6 +1,10 @@
 package scanner
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -13,11 +17,16 @@ type Scanner struct {
 	enabled  bool
 	plugins  []ScannerPlugin
 	disabled map[string]bool // disabled plugin names
+	redact   *RedactConfig
+	idKey    []byte // keys finding IDs, so they do not give away the values found
 }
 
 // New creates a Scanner with all built-in plugins enabled.
 func New() *Scanner {
+	idKey := make([]byte, 32)
+	rand.Read(idKey)
 	s := &Scanner{
+		idKey:    idKey,
 		enabled:  true,
 		disabled: make(map[string]bool),
 		plugins: []ScannerPlugin{
@@ -79,6 +88,21 @@ func (s *Scanner) Scan(text string) []Alert {
 
 // ScanWithDirection runs all enabled plugins with a specific direction.
 func (s *Scanner) ScanWithDirection(text string, dir Direction) []Alert {
+	var alerts []Alert
+	seen := make(map[string]bool)
+	for _, a := range s.scan(text, dir) {
+		// The same value repeated is one finding
+		if !seen[a.FindingID] {
+			seen[a.FindingID] = true
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts
+}
+
+// scan returns every match of the enabled plugins, with the plugin that
+// found it, its finding ID and a confidence
+func (s *Scanner) scan(text string, dir Direction) []Alert {
 	s.mu.RLock()
 	if !s.enabled || len(text) == 0 {
 		s.mu.RUnlock()
@@ -94,13 +118,30 @@ func (s *Scanner) ScanWithDirection(text string, dir Direction) []Alert {
 
 	var alerts []Alert
 	for _, p := range plugins {
-		if a := p.Scan(text, dir); len(a) > 0 {
-			alerts = append(alerts, a...)
+		for _, a := range p.Scan(text, dir) {
+			a.Plugin = p.Name()
+			if a.Confidence == 0 {
+				a.Confidence = defaultConfidence(a.Level)
+			}
+			a.FindingID = s.findingID(a, text)
+			alerts = append(alerts, a)
 		}
 	}
 	return alerts
 }
 
+// findingID identifies the value an alert found in text and the rule that
+// found it
+func (s *Scanner) findingID(a Alert, text string) string {
+	value := a.Match
+	if a.Start < a.End && a.End <= len(text) {
+		value = text[a.Start:a.End]
+	}
+	mac := hmac.New(sha256.New, s.idKey)
+	mac.Write([]byte(a.RuleID + "\x00" + value))
+	return hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
 // ReplaceCustomRules hot-reloads custom rules from web push.
 func (s *Scanner) ReplaceCustomRules(defs []CustomRuleDef) {
 	s.mu.Lock()
@@ -15,7 +15,7 @@ var secretRules = []struct {
 	// Exact format matches (near-zero false positives)
 	{"aws_key", "AWS Access Key", "AWS access key detected. Consider rotating.", `AKIA[0-9A-Z]{16}`, AlertCritical},
 	{"aws_secret", "AWS Secret Key", "AWS secret key detected. Rotate immediately.", `(?i)aws_secret_access_key\s*[:=]\s*[A-Za-z0-9/+=]{40}`, AlertCritical},
-	{"private_key", "Private Key", "Private key content detected.", `-----BEGIN\s+(RSA|EC|DSA|OPENSSH|PGP|ENCRYPTED)?\s*PRIVATE KEY-----`, AlertCritical},
+	{"private_key", "Private Key", "Private key content detected.", `-----BEGIN\s+(RSA|EC|DSA|OPENSSH|PGP|ENCRYPTED)?\s*PRIVATE KEY-----(?:[A-Za-z0-9+/=\s:,.\-]*?-----END[ A-Z]*PRIVATE KEY-----)?`, AlertCritical},
 	{"github_token", "GitHub Token", "GitHub personal access token detected.", `ghp_[A-Za-z0-9]{36}`, AlertCritical},
 	{"github_oauth", "GitHub OAuth Token", "GitHub OAuth token detected.", `gho_[A-Za-z0-9]{36}`, AlertCritical},
 	{"slack_token", "Slack Token", "Slack token detected.", `xox[baprs]-[0-9a-zA-Z\-]{10,}`, AlertCritical},
@@ -50,10 +50,18 @@ type compiledRule struct {
 	pattern         *regexp.Regexp
 }
 
+// Scan matches the rules, drops matches their checks reject, then looks for
+// random-looking values assigned where no rule matched
 func (s *SecretsScanner) Scan(text string, dir Direction) []Alert {
 	var alerts []Alert
+	var matched [][]int
 	for _, r := range compiledSecretRules {
-		if loc := r.pattern.FindStringIndex(text); loc != nil {
+		for _, loc := range r.pattern.FindAllStringIndex(text, maxMatches) {
+			confidence := checkConfidence(secretChecks, r, text[loc[0]:loc[1]])
+			if confidence == 0 {
+				continue
+			}
+			matched = append(matched, loc)
 			alerts = append(alerts, Alert{
 				Category:    CategorySensitiveData,
 				Level:       r.level,
@@ -61,8 +69,11 @@ func (s *SecretsScanner) Scan(text string, dir Direction) []Alert {
 				Title:       r.title,
 				Description: r.desc,
 				Match:       Redact(text[loc[0]:loc[1]]),
+				Confidence:  confidence,
+				Start:       loc[0],
+				End:         loc[1],
 			})
 		}
 	}
-	return alerts
+	return append(alerts, entropyAlerts(text, matched)...)
 }
@@ -0,0 +1,267 @@
+package scanner
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// streamOverlap is how much of the text already scanned is scanned
+	// again with the next chunk, so matches split between chunks are found
+	streamOverlap = 512
+	// maxHeld bounds how far back a match still growing at the end of the
+	// text is held for the next chunk before it is reported as it is
+	maxHeld = 8 << 10
+	// maxSeen bounds the findings a stream remembers as reported
+	maxSeen = 4096
+)
+
+// Stream scans text that arrives in chunks, such as the output of a session.
+// A secret split between two chunks is found, each finding is reported once,
+// and a match that reaches the end of a chunk is held until the next one
+// shows whether it goes on (a longer token, a whole JWT) or Flush is called.
+//
+// With redaction configured, Write also holds back text that the next chunk
+// could turn into a secret, since what it returns is sent on right away: the
+// trailing run of non-space text (every value the masks cover is one), a
+// match still growing at the end and a private key block whose END has not
+// arrived. Release or Flush passes it on, masked as far as it got.
+type Stream struct {
+	s   *Scanner
+	dir Direction
+
+	mu      sync.Mutex
+	tail    string          // the end of the text passed on so far
+	pending string          // text held back from the caller, after tail
+	held    []Alert         // matches reaching the end of the text; offsets are into tail+pending
+	seen    map[string]bool // finding IDs reported
+	openKey string          // finding ID of a private key passed on without its END yet
+}
+
+// NewStream starts a streaming scan in one direction
+func (s *Scanner) NewStream(dir Direction) *Stream {
+	return &Stream{s: s, dir: dir, seen: make(map[string]bool)}
+}
+
+// Write scans a chunk together with the end of the text before it. It
+// returns the text to pass on, with the findings the redaction config
+// selects masked, and the findings not reported before; their offsets are
+// into the text scanned, not into the chunk.
+func (st *Stream) Write(chunk string) (string, []Alert) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if chunk == "" {
+		return chunk, nil
+	}
+	return st.write(chunk, false)
+}
+
+// WriteMessage scans text that is whole on its own, such as a line of
+// stderr, like Write but without holding any of it back. A private key
+// block it leaves open stays masked in what follows until its END.
+func (st *Stream) WriteMessage(text string) (string, []Alert) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if text == "" && st.pending == "" {
+		return text, nil
+	}
+	return st.write(text, true)
+}
+
+// Holding reports whether Write held text back
+func (st *Stream) Holding() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.pending != ""
+}
+
+// Release passes on the text Write held back, masked as far as it is known
+// to be a secret, for when no next chunk came in time
+func (st *Stream) Release() (string, []Alert) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.pending == "" {
+		return "", nil
+	}
+	return st.write("", true)
+}
+
+// write scans chunk after the text seen so far and returns what to pass on;
+// with release nothing is held back
+func (st *Stream) write(chunk string, release bool) (string, []Alert) {
+	text := st.tail + st.pending + chunk
+	base := len(st.tail)
+	all := st.s.scan(text, st.dir)
+	cfg := st.s.Redaction()
+	for i := range all {
+		all[i].Redacted = cfg.Selects(all[i])
+	}
+	if cfg == nil {
+		st.openKey = ""
+	}
+	if st.openKey != "" {
+		// A key that began in text passed on is the open one, found again
+		kept := all[:0]
+		for _, a := range all {
+			if a.RuleID != "private_key" || a.Start >= base {
+				kept = append(kept, a)
+			}
+		}
+		all = kept
+	}
+	open := extendKeys(text, all)
+	if st.openKey != "" {
+		// The rest of a key block passed on before; masked up to its END
+		cont := Alert{RuleID: "private_key", FindingID: st.openKey, Redacted: true, Start: -1, End: len(text)}
+		if loc := keyEnd.FindStringIndex(text); loc != nil && loc[1] > base {
+			cont.End = loc[1]
+		}
+		if cont.End == len(text) && open == "" {
+			open = st.openKey
+		}
+		all = append(all, cont)
+	}
+
+	cut := len(text)
+	if cfg != nil && !release {
+		cut = holdFrom(text, base, all)
+	}
+	masked := maskSpans(text[:cut], base, all)
+	if cut == len(text) {
+		st.openKey = open
+	}
+
+	st.held = st.held[:0]
+	keep := len(text) - streamOverlap
+	var alerts []Alert
+	for _, a := range all {
+		if a.Start < 0 || st.seen[a.FindingID] || containsFinding(st.held, a.FindingID) {
+			continue
+		}
+		// A key block still open is growing too, unless passed on as it is
+		growing := a.End == len(text) || a.maskEnd() == len(text) && cut < len(text)
+		if growing && a.FindingID != st.openKey && a.Start >= len(text)-maxHeld {
+			st.held = append(st.held, a)
+			if a.Start < keep {
+				keep = a.Start
+			}
+			continue
+		}
+		st.report(a.FindingID)
+		alerts = append(alerts, a)
+	}
+
+	if keep > cut {
+		keep = cut
+	}
+	if keep < 0 {
+		keep = 0
+	}
+	for keep > 0 && keep < len(text) && !utf8.RuneStart(text[keep]) {
+		keep--
+	}
+	st.tail = strings.Clone(text[keep:cut])
+	st.pending = strings.Clone(text[cut:])
+	for i := range st.held {
+		st.held[i].Start -= keep
+		st.held[i].End -= keep
+	}
+	return masked, alerts
+}
+
+// keyBegin is the start of a private key's BEGIN line at the end of text
+var keyBegin = regexp.MustCompile(`-{1,5}(B(E(G(I(N[ A-Z]*)?)?)?)?)?$`)
+
+// holdFrom returns where the text to hold back for the next chunk starts:
+// at the trailing run of non-space text of text[base:] (at most
+// streamOverlap of it) or earlier, at a redacted match still growing or
+// open at the end. A mask is never cut in two; a match that began in text
+// already passed on, or is too long to hold, is passed on masked whole.
+func holdFrom(text string, base int, alerts []Alert) int {
+	cut := len(text)
+	for cut > base && len(text)-cut < streamOverlap {
+		r, size := utf8.DecodeLastRuneInString(text[:cut])
+		if unicode.IsSpace(r) {
+			break
+		}
+		cut -= size
+	}
+
+	// A key's BEGIN line not finished yet goes with the key
+	if loc := keyBegin.FindStringIndex(text[base:cut]); loc != nil {
+		cut = base + loc[0]
+	}
+
+	floor := base
+	for changed := true; changed; {
+		changed = false
+		for _, a := range alerts {
+			if !a.Redacted || a.Start >= cut || a.maskEnd() <= cut {
+				continue
+			}
+			if a.Start >= floor && len(text)-a.Start <= maxHeld {
+				cut = a.Start
+			} else {
+				cut, floor = a.maskEnd(), a.maskEnd()
+			}
+			changed = true
+		}
+	}
+	return cut
+}
+
+// Unreported returns the alerts this stream has not reported yet and
+// remembers them as reported, for findings in content scanned on its own
+func (st *Stream) Unreported(alerts []Alert) []Alert {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var fresh []Alert
+	for _, a := range alerts {
+		if !st.seen[a.FindingID] {
+			st.report(a.FindingID)
+			fresh = append(fresh, a)
+		}
+	}
+	return fresh
+}
+
+// Flush passes on the text held back, reports the matches held for a next
+// chunk and forgets the text scanned so far; the findings reported stay
+// remembered
+func (st *Stream) Flush() (string, []Alert) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var text string
+	var alerts []Alert
+	if st.pending != "" {
+		text, alerts = st.write("", true)
+	}
+	for _, a := range st.held {
+		if !st.seen[a.FindingID] {
+			st.report(a.FindingID)
+			alerts = append(alerts, a)
+		}
+	}
+	st.tail, st.pending, st.held, st.openKey = "", "", nil, ""
+	return text, alerts
+}
+
+func (st *Stream) report(findingID string) {
+	if len(st.seen) >= maxSeen {
+		st.seen = make(map[string]bool)
+	}
+	st.seen[findingID] = true
+}
+
+func containsFinding(alerts []Alert, findingID string) bool {
+	for _, a := range alerts {
+		if a.FindingID == findingID {
+			return true
+		}
+	}
+	return false
+}
@@ -35,8 +35,27 @@ type Alert struct {
 	Title       string        `json:"title"`
 	Description string        `json:"description"`
 	Match       string        `json:"match"`
+	Plugin      string        `json:"plugin,omitempty"`
+	FindingID   string        `json:"findingId,omitempty"` // the same for the same value found by the same rule
+	Redacted    bool          `json:"redacted,omitempty"`  // masked in the content sent off the device
+	Confidence  float64       `json:"confidence"`          // 0 to 1, how likely the match is what the rule looks for
+	Start       int           `json:"-"`                   // byte offsets of the match in the scanned text
+	End         int           `json:"-"`
+
+	maskTo int // where masking the match ends when past End, as for a private key block
 }
 
+// maskEnd returns where masking the alert's match ends
+func (a Alert) maskEnd() int {
+	if a.maskTo > a.End {
+		return a.maskTo
+	}
+	return a.End
+}
+
+// maxMatches caps the matches of one rule reported per scan
+const maxMatches = 32
+
 // ScannerPlugin is the interface all scanners implement
 type ScannerPlugin interface {
 	Name() string
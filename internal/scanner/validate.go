@@ -0,0 +1,333 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"hash/crc32"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Confidence is how likely a match is what its rule looks for, from 0 to 1.
+// Exact formats that pass a structural check score near 1; keyword matches
+// score by how random their value is.
+const (
+	confidenceExact   = 0.9 // exact formats with nothing to check
+	confidenceContext = 0.6 // keyword matches with nothing to check
+	confidenceFailed  = 0.2 // the format fits but a structural check fails
+)
+
+// defaultConfidence is the confidence of alerts whose plugin sets none
+func defaultConfidence(level AlertLevel) float64 {
+	if level == AlertCritical {
+		return 0.8
+	}
+	return 0.5
+}
+
+// secretChecks validate what a secrets rule matched and return its
+// confidence; 0 drops the match
+var secretChecks = map[string]func(match string) float64{
+	"private_key":    checkPrivateKey,
+	"github_token":   checkGitHubToken,
+	"github_oauth":   checkGitHubToken,
+	"jwt_token":      checkJWT,
+	"generic_secret": func(m string) float64 { return valueConfidence(assignedValue(m)) },
+	"password_field": checkPassword,
+	"bearer_token":   checkBearer,
+}
+
+// piiChecks are secretChecks for PII rules
+var piiChecks = map[string]func(match string) float64{
+	"pii_credit_card": checkCreditCard,
+}
+
+// checkConfidence runs a rule's check, if it has one
+func checkConfidence(checks map[string]func(string) float64, r compiledRule, match string) float64 {
+	if check, ok := checks[r.id]; ok {
+		return check(match)
+	}
+	if r.level == AlertCritical {
+		return confidenceExact
+	}
+	return confidenceContext
+}
+
+// checkPrivateKey parses the key when the whole PEM block was matched; a
+// header alone, as when the block is split between chunks, is still likely
+func checkPrivateKey(match string) float64 {
+	if !strings.Contains(match, "-----END") {
+		return 0.8
+	}
+	if block, _ := pem.Decode([]byte(match)); block != nil && strings.HasSuffix(block.Type, "PRIVATE KEY") {
+		return 1
+	}
+	return 0.4
+}
+
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// checkGitHubToken verifies the checksum GitHub ends its tokens with: the
+// CRC32 of the 30 random characters, base62 encoded into the last six.
+// Tokens failing it are usually made up, as in docs and tests.
+func checkGitHubToken(match string) float64 {
+	body := match[strings.IndexByte(match, '_')+1:]
+	if len(body) != 36 {
+		return confidenceFailed
+	}
+	if githubChecksum(body[:30]) != body[30:] {
+		return confidenceFailed
+	}
+	return 0.99
+}
+
+func githubChecksum(random string) string {
+	n := crc32.ChecksumIEEE([]byte(random))
+	var sum [6]byte
+	for i := len(sum) - 1; i >= 0; i-- {
+		sum[i] = base62[n%62]
+		n /= 62
+	}
+	return string(sum[:])
+}
+
+// checkJWT decodes the header of a token: a real one is a JSON object
+// naming its algorithm
+func checkJWT(match string) float64 {
+	header := match[:strings.IndexByte(match, '.')]
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(header, "="))
+	if err != nil {
+		return confidenceFailed
+	}
+	var h map[string]interface{}
+	if json.Unmarshal(data, &h) != nil {
+		return confidenceFailed
+	}
+	if alg, _ := h["alg"].(string); alg != "" {
+		return 0.95
+	}
+	return 0.5
+}
+
+// checkCreditCard drops numbers failing the Luhn check, which every card
+// number passes
+func checkCreditCard(match string) float64 {
+	var digits []int
+	for _, c := range match {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, int(c-'0'))
+		}
+	}
+	if !luhn(digits) {
+		return 0
+	}
+	return confidenceExact
+}
+
+func luhn(digits []int) bool {
+	if len(digits) < 12 {
+		return false
+	}
+	sum := 0
+	for i := range digits {
+		d := digits[len(digits)-1-i]
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// checkPassword drops placeholders and references to a password kept
+// elsewhere; a literal scores by how random it is, but even a weak one counts
+func checkPassword(match string) float64 {
+	value := assignedValue(match)
+	if placeholder(value) {
+		return 0
+	}
+	return math.Max(0.3, entropyConfidence(value))
+}
+
+func checkBearer(match string) float64 {
+	fields := strings.Fields(match)
+	return valueConfidence(fields[len(fields)-1])
+}
+
+// valueConfidence scores the value of a keyword match; placeholders and
+// values too regular to be secrets are dropped
+func valueConfidence(value string) float64 {
+	if placeholder(value) {
+		return 0
+	}
+	c := entropyConfidence(value)
+	if c < 0.1 {
+		return 0
+	}
+	return c
+}
+
+// assignedValue is the value of a "key = value" or "key: value" match
+func assignedValue(match string) string {
+	i := strings.IndexAny(match, ":=")
+	if i < 0 {
+		return match
+	}
+	return strings.Trim(match[i+1:], " \t'\"`,;")
+}
+
+var placeholderWords = []string{
+	"xxx", "***", "...", "<", ">", "${", "{{", "%(", "your", "example", "sample",
+	"changeme", "change_me", "placeholder", "redacted", "dummy", "getenv", "environ", "process.env",
+}
+
+var placeholderValues = map[string]bool{
+	"string": true, "str": true, "null": true, "none": true, "nil": true, "undefined": true,
+	"true": true, "false": true, "required": true, "optional": true, "password": true,
+	"passwd": true, "secret": true, "token": true, "test": true, "empty": true,
+}
+
+// placeholder reports whether a value stands in for a secret rather than
+// being one: a type, a template, an environment lookup, a row of x's
+func placeholder(value string) bool {
+	v := strings.ToLower(value)
+	if v == "" || strings.HasPrefix(v, "$") || placeholderValues[v] {
+		return true
+	}
+	for _, w := range placeholderWords {
+		if strings.Contains(v, w) {
+			return true
+		}
+	}
+	return strings.Count(v, v[:1]) == len(v)
+}
+
+// shannonEntropy is the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	h := 0.0
+	n := float64(len(s))
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// normalizedEntropy is the entropy of s relative to the most a string of
+// its length and alphabet can have, so short and hex strings compare with
+// long base64 ones. Random strings score above 0.85, words and
+// identifiers around 0.75.
+func normalizedEntropy(s string) float64 {
+	alphabet := 64.0
+	if isHex(s) {
+		alphabet = 16
+	}
+	most := math.Log2(math.Min(float64(len(s)), alphabet))
+	if most <= 0 {
+		return 0
+	}
+	return shannonEntropy(s) / most
+}
+
+// entropyConfidence maps how random a value is to a confidence
+func entropyConfidence(value string) float64 {
+	c := (normalizedEntropy(value) - 0.6) * 2.5
+	return math.Round(math.Max(0, math.Min(0.9, c))*100) / 100
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// An assignment: a name, then ":", "=" or ":=", then a token-like value
+var assignmentPattern = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_.\-]*)["']?\s*(?::=|=|:)\s*["'` + "`" + `]?([A-Za-z0-9+/_\-.~=]{20,})`)
+
+// Names that say their value is a secret
+var secretNamePattern = regexp.MustCompile(`(?i)key|token|secret|pass|pwd|auth|cred|private|signature|salt`)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// entropyAlerts finds random-looking values assigned to a name that no rule
+// with a known format matched. A hex string or a UUID (hashes, IDs), or a
+// value with slashes or dots (paths, hosts, versions) counts only under a
+// name that says it is a secret.
+func entropyAlerts(text string, matched [][]int) []Alert {
+	var alerts []Alert
+	for _, loc := range assignmentPattern.FindAllStringSubmatchIndex(text, maxMatches) {
+		start, end := loc[4], loc[5]
+		if overlaps(matched, start, end) {
+			continue
+		}
+		name, value := text[loc[2]:loc[3]], strings.TrimRight(text[start:end], ".")
+		end = start + len(value)
+		if len(value) < 20 || placeholder(value) || !hasLetterAndDigit(value) {
+			continue
+		}
+		named := secretNamePattern.MatchString(name)
+		if !named && (isHex(value) || uuidPattern.MatchString(value) || strings.ContainsAny(value, "/.")) {
+			continue
+		}
+		h := normalizedEntropy(value)
+		threshold, weight := 0.85, 0.65
+		if named {
+			threshold, weight = 0.75, 0.85
+		}
+		if h < threshold {
+			continue
+		}
+		alerts = append(alerts, Alert{
+			Category:    CategorySensitiveData,
+			Level:       AlertWarning,
+			RuleID:      "high_entropy",
+			Title:       "High-Entropy Value",
+			Description: "Random-looking value assigned to " + name + "; possibly a secret.",
+			Match:       Redact(value),
+			Confidence:  math.Round(math.Min(h, 1)*weight*100) / 100,
+			Start:       start,
+			End:         end,
+		})
+	}
+	return alerts
+}
+
+func overlaps(spans [][]int, start, end int) bool {
+	for _, s := range spans {
+		if start < s[1] && s[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLetterAndDigit(s string) bool {
+	var letter, digit bool
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			letter = true
+		}
+	}
+	return letter && digit
+}
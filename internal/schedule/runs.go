@@ -0,0 +1,200 @@
+package schedule
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Run statuses
+const (
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+	RunTimeout   = "timeout"
+	RunSkipped   = "skipped" // the previous run was still going
+)
+
+// maxRunsPerSchedule bounds the run records kept for each schedule
+const maxRunsPerSchedule = 50
+
+// Run records one execution of a schedule
+type Run struct {
+	ID         string    `json:"id"`
+	ScheduleID string    `json:"scheduleId"`
+	SessionID  string    `json:"sessionId,omitempty"`
+	CLIType    string    `json:"cliType"`
+	WorkDir    string    `json:"workDir"`
+	DueAt      time.Time `json:"dueAt"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+	Status     string    `json:"status"`
+	StopReason string    `json:"stopReason,omitempty"`
+	Summary    string    `json:"summary,omitempty"` // last 500 characters of the agent's reply
+	Error      string    `json:"error,omitempty"`
+	Reported   bool      `json:"reported"`
+}
+
+// Done reports whether the run has finished
+func (r *Run) Done() bool {
+	return r.Status != RunRunning
+}
+
+// RunStore persists run records, one file per run, so results of runs that
+// finished while the bridge was offline can be reported later
+type RunStore struct {
+	mu   sync.Mutex
+	dir  string
+	runs map[string]*Run
+}
+
+// NewRunStore opens the run records in dir
+func NewRunStore(dir string) *RunStore {
+	os.MkdirAll(dir, 0755)
+	s := &RunStore{dir: dir, runs: make(map[string]*Run)}
+	s.load()
+	return s
+}
+
+// Save creates or replaces a run record and trims old runs of its schedule
+func (s *RunStore) Save(r Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[r.ID] = &r
+	s.write(&r)
+	s.trim(r.ScheduleID)
+}
+
+// Update applies update to a run, returning false if there is no such run
+func (s *RunStore) Update(id string, update func(*Run)) (Run, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.runs[id]
+	if !ok {
+		return Run{}, false
+	}
+	update(r)
+	s.write(r)
+	return *r, true
+}
+
+// Get returns a run by ID
+func (s *RunStore) Get(id string) (Run, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.runs[id]
+	if !ok {
+		return Run{}, false
+	}
+	return *r, true
+}
+
+// List returns the runs of a schedule (all schedules if scheduleID is
+// empty), newest first, at most limit of them (0 = all)
+func (s *RunStore) List(scheduleID string, limit int) []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var list []Run
+	for _, r := range s.runs {
+		if scheduleID == "" || r.ScheduleID == scheduleID {
+			list = append(list, *r)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.After(list[j].StartedAt) })
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+// Running returns the unfinished run of a schedule, if there is one
+func (s *RunStore) Running(scheduleID string) (Run, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.runs {
+		if r.ScheduleID == scheduleID && !r.Done() {
+			return *r, true
+		}
+	}
+	return Run{}, false
+}
+
+// Unreported returns finished runs that have not been reported, oldest first
+func (s *RunStore) Unreported() []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var list []Run
+	for _, r := range s.runs {
+		if r.Done() && !r.Reported {
+			list = append(list, *r)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	return list
+}
+
+// Interrupted marks runs left running by a previous bridge process as failed
+func (s *RunStore) Interrupted() []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var list []Run
+	for _, r := range s.runs {
+		if r.Status == RunRunning {
+			r.Status = RunFailed
+			r.Error = "bridge stopped before the run finished"
+			r.FinishedAt = time.Now()
+			s.write(r)
+			list = append(list, *r)
+		}
+	}
+	return list
+}
+
+func (s *RunStore) trim(scheduleID string) {
+	var list []*Run
+	for _, r := range s.runs {
+		if r.ScheduleID == scheduleID {
+			list = append(list, r)
+		}
+	}
+	if len(list) <= maxRunsPerSchedule {
+		return
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.After(list[j].StartedAt) })
+	for _, r := range list[maxRunsPerSchedule:] {
+		// keep runs that are going or still have to be reported
+		if !r.Done() || !r.Reported {
+			continue
+		}
+		delete(s.runs, r.ID)
+		os.Remove(filepath.Join(s.dir, r.ID+".json"))
+	}
+}
+
+func (s *RunStore) write(r *Run) {
+	data, _ := json.MarshalIndent(r, "", "  ")
+	os.WriteFile(filepath.Join(s.dir, r.ID+".json"), data, 0644)
+}
+
+func (s *RunStore) load() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var r Run
+		if json.Unmarshal(data, &r) == nil && r.ID != "" {
+			s.runs[r.ID] = &r
+		}
+	}
+}
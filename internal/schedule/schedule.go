@@ -0,0 +1,240 @@
+// Package schedule runs agent sessions at times given by cron expressions,
+// whether or not a web client is connected, and keeps a record of each run
+// until it has been reported.
+package schedule
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a scheduled run when the schedule sets no timeout
+const DefaultTimeout = 30 * time.Minute
+
+// Schedule is one entry of the "schedules" section of the device config
+type Schedule struct {
+	ID             string `json:"id"`
+	Name           string `json:"name,omitempty"`
+	Cron           string `json:"cron"`               // "30 2 * * 1-5", "@daily", ...
+	Timezone       string `json:"timezone,omitempty"` // IANA name, default: local time
+	CLIType        string `json:"cliType"`
+	WorkDir        string `json:"workDir"`
+	PermissionMode string `json:"permissionMode,omitempty"`
+	Prompt         string `json:"prompt"`
+	TimeoutMinutes int    `json:"timeoutMinutes,omitempty"` // default 30
+	Disabled       bool   `json:"disabled,omitempty"`
+}
+
+// Timeout returns how long a run of the schedule may take
+func (s Schedule) Timeout() time.Duration {
+	if s.TimeoutMinutes > 0 {
+		return time.Duration(s.TimeoutMinutes) * time.Minute
+	}
+	return DefaultTimeout
+}
+
+func (s Schedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+// Validate checks the fields needed to start a run
+func (s Schedule) Validate() error {
+	if s.ID == "" {
+		return fmt.Errorf("schedule without id")
+	}
+	if s.CLIType == "" || s.WorkDir == "" || s.Prompt == "" {
+		return fmt.Errorf("schedule %s: cliType, workDir and prompt are required", s.ID)
+	}
+	if _, err := ParseCron(s.Cron); err != nil {
+		return fmt.Errorf("schedule %s: %w", s.ID, err)
+	}
+	if _, err := s.location(); err != nil {
+		return fmt.Errorf("schedule %s: timezone: %w", s.ID, err)
+	}
+	return nil
+}
+
+type entry struct {
+	Schedule
+	cron *Cron
+	loc  *time.Location
+	next time.Time
+}
+
+// FireFunc starts a run of a schedule that came due at the given time
+type FireFunc func(s Schedule, at time.Time)
+
+// Scheduler fires schedules when their cron expression comes due. Runs missed
+// while the bridge was not running are not caught up.
+type Scheduler struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	fire    FireFunc
+	now     func() time.Time
+	wake    chan struct{}
+	stop    chan struct{}
+	started bool
+}
+
+// NewScheduler creates a scheduler that calls fire for every due schedule
+func NewScheduler(fire FireFunc) *Scheduler {
+	return &Scheduler{
+		entries: make(map[string]*entry),
+		fire:    fire,
+		now:     time.Now,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Set replaces the schedules. Invalid entries are skipped and returned as errors.
+func (s *Scheduler) Set(schedules []Schedule) []error {
+	var errs []error
+	entries := make(map[string]*entry, len(schedules))
+	now := s.now()
+	for _, sc := range schedules {
+		if err := sc.Validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c, _ := ParseCron(sc.Cron)
+		loc, _ := sc.location()
+		e := &entry{Schedule: sc, cron: c, loc: loc}
+		if !sc.Disabled {
+			e.next = c.Next(now.In(loc))
+		}
+		entries[sc.ID] = e
+	}
+
+	s.mu.Lock()
+	s.entries = entries
+	s.mu.Unlock()
+	s.notify()
+	return errs
+}
+
+// Get returns a schedule by ID
+func (s *Scheduler) Get(id string) (Schedule, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return Schedule{}, false
+	}
+	return e.Schedule, true
+}
+
+// Status is a schedule with its next run time (zero when disabled)
+type Status struct {
+	Schedule
+	NextRun time.Time `json:"nextRun,omitempty"`
+}
+
+// List returns all schedules sorted by ID
+func (s *Scheduler) List() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Status, 0, len(s.entries))
+	for _, e := range s.entries {
+		list = append(list, Status{Schedule: e.Schedule, NextRun: e.next})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Start runs the timer loop until Stop is called
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = true
+	s.stop = make(chan struct{})
+	stop := s.stop
+	s.mu.Unlock()
+	go s.loop(stop)
+}
+
+// Stop ends the timer loop
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		close(s.stop)
+		s.started = false
+	}
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) loop(stop <-chan struct{}) {
+	for {
+		wait := time.Hour
+		if next := s.nextDue(); !next.IsZero() {
+			wait = time.Until(next)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+		s.fireDue(s.now())
+	}
+}
+
+// nextDue returns the earliest next run time of the enabled schedules
+func (s *Scheduler) nextDue() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var earliest time.Time
+	for _, e := range s.entries {
+		if e.next.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || e.next.Before(earliest) {
+			earliest = e.next
+		}
+	}
+	return earliest
+}
+
+// fireDue starts every schedule whose next run time has passed and advances it
+func (s *Scheduler) fireDue(now time.Time) {
+	type due struct {
+		sc Schedule
+		at time.Time
+	}
+	var fired []due
+
+	s.mu.Lock()
+	for _, e := range s.entries {
+		if e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+		fired = append(fired, due{e.Schedule, e.next})
+		e.next = e.cron.Next(now.In(e.loc))
+	}
+	s.mu.Unlock()
+
+	// Each run is started on its own goroutine so that a slow session start
+	// cannot hold up the timer loop and make later schedules miss their time.
+	for _, d := range fired {
+		log.Printf("[Schedule] ⏰ %s due at %s", d.sc.ID, d.at.Format(time.RFC3339))
+		go s.fire(d.sc, d.at)
+	}
+}
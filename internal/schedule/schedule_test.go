@@ -0,0 +1,196 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, expr string) *Cron {
+	t.Helper()
+	c, err := ParseCron(expr)
+	if err != nil {
+		t.Fatalf("ParseCron(%q): %v", expr, err)
+	}
+	return c
+}
+
+func date(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expr, from, want string
+	}{
+		{"* * * * *", "2026-03-10 08:15", "2026-03-10 08:16"},
+		{"30 2 * * *", "2026-03-10 08:15", "2026-03-11 02:30"},
+		{"*/15 * * * *", "2026-03-10 08:15", "2026-03-10 08:30"},
+		{"0 9 * * 1-5", "2026-03-13 10:00", "2026-03-16 09:00"}, // Friday -> Monday
+		{"0 9 * * mon,wed", "2026-03-10 10:00", "2026-03-11 09:00"},
+		{"0 0 1 */3 *", "2026-02-10 00:00", "2026-04-01 00:00"},
+		{"0 0 29 feb *", "2026-03-01 00:00", "2028-02-29 00:00"},
+		{"@daily", "2026-12-31 23:59", "2027-01-01 00:00"},
+		{"@hourly", "2026-03-10 08:00", "2026-03-10 09:00"},
+		{"0 12 * * 7", "2026-03-10 00:00", "2026-03-15 12:00"}, // 7 is Sunday
+		{"5/20 * * * *", "2026-03-10 08:30", "2026-03-10 08:45"},
+		// day-of-month and day-of-week both restricted: either matches
+		{"0 0 13 * fri", "2026-03-01 00:00", "2026-03-06 00:00"},
+	}
+	for _, tt := range tests {
+		got := mustParse(t, tt.expr).Next(date(tt.from))
+		if want := date(tt.want); !got.Equal(want) {
+			t.Errorf("%q after %s = %s, want %s", tt.expr, tt.from, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+}
+
+func TestCronNeverMatches(t *testing.T) {
+	if got := mustParse(t, "0 0 30 2 *").Next(date("2026-01-01 00:00")); !got.IsZero() {
+		t.Errorf("Feb 30 matched %s", got)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "x * * * *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) accepted", expr)
+		}
+	}
+}
+
+func TestSchedulerFiresDueSchedules(t *testing.T) {
+	fired := make(chan string, 4)
+	s := NewScheduler(func(sc Schedule, at time.Time) { fired <- sc.ID })
+	now := date("2026-03-10 08:00")
+	s.now = func() time.Time { return now }
+
+	errs := s.Set([]Schedule{
+		{ID: "a", Cron: "5 8 * * *", Timezone: "UTC", CLIType: "claude", WorkDir: "/tmp", Prompt: "hi"},
+		{ID: "b", Cron: "0 9 * * *", Timezone: "UTC", CLIType: "claude", WorkDir: "/tmp", Prompt: "hi"},
+		{ID: "off", Cron: "* * * * *", Timezone: "UTC", CLIType: "claude", WorkDir: "/tmp", Prompt: "hi", Disabled: true},
+		{ID: "bad", Cron: "nope", CLIType: "claude", WorkDir: "/tmp", Prompt: "hi"},
+	})
+	if len(errs) != 1 {
+		t.Fatalf("errs = %v, want one for the bad cron", errs)
+	}
+
+	s.fireDue(date("2026-03-10 08:04"))
+	expectNoFire(t, fired)
+	s.fireDue(date("2026-03-10 08:05"))
+	if id := expectFire(t, fired); id != "a" {
+		t.Fatalf("fired %q, want a", id)
+	}
+	// a is not fired again until tomorrow
+	s.fireDue(date("2026-03-10 08:30"))
+	expectNoFire(t, fired)
+
+	for _, st := range s.List() {
+		switch st.ID {
+		case "a":
+			if !st.NextRun.Equal(date("2026-03-11 08:05")) {
+				t.Errorf("a next = %s", st.NextRun)
+			}
+		case "off":
+			if !st.NextRun.IsZero() {
+				t.Errorf("disabled schedule has next run %s", st.NextRun)
+			}
+		}
+	}
+}
+
+func TestRunStore(t *testing.T) {
+	dir := t.TempDir()
+	s := NewRunStore(dir)
+	start := date("2026-03-10 08:00")
+	s.Save(Run{ID: "r1", ScheduleID: "a", StartedAt: start, Status: RunRunning})
+	s.Save(Run{ID: "r2", ScheduleID: "a", StartedAt: start.Add(time.Hour), Status: RunCompleted})
+
+	if got := s.Unreported(); len(got) != 1 || got[0].ID != "r2" {
+		t.Fatalf("unreported = %v, want [r2]", got)
+	}
+	s.Update("r2", func(r *Run) { r.Reported = true })
+
+	// a new process finds r1 interrupted and still unreported
+	reopened := NewRunStore(dir)
+	if got := reopened.Interrupted(); len(got) != 1 || got[0].ID != "r1" || got[0].Status != RunFailed {
+		t.Fatalf("interrupted = %v", got)
+	}
+	if got := reopened.Unreported(); len(got) != 1 || got[0].ID != "r1" {
+		t.Fatalf("unreported after restart = %v, want [r1]", got)
+	}
+	if got := reopened.List("a", 1); len(got) != 1 || got[0].ID != "r2" {
+		t.Fatalf("latest run = %v, want r2", got)
+	}
+}
+
+func TestRunStoreTrimKeepsUnreported(t *testing.T) {
+	s := NewRunStore(t.TempDir())
+	start := date("2026-03-10 08:00")
+	s.Save(Run{ID: "old-unreported", ScheduleID: "a", StartedAt: start, Status: RunFailed})
+	for i := 0; i < maxRunsPerSchedule+5; i++ {
+		s.Save(Run{ID: "r" + time.Duration(i).String(), ScheduleID: "a", StartedAt: start.Add(time.Duration(i+1) * time.Minute), Status: RunCompleted, Reported: true})
+	}
+	if _, ok := s.Get("old-unreported"); !ok {
+		t.Error("unreported run was trimmed")
+	}
+	if got := len(s.List("a", 0)); got != maxRunsPerSchedule+1 {
+		t.Errorf("kept %d runs, want %d", got, maxRunsPerSchedule+1)
+	}
+}
+
+func TestSchedulerSlowRunDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	fired := make(chan string, 4)
+	s := NewScheduler(func(sc Schedule, at time.Time) {
+		fired <- sc.ID
+		if sc.ID == "slow" {
+			<-release
+		}
+	})
+	s.now = func() time.Time { return date("2026-03-10 08:00") }
+	s.Set([]Schedule{
+		{ID: "slow", Cron: "5 8 * * *", Timezone: "UTC", CLIType: "claude", WorkDir: "/tmp", Prompt: "hi"},
+		{ID: "fast", Cron: "6 8 * * *", Timezone: "UTC", CLIType: "claude", WorkDir: "/tmp", Prompt: "hi"},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		s.fireDue(date("2026-03-10 08:05"))
+		s.fireDue(date("2026-03-10 08:06"))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("fireDue blocked on a slow run")
+	}
+	got := map[string]bool{expectFire(t, fired): true, expectFire(t, fired): true}
+	if !got["slow"] || !got["fast"] {
+		t.Errorf("fired = %v, want slow and fast", got)
+	}
+}
+
+func expectFire(t *testing.T, fired <-chan string) string {
+	t.Helper()
+	select {
+	case id := <-fired:
+		return id
+	case <-time.After(time.Second):
+		t.Fatal("schedule did not fire")
+		return ""
+	}
+}
+
+func expectNoFire(t *testing.T, fired <-chan string) {
+	t.Helper()
+	select {
+	case id := <-fired:
+		t.Fatalf("unexpected fire of %s", id)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
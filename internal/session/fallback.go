@@ -0,0 +1,210 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/open-agents/bridge/internal/protocol"
+	"github.com/open-agents/bridge/internal/storage"
+)
+
+// Error classes used to pick a fallback (FallbackConfig.OnError)
+const (
+	ErrorRateLimit = "rate_limit"
+	ErrorAuth      = "auth"
+	ErrorTimeout   = "timeout"
+	ErrorCrash     = "crash"
+)
+
+// acpAuthRequired is the JSON-RPC error code ACP agents use for auth_required
+const acpAuthRequired = -32000
+
+var errorPatterns = []struct {
+	class    string
+	patterns []string
+}{
+	{ErrorRateLimit, []string{"rate limit", "rate_limit", "ratelimit", "too many requests", "429", "quota", "overloaded", "529", "resource_exhausted"}},
+	{ErrorAuth, []string{"unauthorized", "401", "authentication", "auth_required", "invalid api key", "invalid_api_key", "api key not", "not logged in", "please log in", "please login", "permission_denied"}},
+	{ErrorTimeout, []string{"timed out", "timeout", "deadline exceeded", "etimedout"}},
+	{ErrorCrash, []string{"panic:", "segmentation fault", "fatal error", "uncaught exception", "unhandled rejection", "traceback (most recent call last)"}},
+}
+
+// stderrClasses are the error classes read from the agent's stderr. CLIs
+// that hit a rate limit or lose their login often only say so there, but
+// stderr is also log output, where file names and ordinary messages would
+// pass for a timeout or a crash.
+var stderrClasses = map[string]bool{ErrorRateLimit: true, ErrorAuth: true}
+
+// ClassifyError maps a protocol error to an error class, or "" when the error
+// is not one a fallback can help with. The agent's JSON-RPC errors and its
+// process exiting unexpectedly count, and stderr lines for stderrClasses.
+func ClassifyError(msg protocol.Message) string {
+	if msg.Type != protocol.MessageTypeError {
+		return ""
+	}
+	source, _ := msg.Meta["source"].(string)
+	switch source {
+	case "exit":
+		return ErrorCrash
+	case "rpc", "stderr":
+	default:
+		return ""
+	}
+
+	text := strings.ToLower(fmt.Sprintf("%v", msg.Content))
+	for _, p := range errorPatterns {
+		if source == "stderr" && !stderrClasses[p.class] {
+			continue
+		}
+		for _, pattern := range p.patterns {
+			// In a log line a bare status code is as likely a line number or
+			// part of a path, unless the line reports an error
+			if source == "stderr" && isNumber(pattern) && !containsWord(text, "error") {
+				continue
+			}
+			if containsWord(text, pattern) {
+				return p.class
+			}
+		}
+	}
+
+	// JSON-RPC errors without a recognizable message
+	if code, ok := msg.Meta["code"].(int); ok && code == acpAuthRequired {
+		return ErrorAuth
+	}
+	return ""
+}
+
+// containsWord reports whether text contains word with no letter or digit
+// right before or after it, so that "429" does not match "14290"
+func containsWord(text, word string) bool {
+	for i := 0; ; {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isNumber(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// SelectFallback returns the first configured fallback for cliType whose
+// OnError matches errorClass, skipping CLIs already tried in this chain
+func SelectFallback(cliType, errorClass string, fallbacks []FallbackConfig, tried []string) string {
+	if errorClass == "" {
+		return ""
+	}
+	for _, f := range fallbacks {
+		if f.CLIType != cliType || f.Fallback == "" || f.Fallback == cliType {
+			continue
+		}
+		if containsString(tried, f.Fallback) || !matchesOnError(f.OnError, errorClass) {
+			continue
+		}
+		return f.Fallback
+	}
+	return ""
+}
+
+// matchesOnError checks an OnError setting ("any", a class, or a comma-separated list)
+func matchesOnError(onError, errorClass string) bool {
+	if onError == "" || onError == "any" {
+		return true
+	}
+	for _, c := range strings.Split(onError, ",") {
+		if strings.TrimSpace(c) == errorClass {
+			return true
+		}
+	}
+	return false
+}
+
+// Limits for the history summary handed to a fallback session
+const (
+	summaryMessages   = 12
+	summaryMessageLen = 600
+	summaryTotalLen   = 6000
+)
+
+// FallbackPrompt builds the prompt that continues a failed session's work on
+// another CLI: a summary of the conversation followed by the last user prompt
+func FallbackPrompt(fromCLI, reason string, history []storage.Message, lastPrompt string) string {
+	// The last prompt is repeated at the end, so leave it out of the summary
+	if n := len(history); n > 0 && history[n-1].Role == "user" && history[n-1].Content == lastPrompt {
+		history = history[:n-1]
+	}
+	if len(history) > summaryMessages {
+		history = history[len(history)-summaryMessages:]
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[This conversation is continuing from a %s session that failed (%s).", fromCLI, reason)
+	if len(history) == 0 {
+		sb.WriteString("]\n\n")
+	} else {
+		sb.WriteString(" Summary of the conversation so far:]\n\n")
+		var lines []string
+		total := 0
+		// Keep the most recent messages when the summary is too long
+		for i := len(history) - 1; i >= 0; i-- {
+			m := history[i]
+			if (m.Role != "user" && m.Role != "assistant") || m.Type != "" {
+				continue
+			}
+			role := "User"
+			if m.Role == "assistant" {
+				role = "Assistant"
+			}
+			line := fmt.Sprintf("%s: %s", role, truncate(strings.TrimSpace(m.Content), summaryMessageLen))
+			if total+len(line) > summaryTotalLen {
+				break
+			}
+			total += len(line)
+			lines = append([]string{line}, lines...)
+		}
+		sb.WriteString(strings.Join(lines, "\n\n"))
+		sb.WriteString("\n\n")
+	}
+
+	if lastPrompt != "" {
+		sb.WriteString("[Please continue with the last request:]\n\n")
+		sb.WriteString(lastPrompt)
+	} else {
+		sb.WriteString("[Please continue where the previous session left off.]")
+	}
+	return sb.String()
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + "…"
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
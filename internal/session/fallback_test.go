@@ -0,0 +1,112 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/open-agents/bridge/internal/protocol"
+	"github.com/open-agents/bridge/internal/storage"
+)
+
+func errMsg(content string, meta map[string]interface{}) protocol.Message {
+	return protocol.Message{Type: protocol.MessageTypeError, Content: content, Meta: meta}
+}
+
+// rpc is the meta of an ACP JSON-RPC error
+func rpc(code int) map[string]interface{} {
+	return map[string]interface{}{"protocol": "acp", "source": "rpc", "code": code}
+}
+
+func TestClassifyError(t *testing.T) {
+	stderr := map[string]interface{}{"protocol": "acp", "source": "stderr"}
+	tests := []struct {
+		name string
+		msg  protocol.Message
+		want string
+	}{
+		{"rate limit", errMsg("API Error: 429 Too Many Requests", rpc(-32603)), ErrorRateLimit},
+		{"overloaded", errMsg(`{"type":"overloaded_error"}`, rpc(-32603)), ErrorRateLimit},
+		{"auth text", errMsg("Invalid API key · Please run /login", rpc(-32603)), ErrorAuth},
+		{"auth code", errMsg("Authentication required", rpc(-32000)), ErrorAuth},
+		{"bare auth code", errMsg("", rpc(-32000)), ErrorAuth},
+		{"timeout", errMsg("request timed out after 600s", rpc(-32603)), ErrorTimeout},
+		{"code inside a number", errMsg("failed to read chunk 14290", rpc(-32603)), ""},
+		{"crash exit", errMsg("agent process exited unexpectedly: exit status 1", map[string]interface{}{"source": "exit"}), ErrorCrash},
+		{"stderr panic", errMsg("panic: runtime error: index out of range", stderr), ""},
+		{"stderr timeout", errMsg("[DEBUG] request timed out, retrying in 2s", stderr), ""},
+		{"stderr path", errMsg("reading src/429/handler.go:401 (timeout.go)", stderr), ""},
+		{"stderr chatter", errMsg("npm warn deprecated inflight@1.0.6", stderr), ""},
+		// Lines agent CLIs print when they are rate limited or logged out
+		{"claude rate limit", errMsg(`API Error: 429 {"type":"error","error":{"type":"rate_limit_error","message":"This request would exceed the rate limit for your organization"}}`, stderr), ErrorRateLimit},
+		{"claude overloaded", errMsg(`API Error: 529 {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, stderr), ErrorRateLimit},
+		{"claude login", errMsg("Invalid API key · Please run /login", stderr), ErrorAuth},
+		{"gemini quota", errMsg("[API Error: You have exhausted your daily quota on this model.]", stderr), ErrorRateLimit},
+		{"gemini exhausted", errMsg(`Error: {"error":{"code":429,"message":"Resource has been exhausted (e.g. check quota).","status":"RESOURCE_EXHAUSTED"}}`, stderr), ErrorRateLimit},
+		{"codex rate limit", errMsg("stream error: exceeded retry limit, last status: 429 Too Many Requests", stderr), ErrorRateLimit},
+		{"qwen auth", errMsg("[API Error: 401 Incorrect API key provided.]", stderr), ErrorAuth},
+		{"codex auth", errMsg("unexpected status 401 Unauthorized: Missing bearer authentication in header", stderr), ErrorAuth},
+		{"resource limit", errMsg("memory limit exceeded: timeout", map[string]interface{}{"source": "limit"}), ""},
+		{"no source", errMsg("rate limit", nil), ""},
+		{"not an error", protocol.Message{Type: protocol.MessageTypeContent, Content: "rate limit"}, ""},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.msg); got != tt.want {
+			t.Errorf("%s: ClassifyError = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectFallback(t *testing.T) {
+	fallbacks := []FallbackConfig{
+		{CLIType: "claude", Fallback: "gemini", OnError: "rate_limit"},
+		{CLIType: "claude", Fallback: "qwen", OnError: "auth, crash"},
+		{CLIType: "gemini", Fallback: "claude"},
+	}
+
+	if got := SelectFallback("claude", ErrorRateLimit, fallbacks, nil); got != "gemini" {
+		t.Errorf("rate limit: got %q, want gemini", got)
+	}
+	if got := SelectFallback("claude", ErrorCrash, fallbacks, nil); got != "qwen" {
+		t.Errorf("crash: got %q, want qwen", got)
+	}
+	if got := SelectFallback("claude", ErrorTimeout, fallbacks, nil); got != "" {
+		t.Errorf("timeout: got %q, want no fallback", got)
+	}
+	if got := SelectFallback("gemini", ErrorTimeout, fallbacks, nil); got != "claude" {
+		t.Errorf("any: got %q, want claude", got)
+	}
+	// claude -> gemini -> claude would loop
+	if got := SelectFallback("gemini", ErrorRateLimit, fallbacks, []string{"claude"}); got != "" {
+		t.Errorf("loop: got %q, want no fallback", got)
+	}
+	if got := SelectFallback("claude", "", fallbacks, nil); got != "" {
+		t.Errorf("unclassified: got %q, want no fallback", got)
+	}
+}
+
+func TestFallbackPrompt(t *testing.T) {
+	history := []storage.Message{
+		{Role: "user", Content: "add a login page"},
+		{Role: "assistant", Content: "Created login.tsx with a form."},
+		{Role: "system", Content: "internal note"},
+		{Role: "user", Content: "now add tests"},
+	}
+	prompt := FallbackPrompt("claude", ErrorRateLimit, history, "now add tests")
+
+	for _, want := range []string{"claude session that failed (rate_limit)", "User: add a login page", "Assistant: Created login.tsx"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "internal note") {
+		t.Error("system messages should not be summarized")
+	}
+	if strings.Count(prompt, "now add tests") != 1 || !strings.HasSuffix(prompt, "now add tests") {
+		t.Errorf("last prompt should appear once, at the end:\n%s", prompt)
+	}
+
+	long := strings.Repeat("界", 1000)
+	if p := FallbackPrompt("claude", ErrorCrash, []storage.Message{{Role: "assistant", Content: long}}, ""); !strings.Contains(p, "…") {
+		t.Error("long messages should be truncated")
+	}
+}
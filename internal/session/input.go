@@ -0,0 +1,129 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// QueuedPrompt is user input waiting for the agent to finish its turn
+type QueuedPrompt struct {
+	ID       string    `json:"id"`
+	Content  string    `json:"content"`
+	ClientID string    `json:"clientId,omitempty"`
+	QueuedAt time.Time `json:"queuedAt"`
+}
+
+// InputQueue serializes prompts to a session: one turn at a time, later
+// prompts wait until the agent reports it is idle again
+type InputQueue struct {
+	mu    sync.Mutex
+	busy  bool
+	items []QueuedPrompt
+}
+
+// NewInputQueue creates an idle, empty queue
+func NewInputQueue() *InputQueue {
+	return &InputQueue{}
+}
+
+// Submit takes a prompt for delivery. When the agent is idle it returns true
+// and the caller delivers the prompt now (the queue turns busy). Otherwise
+// the prompt is queued, at the head if front is set, and its 1-based
+// position is returned.
+func (q *InputQueue) Submit(p QueuedPrompt, front bool) (deliver bool, position int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.busy {
+		q.busy = true
+		return true, 0
+	}
+	if p.QueuedAt.IsZero() {
+		p.QueuedAt = time.Now()
+	}
+	if front {
+		q.items = append([]QueuedPrompt{p}, q.items...)
+		return false, 1
+	}
+	q.items = append(q.items, p)
+	return false, len(q.items)
+}
+
+// Begin marks a turn as started by input that bypassed Submit
+func (q *InputQueue) Begin() {
+	q.mu.Lock()
+	q.busy = true
+	q.mu.Unlock()
+}
+
+// Next is called when a turn ends. It pops the next prompt to deliver (the
+// queue stays busy), or marks the queue idle when nothing is waiting.
+func (q *InputQueue) Next() (QueuedPrompt, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		q.busy = false
+		return QueuedPrompt{}, false
+	}
+	p := q.items[0]
+	q.items = q.items[1:]
+	q.busy = true
+	return p, true
+}
+
+// Take pops the prompt with the given ID if it is next in line, for
+// delivering it without waiting for the agent (the queue stays busy)
+func (q *InputQueue) Take(id string) (QueuedPrompt, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 || q.items[0].ID != id {
+		return QueuedPrompt{}, false
+	}
+	p := q.items[0]
+	q.items = q.items[1:]
+	q.busy = true
+	return p, true
+}
+
+// End marks the turn as over without delivering anything, e.g. when the
+// prompt never reached the agent
+func (q *InputQueue) End() {
+	q.mu.Lock()
+	q.busy = false
+	q.mu.Unlock()
+}
+
+// Busy reports whether a turn is in progress
+func (q *InputQueue) Busy() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.busy
+}
+
+// Pending returns a copy of the waiting prompts in delivery order
+func (q *InputQueue) Pending() []QueuedPrompt {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]QueuedPrompt{}, q.items...)
+}
+
+// Remove drops a waiting prompt and reports whether it was queued
+func (q *InputQueue) Remove(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, p := range q.items {
+		if p.ID == id {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clear drops every waiting prompt and returns them
+func (q *InputQueue) Clear() []QueuedPrompt {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := q.items
+	q.items = nil
+	return items
+}
@@ -0,0 +1,73 @@
+package session
+
+import "testing"
+
+func TestInputQueueDeliversInOrder(t *testing.T) {
+	q := NewInputQueue()
+
+	if deliver, _ := q.Submit(QueuedPrompt{ID: "1"}, false); !deliver {
+		t.Fatal("idle queue should deliver immediately")
+	}
+	if deliver, pos := q.Submit(QueuedPrompt{ID: "2"}, false); deliver || pos != 1 {
+		t.Fatalf("busy queue: deliver=%v pos=%d", deliver, pos)
+	}
+	if _, pos := q.Submit(QueuedPrompt{ID: "3"}, false); pos != 2 {
+		t.Fatalf("pos = %d, want 2", pos)
+	}
+	if q.Pending()[0].QueuedAt.IsZero() {
+		t.Error("queued prompt has no timestamp")
+	}
+
+	for _, want := range []string{"2", "3"} {
+		p, ok := q.Next()
+		if !ok || p.ID != want {
+			t.Fatalf("Next() = %q, %v, want %q", p.ID, ok, want)
+		}
+		if !q.Busy() {
+			t.Fatal("queue should stay busy while a queued prompt runs")
+		}
+	}
+	if _, ok := q.Next(); ok || q.Busy() {
+		t.Fatal("empty queue should go idle")
+	}
+}
+
+func TestInputQueueInterrupt(t *testing.T) {
+	q := NewInputQueue()
+	q.Begin()
+	q.Submit(QueuedPrompt{ID: "later"}, false)
+	if _, pos := q.Submit(QueuedPrompt{ID: "now"}, true); pos != 1 {
+		t.Fatalf("interrupt prompt at position %d, want 1", pos)
+	}
+
+	if _, ok := q.Take("later"); ok {
+		t.Fatal("Take should only pop the head")
+	}
+	p, ok := q.Take("now")
+	if !ok || p.ID != "now" {
+		t.Fatalf("Take(now) = %q, %v", p.ID, ok)
+	}
+	// The agent's late turn end then delivers the rest
+	if p, _ := q.Next(); p.ID != "later" {
+		t.Fatalf("Next() = %q", p.ID)
+	}
+}
+
+func TestInputQueueRemoveClearEnd(t *testing.T) {
+	q := NewInputQueue()
+	q.Begin()
+	q.Submit(QueuedPrompt{ID: "a"}, false)
+	q.Submit(QueuedPrompt{ID: "b"}, false)
+
+	if !q.Remove("a") || q.Remove("a") {
+		t.Fatal("Remove should drop a queued prompt exactly once")
+	}
+	if dropped := q.Clear(); len(dropped) != 1 || dropped[0].ID != "b" {
+		t.Fatalf("Clear() = %v", dropped)
+	}
+
+	q.End()
+	if deliver, _ := q.Submit(QueuedPrompt{ID: "c"}, false); !deliver {
+		t.Fatal("queue should be idle after End")
+	}
+}
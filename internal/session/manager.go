@@ -1,12 +1,20 @@
 package session
 
 import (
+	"fmt"
 	"log"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/open-agents/bridge/internal/adapter"
+	"github.com/open-agents/bridge/internal/clidef"
+	"github.com/open-agents/bridge/internal/config"
+	"github.com/open-agents/bridge/internal/limits"
+	"github.com/open-agents/bridge/internal/policy"
 	"github.com/open-agents/bridge/internal/protocol"
+	"github.com/open-agents/bridge/internal/sandbox"
 )
 
 type OutputCallback func(sessionID string, msg protocol.Message)
@@ -22,6 +30,11 @@ type Manager struct {
 	maxConcurrent  int
 	queue          []QueueItem
 	queueMu        sync.Mutex
+	resourceLimits map[string]limits.Limits // per CLI type, "*" applies to all
+	sandboxConfig  *sandbox.Config          // device defaults for sandboxed sessions
+	owners         *Ownership               // which client controls each session
+	clis           *clidef.Registry         // how to launch each CLI type
+	policy         *policy.Policy           // device policy (nil = allow everything)
 }
 
 type QueueItem struct {
@@ -39,7 +52,7 @@ type Session struct {
 	ID             string
 	CLIType        string
 	WorkDir        string
-	PermissionMode string // "default", "plan", "accept-edits", "accept-all"
+	PermissionMode string // "default", "plan", "accept-edits", "accept-all", "sandbox"
 	Status         string // "active", "completed", "error", "replaced"
 	Protocol       *protocol.Manager
 	CreatedAt      time.Time
@@ -52,19 +65,128 @@ type Session struct {
 	StartedAt time.Time // Task start time for duration tracking
 	Output    []byte    // Collected CLI output for artifacts extraction
 	ExitCode  int       // Process exit code (set when session exits)
+
+	ScheduleRunID string // Scheduled run this session executes (if any)
+
+	Limits    *limits.Group // Resource limits applied to the CLI and its commands
+	Sandboxed bool          // CLI and its commands run inside the namespace sandbox
+
+	Options       CreateOptions // Options the session was created with (reused by fallbacks)
+	LastPrompt    string        // Last prompt delivered to the CLI
+	FallbackChain []string      // CLI types this conversation already ran on before this one
+
+	Input *InputQueue // Prompts waiting for the agent to finish its turn
+}
+
+// CreateOptions holds all parameters for creating a session
+type CreateOptions struct {
+	CLIType        string
+	WorkDir        string
+	SessionID      string
+	Cols           int
+	Rows           int
+	PermissionMode string
+	Limits         *limits.Limits  // Per-session override of configured limits
+	Sandbox        *sandbox.Config // Non-nil runs the session sandboxed, overriding device defaults
+	FallbackChain  []string        // CLI types tried before this session (set by fallbacks)
 }
 
 func NewManager() *Manager {
 	return &Manager{
 		sessions:      make(map[string]*Session),
 		maxConcurrent: 3,
+		owners:        NewOwnership(),
+		clis:          clidef.NewRegistry(nil),
 	}
 }
 
+// Owners returns the session ownership table
+func (m *Manager) Owners() *Ownership {
+	return m.owners
+}
+
 func (m *Manager) SetMaxConcurrent(n int) {
 	m.maxConcurrent = n
 }
 
+// SetResourceLimits sets the configured resource limits per CLI type ("*" = all CLIs)
+func (m *Manager) SetResourceLimits(l map[string]limits.Limits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resourceLimits = l
+}
+
+// effectiveLimits layers global and per-CLI limits and narrows them by the
+// session's own; a session cannot raise what the device configures
+func (m *Manager) effectiveLimits(cliType string, override *limits.Limits) *limits.Limits {
+	var global, perCLI *limits.Limits
+	if l, ok := m.resourceLimits["*"]; ok {
+		global = &l
+	}
+	if l, ok := m.resourceLimits[cliType]; ok {
+		perCLI = &l
+	}
+	return limits.Narrow(limits.Merge(global, perCLI), override)
+}
+
+// SetCLIDefinitions applies configured CLI definitions on top of the
+// built-ins, for sessions and for adapter.Get
+func (m *Manager) SetCLIDefinitions(defs map[string]clidef.Definition) {
+	m.clis.Set(defs)
+	adapter.SetDefinitions(defs)
+}
+
+// CLIs returns the registry of CLI definitions
+func (m *Manager) CLIs() *clidef.Registry {
+	return m.clis
+}
+
+// SetPolicy sets the device policy enforced when sessions are created
+func (m *Manager) SetPolicy(p *policy.Policy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = p
+}
+
+// CheckPolicy reports whether the device policy allows a session with these
+// parameters, without checking session caps. Errors are *policy.Violation.
+func (m *Manager) CheckPolicy(cliType, workDir, permissionMode string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.policy.Check(cliType, workDir, permissionMode)
+}
+
+// SetSandboxConfig sets the device defaults for sandboxed sessions
+func (m *Manager) SetSandboxConfig(cfg *sandbox.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sandboxConfig = cfg
+}
+
+// applySandbox resolves the sandbox for a session, if any, into the adapter config
+func (m *Manager) applySandbox(opts CreateOptions, permissionMode string, def clidef.Definition, config *protocol.AdapterConfig) (bool, error) {
+	if opts.Sandbox == nil && permissionMode != "sandbox" {
+		return false, nil
+	}
+	cfg := m.sandboxConfig.Merge(opts.Sandbox)
+
+	cliSpec, err := cfg.Spec(config.WorkDir, cliStatePaths(def)...)
+	if err != nil {
+		return false, err
+	}
+	cmdSpec, err := cfg.CommandSpec(config.WorkDir)
+	if err != nil {
+		return false, err
+	}
+	cliSpec.Hidden = bridgeState()
+	cmdSpec.Hidden = cliSpec.Hidden
+	config.Sandbox = &cliSpec
+	config.CommandSandbox = &cmdSpec
+	log.Printf("[SessionManager] Sandbox enabled: workDir=%s, network=%s, commandNetwork=%s",
+		cliSpec.WorkDir, cliSpec.Network, cmdSpec.Network)
+	return true, nil
+}
+
 func (m *Manager) ActiveCount() int {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -117,9 +239,24 @@ func (m *Manager) CreateWithID(cliType, workDir, sessionID string) (*Session, er
 }
 
 func (m *Manager) CreateWithIDAndSize(cliType, workDir, sessionID string, cols, rows int, permissionMode string) (*Session, error) {
+	return m.CreateWithOptions(CreateOptions{
+		CLIType:        cliType,
+		WorkDir:        workDir,
+		SessionID:      sessionID,
+		Cols:           cols,
+		Rows:           rows,
+		PermissionMode: permissionMode,
+	})
+}
+
+// CreateWithOptions creates (or resumes) a session from the given options
+func (m *Manager) CreateWithOptions(opts CreateOptions) (*Session, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	cliType, workDir, sessionID := opts.CLIType, opts.WorkDir, opts.SessionID
+	cols, rows, permissionMode := opts.Cols, opts.Rows, opts.PermissionMode
+
 	// Use provided sessionID or generate a new one
 	if sessionID == "" {
 		sessionID = uuid.New().String()
@@ -130,6 +267,12 @@ func (m *Manager) CreateWithIDAndSize(cliType, workDir, sessionID string, cols,
 		permissionMode = "default"
 	}
 
+	// Device policy applies to resumed sessions as well as new ones
+	if err := m.policy.Check(cliType, workDir, permissionMode); err != nil {
+		log.Printf("[SessionManager] ⛔ Session %s refused: %v", sessionID, err)
+		return nil, err
+	}
+
 	// ✅ Check if session with same ID already exists
 	if existingSess, exists := m.sessions[sessionID]; exists {
 		log.Printf("[SessionManager] 🔍 Session %s already exists, attempting recovery...", sessionID)
@@ -176,6 +319,7 @@ func (m *Manager) CreateWithIDAndSize(cliType, workDir, sessionID string, cols,
 			log.Printf("[SessionManager]   └─ Disconnecting old protocol connection")
 			existingSess.Protocol.Disconnect()
 		}
+		existingSess.Limits.Close()
 		existingSess.Status = "replaced"
 
 		// Remove from active sessions
@@ -186,6 +330,11 @@ func (m *Manager) CreateWithIDAndSize(cliType, workDir, sessionID string, cols,
 			sessionID, cliType, workDir)
 	}
 
+	if err := m.policy.CheckCapacity(cliType, m.activeCountByCLILocked(cliType)); err != nil {
+		log.Printf("[SessionManager] ⛔ Session %s refused: %v", sessionID, err)
+		return nil, err
+	}
+
 	// Create protocol manager
 	protocolMgr := protocol.NewManager()
 
@@ -197,6 +346,9 @@ func (m *Manager) CreateWithIDAndSize(cliType, workDir, sessionID string, cols,
 		Status:         "active",
 		Protocol:       protocolMgr,
 		CreatedAt:      time.Now(),
+		Options:        opts,
+		FallbackChain:  opts.FallbackChain,
+		Input:          NewInputQueue(),
 	}
 
 	// Set up message callback with output collection
@@ -204,7 +356,7 @@ func (m *Manager) CreateWithIDAndSize(cliType, workDir, sessionID string, cols,
 	protocolMgr.Subscribe(func(msg protocol.Message) {
 		log.Printf("[SessionManager] Message received: type=%s", msg.Type)
 
-			// Collect output for multi-agent tasks
+		// Collect output for multi-agent tasks
 		if sess.JobID != "" && msg.Type == protocol.MessageTypeContent {
 			if content, ok := msg.Content.(string); ok {
 				sess.Output = append(sess.Output, []byte(content)...)
@@ -217,29 +369,43 @@ func (m *Manager) CreateWithIDAndSize(cliType, workDir, sessionID string, cols,
 		}
 	})
 
-	// Get CLI command and args
-	command, args := m.getCLICommand(cliType)
+	// Get CLI command and args from its definition
+	def := m.clis.Get(cliType)
 
-	// Connect with auto-detection
+	// Connect with the CLI's preferred protocol (auto-detection by default)
 	config := protocol.AdapterConfig{
-		WorkDir: workDir,
-		Command: command,
-		Args:    args,
-		Cols:    cols,
-		Rows:    rows,
+		WorkDir:   workDir,
+		Command:   def.Command,
+		Args:      append([]string(nil), def.Args...),
+		Cols:      cols,
+		Rows:      rows,
+		Protocol:  def.Protocol,
+		CustomEnv: make(map[string]string, len(def.Env)),
 	}
-
-	// For claude CLI, unset CLAUDECODE to allow nested sessions
-	if cliType == "claude" {
-		config.CustomEnv = map[string]string{
-			"CLAUDECODE": "",
-		}
+	for k, v := range def.Env {
+		config.CustomEnv[k] = v
 	}
 
 	// Apply permission mode settings
-	m.applyPermissionMode(permissionMode, cliType, &config)
+	m.applyPermissionMode(permissionMode, def, &config)
+
+	// Confine the CLI and its commands to the workDir if requested
+	sandboxed, err := m.applySandbox(opts, permissionMode, def, &config)
+	if err != nil {
+		return nil, err
+	}
+	sess.Sandboxed = sandboxed
+
+	// Apply resource limits to the CLI process and its terminal commands
+	group, err := limits.NewGroup(sessionID, m.effectiveLimits(cliType, opts.Limits))
+	if err != nil {
+		return nil, err
+	}
+	sess.Limits = group
+	config.Limits = group
 
 	if err := protocolMgr.Connect(config); err != nil {
+		group.Close()
 		return nil, err
 	}
 
@@ -259,6 +425,17 @@ func (m *Manager) CreateWithIDAndSize(cliType, workDir, sessionID string, cols,
 	return sess, nil
 }
 
+// activeCountByCLILocked returns the active sessions of one CLI type (must be called with lock held)
+func (m *Manager) activeCountByCLILocked(cliType string) int {
+	count := 0
+	for _, s := range m.sessions {
+		if s.Status == "active" && s.CLIType == cliType {
+			count++
+		}
+	}
+	return count
+}
+
 // activeCountLocked returns active session count (must be called with lock held)
 func (m *Manager) activeCountLocked() int {
 	count := 0
@@ -343,7 +520,9 @@ func (m *Manager) cleanupIdleSessions(maxIdleTime time.Duration) {
 					log.Printf("[SessionManager]     └─ Disconnecting protocol")
 					sess.Protocol.Disconnect()
 				}
+				sess.Limits.Close()
 				delete(m.sessions, id)
+				m.owners.Remove(id)
 				cleaned++
 				log.Printf("[SessionManager]     └─ ✅ Cleaned up (idle for %v)", idleTime)
 			} else {
@@ -389,96 +568,36 @@ func (m *Manager) GetStats() map[string]int {
 	return stats
 }
 
-// applyPermissionMode configures the adapter based on permission mode
-func (m *Manager) applyPermissionMode(permissionMode, cliType string, config *protocol.AdapterConfig) {
-	log.Printf("[SessionManager] Applying permission mode: %s for CLI: %s", permissionMode, cliType)
+// applyPermissionMode adds the CLI's flags and environment for a permission mode
+func (m *Manager) applyPermissionMode(permissionMode string, def clidef.Definition, config *protocol.AdapterConfig) {
+	log.Printf("[SessionManager] Applying permission mode: %s for CLI: %s", permissionMode, def.Command)
 
 	// Initialize CustomEnv if nil
 	if config.CustomEnv == nil {
 		config.CustomEnv = make(map[string]string)
 	}
 
-	switch permissionMode {
-	case "accept-all":
-		// Auto-accept all operations
-		switch cliType {
-		case "claude":
-			config.CustomEnv["CLAUDE_PERMISSION_MODE"] = "accept-all"
-			config.Args = append(config.Args, "--dangerously-skip-permissions")
-		case "qwen":
-			config.CustomEnv["QWEN_PERMISSION_MODE"] = "accept-all"
-		case "goose":
-			config.CustomEnv["GOOSE_MODE"] = "auto"
-		case "gemini":
-			config.CustomEnv["GEMINI_PERMISSION_MODE"] = "accept-all"
-		case "aider":
-			// Aider uses --yes for auto-accept
-			config.Args = append(config.Args, "--yes")
-		}
-
-	case "accept-edits":
-		// Auto-accept file edits only
-		switch cliType {
-		case "claude":
-			config.CustomEnv["CLAUDE_PERMISSION_MODE"] = "accept-edits"
-		case "qwen":
-			config.CustomEnv["QWEN_PERMISSION_MODE"] = "accept-edits"
-		case "goose":
-			config.CustomEnv["GOOSE_MODE"] = "auto-edit"
-		case "gemini":
-			config.CustomEnv["GEMINI_PERMISSION_MODE"] = "accept-edits"
-		case "aider":
-			// Aider doesn't distinguish between edits and commands
-			// Use --yes for auto-accept in edit mode too
-			config.Args = append(config.Args, "--yes")
-		}
+	// Default mode asks for confirmation on sensitive operations; most CLIs
+	// need nothing extra for it. Sandbox mode auto-accepts like accept-all;
+	// the sandbox confines what the agent can touch.
+	mode := def.ModeFor(permissionMode)
+	config.Args = append(config.Args, mode.Args...)
+	for k, v := range mode.Env {
+		config.CustomEnv[k] = v
+	}
+}
 
-	case "plan":
-		// Plan mode - show plan before execution
-		switch cliType {
-		case "claude":
-			config.CustomEnv["CLAUDE_PERMISSION_MODE"] = "plan"
-			config.Args = append(config.Args, "--plan")
-		case "qwen":
-			config.CustomEnv["QWEN_PERMISSION_MODE"] = "plan"
-		case "goose":
-			config.CustomEnv["GOOSE_MODE"] = "plan"
-		case "gemini":
-			config.CustomEnv["GEMINI_PERMISSION_MODE"] = "plan"
-		}
+// bridgeState lists the bridge's own files a sandboxed session must not see:
+// its config directory, or, when that holds a writable tree, the control
+// sockets and tokens under run/ and the keys under keys/
+func bridgeState() []string {
+	dir := config.ConfigDir()
+	return []string{dir, filepath.Join(dir, "run"), filepath.Join(dir, "keys")}
+}
 
-	default:
-		// Default mode - ask for confirmation on sensitive operations
-		// Most CLIs use this as default, no env vars needed
-		log.Printf("[SessionManager] Using default permission mode")
-	}
-}
-
-func (m *Manager) getCLICommand(cliType string) (string, []string) {
-	switch cliType {
-	case "claude":
-		// Claude Code ACP via npx
-		return "npx", []string{"@zed-industries/claude-code-acp"}
-	case "qwen":
-		return "qwen-code", []string{"--experimental-acp"}
-	case "goose":
-		return "goose", []string{"acp"}
-	case "gemini":
-		return "gemini-cli", []string{"--acp"}
-	case "kiro":
-		return "kiro", []string{"chat"}
-	case "cline":
-		return "cline", nil
-	case "codex":
-		return "codex", nil
-	case "aider":
-		// Aider - AI pair programming in terminal (PTY mode)
-		// Installation: pip install aider-chat
-		// Uses its own protocol, not ACP
-		return "aider", []string{"--no-auto-commits", "--pretty"}
-	default:
-		return cliType, nil
-	}
+// cliStatePaths lists the per-user state a CLI must be able to write when sandboxed
+func cliStatePaths(def clidef.Definition) []string {
+	return append([]string{"~/.cache", "~/.npm"}, def.StatePaths...)
 }
 
 func (m *Manager) Get(id string) *Session {
@@ -530,6 +649,7 @@ func (m *Manager) StopWithExitCode(id string, exitCode int) error {
 	if sess.Protocol != nil {
 		sess.Protocol.Disconnect()
 	}
+	sess.Limits.Close()
 
 	// Determine final status based on exit code
 	if exitCode == 0 {
@@ -543,6 +663,7 @@ func (m *Manager) StopWithExitCode(id string, exitCode int) error {
 	taskID := sess.TaskID
 
 	delete(m.sessions, id)
+	m.owners.Remove(id)
 
 	// Call exit callback if set and this is a multi-agent task
 	if m.exitCallback != nil && jobID != "" && taskID != "" {
@@ -560,6 +681,8 @@ func (m *Manager) StopAll() {
 		if sess.Protocol != nil {
 			sess.Protocol.Disconnect()
 		}
+		sess.Limits.Close()
+		m.owners.Remove(sess.ID)
 	}
 	m.sessions = make(map[string]*Session)
 }
@@ -592,13 +715,29 @@ func (s *Session) GetMultiAgentMetadata() (jobID, taskID string, startedAt time.
 	return s.JobID, s.TaskID, s.StartedAt
 }
 
+// Resize resizes the session's terminal; sessions without one ignore it
 func (s *Session) Resize(cols, rows int) error {
-	// Resize is handled by the protocol adapter
-	// For now, we don't expose this in the protocol interface
-	// TODO: Add resize support to protocol.Adapter interface if needed
+	if s.Protocol == nil || cols <= 0 || rows <= 0 {
+		return nil
+	}
+	if t, ok := s.Protocol.GetAdapter().(protocol.Terminal); ok {
+		return t.Resize(cols, rows)
+	}
 	return nil
 }
 
+// WriteRaw sends keystrokes to the session's terminal as they are
+func (s *Session) WriteRaw(data []byte) error {
+	if s.Protocol == nil {
+		return fmt.Errorf("session %s is not connected", s.ID)
+	}
+	t, ok := s.Protocol.GetAdapter().(protocol.Terminal)
+	if !ok {
+		return fmt.Errorf("session %s has no terminal (protocol %s)", s.ID, s.GetProtocolName())
+	}
+	return t.WriteRaw(data)
+}
+
 func (m *Manager) Resize(id string, cols, rows int) error {
 	m.mu.RLock()
 	sess, ok := m.sessions[id]
@@ -621,7 +760,7 @@ func (s *Session) GetProtocolName() string {
 type FallbackConfig struct {
 	CLIType  string
 	Fallback string
-	OnError  string // "rate_limit", "timeout", "any"
+	OnError  string // "rate_limit", "auth", "timeout", "crash", "any" or a comma-separated list
 }
 
 // GetFallbackCLI returns the fallback CLI type for a given CLI, or empty string if none
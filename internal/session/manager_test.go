@@ -0,0 +1,29 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/open-agents/bridge/internal/policy"
+)
+
+func TestCreateRefusedByPolicy(t *testing.T) {
+	m := NewManager()
+	m.SetPolicy(policy.New(map[string]bool{"codex": false}, &policy.Config{
+		AllowedPermissionModes: []string{"default"},
+	}))
+
+	for _, opts := range []CreateOptions{
+		{CLIType: "codex", WorkDir: t.TempDir()},
+		{CLIType: "claude", WorkDir: t.TempDir(), PermissionMode: "accept-all"},
+	} {
+		_, err := m.CreateWithOptions(opts)
+		var v *policy.Violation
+		if !errors.As(err, &v) {
+			t.Errorf("%+v: err = %v, want a policy violation", opts, err)
+		}
+	}
+	if n := len(m.List()); n != 0 {
+		t.Errorf("%d sessions created despite the policy", n)
+	}
+}
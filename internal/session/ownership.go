@@ -0,0 +1,135 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// Client kinds that can control a session
+const (
+	ClientWeb   = "web"   // browser connected through the server
+	ClientLocal = "local" // terminal attached on this machine
+)
+
+// Controller is the client currently holding input rights on a session
+type Controller struct {
+	ClientID string    `json:"clientId"`
+	Kind     string    `json:"kind"`
+	Since    time.Time `json:"since"`
+}
+
+// Ownership tracks the controller of each session. Only the controller may
+// send input; every other client attached to the session is a read-only viewer.
+type Ownership struct {
+	mu          sync.Mutex
+	controllers map[string]*Controller // sessionID -> controller
+	ended       map[string]Controller  // sessionID -> last controller of an ended session
+}
+
+// NewOwnership creates an empty ownership table
+func NewOwnership() *Ownership {
+	return &Ownership{controllers: make(map[string]*Controller), ended: make(map[string]Controller)}
+}
+
+// Controller returns the current controller of a session, or nil
+func (o *Ownership) Controller(sessionID string) *Controller {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if c, ok := o.controllers[sessionID]; ok {
+		cp := *c
+		return &cp
+	}
+	return nil
+}
+
+// Claim checks that clientID may send input to a session. A session without
+// a controller is claimed implicitly. It returns the controller after the
+// call, whether clientID holds control, and whether control was just claimed.
+// An empty clientID is never given control.
+func (o *Ownership) Claim(sessionID, clientID, kind string) (ctrl Controller, allowed, claimed bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if c, ok := o.controllers[sessionID]; ok {
+		return *c, clientID != "" && c.ClientID == clientID, false
+	}
+	if clientID == "" {
+		return Controller{}, false, false
+	}
+	c := &Controller{ClientID: clientID, Kind: kind, Since: time.Now()}
+	o.controllers[sessionID] = c
+	return *c, true, true
+}
+
+// Takeover gives control to clientID, returning the previous controller (or nil)
+func (o *Ownership) Takeover(sessionID, clientID, kind string) (ctrl Controller, previous *Controller) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if prev, ok := o.controllers[sessionID]; ok {
+		if prev.ClientID == clientID {
+			return *prev, nil
+		}
+		previous = prev
+	}
+	c := &Controller{ClientID: clientID, Kind: kind, Since: time.Now()}
+	o.controllers[sessionID] = c
+	return *c, previous
+}
+
+// Release gives up control; only the controller itself can release
+func (o *Ownership) Release(sessionID, clientID string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if c, ok := o.controllers[sessionID]; ok && c.ClientID == clientID {
+		delete(o.controllers, sessionID)
+		return true
+	}
+	return false
+}
+
+// ReleaseClient drops every session controlled by a client (e.g. when a local
+// terminal detaches) and returns the affected session IDs
+func (o *Ownership) ReleaseClient(clientID string) []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var released []string
+	for id, c := range o.controllers {
+		if c.ClientID == clientID {
+			delete(o.controllers, id)
+			released = append(released, id)
+		}
+	}
+	return released
+}
+
+// Remove forgets a session's controller (the session has ended), keeping
+// it as the one allowed to act on what the session left behind
+func (o *Ownership) Remove(sessionID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if c, ok := o.controllers[sessionID]; ok {
+		o.ended[sessionID] = *c
+	}
+	delete(o.controllers, sessionID)
+}
+
+// ClaimEnded is Claim for a session that has ended: only its last
+// controller may act on it, and one that never had a controller is claimed
+// implicitly. It returns that controller and whether clientID is it.
+func (o *Ownership) ClaimEnded(sessionID, clientID, kind string) (ctrl Controller, allowed bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if c, ok := o.ended[sessionID]; ok {
+		return c, clientID != "" && c.ClientID == clientID
+	}
+	if clientID == "" {
+		return Controller{}, false
+	}
+	c := Controller{ClientID: clientID, Kind: kind, Since: time.Now()}
+	o.ended[sessionID] = c
+	return c, true
+}
@@ -0,0 +1,90 @@
+package session
+
+import "testing"
+
+func TestOwnershipClaimAndTakeover(t *testing.T) {
+	o := NewOwnership()
+
+	ctrl, allowed, claimed := o.Claim("s1", "alice", ClientWeb)
+	if !allowed || !claimed || ctrl.ClientID != "alice" {
+		t.Fatalf("first input should claim control, got %+v allowed=%v claimed=%v", ctrl, allowed, claimed)
+	}
+	if _, allowed, claimed := o.Claim("s1", "alice", ClientWeb); !allowed || claimed {
+		t.Error("controller should keep control without re-claiming")
+	}
+	if ctrl, allowed, _ := o.Claim("s1", "bob", ClientWeb); allowed || ctrl.ClientID != "alice" {
+		t.Errorf("viewer input should be rejected, got allowed=%v controller=%s", allowed, ctrl.ClientID)
+	}
+
+	ctrl, previous := o.Takeover("s1", "bob", ClientLocal)
+	if ctrl.ClientID != "bob" || ctrl.Kind != ClientLocal || previous == nil || previous.ClientID != "alice" {
+		t.Errorf("takeover: got %+v, previous %+v", ctrl, previous)
+	}
+	if _, allowed, _ := o.Claim("s1", "alice", ClientWeb); allowed {
+		t.Error("previous controller should now be a viewer")
+	}
+}
+
+func TestOwnershipRelease(t *testing.T) {
+	o := NewOwnership()
+	o.Claim("s1", "alice", ClientWeb)
+	o.Claim("s2", "alice", ClientWeb)
+	o.Claim("s3", "bob", ClientWeb)
+
+	if o.Release("s1", "bob") {
+		t.Error("only the controller may release")
+	}
+	if !o.Release("s1", "alice") || o.Controller("s1") != nil {
+		t.Error("controller release should clear control")
+	}
+	if _, allowed, claimed := o.Claim("s1", "bob", ClientWeb); !allowed || !claimed {
+		t.Error("released session should be claimable")
+	}
+
+	released := o.ReleaseClient("alice")
+	if len(released) != 1 || released[0] != "s2" {
+		t.Errorf("ReleaseClient = %v, want [s2]", released)
+	}
+	o.Remove("s3")
+	if o.Controller("s3") != nil {
+		t.Error("removed session should have no controller")
+	}
+}
+
+func TestOwnershipRejectsAnonymousClients(t *testing.T) {
+	o := NewOwnership()
+	if _, allowed, claimed := o.Claim("s1", "", ClientWeb); allowed || claimed || o.Controller("s1") != nil {
+		t.Error("a client without an id should not claim control")
+	}
+	o.Claim("s1", "alice", ClientWeb)
+	if _, allowed, _ := o.Claim("s1", "", ClientWeb); allowed {
+		t.Error("a client without an id should not pass for the controller")
+	}
+}
+
+func TestOwnershipClaimEnded(t *testing.T) {
+	o := NewOwnership()
+	o.Claim("s1", "alice", ClientWeb)
+	o.Remove("s1")
+
+	if o.Controller("s1") != nil {
+		t.Error("an ended session has no live controller")
+	}
+	if ctrl, allowed := o.ClaimEnded("s1", "bob", ClientWeb); allowed || ctrl.ClientID != "alice" {
+		t.Errorf("another client acted on an ended session: allowed=%v controller=%s", allowed, ctrl.ClientID)
+	}
+	if _, allowed := o.ClaimEnded("s1", "alice", ClientWeb); !allowed {
+		t.Error("the last controller should keep control of the ended session")
+	}
+
+	// A session nobody controlled goes to the first client to act on it
+	if _, allowed := o.ClaimEnded("s2", "", ClientWeb); allowed {
+		t.Error("an empty client ID claimed an ended session")
+	}
+	if _, allowed := o.ClaimEnded("s2", "bob", ClientWeb); !allowed {
+		t.Error("first client should claim an ended session without a controller")
+	}
+	if _, allowed := o.ClaimEnded("s2", "alice", ClientWeb); allowed {
+		t.Error("ended session claimed twice")
+	}
+}
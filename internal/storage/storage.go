@@ -8,13 +8,23 @@ import (
 	"time"
 )
 
+// Message types beyond plain prompts and replies
+const (
+	TypeThought    = "thought"    // agent thinking (role assistant)
+	TypeToolCall   = "tool_call"  // tool invocation, updated in place (role tool)
+	TypePermission = "permission" // permission request and its decision (role system)
+	TypeUsage      = "usage"      // token usage report (role system)
+)
+
 // Message represents a chat message
 type Message struct {
-	ID        string    `json:"id"`
-	SessionID string    `json:"sessionId"`
-	Role      string    `json:"role"` // user, assistant, system
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
+	ID        string                 `json:"id"`
+	SessionID string                 `json:"sessionId"`
+	Role      string                 `json:"role"`           // user, assistant, tool, system
+	Type      string                 `json:"type,omitempty"` // "" for plain text, else one of the Type* constants
+	Content   string                 `json:"content"`
+	Meta      map[string]interface{} `json:"meta,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
 }
 
 // SessionHistory stores messages for a session
@@ -67,6 +77,32 @@ func (s *Store) CreateSession(sessionID, deviceID, cliType, workDir string) *Ses
 	return h
 }
 
+// ForkSession starts a new session history holding a copy of another session's
+// messages, e.g. when a conversation moves to a fallback CLI
+func (s *Store) ForkSession(srcID, sessionID, deviceID, cliType, workDir string) *SessionHistory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := &SessionHistory{
+		SessionID: sessionID,
+		DeviceID:  deviceID,
+		CLIType:   cliType,
+		WorkDir:   workDir,
+		Messages:  []Message{},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if src, ok := s.sessions[srcID]; ok {
+		for _, m := range src.Messages {
+			m.SessionID = sessionID
+			h.Messages = append(h.Messages, m)
+		}
+	}
+	s.sessions[sessionID] = h
+	s.save(sessionID)
+	return h
+}
+
 // AddMessage adds a message to a session
 func (s *Store) AddMessage(sessionID string, msg Message) {
 	s.mu.Lock()
@@ -91,6 +127,27 @@ func (s *Store) AddMessage(sessionID string, msg Message) {
 	s.save(sessionID)
 }
 
+// UpdateMessage applies update to the message with the given ID, returning
+// false if the session has no such message
+func (s *Store) UpdateMessage(sessionID, messageID string, update func(*Message)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.sessions[sessionID]
+	if !ok {
+		return false
+	}
+	for i := len(h.Messages) - 1; i >= 0; i-- {
+		if h.Messages[i].ID == messageID {
+			update(&h.Messages[i])
+			h.UpdatedAt = time.Now()
+			s.save(sessionID)
+			return true
+		}
+	}
+	return false
+}
+
 // GetSession returns a session by ID
 func (s *Store) GetSession(sessionID string) *SessionHistory {
 	s.mu.RLock()
@@ -0,0 +1,158 @@
+package transcript
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffLine is one line of a unified diff: ' ' context, '-' removed, '+' added, '@' hunk header
+type diffLine struct {
+	Op   byte
+	Text string
+}
+
+// Class returns the CSS class used for the line in HTML output
+func (l diffLine) Class() string {
+	switch l.Op {
+	case '+':
+		return "add"
+	case '-':
+		return "del"
+	case '@':
+		return "hunk"
+	default:
+		return "ctx"
+	}
+}
+
+// String renders the line as it appears in a unified diff
+func (l diffLine) String() string {
+	if l.Op == '@' {
+		return l.Text
+	}
+	return string(l.Op) + l.Text
+}
+
+const (
+	diffContext = 3
+	// Above this many line pairs the LCS table gets too big; show a full replacement instead
+	maxDiffCells = 4_000_000
+)
+
+// unifiedDiff computes a line diff between oldText and newText as unified diff hunks
+func unifiedDiff(oldText, newText string) []diffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	var ops []diffLine
+	if len(a)*len(b) > maxDiffCells {
+		for _, l := range a {
+			ops = append(ops, diffLine{'-', l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffLine{'+', l})
+		}
+	} else {
+		ops = lcsDiff(a, b)
+	}
+	return hunks(ops)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// lcsDiff produces the edit script from a longest-common-subsequence table
+func lcsDiff(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffLine, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffLine{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffLine{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffLine{'+', b[j]})
+	}
+	return ops
+}
+
+// hunks trims unchanged lines to diffContext around each change and adds @@ headers
+func hunks(ops []diffLine) []diffLine {
+	var out []diffLine
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first].Op == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		// Extend the hunk while changes are close enough to share context
+		end := first
+		for k := first; k < len(ops); k++ {
+			if ops[k].Op != ' ' {
+				end = k + 1
+			} else if k-end >= 2*diffContext {
+				break
+			}
+		}
+
+		from := max(start, first-diffContext)
+		to := min(len(ops), end+diffContext)
+
+		// Line numbers at the start of the hunk
+		for k := start; k < from; k++ {
+			oldLine++
+			newLine++
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[from:to] {
+			if op.Op != '+' {
+				oldCount++
+			}
+			if op.Op != '-' {
+				newCount++
+			}
+		}
+		out = append(out, diffLine{'@', fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldLine, oldCount, newLine, newCount)})
+		out = append(out, ops[from:to]...)
+		oldLine += oldCount
+		newLine += newCount
+		start = to
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+package transcript
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// fence returns a code fence longer than any backtick run in text
+func fence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func codeBlock(lang, text string) string {
+	f := fence(text)
+	return f + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + f + "\n"
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func renderMarkdown(w io.Writer, doc *document) error {
+	var sb strings.Builder
+	h := doc.Session
+
+	fmt.Fprintf(&sb, "# Session %s\n\n", h.SessionID)
+	fmt.Fprintf(&sb, "- **CLI:** %s\n", h.CLIType)
+	if h.WorkDir != "" {
+		fmt.Fprintf(&sb, "- **Work dir:** `%s`\n", h.WorkDir)
+	}
+	fmt.Fprintf(&sb, "- **Started:** %s\n", formatTime(h.CreatedAt))
+	fmt.Fprintf(&sb, "- **Updated:** %s\n", formatTime(h.UpdatedAt))
+	if u := doc.Usage; u != nil {
+		fmt.Fprintf(&sb, "- **Tokens:** %d in / %d out", u.InputTokens, u.OutputTokens)
+		if u.CacheRead > 0 || u.CacheCreation > 0 {
+			fmt.Fprintf(&sb, " (cache: %d read, %d written)", u.CacheRead, u.CacheCreation)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+
+	for _, e := range doc.Entries {
+		switch e.Kind {
+		case kindPrompt:
+			fmt.Fprintf(&sb, "## User · %s\n\n%s\n\n", formatTime(e.Time), e.Text)
+		case kindReply:
+			fmt.Fprintf(&sb, "## Assistant · %s\n\n%s\n\n", formatTime(e.Time), e.Text)
+		case kindThought:
+			fmt.Fprintf(&sb, "<details>\n<summary>Thinking</summary>\n\n%s\n\n</details>\n\n", e.Text)
+		case kindTool:
+			fmt.Fprintf(&sb, "### Tool: %s", e.Title)
+			if e.Status != "" {
+				fmt.Fprintf(&sb, " (%s)", e.Status)
+			}
+			sb.WriteString("\n\n")
+			if e.Input != "" {
+				fmt.Fprintf(&sb, "<details>\n<summary>Input</summary>\n\n%s\n</details>\n\n", codeBlock("json", e.Input))
+			}
+			for _, d := range e.Diffs {
+				if d.Path != "" {
+					fmt.Fprintf(&sb, "`%s`\n\n", d.Path)
+				}
+				lines := make([]string, len(d.Lines))
+				for i, l := range d.Lines {
+					lines[i] = l.String()
+				}
+				sb.WriteString(codeBlock("diff", strings.Join(lines, "\n")))
+				sb.WriteString("\n")
+			}
+			if e.Text != "" {
+				sb.WriteString(codeBlock("", e.Text))
+				sb.WriteString("\n")
+			}
+		case kindPermission:
+			fmt.Fprintf(&sb, "> **Permission:** %s — %s", e.Title, e.Status)
+			if e.Detail != "" {
+				fmt.Fprintf(&sb, " %s", e.Detail)
+			}
+			sb.WriteString("\n\n")
+		case kindNote:
+			fmt.Fprintf(&sb, "> _%s_\n\n", e.Text)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time": formatTime,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Session {{.Session.SessionID}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #1f2328; }
+header { border-bottom: 1px solid #d0d7de; margin-bottom: 1.5em; }
+header dl { display: grid; grid-template-columns: max-content auto; gap: .25em 1em; }
+header dt { font-weight: 600; }
+.entry { margin: 1em 0; padding: .75em 1em; border-radius: 6px; }
+.prompt { background: #ddf4ff; }
+.reply { background: #f6f8fa; }
+.thought { background: #fff8c5; }
+.tool { border: 1px solid #d0d7de; }
+.permission, .note { color: #57606a; font-style: italic; }
+.meta { font-size: .8em; color: #57606a; margin-bottom: .5em; }
+.text { white-space: pre-wrap; }
+pre { background: #f6f8fa; padding: .5em; overflow-x: auto; }
+.diff span { display: block; }
+.diff .add { background: #dafbe1; }
+.diff .del { background: #ffebe9; }
+.diff .hunk { color: #8250df; }
+</style>
+</head>
+<body>
+<header>
+<h1>Session {{.Session.SessionID}}</h1>
+<dl>
+<dt>CLI</dt><dd>{{.Session.CLIType}}</dd>
+{{- if .Session.WorkDir}}<dt>Work dir</dt><dd><code>{{.Session.WorkDir}}</code></dd>{{end}}
+<dt>Started</dt><dd>{{time .Session.CreatedAt}}</dd>
+<dt>Updated</dt><dd>{{time .Session.UpdatedAt}}</dd>
+{{- with .Usage}}<dt>Tokens</dt><dd>{{.InputTokens}} in / {{.OutputTokens}} out{{if or .CacheRead .CacheCreation}} (cache: {{.CacheRead}} read, {{.CacheCreation}} written){{end}}</dd>{{end}}
+</dl>
+</header>
+{{range .Entries}}
+{{- if eq .Kind "prompt"}}<div class="entry prompt"><div class="meta">User · {{time .Time}}</div><div class="text">{{.Text}}</div></div>
+{{else if eq .Kind "reply"}}<div class="entry reply"><div class="meta">Assistant · {{time .Time}}</div><div class="text">{{.Text}}</div></div>
+{{else if eq .Kind "thought"}}<details class="entry thought"><summary>Thinking</summary><div class="text">{{.Text}}</div></details>
+{{else if eq .Kind "tool"}}<div class="entry tool"><div class="meta">Tool: <strong>{{.Title}}</strong>{{if .Status}} ({{.Status}}){{end}}</div>
+{{- if .Input}}<details><summary>Input</summary><pre>{{.Input}}</pre></details>{{end}}
+{{- range .Diffs}}{{if .Path}}<div><code>{{.Path}}</code></div>{{end}}<pre class="diff">{{range .Lines}}<span class="{{.Class}}">{{.String}}</span>{{end}}</pre>{{end}}
+{{- if .Text}}<pre>{{.Text}}</pre>{{end}}</div>
+{{else if eq .Kind "permission"}}<div class="entry permission">Permission: {{.Title}} — {{.Status}}{{if .Detail}} {{.Detail}}{{end}}</div>
+{{else if eq .Kind "note"}}<div class="entry note">{{.Text}}</div>
+{{end}}
+{{- end}}
+<footer class="meta">Exported {{time .Generated}}</footer>
+</body>
+</html>
+`))
+
+func renderHTML(w io.Writer, doc *document) error {
+	return htmlTemplate.Execute(w, doc)
+}
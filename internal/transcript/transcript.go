@@ -0,0 +1,278 @@
+// Package transcript renders stored session history as Markdown, HTML or JSONL.
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/open-agents/bridge/internal/storage"
+)
+
+// Supported export formats
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+	FormatJSONL    = "jsonl"
+)
+
+// Options controls what goes into a transcript
+type Options struct {
+	Thoughts bool // include agent thinking
+}
+
+// ParseFormat normalizes a user-supplied format name
+func ParseFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "", "md", "markdown":
+		return FormatMarkdown, nil
+	case "html", "htm":
+		return FormatHTML, nil
+	case "jsonl", "ndjson":
+		return FormatJSONL, nil
+	}
+	return "", fmt.Errorf("unsupported format %q (want md, html or jsonl)", format)
+}
+
+// MimeType returns the content type of a format
+func MimeType(format string) string {
+	switch format {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	default:
+		return "text/markdown; charset=utf-8"
+	}
+}
+
+// Filename suggests a file name for an exported session
+func Filename(h *storage.SessionHistory, format string) string {
+	id := h.SessionID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return fmt.Sprintf("session-%s.%s", id, format)
+}
+
+// Render writes the transcript of h in the given format
+func Render(w io.Writer, h *storage.SessionHistory, format string, opts Options) error {
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdown(w, build(h, opts))
+	case FormatHTML:
+		return renderHTML(w, build(h, opts))
+	case FormatJSONL:
+		return renderJSONL(w, h, opts)
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+// Entry kinds of the rendered transcript
+const (
+	kindPrompt     = "prompt"
+	kindReply      = "reply"
+	kindThought    = "thought"
+	kindTool       = "tool"
+	kindPermission = "permission"
+	kindNote       = "note"
+)
+
+type document struct {
+	Session   *storage.SessionHistory
+	Entries   []entry
+	Usage     *usage
+	Generated time.Time
+}
+
+type entry struct {
+	Kind   string
+	Time   time.Time
+	Title  string // tool or permission title
+	Text   string // prompt/reply/thought/note text, tool output
+	Status string // tool status or permission decision
+	Detail string // extra line, e.g. who decided a permission
+	Input  string // pretty-printed tool input
+	Diffs  []fileDiff
+}
+
+type fileDiff struct {
+	Path  string
+	Lines []diffLine
+}
+
+type usage struct {
+	InputTokens   int
+	OutputTokens  int
+	CacheCreation int
+	CacheRead     int
+}
+
+// build turns stored messages into transcript entries
+func build(h *storage.SessionHistory, opts Options) *document {
+	doc := &document{Session: h, Generated: time.Now()}
+	for _, m := range h.Messages {
+		switch {
+		case m.Role == "user" && m.Type == "":
+			doc.Entries = append(doc.Entries, entry{Kind: kindPrompt, Time: m.Timestamp, Text: m.Content})
+		case m.Role == "assistant" && m.Type == "":
+			doc.Entries = append(doc.Entries, entry{Kind: kindReply, Time: m.Timestamp, Text: m.Content})
+		case m.Type == storage.TypeThought:
+			if opts.Thoughts {
+				doc.Entries = append(doc.Entries, entry{Kind: kindThought, Time: m.Timestamp, Text: m.Content})
+			}
+		case m.Type == storage.TypeToolCall:
+			doc.Entries = append(doc.Entries, toolEntry(m))
+		case m.Type == storage.TypePermission:
+			doc.Entries = append(doc.Entries, permissionEntry(m))
+		case m.Type == storage.TypeUsage:
+			// Agents report cumulative usage, so the latest report is the total
+			doc.Usage = &usage{
+				InputTokens:   metaInt(m.Meta, "inputTokens"),
+				OutputTokens:  metaInt(m.Meta, "outputTokens"),
+				CacheCreation: metaInt(m.Meta, "cacheCreation"),
+				CacheRead:     metaInt(m.Meta, "cacheRead"),
+			}
+		case m.Role == "system":
+			doc.Entries = append(doc.Entries, entry{Kind: kindNote, Time: m.Timestamp, Text: m.Content})
+		}
+	}
+	return doc
+}
+
+func toolEntry(m storage.Message) entry {
+	e := entry{Kind: kindTool, Time: m.Timestamp, Title: m.Content}
+	e.Status, _ = m.Meta["status"].(string)
+	if e.Title == "" {
+		e.Title = "Tool call"
+	}
+
+	input, _ := m.Meta["input"].(map[string]interface{})
+	if len(input) > 0 {
+		data, _ := json.MarshalIndent(input, "", "  ")
+		e.Input = string(data)
+	}
+
+	// ACP content: diffs and text output
+	var output []string
+	if content, ok := m.Meta["content"].([]interface{}); ok {
+		for _, c := range content {
+			item, _ := c.(map[string]interface{})
+			switch item["type"] {
+			case "diff":
+				path, _ := item["path"].(string)
+				oldText, _ := item["oldText"].(string)
+				newText, _ := item["newText"].(string)
+				e.Diffs = append(e.Diffs, fileDiff{Path: path, Lines: unifiedDiff(oldText, newText)})
+			case "content":
+				if block, ok := item["content"].(map[string]interface{}); ok {
+					if text, _ := block["text"].(string); text != "" {
+						output = append(output, text)
+					}
+				}
+			}
+		}
+	}
+
+	// Edit tools that only report their input (old_string/new_string)
+	if len(e.Diffs) == 0 {
+		oldText, hasOld := input["old_string"].(string)
+		newText, hasNew := input["new_string"].(string)
+		if hasOld && hasNew {
+			path, _ := input["file_path"].(string)
+			e.Diffs = append(e.Diffs, fileDiff{Path: path, Lines: unifiedDiff(oldText, newText)})
+		}
+	}
+
+	if len(output) == 0 {
+		if s, ok := m.Meta["result"].(string); ok && s != "" {
+			output = append(output, s)
+		}
+	}
+	e.Text = strings.Join(output, "\n")
+	return e
+}
+
+func permissionEntry(m storage.Message) entry {
+	e := entry{Kind: kindPermission, Time: m.Timestamp, Title: m.Content}
+	e.Status, _ = m.Meta["status"].(string)
+	if option, _ := m.Meta["optionId"].(string); option != "" {
+		e.Status += " (" + option + ")"
+	}
+	if by, _ := m.Meta["decidedBy"].(string); by != "" {
+		e.Detail = "by " + by
+	}
+	if e.Title == "" {
+		e.Title, _ = m.Meta["description"].(string)
+	}
+	return e
+}
+
+func metaInt(meta map[string]interface{}, key string) int {
+	switch v := meta[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// jsonlRecord tags each JSONL line as session metadata or a message
+type jsonlRecord struct {
+	Kind string `json:"kind"`
+	storage.Message
+}
+
+func renderJSONL(w io.Writer, h *storage.SessionHistory, opts Options) error {
+	enc := json.NewEncoder(w)
+	header := map[string]interface{}{
+		"kind":      "session",
+		"sessionId": h.SessionID,
+		"deviceId":  h.DeviceID,
+		"cliType":   h.CLIType,
+		"workDir":   h.WorkDir,
+		"createdAt": h.CreatedAt,
+		"updatedAt": h.UpdatedAt,
+	}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	for _, m := range h.Messages {
+		if m.Type == storage.TypeThought && !opts.Thoughts {
+			continue
+		}
+		if err := enc.Encode(jsonlRecord{Kind: "message", Message: m}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find looks a session up by ID or unique ID prefix
+func Find(sessions []*storage.SessionHistory, id string) (*storage.SessionHistory, error) {
+	var matches []*storage.SessionHistory
+	for _, h := range sessions {
+		if h.SessionID == id {
+			return h, nil
+		}
+		if strings.HasPrefix(h.SessionID, id) {
+			matches = append(matches, h)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("session %s not found", id)
+	case 1:
+		return matches[0], nil
+	}
+	ids := make([]string, len(matches))
+	for i, h := range matches {
+		ids[i] = h.SessionID
+	}
+	sort.Strings(ids)
+	return nil, fmt.Errorf("session id %s is ambiguous: %s", id, strings.Join(ids, ", "))
+}
@@ -0,0 +1,160 @@
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-agents/bridge/internal/storage"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	newText := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\n"
+
+	var got []string
+	for _, l := range unifiedDiff(oldText, newText) {
+		got = append(got, l.String())
+	}
+	want := []string{
+		"@@ -1,10 +1,11 @@",
+		" a", " b", " c", "-d", "+D", " e", " f", " g", " h", " i", " j", "+k",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("diff =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestUnifiedDiffSplitsDistantHunks(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 0; i < 30; i++ {
+		line := string(rune('a' + i%26))
+		oldLines = append(oldLines, line)
+		newLines = append(newLines, line)
+	}
+	newLines[2] = "X"
+	newLines[25] = "Y"
+
+	var headers []string
+	for _, l := range unifiedDiff(strings.Join(oldLines, "\n"), strings.Join(newLines, "\n")) {
+		if l.Op == '@' {
+			headers = append(headers, l.Text)
+		}
+	}
+	want := []string{"@@ -1,6 +1,6 @@", "@@ -23,7 +23,7 @@"}
+	if strings.Join(headers, "|") != strings.Join(want, "|") {
+		t.Fatalf("headers = %v, want %v", headers, want)
+	}
+}
+
+func TestFence(t *testing.T) {
+	if f := fence("no ticks"); f != "```" {
+		t.Errorf("fence = %q", f)
+	}
+	if f := fence("has ```` four"); f != "`````" {
+		t.Errorf("fence = %q", f)
+	}
+}
+
+func testHistory() *storage.SessionHistory {
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	return &storage.SessionHistory{
+		SessionID: "abcdef123456",
+		CLIType:   "claude",
+		WorkDir:   "/work",
+		CreatedAt: ts,
+		UpdatedAt: ts,
+		Messages: []storage.Message{
+			{ID: "1", Role: "user", Content: "fix the typo", Timestamp: ts},
+			{ID: "2", Role: "assistant", Type: storage.TypeThought, Content: "looking at main.go", Timestamp: ts},
+			{ID: "tool:t1", Role: "tool", Type: storage.TypeToolCall, Content: "Edit main.go", Timestamp: ts, Meta: map[string]interface{}{
+				"status": "completed",
+				"content": []interface{}{
+					map[string]interface{}{"type": "diff", "path": "/work/main.go", "oldText": "helo\n", "newText": "hello\n"},
+				},
+			}},
+			{ID: "perm:1", Role: "system", Type: storage.TypePermission, Content: "Bash", Timestamp: ts, Meta: map[string]interface{}{
+				"status": "approved", "decidedBy": "web",
+			}},
+			{ID: "3", Role: "assistant", Content: "Fixed.", Timestamp: ts},
+			{ID: "4", Role: "system", Type: storage.TypeUsage, Timestamp: ts, Meta: map[string]interface{}{
+				"inputTokens": float64(120), "outputTokens": float64(40),
+			}},
+		},
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	var sb strings.Builder
+	if err := Render(&sb, testHistory(), FormatMarkdown, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	for _, want := range []string{
+		"# Session abcdef123456",
+		"- **Tokens:** 120 in / 40 out",
+		"fix the typo",
+		"### Tool: Edit main.go (completed)",
+		"-helo\n+hello",
+		"> **Permission:** Bash — approved by web",
+		"Fixed.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "looking at main.go") {
+		t.Error("thoughts rendered without Options.Thoughts")
+	}
+}
+
+func TestRenderHTMLEscapes(t *testing.T) {
+	h := testHistory()
+	h.Messages[0].Content = "<script>alert(1)</script>"
+	var sb strings.Builder
+	if err := Render(&sb, h, FormatHTML, Options{Thoughts: true}); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	if strings.Contains(out, "<script>alert") {
+		t.Error("prompt not escaped")
+	}
+	if !strings.Contains(out, `<span class="add">&#43;hello</span>`) || !strings.Contains(out, "looking at main.go") {
+		t.Errorf("unexpected html:\n%s", out)
+	}
+}
+
+func TestRenderJSONL(t *testing.T) {
+	var sb strings.Builder
+	if err := Render(&sb, testHistory(), FormatJSONL, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	scanner := bufio.NewScanner(strings.NewReader(sb.String()))
+	for scanner.Scan() {
+		var rec map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		kinds = append(kinds, rec["kind"].(string))
+	}
+	// Header plus every message except the thought
+	if len(kinds) != 6 || kinds[0] != "session" || kinds[1] != "message" {
+		t.Fatalf("kinds = %v", kinds)
+	}
+}
+
+func TestFind(t *testing.T) {
+	sessions := []*storage.SessionHistory{{SessionID: "abc123"}, {SessionID: "abd456"}}
+	if h, err := Find(sessions, "abc"); err != nil || h.SessionID != "abc123" {
+		t.Errorf("Find(abc) = %v, %v", h, err)
+	}
+	if _, err := Find(sessions, "ab"); err == nil {
+		t.Error("ambiguous prefix accepted")
+	}
+	if _, err := Find(sessions, "zzz"); err == nil {
+		t.Error("unknown id accepted")
+	}
+}
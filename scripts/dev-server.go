@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -22,6 +24,7 @@ type Client struct {
 	userID   string
 	clientType string // "web" or "bridge"
 	deviceID string
+	clientID string // stamped on what a web client sends, so the bridge can tell clients apart
 }
 
 type Hub struct {
@@ -35,7 +38,7 @@ func (h *Hub) register(c *Client) {
 	h.mu.Lock()
 	h.clients[c] = true
 	h.mu.Unlock()
-	log.Printf("[+] %s connected: user=%s device=%s", c.clientType, c.userID, c.deviceID)
+	log.Printf("[+] %s connected: user=%s device=%s client=%s", c.clientType, c.userID, c.deviceID, c.clientID)
 	h.broadcastStatus()
 }
 
@@ -98,6 +101,9 @@ func handleWS(w http.ResponseWriter, r *http.Request) {
 		clientType: clientType,
 		deviceID:   deviceID,
 	}
+	if clientType != "bridge" {
+		client.clientID = newClientID()
+	}
 
 	hub.register(client)
 	defer hub.unregister(client)
@@ -114,8 +120,40 @@ func handleWS(w http.ResponseWriter, r *http.Request) {
 			log.Printf("[%s->] %s: %s", client.clientType, data["type"], string(msg)[:min(100, len(msg))])
 		}
 
-		hub.forward(client, msg)
+		hub.forward(client, stampClient(client, msg))
+	}
+}
+
+// newClientID identifies one web connection
+func newClientID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "dev-" + hex.EncodeToString(b)
+}
+
+// stampClient sets the clientId of a message from a web client to its
+// connection's, replacing any the client sent, as the real server does
+func stampClient(c *Client, msg []byte) []byte {
+	if c.clientID == "" {
+		return msg
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(msg, &data); err != nil {
+		return msg
+	}
+	if data["payload"] == nil {
+		data["payload"] = map[string]interface{}{}
+	}
+	payload, ok := data["payload"].(map[string]interface{})
+	if !ok {
+		return msg
+	}
+	payload["clientId"] = c.clientID
+	stamped, err := json.Marshal(data)
+	if err != nil {
+		return msg
 	}
+	return stamped
 }
 
 func handlePair(w http.ResponseWriter, r *http.Request) {